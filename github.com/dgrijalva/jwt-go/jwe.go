@@ -0,0 +1,151 @@
+package jwt
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"hash"
+	"strings"
+)
+
+// JWE key management algorithms
+const (
+	JWEAlgRSAOAEP    = "RSA-OAEP"
+	JWEAlgRSAOAEP256 = "RSA-OAEP-256"
+)
+
+// JWE content encryption algorithms
+const (
+	JWEEncA256GCM = "A256GCM"
+)
+
+var (
+	ErrJWEMalformed          = errors.New("JWE is malformed")
+	ErrJWEUnsupportedAlg     = errors.New("JWE algorithm is not supported")
+	ErrJWEDecryptionFailed   = errors.New("JWE decryption failed")
+	ErrJWEHeaderNotPermitted = errors.New("JWE header cannot override alg or enc")
+)
+
+// EncryptJWE encrypts plaintext for key and returns a JWE in compact serialization
+// (RFC 7516). The content is encrypted with A256GCM using a random key, which is
+// encrypted with RSA-OAEP or RSA-OAEP-256 depending on alg.
+//
+// Extra header parameters can be set with header, e.g. {"cty": "JWT", "kid": "..."}
+// to encrypt a signed token.
+func EncryptJWE(plaintext []byte, alg string, key *rsa.PublicKey, header map[string]interface{}) (string, error) {
+	h, err := jweHash(alg)
+	if err != nil {
+		return "", err
+	}
+	hdr := map[string]interface{}{}
+	for k, v := range header {
+		if k == "alg" || k == "enc" {
+			return "", ErrJWEHeaderNotPermitted
+		}
+		hdr[k] = v
+	}
+	hdr["alg"] = alg
+	hdr["enc"] = JWEEncA256GCM
+	hdrJSON, err := json.Marshal(hdr)
+	if err != nil {
+		return "", err
+	}
+	protected := EncodeSegment(hdrJSON)
+
+	// Content encryption key
+	cek := make([]byte, 32)
+	if _, err = rand.Read(cek); err != nil {
+		return "", err
+	}
+	encKey, err := rsa.EncryptOAEP(h, rand.Reader, key, cek, nil)
+	if err != nil {
+		return "", err
+	}
+	gcm, err := newGCM(cek)
+	if err != nil {
+		return "", err
+	}
+	iv := make([]byte, gcm.NonceSize())
+	if _, err = rand.Read(iv); err != nil {
+		return "", err
+	}
+	sealed := gcm.Seal(nil, iv, plaintext, []byte(protected))
+	ciphertext, tag := sealed[:len(sealed)-gcm.Overhead()], sealed[len(sealed)-gcm.Overhead():]
+
+	return strings.Join([]string{
+		protected,
+		EncodeSegment(encKey),
+		EncodeSegment(iv),
+		EncodeSegment(ciphertext),
+		EncodeSegment(tag),
+	}, "."), nil
+}
+
+// DecryptJWE decrypts a JWE in compact serialization with key and returns the
+// plaintext and the protected header.
+func DecryptJWE(jwe string, key *rsa.PrivateKey) ([]byte, map[string]interface{}, error) {
+	parts := strings.Split(jwe, ".")
+	if len(parts) != 5 {
+		return nil, nil, ErrJWEMalformed
+	}
+	segs := make([][]byte, 5)
+	for i, p := range parts {
+		b, err := DecodeSegment(p)
+		if err != nil {
+			return nil, nil, ErrJWEMalformed
+		}
+		segs[i] = b
+	}
+	var hdr map[string]interface{}
+	if err := json.Unmarshal(segs[0], &hdr); err != nil {
+		return nil, nil, ErrJWEMalformed
+	}
+	alg, _ := hdr["alg"].(string)
+	h, err := jweHash(alg)
+	if err != nil {
+		return nil, nil, err
+	}
+	if enc, _ := hdr["enc"].(string); enc != JWEEncA256GCM {
+		return nil, nil, ErrJWEUnsupportedAlg
+	}
+	cek, err := rsa.DecryptOAEP(h, nil, key, segs[1], nil)
+	if err != nil || len(cek) != 32 {
+		return nil, nil, ErrJWEDecryptionFailed
+	}
+	gcm, err := newGCM(cek)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(segs[2]) != gcm.NonceSize() || len(segs[4]) != gcm.Overhead() {
+		return nil, nil, ErrJWEMalformed
+	}
+	sealed := append(segs[3], segs[4]...)
+	plaintext, err := gcm.Open(nil, segs[2], sealed, []byte(parts[0]))
+	if err != nil {
+		return nil, nil, ErrJWEDecryptionFailed
+	}
+	return plaintext, hdr, nil
+}
+
+func jweHash(alg string) (hash.Hash, error) {
+	switch alg {
+	case JWEAlgRSAOAEP:
+		return sha1.New(), nil
+	case JWEAlgRSAOAEP256:
+		return sha256.New(), nil
+	}
+	return nil, ErrJWEUnsupportedAlg
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,75 @@
+package jwt_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/dgrijalva/jwt-go/test"
+)
+
+func TestJWE(t *testing.T) {
+	priv := test.LoadRSAPrivateKeyFromDisk("test/sample_key")
+	pub := test.LoadRSAPublicKeyFromDisk("test/sample_key.pub")
+	signed := test.MakeSampleToken(jwt.MapClaims{"email": "john@example.com"}, priv)
+
+	for _, alg := range []string{jwt.JWEAlgRSAOAEP, jwt.JWEAlgRSAOAEP256} {
+		jwe, err := jwt.EncryptJWE([]byte(signed), alg, pub, map[string]interface{}{"cty": "JWT"})
+		if err != nil {
+			t.Errorf("[%v] Error encrypting: %v", alg, err)
+			continue
+		}
+		if parts := strings.Split(jwe, "."); len(parts) != 5 {
+			t.Errorf("[%v] Expected 5 segments, got %d", alg, len(parts))
+		}
+		if strings.Contains(jwe, "john") {
+			t.Errorf("[%v] Plaintext leaked", alg)
+		}
+		plaintext, header, err := jwt.DecryptJWE(jwe, priv)
+		if err != nil {
+			t.Errorf("[%v] Error decrypting: %v", alg, err)
+			continue
+		}
+		if string(plaintext) != signed {
+			t.Errorf("[%v] Plaintext mismatch", alg)
+		}
+		if header["alg"] != alg || header["enc"] != jwt.JWEEncA256GCM || header["cty"] != "JWT" {
+			t.Errorf("[%v] Unexpected header: %v", alg, header)
+		}
+
+		// The nested token verifies
+		if _, err = jwt.Parse(string(plaintext), func(*jwt.Token) (interface{}, error) { return pub, nil }); err != nil {
+			t.Errorf("[%v] Error verifying nested token: %v", alg, err)
+		}
+	}
+}
+
+func TestJWEInvalid(t *testing.T) {
+	priv := test.LoadRSAPrivateKeyFromDisk("test/sample_key")
+	jwe, err := jwt.EncryptJWE([]byte("secret"), jwt.JWEAlgRSAOAEP256, &priv.PublicKey, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parts := strings.Split(jwe, ".")
+
+	// Tampering with the header must fail authentication
+	tampered := append([]string{}, parts...)
+	tampered[0] = jwt.EncodeSegment([]byte(`{"alg":"RSA-OAEP-256","enc":"A256GCM","kid":"x"}`))
+	if _, _, err = jwt.DecryptJWE(strings.Join(tampered, "."), priv); err != jwt.ErrJWEDecryptionFailed {
+		t.Errorf("Expected decryption error for tampered header, got %v", err)
+	}
+	tampered = append([]string{}, parts...)
+	tampered[3] = jwt.EncodeSegment([]byte("tampered"))
+	if _, _, err = jwt.DecryptJWE(strings.Join(tampered, "."), priv); err != jwt.ErrJWEDecryptionFailed {
+		t.Errorf("Expected decryption error for tampered ciphertext, got %v", err)
+	}
+	if _, _, err = jwt.DecryptJWE(strings.Join(parts[:4], "."), priv); err != jwt.ErrJWEMalformed {
+		t.Errorf("Expected malformed error, got %v", err)
+	}
+	if _, err = jwt.EncryptJWE(nil, "RSA1_5", &priv.PublicKey, nil); err != jwt.ErrJWEUnsupportedAlg {
+		t.Errorf("Expected unsupported algorithm error, got %v", err)
+	}
+	if _, err = jwt.EncryptJWE(nil, jwt.JWEAlgRSAOAEP, &priv.PublicKey, map[string]interface{}{"enc": "A128GCM"}); err != jwt.ErrJWEHeaderNotPermitted {
+		t.Errorf("Expected header error, got %v", err)
+	}
+}
@@ -0,0 +1,327 @@
+package jwt
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/json"
+	"errors"
+	"math/big"
+)
+
+var (
+	ErrInvalidJWK     = errors.New("Invalid JWK")
+	ErrJWKNotFound    = errors.New("JWK not found")
+	ErrUnsupportedJWK = errors.New("Unsupported JWK key type or curve")
+)
+
+// JWK is a JSON Web Key as defined by RFC 7517.
+// Key holds one of *rsa.PublicKey, *rsa.PrivateKey, *ecdsa.PublicKey,
+// *ecdsa.PrivateKey or []byte for symmetric (HMAC) keys.
+type JWK struct {
+	Key       interface{}
+	KeyID     string
+	Algorithm string
+	Use       string
+}
+
+// JWKS is a JSON Web Key Set as defined by RFC 7517.
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// jwkJSON is the JSON representation of a JWK
+type jwkJSON struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid,omitempty"`
+	Use string `json:"use,omitempty"`
+	Alg string `json:"alg,omitempty"`
+	// EC
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	Y   string `json:"y,omitempty"`
+	// RSA
+	N  string `json:"n,omitempty"`
+	E  string `json:"e,omitempty"`
+	P  string `json:"p,omitempty"`
+	Q  string `json:"q,omitempty"`
+	Dp string `json:"dp,omitempty"`
+	Dq string `json:"dq,omitempty"`
+	Qi string `json:"qi,omitempty"`
+	// RSA and EC private exponent
+	D string `json:"d,omitempty"`
+	// Symmetric
+	K string `json:"k,omitempty"`
+}
+
+// Public returns a copy of the JWK holding only the public part of the key.
+// Symmetric keys are returned as is.
+func (k JWK) Public() JWK {
+	switch key := k.Key.(type) {
+	case *rsa.PrivateKey:
+		k.Key = &key.PublicKey
+	case *ecdsa.PrivateKey:
+		k.Key = &key.PublicKey
+	}
+	return k
+}
+
+// MarshalJSON implements json.Marshaler
+func (k JWK) MarshalJSON() ([]byte, error) {
+	j, err := k.toJSON()
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(j)
+}
+
+func (k JWK) toJSON() (*jwkJSON, error) {
+	j := &jwkJSON{Kid: k.KeyID, Use: k.Use, Alg: k.Algorithm}
+	switch key := k.Key.(type) {
+	case *rsa.PublicKey:
+		j.Kty = "RSA"
+		j.N = encodeInt(key.N, 0)
+		j.E = encodeInt(big.NewInt(int64(key.E)), 0)
+	case *rsa.PrivateKey:
+		if len(key.Primes) != 2 {
+			return nil, ErrUnsupportedJWK
+		}
+		key.Precompute()
+		j.Kty = "RSA"
+		j.N = encodeInt(key.N, 0)
+		j.E = encodeInt(big.NewInt(int64(key.E)), 0)
+		j.D = encodeInt(key.D, 0)
+		j.P = encodeInt(key.Primes[0], 0)
+		j.Q = encodeInt(key.Primes[1], 0)
+		j.Dp = encodeInt(key.Precomputed.Dp, 0)
+		j.Dq = encodeInt(key.Precomputed.Dq, 0)
+		j.Qi = encodeInt(key.Precomputed.Qinv, 0)
+	case *ecdsa.PublicKey:
+		if err := j.setEC(key); err != nil {
+			return nil, err
+		}
+	case *ecdsa.PrivateKey:
+		if err := j.setEC(&key.PublicKey); err != nil {
+			return nil, err
+		}
+		j.D = encodeInt(key.D, curveSize(key.Curve))
+	case []byte:
+		j.Kty = "oct"
+		j.K = EncodeSegment(key)
+	default:
+		return nil, ErrInvalidKeyType
+	}
+	return j, nil
+}
+
+func (j *jwkJSON) setEC(key *ecdsa.PublicKey) error {
+	crv := curveName(key.Curve)
+	if crv == "" {
+		return ErrUnsupportedJWK
+	}
+	size := curveSize(key.Curve)
+	j.Kty = "EC"
+	j.Crv = crv
+	j.X = encodeInt(key.X, size)
+	j.Y = encodeInt(key.Y, size)
+	return nil
+}
+
+// UnmarshalJSON implements json.Unmarshaler
+func (k *JWK) UnmarshalJSON(b []byte) error {
+	var j jwkJSON
+	if err := json.Unmarshal(b, &j); err != nil {
+		return err
+	}
+	key, err := j.key()
+	if err != nil {
+		return err
+	}
+	*k = JWK{Key: key, KeyID: j.Kid, Algorithm: j.Alg, Use: j.Use}
+	return nil
+}
+
+func (j *jwkJSON) key() (interface{}, error) {
+	switch j.Kty {
+	case "RSA":
+		n, err := decodeInt(j.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeInt(j.E)
+		if err != nil {
+			return nil, err
+		}
+		pub := rsa.PublicKey{N: n, E: int(e.Int64())}
+		if j.D == "" {
+			return &pub, nil
+		}
+		priv := &rsa.PrivateKey{PublicKey: pub}
+		if priv.D, err = decodeInt(j.D); err != nil {
+			return nil, err
+		}
+		p, err := decodeInt(j.P)
+		if err != nil {
+			return nil, err
+		}
+		q, err := decodeInt(j.Q)
+		if err != nil {
+			return nil, err
+		}
+		priv.Primes = []*big.Int{p, q}
+		if err = priv.Validate(); err != nil {
+			return nil, err
+		}
+		priv.Precompute()
+		return priv, nil
+	case "EC":
+		curve := curveByName(j.Crv)
+		if curve == nil {
+			return nil, ErrUnsupportedJWK
+		}
+		x, err := decodeInt(j.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeInt(j.Y)
+		if err != nil {
+			return nil, err
+		}
+		if !curve.IsOnCurve(x, y) {
+			return nil, ErrInvalidJWK
+		}
+		pub := ecdsa.PublicKey{Curve: curve, X: x, Y: y}
+		if j.D == "" {
+			return &pub, nil
+		}
+		d, err := decodeInt(j.D)
+		if err != nil {
+			return nil, err
+		}
+		return &ecdsa.PrivateKey{PublicKey: pub, D: d}, nil
+	case "oct":
+		if j.K == "" {
+			return nil, ErrInvalidJWK
+		}
+		return DecodeSegment(j.K)
+	case "":
+		return nil, ErrInvalidJWK
+	}
+	return nil, ErrUnsupportedJWK
+}
+
+// Thumbprint computes the RFC 7638 thumbprint of the key using the hash h.
+// The thumbprint of a private key is the one of its public key.
+func (k JWK) Thumbprint(h crypto.Hash) ([]byte, error) {
+	if !h.Available() {
+		return nil, ErrHashUnavailable
+	}
+	j, err := k.Public().toJSON()
+	if err != nil {
+		return nil, err
+	}
+	// Required members only, in lexicographic order
+	var members interface{}
+	switch j.Kty {
+	case "RSA":
+		members = struct {
+			E   string `json:"e"`
+			Kty string `json:"kty"`
+			N   string `json:"n"`
+		}{j.E, j.Kty, j.N}
+	case "EC":
+		members = struct {
+			Crv string `json:"crv"`
+			Kty string `json:"kty"`
+			X   string `json:"x"`
+			Y   string `json:"y"`
+		}{j.Crv, j.Kty, j.X, j.Y}
+	case "oct":
+		members = struct {
+			K   string `json:"k"`
+			Kty string `json:"kty"`
+		}{j.K, j.Kty}
+	}
+	b, err := json.Marshal(members)
+	if err != nil {
+		return nil, err
+	}
+	hasher := h.New()
+	hasher.Write(b)
+	return hasher.Sum(nil), nil
+}
+
+// Key returns the key identified by kid
+func (s *JWKS) Key(kid string) (*JWK, error) {
+	for i := range s.Keys {
+		if s.Keys[i].KeyID == kid {
+			return &s.Keys[i], nil
+		}
+	}
+	return nil, ErrJWKNotFound
+}
+
+// Keyfunc is a Keyfunc returning the key matching the token's "kid" header.
+// Public keys are returned for asymmetric keys. If the JWK defines an algorithm,
+// it must match the token's signing method.
+func (s *JWKS) Keyfunc(token *Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	k, err := s.Key(kid)
+	if err != nil {
+		return nil, err
+	}
+	if k.Algorithm != "" && token.Method != nil && k.Algorithm != token.Method.Alg() {
+		return nil, ErrInvalidKeyType
+	}
+	return k.Public().Key, nil
+}
+
+func encodeInt(i *big.Int, size int) string {
+	b := i.Bytes()
+	if len(b) < size {
+		pad := make([]byte, size)
+		copy(pad[size-len(b):], b)
+		b = pad
+	}
+	return EncodeSegment(b)
+}
+
+func decodeInt(s string) (*big.Int, error) {
+	if s == "" {
+		return nil, ErrInvalidJWK
+	}
+	b, err := DecodeSegment(s)
+	if err != nil {
+		return nil, err
+	}
+	return new(big.Int).SetBytes(b), nil
+}
+
+func curveName(c elliptic.Curve) string {
+	switch c {
+	case elliptic.P256():
+		return "P-256"
+	case elliptic.P384():
+		return "P-384"
+	case elliptic.P521():
+		return "P-521"
+	}
+	return ""
+}
+
+func curveByName(name string) elliptic.Curve {
+	switch name {
+	case "P-256":
+		return elliptic.P256()
+	case "P-384":
+		return elliptic.P384()
+	case "P-521":
+		return elliptic.P521()
+	}
+	return nil
+}
+
+func curveSize(c elliptic.Curve) int {
+	return (c.Params().BitSize + 7) / 8
+}
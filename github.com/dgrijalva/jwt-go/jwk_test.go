@@ -0,0 +1,213 @@
+package jwt_test
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"encoding/json"
+	"io/ioutil"
+	"reflect"
+	"testing"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/dgrijalva/jwt-go/test"
+)
+
+// Example key from RFC 7638, section 3.1
+const rfc7638Key = `{"kty":"RSA","n":"0vx7agoebGcQSuuPiLJXZptN9nndrQmbXEps2aiAFbWhM78LhWx4cbbfAAtVT86zwu1RK7aPFFxuhDR1L6tSoc_BJECPebWKRXjBZCiFV4n3oknjhMstn64tZ_2W-5JsGY4Hc5n9yBXArwl93lqt7_RN5w6Cf0h4QyQ5v-65YGjQR0_FDW2QvzqY368QQMicAtaSqzs8KJZgnYb9c7d0zgdAZHzu6qMQvRL5hajrn1n91CbOpbISD08qNLyrdkt-bFTWhAI4vMQFh6WeZu0fM4lFd2NcRwr3XPksINHaQ-G_xBniIqbw0Ls1jF44-csFCur-kEgU8awapJzKnqDKgw","e":"AQAB","alg":"RS256","kid":"2011-04-29"}`
+
+var jwkTestData = []struct {
+	name string
+	key  func(t *testing.T) interface{}
+	kty  string
+}{
+	{
+		"RSA public",
+		func(t *testing.T) interface{} { return test.LoadRSAPublicKeyFromDisk("test/sample_key.pub") },
+		"RSA",
+	},
+	{
+		"RSA private",
+		func(t *testing.T) interface{} { return test.LoadRSAPrivateKeyFromDisk("test/sample_key") },
+		"RSA",
+	},
+	{
+		"EC256 private",
+		func(t *testing.T) interface{} { return loadECPrivateKey(t, "test/ec256-private.pem") },
+		"EC",
+	},
+	{
+		"EC384 private",
+		func(t *testing.T) interface{} { return loadECPrivateKey(t, "test/ec384-private.pem") },
+		"EC",
+	},
+	{
+		"EC512 public",
+		func(t *testing.T) interface{} { return &loadECPrivateKey(t, "test/ec512-private.pem").PublicKey },
+		"EC",
+	},
+	{
+		"HMAC",
+		func(t *testing.T) interface{} {
+			key, err := ioutil.ReadFile("test/hmacTestKey")
+			if err != nil {
+				t.Fatal(err)
+			}
+			return key
+		},
+		"oct",
+	},
+}
+
+func loadECPrivateKey(t *testing.T, path string) *ecdsa.PrivateKey {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := jwt.ParseECPrivateKeyFromPEM(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+func TestJWKRoundTrip(t *testing.T) {
+	for _, data := range jwkTestData {
+		key := data.key(t)
+		b, err := json.Marshal(jwt.JWK{Key: key, KeyID: "k1", Use: "sig"})
+		if err != nil {
+			t.Errorf("[%v] Error marshalling key: %v", data.name, err)
+			continue
+		}
+		var fields map[string]interface{}
+		json.Unmarshal(b, &fields)
+		if fields["kty"] != data.kty || fields["kid"] != "k1" || fields["use"] != "sig" {
+			t.Errorf("[%v] Unexpected JWK: %s", data.name, b)
+		}
+
+		var k jwt.JWK
+		if err = json.Unmarshal(b, &k); err != nil {
+			t.Errorf("[%v] Error unmarshalling key: %v", data.name, err)
+			continue
+		}
+		if k.KeyID != "k1" || k.Use != "sig" {
+			t.Errorf("[%v] Unexpected key parameters: %+v", data.name, k)
+		}
+		if rk, ok := k.Key.(*rsa.PrivateKey); ok {
+			// Only compare the key material, not precomputed values
+			orig := key.(*rsa.PrivateKey)
+			if rk.N.Cmp(orig.N) != 0 || rk.D.Cmp(orig.D) != 0 || rk.E != orig.E {
+				t.Errorf("[%v] RSA private key mismatch", data.name)
+			}
+			continue
+		}
+		if !reflect.DeepEqual(k.Key, key) {
+			t.Errorf("[%v] Key mismatch after round trip", data.name)
+		}
+	}
+}
+
+func TestJWKPublic(t *testing.T) {
+	priv := loadECPrivateKey(t, "test/ec256-private.pem")
+	b, _ := json.Marshal(jwt.JWK{Key: priv}.Public())
+	var fields map[string]interface{}
+	json.Unmarshal(b, &fields)
+	if _, ok := fields["d"]; ok {
+		t.Errorf("Public JWK contains the private exponent: %s", b)
+	}
+	rsaPriv := test.LoadRSAPrivateKeyFromDisk("test/sample_key")
+	if _, ok := (jwt.JWK{Key: rsaPriv}).Public().Key.(*rsa.PublicKey); !ok {
+		t.Errorf("Public did not return an RSA public key")
+	}
+}
+
+func TestJWKThumbprint(t *testing.T) {
+	var k jwt.JWK
+	if err := json.Unmarshal([]byte(rfc7638Key), &k); err != nil {
+		t.Fatal(err)
+	}
+	tp, err := k.Thumbprint(crypto.SHA256)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := jwt.EncodeSegment(tp); got != "NzbLsXh8uDCcd-6MNwXF4W_7noWXFZAfHkxZsRGC9Xs" {
+		t.Errorf("Unexpected thumbprint: %v", got)
+	}
+
+	// Private and public keys share their thumbprint
+	priv := loadECPrivateKey(t, "test/ec256-private.pem")
+	tp1, _ := jwt.JWK{Key: priv, KeyID: "a"}.Thumbprint(crypto.SHA256)
+	tp2, _ := jwt.JWK{Key: &priv.PublicKey, KeyID: "b"}.Thumbprint(crypto.SHA256)
+	if !reflect.DeepEqual(tp1, tp2) {
+		t.Errorf("Thumbprints of private and public keys differ")
+	}
+}
+
+func TestJWKInvalid(t *testing.T) {
+	for _, data := range []struct {
+		jwk string
+		err error
+	}{
+		{`{"n":"AQAB","e":"AQAB"}`, jwt.ErrInvalidJWK},
+		{`{"kty":"OKP","crv":"Ed25519","x":"AQ"}`, jwt.ErrUnsupportedJWK},
+		{`{"kty":"EC","crv":"P-192","x":"AQ","y":"AQ"}`, jwt.ErrUnsupportedJWK},
+		{`{"kty":"EC","crv":"P-256","x":"AQ","y":"AQ"}`, jwt.ErrInvalidJWK},
+		{`{"kty":"RSA","e":"AQAB"}`, jwt.ErrInvalidJWK},
+		{`{"kty":"oct"}`, jwt.ErrInvalidJWK},
+	} {
+		var k jwt.JWK
+		if err := json.Unmarshal([]byte(data.jwk), &k); err != data.err {
+			t.Errorf("Expected error %v for %v, got %v", data.err, data.jwk, err)
+		}
+	}
+	if _, err := json.Marshal(jwt.JWK{Key: "secret"}); err == nil {
+		t.Errorf("Expected error marshalling an unsupported key type")
+	}
+}
+
+func TestJWKSKeyfunc(t *testing.T) {
+	rsaPriv := test.LoadRSAPrivateKeyFromDisk("test/sample_key")
+	ecPriv := loadECPrivateKey(t, "test/ec256-private.pem")
+	set := jwt.JWKS{Keys: []jwt.JWK{
+		{Key: rsaPriv, KeyID: "rsa", Algorithm: "RS256"},
+		{Key: ecPriv, KeyID: "ec"},
+	}}
+
+	// The set survives a round trip with its public keys
+	b, err := json.Marshal(jwt.JWKS{Keys: []jwt.JWK{set.Keys[0].Public(), set.Keys[1].Public()}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var pub jwt.JWKS
+	if err = json.Unmarshal(b, &pub); err != nil {
+		t.Fatal(err)
+	}
+
+	sign := func(m jwt.SigningMethod, kid string, key interface{}) string {
+		token := jwt.NewWithClaims(m, jwt.MapClaims{"foo": "bar"})
+		token.Header["kid"] = kid
+		s, err := token.SignedString(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return s
+	}
+	for _, data := range []struct {
+		name  string
+		token string
+		valid bool
+	}{
+		{"RS256", sign(jwt.SigningMethodRS256, "rsa", rsaPriv), true},
+		{"ES256", sign(jwt.SigningMethodES256, "ec", ecPriv), true},
+		{"alg mismatch", sign(jwt.SigningMethodPS256, "rsa", rsaPriv), false},
+		{"unknown kid", sign(jwt.SigningMethodES256, "other", ecPriv), false},
+	} {
+		_, err := jwt.Parse(data.token, pub.Keyfunc)
+		if data.valid && err != nil {
+			t.Errorf("[%v] Error while verifying token: %v", data.name, err)
+		}
+		if !data.valid && err == nil {
+			t.Errorf("[%v] Invalid token passed validation", data.name)
+		}
+	}
+}
@@ -11,6 +11,7 @@ type Parser struct {
 	ValidMethods         []string // If populated, only these methods will be considered valid
 	UseJSONNumber        bool     // Use JSON Number format in JSON decoder
 	SkipClaimsValidation bool     // Skip claims validation during token parsing
+	Revoker              Revoker  // If set, tokens are checked for revocation through their "jti" claim
 }
 
 // Parse, validate, and return a token.
@@ -122,6 +123,13 @@ func (p *Parser) ParseWithClaims(tokenString string, claims Claims, keyFunc Keyf
 		vErr.Errors |= ValidationErrorSignatureInvalid
 	}
 
+	// Check revocation of otherwise valid tokens only, so forged tokens never reach the revoker
+	if p.Revoker != nil && vErr.valid() {
+		if err = p.checkRevoked(claimBytes); err != nil {
+			return token, err
+		}
+	}
+
 	if vErr.valid() {
 		token.Valid = true
 		return token, nil
@@ -129,3 +137,23 @@ func (p *Parser) ParseWithClaims(tokenString string, claims Claims, keyFunc Keyf
 
 	return token, vErr
 }
+
+// Look up the "jti" claim in the revoker.  Tokens without a "jti" cannot be revoked
+// and are rejected.
+func (p *Parser) checkRevoked(claimBytes []byte) error {
+	var id struct {
+		Id string `json:"jti"`
+	}
+	// The claims were already decoded once, so this only fails on a non-string jti
+	if err := json.Unmarshal(claimBytes, &id); err != nil || id.Id == "" {
+		return NewValidationError("token has no jti and cannot be checked for revocation", ValidationErrorId)
+	}
+	revoked, err := p.Revoker.IsRevoked(id.Id)
+	if err != nil {
+		return &ValidationError{Inner: err, Errors: ValidationErrorUnverifiable}
+	}
+	if revoked {
+		return &ValidationError{Inner: ErrTokenRevoked, Errors: ValidationErrorId}
+	}
+	return nil
+}
@@ -22,6 +22,8 @@ var (
 	nilKeyFunc        jwt.Keyfunc = nil
 )
 
+var revokedParser = &jwt.Parser{Revoker: jwt.RevokerFunc(func(id string) (bool, error) { return id == "revoked", nil })}
+
 func init() {
 	jwtTestDefaultKey = test.LoadRSAPublicKeyFromDisk("test/sample_key.pub")
 }
@@ -181,6 +183,60 @@ var jwtTestData = []struct {
 		0,
 		&jwt.Parser{UseJSONNumber: true, SkipClaimsValidation: true},
 	},
+	{
+		"revoker with valid jti",
+		"", // autogen
+		defaultKeyFunc,
+		jwt.MapClaims{"foo": "bar", "jti": "valid"},
+		true,
+		0,
+		revokedParser,
+	},
+	{
+		"revoker with revoked jti",
+		"", // autogen
+		defaultKeyFunc,
+		jwt.MapClaims{"foo": "bar", "jti": "revoked"},
+		false,
+		jwt.ValidationErrorId,
+		revokedParser,
+	},
+	{
+		"revoker with revoked jti in StandardClaims",
+		"", // autogen
+		defaultKeyFunc,
+		&jwt.StandardClaims{Id: "revoked"},
+		false,
+		jwt.ValidationErrorId,
+		revokedParser,
+	},
+	{
+		"revoker without jti",
+		"", // autogen
+		defaultKeyFunc,
+		jwt.MapClaims{"foo": "bar"},
+		false,
+		jwt.ValidationErrorId,
+		revokedParser,
+	},
+	{
+		"revoker with expired token",
+		"", // autogen
+		defaultKeyFunc,
+		jwt.MapClaims{"jti": "revoked", "exp": float64(time.Now().Unix() - 100)},
+		false,
+		jwt.ValidationErrorExpired,
+		revokedParser,
+	},
+	{
+		"revoker error",
+		"", // autogen
+		defaultKeyFunc,
+		jwt.MapClaims{"foo": "bar", "jti": "valid"},
+		false,
+		jwt.ValidationErrorUnverifiable,
+		&jwt.Parser{Revoker: jwt.RevokerFunc(func(string) (bool, error) { return false, keyFuncError })},
+	},
 }
 
 func TestParser_Parse(t *testing.T) {
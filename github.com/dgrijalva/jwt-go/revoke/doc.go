@@ -0,0 +1,12 @@
+// Utility package for revoking JWT tokens before they expire
+// and rotating refresh tokens.
+//
+// A Store keeps track of revoked token ids ("jti" claim) and is a jwt.Revoker,
+// so it can be set on a jwt.Parser.  MemoryStore is suitable for a single
+// process, RedisStore shares revocations between processes.
+//
+// Manager issues access/refresh token pairs.  Each pair belongs to a token
+// family that starts when the user logs in.  The refresh token is rotated each
+// time it is used, and presenting an already used refresh token revokes all the
+// tokens of its family, as it was most likely stolen.
+package revoke
@@ -0,0 +1,217 @@
+package revoke
+
+import (
+	"crypto/rand"
+	"errors"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+)
+
+// Default token lifetimes
+var (
+	DefaultAccessTTL  = 15 * time.Minute
+	DefaultRefreshTTL = 30 * 24 * time.Hour
+)
+
+// Error constants
+var (
+	ErrRefreshTokenReused = errors.New("refresh token was already used, token family revoked")
+	ErrNotRefreshToken    = errors.New("token is not a refresh token")
+	ErrNotAccessToken     = errors.New("token is not an access token")
+)
+
+// Claims of the tokens issued by a Manager
+type Claims struct {
+	jwt.StandardClaims
+	Family  string `json:"fam"`
+	Refresh bool   `json:"refresh,omitempty"`
+}
+
+// An access and refresh token pair
+type TokenPair struct {
+	AccessToken      string
+	RefreshToken     string
+	AccessExpiresAt  time.Time
+	RefreshExpiresAt time.Time
+}
+
+// Manager issues access/refresh token pairs and rotates refresh tokens.
+// Method, SigningKey and Store are required.
+type Manager struct {
+	Method     jwt.SigningMethod
+	SigningKey interface{}
+	VerifyKey  interface{} // Key used to verify tokens.  Defaults to SigningKey
+	Issuer     string      // If set, the "iss" claim of issued tokens, checked when parsing
+	AccessTTL  time.Duration
+	RefreshTTL time.Duration
+	Store      Store
+}
+
+// Issue starts a new token family for subject and returns its first token pair
+func (m *Manager) Issue(subject string) (*TokenPair, error) {
+	family, err := newID()
+	if err != nil {
+		return nil, err
+	}
+	return m.issue(subject, family, nil)
+}
+
+// Refresh returns a new token pair for a refresh token.  The refresh token is
+// revoked and cannot be used again: a second use is reported as
+// ErrRefreshTokenReused, and revokes all the tokens of the family.
+func (m *Manager) Refresh(refreshToken string) (*TokenPair, error) {
+	// Revocation is checked by the rotation, so an already used token is detected
+	claims, err := m.parse(refreshToken, false)
+	if err != nil {
+		return nil, err
+	}
+	if !claims.Refresh {
+		return nil, ErrNotRefreshToken
+	}
+	return m.issue(claims.Subject, claims.Family, claims)
+}
+
+// Parse parses and validates an access token, including its revocation
+func (m *Manager) Parse(accessToken string) (*Claims, error) {
+	claims, err := m.parse(accessToken, true)
+	if err != nil {
+		return nil, err
+	}
+	if claims.Refresh {
+		return nil, ErrNotAccessToken
+	}
+	return claims, nil
+}
+
+// Revoke revokes all the tokens of the family of a token, e.g. on logout.
+// The token may be an access or a refresh token.
+func (m *Manager) Revoke(token string) error {
+	claims, err := m.parse(token, false)
+	if err != nil {
+		return err
+	}
+	return m.Store.RevokeFamily(claims.Family)
+}
+
+// issue returns a new token pair of family, rotating the old refresh token
+// if any.  Once the rotation succeeded the old refresh token is spent, so it
+// comes last and nothing may fail after it.
+func (m *Manager) issue(subject, family string, old *Claims) (*TokenPair, error) {
+	now := jwt.TimeFunc()
+	pair := &TokenPair{
+		AccessExpiresAt:  now.Add(ttl(m.AccessTTL, DefaultAccessTTL)),
+		RefreshExpiresAt: now.Add(ttl(m.RefreshTTL, DefaultRefreshTTL)),
+	}
+	access, err := m.newClaims(subject, family, now, pair.AccessExpiresAt)
+	if err != nil {
+		return nil, err
+	}
+	refresh, err := m.newClaims(subject, family, now, pair.RefreshExpiresAt)
+	if err != nil {
+		return nil, err
+	}
+	refresh.Refresh = true
+
+	if pair.AccessToken, err = jwt.NewWithClaims(m.Method, access).SignedString(m.SigningKey); err != nil {
+		return nil, err
+	}
+	if pair.RefreshToken, err = jwt.NewWithClaims(m.Method, refresh).SignedString(m.SigningKey); err != nil {
+		return nil, err
+	}
+
+	if old == nil {
+		// A new family is created by the rotation, before tokens can be added
+		if err = m.rotate(family, "", refresh.Id, pair.RefreshExpiresAt); err != nil {
+			return nil, err
+		}
+		if err = m.Store.Add(family, access.Id, pair.AccessExpiresAt); err != nil {
+			return nil, err
+		}
+		return pair, nil
+	}
+
+	if err = m.Store.Add(family, access.Id, pair.AccessExpiresAt); err != nil {
+		return nil, err
+	}
+	// The family moves on with the rotation, revoking only matters to other parsers
+	if err = m.Store.Revoke(old.Id, time.Unix(old.ExpiresAt, 0)); err != nil {
+		return nil, err
+	}
+	if err = m.rotate(family, old.Id, refresh.Id, pair.RefreshExpiresAt); err != nil {
+		return nil, err
+	}
+	return pair, nil
+}
+
+// rotate replaces the current refresh token of family, revoking the family
+// if old was used before.
+func (m *Manager) rotate(family, old, next string, exp time.Time) error {
+	ok, err := m.Store.Rotate(family, old, next, exp)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		// old is not the current refresh token of the family: it was used before
+		if err = m.Store.RevokeFamily(family); err != nil {
+			return err
+		}
+		return ErrRefreshTokenReused
+	}
+	return nil
+}
+
+func (m *Manager) newClaims(subject, family string, now, exp time.Time) (*Claims, error) {
+	id, err := newID()
+	if err != nil {
+		return nil, err
+	}
+	return &Claims{
+		StandardClaims: jwt.StandardClaims{
+			Id:        id,
+			Subject:   subject,
+			Issuer:    m.Issuer,
+			IssuedAt:  now.Unix(),
+			ExpiresAt: exp.Unix(),
+		},
+		Family: family,
+	}, nil
+}
+
+func (m *Manager) parse(token string, checkRevoked bool) (*Claims, error) {
+	p := &jwt.Parser{ValidMethods: []string{m.Method.Alg()}}
+	if checkRevoked {
+		p.Revoker = m.Store
+	}
+	key := m.VerifyKey
+	if key == nil {
+		key = m.SigningKey
+	}
+	claims := &Claims{}
+	if _, err := p.ParseWithClaims(token, claims, func(*jwt.Token) (interface{}, error) { return key, nil }); err != nil {
+		return nil, err
+	}
+	if m.Issuer != "" && !claims.VerifyIssuer(m.Issuer, true) {
+		return nil, jwt.NewValidationError("token has an invalid issuer", jwt.ValidationErrorIssuer)
+	}
+	if claims.Family == "" {
+		return nil, jwt.NewValidationError("token has no family", jwt.ValidationErrorClaimsInvalid)
+	}
+	return claims, nil
+}
+
+func ttl(d, def time.Duration) time.Duration {
+	if d <= 0 {
+		return def
+	}
+	return d
+}
+
+// Random token and family ids
+func newID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return jwt.EncodeSegment(b), nil
+}
@@ -0,0 +1,142 @@
+package revoke_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/dgrijalva/jwt-go/revoke"
+	"github.com/dgrijalva/jwt-go/test"
+)
+
+func newManager(store revoke.Store) *revoke.Manager {
+	key := test.LoadRSAPrivateKeyFromDisk("../test/sample_key")
+	return &revoke.Manager{
+		Method:     jwt.SigningMethodRS256,
+		SigningKey: key,
+		VerifyKey:  &key.PublicKey,
+		Issuer:     "test",
+		Store:      store,
+	}
+}
+
+func TestManagerRotation(t *testing.T) {
+	m := newManager(revoke.NewMemoryStore())
+
+	first, err := m.Issue("john")
+	if err != nil {
+		t.Fatal(err)
+	}
+	claims, err := m.Parse(first.AccessToken)
+	if err != nil {
+		t.Fatalf("Error while parsing access token: %v", err)
+	}
+	if claims.Subject != "john" || claims.Issuer != "test" || claims.Family == "" {
+		t.Errorf("Unexpected claims: %+v", claims)
+	}
+	if _, err = m.Parse(first.RefreshToken); err != revoke.ErrNotAccessToken {
+		t.Errorf("Expected ErrNotAccessToken, got %v", err)
+	}
+	if _, err = m.Refresh(first.AccessToken); err != revoke.ErrNotRefreshToken {
+		t.Errorf("Expected ErrNotRefreshToken, got %v", err)
+	}
+
+	second, err := m.Refresh(first.RefreshToken)
+	if err != nil {
+		t.Fatalf("Error while refreshing: %v", err)
+	}
+	if second.RefreshToken == first.RefreshToken {
+		t.Errorf("Refresh token was not rotated")
+	}
+	// The first access token is still valid until it expires
+	if _, err = m.Parse(first.AccessToken); err != nil {
+		t.Errorf("Error while parsing first access token: %v", err)
+	}
+	if _, err = m.Parse(second.AccessToken); err != nil {
+		t.Errorf("Error while parsing second access token: %v", err)
+	}
+
+	// Reusing the first refresh token revokes the whole family
+	if _, err = m.Refresh(first.RefreshToken); err != revoke.ErrRefreshTokenReused {
+		t.Fatalf("Expected ErrRefreshTokenReused, got %v", err)
+	}
+	for _, token := range []string{first.AccessToken, second.AccessToken} {
+		_, err = m.Parse(token)
+		if ve, ok := err.(*jwt.ValidationError); !ok || ve.Errors != jwt.ValidationErrorId || ve.Inner != jwt.ErrTokenRevoked {
+			t.Errorf("Expected revoked token, got %v", err)
+		}
+	}
+	if _, err = m.Refresh(second.RefreshToken); err != revoke.ErrRefreshTokenReused {
+		t.Errorf("Expected ErrRefreshTokenReused for the current refresh token, got %v", err)
+	}
+}
+
+func TestManagerRevoke(t *testing.T) {
+	m := newManager(revoke.NewMemoryStore())
+	pair, err := m.Issue("john")
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := m.Issue("john")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = m.Revoke(pair.AccessToken); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = m.Parse(pair.AccessToken); err == nil {
+		t.Errorf("Revoked token passed validation")
+	}
+	if _, err = m.Refresh(pair.RefreshToken); err == nil {
+		t.Errorf("Revoked refresh token was refreshed")
+	}
+	// Other families are not affected
+	if _, err = m.Parse(other.AccessToken); err != nil {
+		t.Errorf("Error while parsing token of another family: %v", err)
+	}
+}
+
+func TestManagerInvalid(t *testing.T) {
+	m := newManager(revoke.NewMemoryStore())
+	pair, err := m.Issue("john")
+	if err != nil {
+		t.Fatal(err)
+	}
+	other := newManager(m.Store)
+	other.Issuer = "other"
+	if _, err = other.Parse(pair.AccessToken); err == nil {
+		t.Errorf("Token with invalid issuer passed validation")
+	}
+
+	// Expired refresh tokens are rejected
+	m.RefreshTTL = time.Second
+	pair, err = m.Issue("john")
+	if err != nil {
+		t.Fatal(err)
+	}
+	jwt.TimeFunc = func() time.Time { return time.Now().Add(time.Minute) }
+	defer func() { jwt.TimeFunc = time.Now }()
+	_, err = m.Refresh(pair.RefreshToken)
+	if ve, ok := err.(*jwt.ValidationError); !ok || ve.Errors&jwt.ValidationErrorExpired == 0 {
+		t.Errorf("Expected expired error, got %v", err)
+	}
+}
+
+func TestManagerSigningFailure(t *testing.T) {
+	m := newManager(revoke.NewMemoryStore())
+	pair, err := m.Issue("john")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A refresh failing to sign the new tokens does not spend the refresh token
+	key := m.SigningKey
+	m.SigningKey = []byte("not an RSA key")
+	if _, err = m.Refresh(pair.RefreshToken); err == nil {
+		t.Fatal("Refresh with an invalid signing key succeeded")
+	}
+	m.SigningKey = key
+	if _, err = m.Refresh(pair.RefreshToken); err != nil {
+		t.Errorf("Error while refreshing after a signing failure: %v", err)
+	}
+}
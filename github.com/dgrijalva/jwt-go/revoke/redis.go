@@ -0,0 +1,128 @@
+package revoke
+
+import (
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"gopkg.in/redis.v5"
+)
+
+// Keys:
+//   <prefix>revoked:<id>      revoked token id, expiring with the token
+//   <prefix>family:<family>   hash holding the current refresh token of the family
+//   <prefix>tokens:<family>   sorted set of the family's token ids, scored by expiration
+//
+// Both family keys expire with the family's last token.
+
+// Add a token to a family and extend the family's expiration.
+// KEYS: family, tokens
+// ARGV: id, exp (unix seconds), revoked key prefix
+var redisAdd = redis.NewScript(`
+if redis.call('EXISTS', KEYS[1]) == 0 then
+	local k = ARGV[3] .. ARGV[1]
+	redis.call('SET', k, '1')
+	redis.call('EXPIREAT', k, ARGV[2])
+	return 0
+end
+redis.call('ZADD', KEYS[2], ARGV[2], ARGV[1])
+local last = redis.call('ZRANGE', KEYS[2], -1, -1, 'WITHSCORES')
+redis.call('EXPIREAT', KEYS[1], last[2])
+redis.call('EXPIREAT', KEYS[2], last[2])
+return 1
+`)
+
+// Compare and swap the current refresh token of a family.
+// KEYS: family, tokens
+// ARGV: old, next, exp (unix seconds)
+var redisRotate = redis.NewScript(`
+local cur = redis.call('HGET', KEYS[1], 'current')
+if cur == false then
+	if ARGV[1] ~= '' then
+		return 0
+	end
+elseif cur ~= ARGV[1] then
+	return 0
+end
+redis.call('HSET', KEYS[1], 'current', ARGV[2])
+redis.call('ZADD', KEYS[2], ARGV[3], ARGV[2])
+local last = redis.call('ZRANGE', KEYS[2], -1, -1, 'WITHSCORES')
+redis.call('EXPIREAT', KEYS[1], last[2])
+redis.call('EXPIREAT', KEYS[2], last[2])
+return 1
+`)
+
+// Revoke all the unexpired tokens of a family and delete it.
+// KEYS: family, tokens
+// ARGV: now (unix seconds), revoked key prefix
+var redisRevokeFamily = redis.NewScript(`
+local ids = redis.call('ZRANGEBYSCORE', KEYS[2], '(' .. ARGV[1], '+inf', 'WITHSCORES')
+for i = 1, #ids, 2 do
+	local k = ARGV[2] .. ids[i]
+	redis.call('SET', k, '1')
+	redis.call('EXPIREAT', k, ids[i + 1])
+end
+redis.call('DEL', KEYS[1], KEYS[2])
+return #ids / 2
+`)
+
+// RedisStore is a Store keeping its state in Redis, so revocations are
+// shared between processes.
+//
+// The scripts used by the store access keys they do not declare, so all the
+// keys of a store must live on the same node when used with Redis Cluster.
+type RedisStore struct {
+	client redis.Cmdable
+	prefix string
+}
+
+// NewRedisStore creates a RedisStore using client.  All keys are prefixed with
+// prefix, e.g. "jwt:".
+func NewRedisStore(client redis.Cmdable, prefix string) *RedisStore {
+	return &RedisStore{client: client, prefix: prefix}
+}
+
+func (s *RedisStore) revokedKey(id string) string {
+	return s.prefix + "revoked:" + id
+}
+
+func (s *RedisStore) familyKeys(family string) []string {
+	return []string{s.prefix + "family:" + family, s.prefix + "tokens:" + family}
+}
+
+// IsRevoked implements jwt.Revoker
+func (s *RedisStore) IsRevoked(id string) (bool, error) {
+	return s.client.Exists(s.revokedKey(id)).Result()
+}
+
+// Revoke implements Store
+func (s *RedisStore) Revoke(id string, exp time.Time) error {
+	ttl := exp.Sub(jwt.TimeFunc())
+	if ttl <= 0 {
+		// Already expired
+		return nil
+	}
+	if ttl < time.Second {
+		ttl = time.Second
+	}
+	return s.client.Set(s.revokedKey(id), "1", ttl).Err()
+}
+
+// Add implements Store
+func (s *RedisStore) Add(family, id string, exp time.Time) error {
+	return redisAdd.Run(s.client, s.familyKeys(family), id, exp.Unix(), s.revokedKey("")).Err()
+}
+
+// Rotate implements Store
+func (s *RedisStore) Rotate(family, old, next string, exp time.Time) (bool, error) {
+	res, err := redisRotate.Run(s.client, s.familyKeys(family), old, next, exp.Unix()).Result()
+	if err != nil {
+		return false, err
+	}
+	n, _ := res.(int64)
+	return n == 1, nil
+}
+
+// RevokeFamily implements Store
+func (s *RedisStore) RevokeFamily(family string) error {
+	return redisRevokeFamily.Run(s.client, s.familyKeys(family), jwt.TimeFunc().Unix(), s.revokedKey("")).Err()
+}
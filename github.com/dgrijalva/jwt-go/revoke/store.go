@@ -0,0 +1,157 @@
+package revoke
+
+import (
+	"sync"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+)
+
+// Store keeps track of revoked tokens and of the tokens of each family.
+// Implementations must be safe for concurrent use.
+type Store interface {
+	jwt.Revoker
+
+	// Revoke revokes the token id.  exp is the token expiration, after which
+	// the token does not need to be remembered.
+	Revoke(id string, exp time.Time) error
+
+	// Add records the token id as part of family, so it is revoked along with
+	// the family.
+	Add(family, id string, exp time.Time) error
+
+	// Rotate replaces the current refresh token of family, old, with next and
+	// records next as part of family.  It returns false and leaves the family
+	// untouched if old is not the current refresh token.  An empty old starts
+	// a new family.
+	Rotate(family, old, next string, exp time.Time) (bool, error)
+
+	// RevokeFamily revokes all the tokens of family.  The family cannot be
+	// rotated anymore.
+	RevokeFamily(family string) error
+}
+
+// How often expired entries are removed from a MemoryStore
+var MemorySweepInterval = time.Minute
+
+// MemoryStore is a Store keeping its state in memory
+type MemoryStore struct {
+	mu       sync.Mutex
+	revoked  map[string]time.Time
+	families map[string]*memoryFamily
+	swept    time.Time
+}
+
+type memoryFamily struct {
+	current string
+	tokens  map[string]time.Time
+	exp     time.Time
+}
+
+// NewMemoryStore creates an empty MemoryStore
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		revoked:  map[string]time.Time{},
+		families: map[string]*memoryFamily{},
+		swept:    jwt.TimeFunc(),
+	}
+}
+
+// IsRevoked implements jwt.Revoker
+func (s *MemoryStore) IsRevoked(id string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	exp, ok := s.revoked[id]
+	return ok && jwt.TimeFunc().Before(exp), nil
+}
+
+// Revoke implements Store
+func (s *MemoryStore) Revoke(id string, exp time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sweep()
+	s.revoke(id, exp)
+	return nil
+}
+
+func (s *MemoryStore) revoke(id string, exp time.Time) {
+	if cur, ok := s.revoked[id]; !ok || exp.After(cur) {
+		s.revoked[id] = exp
+	}
+}
+
+// Add implements Store
+func (s *MemoryStore) Add(family, id string, exp time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sweep()
+	f := s.families[family]
+	if f == nil {
+		// Unknown or revoked family: revoke the token right away
+		s.revoke(id, exp)
+		return nil
+	}
+	f.add(id, exp)
+	return nil
+}
+
+// Rotate implements Store
+func (s *MemoryStore) Rotate(family, old, next string, exp time.Time) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sweep()
+	f := s.families[family]
+	if f == nil {
+		if old != "" {
+			return false, nil
+		}
+		f = &memoryFamily{tokens: map[string]time.Time{}}
+		s.families[family] = f
+	} else if f.current != old {
+		return false, nil
+	}
+	f.current = next
+	f.add(next, exp)
+	return true, nil
+}
+
+// RevokeFamily implements Store
+func (s *MemoryStore) RevokeFamily(family string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	f := s.families[family]
+	if f == nil {
+		return nil
+	}
+	for id, exp := range f.tokens {
+		s.revoke(id, exp)
+	}
+	delete(s.families, family)
+	return nil
+}
+
+func (f *memoryFamily) add(id string, exp time.Time) {
+	f.tokens[id] = exp
+	if exp.After(f.exp) {
+		f.exp = exp
+	}
+}
+
+// Remove expired entries.  Must be called with the lock held.
+func (s *MemoryStore) sweep() {
+	now := jwt.TimeFunc()
+	if now.Sub(s.swept) < MemorySweepInterval {
+		return
+	}
+	s.swept = now
+	for id, exp := range s.revoked {
+		if !now.Before(exp) {
+			delete(s.revoked, id)
+		}
+	}
+	for name, f := range s.families {
+		if !now.Before(f.exp) {
+			delete(s.families, name)
+		}
+	}
+}
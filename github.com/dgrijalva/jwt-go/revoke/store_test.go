@@ -0,0 +1,92 @@
+package revoke_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/dgrijalva/jwt-go/revoke"
+	"gopkg.in/redis.v5"
+)
+
+func testStore(t *testing.T, s revoke.Store) {
+	exp := time.Now().Add(time.Hour)
+
+	if revoked, err := s.IsRevoked("a"); err != nil || revoked {
+		t.Errorf("Unexpected revocation: %v %v", revoked, err)
+	}
+	if err := s.Revoke("a", exp); err != nil {
+		t.Fatal(err)
+	}
+	if revoked, err := s.IsRevoked("a"); err != nil || !revoked {
+		t.Errorf("Expected revoked token: %v %v", revoked, err)
+	}
+	// Expired tokens need not be stored
+	if err := s.Revoke("b", time.Now().Add(-time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	if revoked, _ := s.IsRevoked("b"); revoked {
+		t.Errorf("Expired token is still stored")
+	}
+
+	// Rotation
+	for _, data := range []struct {
+		old, next string
+		ok        bool
+	}{
+		{"", "r1", true},
+		{"", "r2", false},
+		{"r1", "r2", true},
+		{"r1", "r3", false},
+		{"r2", "r3", true},
+	} {
+		if ok, err := s.Rotate("fam", data.old, data.next, exp); err != nil || ok != data.ok {
+			t.Errorf("Rotate(%v, %v) = %v %v, expected %v", data.old, data.next, ok, err, data.ok)
+		}
+	}
+	if err := s.Add("fam", "access", exp); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.RevokeFamily("fam"); err != nil {
+		t.Fatal(err)
+	}
+	for _, id := range []string{"r1", "r2", "r3", "access"} {
+		if revoked, err := s.IsRevoked(id); err != nil || !revoked {
+			t.Errorf("Expected %v to be revoked with its family: %v %v", id, revoked, err)
+		}
+	}
+	if ok, _ := s.Rotate("fam", "r3", "r4", exp); ok {
+		t.Errorf("Revoked family was rotated")
+	}
+	// Tokens added to a revoked family are revoked right away
+	if err := s.Add("fam", "late", exp); err != nil {
+		t.Fatal(err)
+	}
+	if revoked, _ := s.IsRevoked("late"); !revoked {
+		t.Errorf("Token added to a revoked family is not revoked")
+	}
+}
+
+func TestMemoryStore(t *testing.T) {
+	testStore(t, revoke.NewMemoryStore())
+}
+
+func TestMemoryStoreExpiration(t *testing.T) {
+	s := revoke.NewMemoryStore()
+	s.Revoke("a", time.Now().Add(time.Minute))
+	jwt.TimeFunc = func() time.Time { return time.Now().Add(time.Hour) }
+	defer func() { jwt.TimeFunc = time.Now }()
+	if revoked, _ := s.IsRevoked("a"); revoked {
+		t.Errorf("Expired revocation is still effective")
+	}
+}
+
+func TestRedisStore(t *testing.T) {
+	client := redis.NewClient(&redis.Options{Addr: ":6379"})
+	defer client.Close()
+	if err := client.Ping().Err(); err != nil {
+		t.Skipf("Redis is not available: %v", err)
+	}
+	prefix := "jwt-go-test:" + time.Now().Format(time.RFC3339Nano) + ":"
+	testStore(t, revoke.NewRedisStore(client, prefix))
+}
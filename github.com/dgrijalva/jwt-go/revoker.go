@@ -0,0 +1,26 @@
+package jwt
+
+import (
+	"errors"
+)
+
+// ErrTokenRevoked is the inner error of the ValidationError returned by the
+// Parser for a revoked token
+var ErrTokenRevoked = errors.New("token is revoked")
+
+// A Revoker reports whether a token was revoked before it expired.  Tokens are
+// identified by their "jti" claim.
+//
+// Set the Revoker on a Parser to reject revoked tokens.  See the revoke subpackage
+// for in-memory and Redis implementations.
+type Revoker interface {
+	IsRevoked(id string) (bool, error)
+}
+
+// RevokerFunc adapts an ordinary function to the Revoker interface
+type RevokerFunc func(id string) (bool, error)
+
+// IsRevoked calls f(id)
+func (f RevokerFunc) IsRevoked(id string) (bool, error) {
+	return f(id)
+}
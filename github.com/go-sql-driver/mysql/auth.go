@@ -0,0 +1,219 @@
+// Go MySQL Driver - A MySQL-Driver for Go's database/sql package
+//
+// Copyright 2017 The Go-MySQL-Driver Authors. All rights reserved.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package mysql
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"io/ioutil"
+)
+
+var errInvalidServerPubKey = errors.New("invalid server public key: expected a PEM encoded RSA public key")
+
+// Parses a PEM encoded RSA public key, as sent by the server or found in the
+// file given with the serverPubKey DSN parameter.
+func parsePubKey(data []byte) (*rsa.PublicKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errInvalidServerPubKey
+	}
+	pkix, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	pubKey, ok := pkix.(*rsa.PublicKey)
+	if !ok {
+		return nil, errInvalidServerPubKey
+	}
+	return pubKey, nil
+}
+
+// Reads the server's RSA public key from a PEM file.
+func readPubKeyFile(name string) (*rsa.PublicKey, error) {
+	data, err := ioutil.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+	return parsePubKey(data)
+}
+
+// Encrypts the null terminated password, XORed with the scramble, with the
+// server's RSA public key
+func encryptPassword(password string, scramble []byte, pubKey *rsa.PublicKey) ([]byte, error) {
+	plain := make([]byte, len(password)+1)
+	copy(plain, password)
+	for i := range plain {
+		plain[i] ^= scramble[i%len(scramble)]
+	}
+	return rsa.EncryptOAEP(sha1.New(), rand.Reader, pubKey, plain, nil)
+}
+
+// The password may only be sent in clear text over TLS or a UNIX socket
+func (mc *mysqlConn) isSecureTransport() bool {
+	return mc.cfg.tls != nil || mc.cfg.Net == "unix"
+}
+
+func (mc *mysqlConn) sendEncryptedPassword(scramble []byte, pubKey *rsa.PublicKey) error {
+	enc, err := encryptPassword(mc.cfg.Passwd, scramble, pubKey)
+	if err != nil {
+		return err
+	}
+	return mc.writeAuthSwitchPacket(enc)
+}
+
+// Computes the auth response of the given plugin for the scramble sent by the
+// server.
+func (mc *mysqlConn) auth(scramble []byte, plugin string) ([]byte, error) {
+	switch plugin {
+	case "caching_sha2_password":
+		return scrambleSHA256Password(scramble, []byte(mc.cfg.Passwd)), nil
+
+	case "mysql_old_password":
+		if !mc.cfg.AllowOldPasswords {
+			return nil, ErrOldPassword
+		}
+		// Note: there are edge cases where this should work but doesn't;
+		// this is currently "wontfix":
+		// https://github.com/go-sql-driver/mysql/issues/184
+		return append(scrambleOldPassword(scramble, []byte(mc.cfg.Passwd)), 0x00), nil
+
+	case "mysql_clear_password":
+		if !mc.cfg.AllowCleartextPasswords {
+			return nil, ErrCleartextPassword
+		}
+		// http://dev.mysql.com/doc/refman/5.7/en/cleartext-authentication-plugin.html
+		// http://dev.mysql.com/doc/refman/5.7/en/pam-authentication-plugin.html
+		return append([]byte(mc.cfg.Passwd), 0x00), nil
+
+	case "mysql_native_password":
+		return scramblePassword(scramble, []byte(mc.cfg.Passwd)), nil
+
+	case "sha256_password":
+		if len(mc.cfg.Passwd) == 0 {
+			return []byte{0x00}, nil
+		}
+		if mc.isSecureTransport() {
+			return append([]byte(mc.cfg.Passwd), 0x00), nil
+		}
+		if mc.cfg.pubKey == nil {
+			// request the public key from the server
+			return []byte{0x01}, nil
+		}
+		return encryptPassword(mc.cfg.Passwd, scramble, mc.cfg.pubKey)
+
+	default:
+		return nil, ErrUnknownPlugin
+	}
+}
+
+// Handles the response to the auth packet, following auth switch requests and
+// the additional exchanges of the SHA256 plugins.
+// https://dev.mysql.com/doc/internals/en/authentication-method-change.html
+func (mc *mysqlConn) handleAuthResult(scramble []byte, plugin string) error {
+	authData, newPlugin, err := mc.readAuthResult()
+	if err != nil {
+		return err
+	}
+
+	if newPlugin != "" {
+		// Switching to the native method is only subject to
+		// allowNativePasswords if the handshake was already answered with
+		// it. Servers defaulting to another plugin request it for accounts
+		// using native passwords.
+		if newPlugin == "mysql_native_password" && plugin == "mysql_native_password" &&
+			!mc.cfg.AllowNativePasswords {
+			return ErrNativePassword
+		}
+
+		// If CLIENT_PLUGIN_AUTH capability is not supported, no new scramble
+		// is sent and we have to keep using the one sent in the init packet.
+		if len(authData) > 0 {
+			scramble = authData
+		}
+		plugin = newPlugin
+
+		authResp, err := mc.auth(scramble, plugin)
+		if err != nil {
+			return err
+		}
+		if err = mc.writeAuthSwitchPacket(authResp); err != nil {
+			return err
+		}
+
+		authData, newPlugin, err = mc.readAuthResult()
+		if err != nil {
+			return err
+		}
+		// Do not allow to change the auth plugin more than once
+		if newPlugin != "" {
+			return ErrMalformPkt
+		}
+	}
+
+	if authData == nil {
+		return nil // auth successful
+	}
+
+	switch plugin {
+	case "caching_sha2_password":
+		if len(authData) != 1 {
+			return ErrMalformPkt
+		}
+		switch authData[0] {
+		case cachingSha2PasswordFastAuthSuccess:
+			return mc.readResultOK()
+
+		case cachingSha2PasswordPerformFullAuthentication:
+			if mc.isSecureTransport() {
+				err = mc.writeAuthSwitchPacket(append([]byte(mc.cfg.Passwd), 0x00))
+			} else {
+				pubKey := mc.cfg.pubKey
+				if pubKey == nil {
+					// request the public key from the server
+					if err = mc.writeAuthSwitchPacket([]byte{cachingSha2PasswordRequestPublicKey}); err != nil {
+						return err
+					}
+					data, _, err := mc.readAuthResult()
+					if err != nil {
+						return err
+					}
+					if pubKey, err = parsePubKey(data); err != nil {
+						return err
+					}
+				}
+				err = mc.sendEncryptedPassword(scramble, pubKey)
+			}
+			if err != nil {
+				return err
+			}
+			return mc.readResultOK()
+
+		default:
+			return ErrMalformPkt
+		}
+
+	case "sha256_password":
+		// the server sent its public key, as requested by the auth response
+		pubKey, err := parsePubKey(authData)
+		if err != nil {
+			return err
+		}
+		if err = mc.sendEncryptedPassword(scramble, pubKey); err != nil {
+			return err
+		}
+		return mc.readResultOK()
+
+	default:
+		return ErrMalformPkt
+	}
+}
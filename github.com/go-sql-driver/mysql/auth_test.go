@@ -0,0 +1,467 @@
+// Go MySQL Driver - A MySQL-Driver for Go's database/sql package
+//
+// Copyright 2017 The Go-MySQL-Driver Authors. All rights reserved.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package mysql
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/url"
+	"os"
+	"testing"
+)
+
+const testAuthPassword = "secret"
+
+var testScramble = []byte{10, 47, 74, 111, 75, 73, 34, 48, 88, 76, 114, 74, 37, 13, 3, 80, 82, 2, 23, 21}
+
+var testServerKey *rsa.PrivateKey
+
+func serverKey(t *testing.T) *rsa.PrivateKey {
+	if testServerKey == nil {
+		key, err := rsa.GenerateKey(rand.Reader, 2048)
+		if err != nil {
+			t.Fatal(err)
+		}
+		testServerKey = key
+	}
+	return testServerKey
+}
+
+func serverKeyPEM(t *testing.T) []byte {
+	der, err := x509.MarshalPKIXPublicKey(&serverKey(t).PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})
+}
+
+// fakeServer plays the server side of the connection phase, using the
+// driver's own packet code.
+type fakeServer struct {
+	mc *mysqlConn
+}
+
+func (s *fakeServer) write(payload ...byte) error {
+	return s.mc.writePacket(append(make([]byte, 4), payload...))
+}
+
+func (s *fakeServer) writeOK() error {
+	return s.write(iOK, 0, 0, 2, 0, 0, 0)
+}
+
+func (s *fakeServer) writeMoreData(data ...byte) error {
+	return s.write(append([]byte{iAuthMoreData}, data...)...)
+}
+
+func (s *fakeServer) writeAuthSwitch(plugin string, scramble []byte) error {
+	data := append([]byte{iEOF}, plugin...)
+	data = append(data, 0x00)
+	data = append(data, scramble...)
+	return s.write(append(data, 0x00)...)
+}
+
+func (s *fakeServer) writeInit(plugin string) error {
+	caps := clientProtocol41 | clientSecureConn | clientPluginAuth | clientPluginAuthLenEncClientData
+	data := []byte{minProtocolVersion}
+	data = append(data, "8.0.11"...)
+	data = append(data, 0x00)
+	data = append(data, 1, 0, 0, 0)
+	data = append(data, testScramble[:8]...)
+	data = append(data, 0x00, byte(caps), byte(caps>>8), 33, 2, 0, byte(caps>>16), byte(caps>>24), 21)
+	data = append(data, make([]byte, 10)...)
+	data = append(data, testScramble[8:]...)
+	data = append(data, 0x00)
+	data = append(data, plugin...)
+	return s.write(append(data, 0x00)...)
+}
+
+// Reads the Handshake Response and returns the auth response and plugin
+func (s *fakeServer) readHandshake() ([]byte, string, error) {
+	data, err := s.mc.readPacket()
+	if err != nil {
+		return nil, "", err
+	}
+	flags := clientFlag(data[0]) | clientFlag(data[1])<<8 | clientFlag(data[2])<<16 | clientFlag(data[3])<<24
+	pos := 4 + 4 + 1 + 23
+	pos += bytes.IndexByte(data[pos:], 0x00) + 1
+
+	var authResp []byte
+	if flags&clientPluginAuthLenEncClientData != 0 {
+		var n int
+		authResp, _, n, err = readLengthEncodedString(data[pos:])
+		if err != nil {
+			return nil, "", err
+		}
+		pos += n
+	} else {
+		n := int(data[pos])
+		authResp = data[pos+1 : pos+1+n]
+		pos += 1 + n
+	}
+	if flags&clientConnectWithDB != 0 {
+		pos += bytes.IndexByte(data[pos:], 0x00) + 1
+	}
+	plugin := string(data[pos : pos+bytes.IndexByte(data[pos:], 0x00)])
+	return append([]byte{}, authResp...), plugin, nil
+}
+
+func (s *fakeServer) read() ([]byte, error) {
+	data, err := s.mc.readPacket()
+	return append([]byte{}, data...), err
+}
+
+// Checks a caching_sha2_password scramble like the server does, knowing only
+// SHA256(SHA256(password))
+func checkSHA256Scramble(resp, scramble []byte, password string) bool {
+	stage1 := sha256.Sum256([]byte(password))
+	stored := sha256.Sum256(stage1[:])
+	crypt := sha256.New()
+	crypt.Write(stored[:])
+	crypt.Write(scramble)
+	hash := crypt.Sum(nil)
+	if len(resp) != len(hash) {
+		return false
+	}
+	for i := range hash {
+		hash[i] ^= resp[i]
+	}
+	return sha256.Sum256(hash) == stored
+}
+
+func decryptPassword(t *testing.T, enc, scramble []byte) string {
+	plain, err := rsa.DecryptOAEP(sha1.New(), nil, serverKey(t), enc, nil)
+	if err != nil {
+		return fmt.Sprintf("<%v>", err)
+	}
+	for i := range plain {
+		plain[i] ^= scramble[i%len(scramble)]
+	}
+	return string(plain)
+}
+
+// Runs the connection phase of a client with the given config against the
+// server function, and returns the result of the client and of the server.
+func testAuth(t *testing.T, cfg *Config, server func(s *fakeServer) error) (error, error) {
+	cc, sc := net.Pipe()
+	defer cc.Close()
+
+	mc := &mysqlConn{
+		cfg:              cfg,
+		netConn:          cc,
+		buf:              newBuffer(cc),
+		maxAllowedPacket: maxPacketSize,
+		maxWriteSize:     maxPacketSize - 1,
+	}
+	s := &fakeServer{&mysqlConn{
+		netConn:          sc,
+		buf:              newBuffer(sc),
+		maxAllowedPacket: maxPacketSize,
+	}}
+
+	serverErr := make(chan error, 1)
+	go func() {
+		err := server(s)
+		// unblock the client if the server gave up
+		sc.Close()
+		serverErr <- err
+	}()
+
+	clientErr := func() error {
+		authData, plugin, err := mc.readInitPacket()
+		if err != nil {
+			return err
+		}
+		authResp, err := mc.auth(authData, plugin)
+		if err != nil {
+			return err
+		}
+		if err = mc.writeAuthPacket(authResp, plugin); err != nil {
+			return err
+		}
+		return mc.handleAuthResult(authData, plugin)
+	}()
+	cc.Close()
+	return clientErr, <-serverErr
+}
+
+func testAuthConfig(t *testing.T) *Config {
+	cfg, err := ParseDSN("user:" + testAuthPassword + "@tcp(127.0.0.1:3306)/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cfg
+}
+
+// Runs testAuth and expects the authentication to succeed
+func expectAuth(t *testing.T, cfg *Config, server func(s *fakeServer) error) {
+	clientErr, serverErr := testAuth(t, cfg, server)
+	if serverErr != nil {
+		t.Fatalf("server: %v", serverErr)
+	}
+	if clientErr != nil {
+		t.Fatalf("client: %v", clientErr)
+	}
+}
+
+func TestScrambleSHA256Password(t *testing.T) {
+	resp := scrambleSHA256Password(testScramble, []byte(testAuthPassword))
+	if !checkSHA256Scramble(resp, testScramble, testAuthPassword) {
+		t.Errorf("scramble %x not accepted", resp)
+	}
+	if checkSHA256Scramble(resp, testScramble, "wrong") {
+		t.Errorf("scramble %x accepted for wrong password", resp)
+	}
+	if resp := scrambleSHA256Password(testScramble, nil); resp != nil {
+		t.Errorf("expected no scramble for empty password, got %x", resp)
+	}
+}
+
+func TestAuthCachingSHA2FastAuth(t *testing.T) {
+	expectAuth(t, testAuthConfig(t), func(s *fakeServer) error {
+		if err := s.writeInit("caching_sha2_password"); err != nil {
+			return err
+		}
+		resp, plugin, err := s.readHandshake()
+		if err != nil {
+			return err
+		}
+		if plugin != "caching_sha2_password" {
+			return fmt.Errorf("unexpected plugin %q", plugin)
+		}
+		if !checkSHA256Scramble(resp, testScramble, testAuthPassword) {
+			return fmt.Errorf("invalid scramble %x", resp)
+		}
+		if err = s.writeMoreData(cachingSha2PasswordFastAuthSuccess); err != nil {
+			return err
+		}
+		return s.writeOK()
+	})
+}
+
+func fullAuthServer(t *testing.T, requestKey bool) func(s *fakeServer) error {
+	return func(s *fakeServer) error {
+		if err := s.writeInit("caching_sha2_password"); err != nil {
+			return err
+		}
+		if _, _, err := s.readHandshake(); err != nil {
+			return err
+		}
+		if err := s.writeMoreData(cachingSha2PasswordPerformFullAuthentication); err != nil {
+			return err
+		}
+		data, err := s.read()
+		if err != nil {
+			return err
+		}
+		if requestKey {
+			if !bytes.Equal(data, []byte{cachingSha2PasswordRequestPublicKey}) {
+				return fmt.Errorf("expected public key request, got %x", data)
+			}
+			if err = s.writeMoreData(serverKeyPEM(t)...); err != nil {
+				return err
+			}
+			if data, err = s.read(); err != nil {
+				return err
+			}
+		}
+		if pw := decryptPassword(t, data, testScramble); pw != testAuthPassword+"\x00" {
+			return fmt.Errorf("unexpected password %q", pw)
+		}
+		return s.writeOK()
+	}
+}
+
+func TestAuthCachingSHA2FullAuthRequestPubKey(t *testing.T) {
+	expectAuth(t, testAuthConfig(t), fullAuthServer(t, true))
+}
+
+func TestAuthCachingSHA2FullAuthServerPubKey(t *testing.T) {
+	cfg := testAuthConfig(t)
+	cfg.pubKey = &serverKey(t).PublicKey
+	expectAuth(t, cfg, fullAuthServer(t, false))
+}
+
+func TestAuthCachingSHA2FullAuthUnixSocket(t *testing.T) {
+	cfg := testAuthConfig(t)
+	cfg.Net = "unix"
+	expectAuth(t, cfg, func(s *fakeServer) error {
+		if err := s.writeInit("caching_sha2_password"); err != nil {
+			return err
+		}
+		if _, _, err := s.readHandshake(); err != nil {
+			return err
+		}
+		if err := s.writeMoreData(cachingSha2PasswordPerformFullAuthentication); err != nil {
+			return err
+		}
+		data, err := s.read()
+		if err != nil {
+			return err
+		}
+		if string(data) != testAuthPassword+"\x00" {
+			return fmt.Errorf("expected clear text password, got %q", data)
+		}
+		return s.writeOK()
+	})
+}
+
+func TestAuthSHA256PasswordRequestPubKey(t *testing.T) {
+	expectAuth(t, testAuthConfig(t), func(s *fakeServer) error {
+		if err := s.writeInit("sha256_password"); err != nil {
+			return err
+		}
+		resp, _, err := s.readHandshake()
+		if err != nil {
+			return err
+		}
+		if !bytes.Equal(resp, []byte{1}) {
+			return fmt.Errorf("expected public key request, got %x", resp)
+		}
+		if err = s.writeMoreData(serverKeyPEM(t)...); err != nil {
+			return err
+		}
+		data, err := s.read()
+		if err != nil {
+			return err
+		}
+		if pw := decryptPassword(t, data, testScramble); pw != testAuthPassword+"\x00" {
+			return fmt.Errorf("unexpected password %q", pw)
+		}
+		return s.writeOK()
+	})
+}
+
+func TestAuthSHA256PasswordServerPubKey(t *testing.T) {
+	cfg := testAuthConfig(t)
+	cfg.pubKey = &serverKey(t).PublicKey
+	expectAuth(t, cfg, func(s *fakeServer) error {
+		if err := s.writeInit("sha256_password"); err != nil {
+			return err
+		}
+		// the encrypted password doesn't fit in a single length byte
+		resp, _, err := s.readHandshake()
+		if err != nil {
+			return err
+		}
+		if pw := decryptPassword(t, resp, testScramble); pw != testAuthPassword+"\x00" {
+			return fmt.Errorf("unexpected password %q", pw)
+		}
+		return s.writeOK()
+	})
+}
+
+func TestAuthSwitch(t *testing.T) {
+	newScramble := []byte("abcdefghijklmnopqrst")
+	expectAuth(t, testAuthConfig(t), func(s *fakeServer) error {
+		if err := s.writeInit("mysql_native_password"); err != nil {
+			return err
+		}
+		if _, _, err := s.readHandshake(); err != nil {
+			return err
+		}
+		if err := s.writeAuthSwitch("caching_sha2_password", newScramble); err != nil {
+			return err
+		}
+		resp, err := s.read()
+		if err != nil {
+			return err
+		}
+		if !checkSHA256Scramble(resp, newScramble, testAuthPassword) {
+			return fmt.Errorf("invalid scramble %x", resp)
+		}
+		if err = s.writeMoreData(cachingSha2PasswordFastAuthSuccess); err != nil {
+			return err
+		}
+		return s.writeOK()
+	})
+}
+
+func switchServer(from, to string) func(s *fakeServer) error {
+	return func(s *fakeServer) error {
+		if err := s.writeInit(from); err != nil {
+			return err
+		}
+		if _, _, err := s.readHandshake(); err != nil {
+			return err
+		}
+		if err := s.writeAuthSwitch(to, testScramble); err != nil {
+			return err
+		}
+		resp, err := s.read()
+		if err != nil {
+			// the client refused to switch
+			return nil
+		}
+		if !bytes.Equal(resp, scramblePassword(testScramble, []byte(testAuthPassword))) {
+			return fmt.Errorf("invalid scramble %x", resp)
+		}
+		return s.writeOK()
+	}
+}
+
+func TestAuthSwitchNativePassword(t *testing.T) {
+	// MySQL 8 asks accounts using native passwords to switch
+	expectAuth(t, testAuthConfig(t), switchServer("caching_sha2_password", "mysql_native_password"))
+
+	clientErr, serverErr := testAuth(t, testAuthConfig(t), switchServer("mysql_native_password", "mysql_native_password"))
+	if serverErr != nil {
+		t.Fatalf("server: %v", serverErr)
+	}
+	if clientErr != ErrNativePassword {
+		t.Fatalf("expected ErrNativePassword, got %v", clientErr)
+	}
+
+	cfg := testAuthConfig(t)
+	cfg.AllowNativePasswords = true
+	expectAuth(t, cfg, switchServer("mysql_native_password", "mysql_native_password"))
+}
+
+func TestAuthSwitchUnknownPlugin(t *testing.T) {
+	clientErr, serverErr := testAuth(t, testAuthConfig(t), switchServer("mysql_native_password", "dialog"))
+	if serverErr != nil {
+		t.Fatalf("server: %v", serverErr)
+	}
+	if clientErr != ErrUnknownPlugin {
+		t.Fatalf("expected ErrUnknownPlugin, got %v", clientErr)
+	}
+}
+
+func TestDSNServerPubKey(t *testing.T) {
+	f, err := ioutil.TempFile("", "mysql-pubkey")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	f.Write(serverKeyPEM(t))
+	f.Close()
+
+	dsn := "user:password@tcp(localhost:5555)/dbname?serverPubKey=" + url.QueryEscape(f.Name())
+	cfg, err := ParseDSN(dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.ServerPubKey != f.Name() || cfg.pubKey == nil || cfg.pubKey.N.Cmp(serverKey(t).N) != 0 {
+		t.Errorf("unexpected server pub key %q %v", cfg.ServerPubKey, cfg.pubKey)
+	}
+	if out := cfg.FormatDSN(); out != dsn {
+		t.Errorf("expected DSN %q, got %q", dsn, out)
+	}
+
+	if _, err = ParseDSN("/dbname?serverPubKey=" + url.QueryEscape(f.Name()+".missing")); err == nil {
+		t.Error("expected error for missing key file")
+	}
+}
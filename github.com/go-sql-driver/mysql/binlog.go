@@ -0,0 +1,591 @@
+// Go MySQL Driver - A MySQL-Driver for Go's database/sql package
+//
+// Copyright 2017 The Go-MySQL-Driver Authors. All rights reserved.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package mysql
+
+import (
+	"bytes"
+	"database/sql/driver"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// binlog_dump flags
+const (
+	binlogDumpNonBlock     uint16 = 0x01
+	binlogDumpThroughGTID  uint16 = 0x04
+	binlogFileHeaderLength        = 4
+)
+
+var binlogFileMagic = []byte{0xfe, 'b', 'i', 'n'}
+
+// Events are bounded by max_allowed_packet, which is at most 1GB
+const maxBinlogEventSize = 1 << 30
+
+var errBinlogServerID = errors.New("binlog: a server id is required to register as a replica")
+
+// BinlogPosition is a position in the binary log of a server, from which a
+// BinlogStream can be started or resumed.
+type BinlogPosition struct {
+	File    string // Binlog file name
+	Pos     uint32 // Offset in the binlog file
+	GTIDSet string // Executed GTID set, e.g. "3e11fa47-71ca-11e1-9e33-c80aa9429562:1-5"
+}
+
+// BinlogConfig configures a BinlogStream.
+type BinlogConfig struct {
+	// ServerID identifies the stream as a replica of the server. It must be
+	// different from the ids of the server and of its other replicas.
+	ServerID uint32
+
+	// Position is where streaming starts. If GTIDSet is set, the server
+	// sends the transactions missing from the set (GTID auto-positioning).
+	// Otherwise it starts at File and Pos, or at the first binlog file if
+	// File is empty.
+	Position BinlogPosition
+
+	// Heartbeat is the period at which the server sends heartbeat events
+	// when there are no other events. It should be lower than the
+	// readTimeout of the DSN. The server default is used if it is 0.
+	Heartbeat time.Duration
+
+	// NonBlocking makes Next return io.EOF once the end of the binary log
+	// is reached, instead of waiting for new events.
+	NonBlocking bool
+}
+
+// BinlogStream streams the events of the binary log of a server, by
+// registering as a replica.
+//
+//  stream, err := mysql.NewBinlogStream("repl:password@tcp(db:3306)/", mysql.BinlogConfig{
+//      ServerID: 1001,
+//      Position: checkpoint,
+//  })
+//  if err != nil {
+//      log.Fatal(err)
+//  }
+//  defer stream.Close()
+//  for {
+//      ev, err := stream.Next()
+//      if err != nil {
+//          log.Fatal(err)
+//      }
+//      if rows, ok := ev.Data.(*mysql.RowsEvent); ok {
+//          apply(ev.Header.Type, rows)
+//      }
+//      checkpoint = stream.Position()
+//  }
+//
+// The user of the DSN needs the REPLICATION SLAVE privilege. Column values of
+// DATE, DATETIME and TIMESTAMP columns are time.Time values if parseTime is
+// set in the DSN.
+type BinlogStream struct {
+	mc     *mysqlConn
+	nc     net.Conn
+	parser binlogParser
+
+	closeOnce sync.Once
+	closeErr  error
+
+	pos       BinlogPosition
+	committed BinlogPosition
+	gtids     *GTIDSet // nil unless streaming from a GTID set
+	gtid      *GTIDEvent
+}
+
+// NewBinlogStream connects to the server given by the DSN and starts
+// streaming its binary log.
+func NewBinlogStream(dsn string, cfg BinlogConfig) (*BinlogStream, error) {
+	if cfg.ServerID == 0 {
+		return nil, errBinlogServerID
+	}
+
+	s := &BinlogStream{
+		pos:       cfg.Position,
+		committed: cfg.Position,
+	}
+	if cfg.Position.GTIDSet != "" {
+		var err error
+		if s.gtids, err = ParseGTIDSet(cfg.Position.GTIDSet); err != nil {
+			return nil, err
+		}
+	}
+
+	conn, err := MySQLDriver{}.Open(dsn)
+	if err != nil {
+		return nil, err
+	}
+	s.mc = conn.(*mysqlConn)
+	s.nc = s.mc.netConn
+	s.parser.parseTime = s.mc.parseTime
+	s.parser.loc = s.mc.cfg.Loc
+
+	if err = s.start(cfg); err != nil {
+		s.mc.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *BinlogStream) start(cfg BinlogConfig) error {
+	// Announce that checksums are understood, or servers with
+	// binlog_checksum=CRC32 refuse to stream. Servers older than 5.6.2 do
+	// not know the variable.
+	err := s.mc.exec("SET @master_binlog_checksum = @@global.binlog_checksum")
+	if me, ok := err.(*MySQLError); ok && me.Number == 1193 {
+		err = nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if cfg.Heartbeat > 0 {
+		// the period is set in nanoseconds
+		query := "SET @master_heartbeat_period = " + strconv.FormatInt(int64(cfg.Heartbeat), 10)
+		if err = s.mc.exec(query); err != nil {
+			return err
+		}
+	}
+
+	if err = s.mc.writeRegisterSlavePacket(cfg.ServerID); err != nil {
+		return err
+	}
+	if err = s.mc.readResultOK(); err != nil {
+		return err
+	}
+
+	var flags uint16
+	if cfg.NonBlocking {
+		flags |= binlogDumpNonBlock
+	}
+	if s.gtids != nil {
+		return s.mc.writeBinlogDumpGTIDPacket(flags, cfg.ServerID, s.gtids)
+	}
+	pos := cfg.Position.Pos
+	if pos < binlogFileHeaderLength {
+		pos = binlogFileHeaderLength
+	}
+	return s.mc.writeBinlogDumpPacket(flags, cfg.ServerID, cfg.Position.File, pos)
+}
+
+// Next returns the next event of the binary log. It blocks until an event is
+// available, unless the stream is non-blocking, in which case io.EOF is
+// returned at the end of the binary log.
+func (s *BinlogStream) Next() (*BinlogEvent, error) {
+	data, err := s.mc.readPacket()
+	if err != nil {
+		return nil, err
+	}
+
+	switch data[0] {
+	case iOK:
+		// the packet buffer is reused, and the event references its data
+		ev, err := s.parser.parse(append([]byte{}, data[1:]...))
+		if err != nil {
+			return nil, err
+		}
+		s.track(ev)
+		return ev, nil
+
+	case iEOF:
+		return nil, io.EOF
+
+	default:
+		return nil, s.mc.handleErrorPacket(data)
+	}
+}
+
+// Keeps track of the position after the last complete transaction
+func (s *BinlogStream) track(ev *BinlogEvent) {
+	switch e := ev.Data.(type) {
+	case *RotateEvent:
+		// rotations happen between transactions
+		s.pos.File, s.pos.Pos = e.NextFile, uint32(e.Position)
+		s.commit()
+		return
+
+	case *GTIDEvent:
+		s.gtid = e
+	}
+
+	// artificial events are not part of the binlog file
+	if ev.Header.LogPos == 0 || ev.Header.Flags&binlogArtificialFlag != 0 {
+		return
+	}
+	s.pos.Pos = ev.Header.LogPos
+
+	switch e := ev.Data.(type) {
+	case *XIDEvent:
+		s.commit()
+	case *QueryEvent:
+		// statements other than BEGIN are transactions on their own, or
+		// end one
+		if e.Query != "BEGIN" {
+			s.commit()
+		}
+	}
+}
+
+func (s *BinlogStream) commit() {
+	if s.gtid != nil && s.gtids != nil {
+		s.gtids.Add(s.gtid.SID, s.gtid.GNO)
+	}
+	s.gtid = nil
+	s.committed.File, s.committed.Pos = s.pos.File, s.pos.Pos
+	if s.gtids != nil {
+		s.committed.GTIDSet = s.gtids.String()
+	}
+}
+
+// Position returns the position following the last complete transaction
+// returned by Next. Passed in the BinlogConfig, it resumes streaming after
+// that transaction. The GTID set is only maintained if streaming was started
+// from a GTID set.
+func (s *BinlogStream) Position() BinlogPosition {
+	return s.committed
+}
+
+// Close closes the connection to the server. It may be called concurrently
+// with Next to interrupt it.
+func (s *BinlogStream) Close() error {
+	s.closeOnce.Do(func() {
+		// The server doesn't read commands while streaming, so the
+		// connection is simply closed.
+		s.closeErr = s.nc.Close()
+	})
+	return s.closeErr
+}
+
+// BinlogReader reads the events of a binary log file, as written by the
+// server or by mysqlbinlog --raw.
+type BinlogReader struct {
+	r      io.Reader
+	parser binlogParser
+}
+
+// NewBinlogReader returns a BinlogReader reading a binary log file from r.
+// String, decimal and temporal column values are returned as []byte.
+func NewBinlogReader(r io.Reader) (*BinlogReader, error) {
+	magic := make([]byte, len(binlogFileMagic))
+	if _, err := io.ReadFull(r, magic); err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(magic, binlogFileMagic) {
+		return nil, errors.New("binlog: not a binary log file")
+	}
+	return &BinlogReader{r: r, parser: binlogParser{loc: time.UTC}}, nil
+}
+
+// Next returns the next event of the file, or io.EOF at its end.
+func (r *BinlogReader) Next() (*BinlogEvent, error) {
+	header := make([]byte, binlogEventHeaderLength)
+	if _, err := io.ReadFull(r.r, header); err != nil {
+		return nil, err
+	}
+	size := int(binary.LittleEndian.Uint32(header[9:13]))
+	if size < binlogEventHeaderLength || size > maxBinlogEventSize {
+		return nil, ErrMalformPkt
+	}
+	// the buffer grows with the data actually read, so that a corrupt size
+	// doesn't allocate more than the file holds
+	n := size
+	if n > 64<<10 {
+		n = 64 << 10
+	}
+	buf := bytes.NewBuffer(make([]byte, 0, n))
+	buf.Write(header)
+	if _, err := io.CopyN(buf, r.r, int64(size-binlogEventHeaderLength)); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+	return r.parser.parse(buf.Bytes())
+}
+
+/******************************************************************************
+*                          Replication Packets                                *
+******************************************************************************/
+
+// https://dev.mysql.com/doc/internals/en/com-register-slave.html
+func (mc *mysqlConn) writeRegisterSlavePacket(serverID uint32) error {
+	// Reset Packet Sequence
+	mc.sequence = 0
+
+	// command, server id, empty hostname, user and password, port, rank
+	// and master id
+	data := mc.buf.takeSmallBuffer(4 + 1 + 4 + 3 + 2 + 4 + 4)
+	if data == nil {
+		// can not take the buffer. Something must be wrong with the connection
+		errLog.Print(ErrBusyBuffer)
+		return driver.ErrBadConn
+	}
+	for i := range data {
+		data[i] = 0
+	}
+
+	data[4] = comRegisterSlave
+	binary.LittleEndian.PutUint32(data[5:], serverID)
+
+	return mc.writePacket(data)
+}
+
+// https://dev.mysql.com/doc/internals/en/com-binlog-dump.html
+func (mc *mysqlConn) writeBinlogDumpPacket(flags uint16, serverID uint32, file string, pos uint32) error {
+	// Reset Packet Sequence
+	mc.sequence = 0
+
+	data := mc.buf.takeBuffer(4 + 1 + 4 + 2 + 4 + len(file))
+	if data == nil {
+		// can not take the buffer. Something must be wrong with the connection
+		errLog.Print(ErrBusyBuffer)
+		return driver.ErrBadConn
+	}
+
+	data[4] = comBinlogDump
+	binary.LittleEndian.PutUint32(data[5:], pos)
+	binary.LittleEndian.PutUint16(data[9:], flags)
+	binary.LittleEndian.PutUint32(data[11:], serverID)
+	copy(data[15:], file)
+
+	return mc.writePacket(data)
+}
+
+// https://dev.mysql.com/doc/internals/en/com-binlog-dump-gtid.html
+func (mc *mysqlConn) writeBinlogDumpGTIDPacket(flags uint16, serverID uint32, gtids *GTIDSet) error {
+	// Reset Packet Sequence
+	mc.sequence = 0
+
+	set := gtids.encode()
+	data := mc.buf.takeBuffer(4 + 1 + 2 + 4 + 4 + 8 + 4 + len(set))
+	if data == nil {
+		// can not take the buffer. Something must be wrong with the connection
+		errLog.Print(ErrBusyBuffer)
+		return driver.ErrBadConn
+	}
+
+	// The file name is empty and the position 4: the server finds the
+	// first transaction missing from the set.
+	data[4] = comBinlogDumpGTID
+	binary.LittleEndian.PutUint16(data[5:], flags|binlogDumpThroughGTID)
+	binary.LittleEndian.PutUint32(data[7:], serverID)
+	binary.LittleEndian.PutUint32(data[11:], 0)
+	binary.LittleEndian.PutUint64(data[15:], binlogFileHeaderLength)
+	binary.LittleEndian.PutUint32(data[23:], uint32(len(set)))
+	copy(data[27:], set)
+
+	return mc.writePacket(data)
+}
+
+/******************************************************************************
+*                                GTID Sets                                    *
+******************************************************************************/
+
+// GTIDSet is a set of global transaction identifiers, such as the value of the
+// gtid_executed system variable.
+type GTIDSet struct {
+	sids map[string][]gtidInterval // sorted, disjoint intervals by server UUID
+}
+
+// interval of transaction numbers [start, end)
+type gtidInterval struct {
+	start, end int64
+}
+
+// gtidIntervals sorts intervals by start
+type gtidIntervals []gtidInterval
+
+func (ivs gtidIntervals) Len() int           { return len(ivs) }
+func (ivs gtidIntervals) Less(i, j int) bool { return ivs[i].start < ivs[j].start }
+func (ivs gtidIntervals) Swap(i, j int)      { ivs[i], ivs[j] = ivs[j], ivs[i] }
+
+// ParseGTIDSet parses the text representation of a GTID set, e.g.
+// "3e11fa47-71ca-11e1-9e33-c80aa9429562:1-5:11,4b2f8a1c-71ca-11e1-9e33-c80aa9429562:1".
+func ParseGTIDSet(s string) (*GTIDSet, error) {
+	set := &GTIDSet{sids: make(map[string][]gtidInterval)}
+	s = strings.Map(func(r rune) rune {
+		if r == ' ' || r == '\n' || r == '\r' || r == '\t' {
+			return -1
+		}
+		return r
+	}, s)
+	if s == "" {
+		return set, nil
+	}
+
+	for _, part := range strings.Split(s, ",") {
+		fields := strings.Split(part, ":")
+		uuid, err := parseUUID(fields[0])
+		if err != nil {
+			return nil, fmt.Errorf("invalid GTID set %q: %v", s, err)
+		}
+		sid := formatUUID(uuid)
+		for _, field := range fields[1:] {
+			bounds := strings.SplitN(field, "-", 2)
+			start, err := strconv.ParseInt(bounds[0], 10, 64)
+			end := start
+			if err == nil && len(bounds) == 2 {
+				end, err = strconv.ParseInt(bounds[1], 10, 64)
+			}
+			if err != nil || start < 1 || end < start {
+				return nil, fmt.Errorf("invalid GTID set %q: bad interval %q", s, field)
+			}
+			set.addInterval(sid, gtidInterval{start, end + 1})
+		}
+	}
+	return set, nil
+}
+
+// Add adds the transaction number gno of the server with the given UUID to
+// the set.
+func (s *GTIDSet) Add(sid string, gno int64) {
+	if uuid, err := parseUUID(sid); err == nil {
+		s.addInterval(formatUUID(uuid), gtidInterval{gno, gno + 1})
+	}
+}
+
+func (s *GTIDSet) addInterval(sid string, iv gtidInterval) {
+	if s.sids == nil {
+		s.sids = make(map[string][]gtidInterval)
+	}
+	ivs := append(s.sids[sid], iv)
+	sort.Sort(gtidIntervals(ivs))
+
+	// merge overlapping and adjacent intervals
+	merged := ivs[:1]
+	for _, iv := range ivs[1:] {
+		last := &merged[len(merged)-1]
+		if iv.start <= last.end {
+			if iv.end > last.end {
+				last.end = iv.end
+			}
+			continue
+		}
+		merged = append(merged, iv)
+	}
+	s.sids[sid] = merged
+}
+
+// Contains reports whether the transaction number gno of the server with the
+// given UUID is in the set.
+func (s *GTIDSet) Contains(sid string, gno int64) bool {
+	uuid, err := parseUUID(sid)
+	if err != nil {
+		return false
+	}
+	for _, iv := range s.sids[formatUUID(uuid)] {
+		if gno >= iv.start && gno < iv.end {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *GTIDSet) sortedSIDs() []string {
+	sids := make([]string, 0, len(s.sids))
+	for sid := range s.sids {
+		sids = append(sids, sid)
+	}
+	sort.Strings(sids)
+	return sids
+}
+
+// String returns the text representation of the set.
+func (s *GTIDSet) String() string {
+	var buf bytes.Buffer
+	for i, sid := range s.sortedSIDs() {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		buf.WriteString(sid)
+		for _, iv := range s.sids[sid] {
+			buf.WriteByte(':')
+			buf.WriteString(strconv.FormatInt(iv.start, 10))
+			if iv.end-1 > iv.start {
+				buf.WriteByte('-')
+				buf.WriteString(strconv.FormatInt(iv.end-1, 10))
+			}
+		}
+	}
+	return buf.String()
+}
+
+// Binary representation used by COM_BINLOG_DUMP_GTID and PREVIOUS_GTIDS
+// events
+func (s *GTIDSet) encode() []byte {
+	sids := s.sortedSIDs()
+	data := make([]byte, 8, 8+len(sids)*(16+8+16))
+	binary.LittleEndian.PutUint64(data, uint64(len(sids)))
+	var b [8]byte
+	for _, sid := range sids {
+		uuid, _ := parseUUID(sid)
+		data = append(data, uuid[:]...)
+		binary.LittleEndian.PutUint64(b[:], uint64(len(s.sids[sid])))
+		data = append(data, b[:]...)
+		for _, iv := range s.sids[sid] {
+			binary.LittleEndian.PutUint64(b[:], uint64(iv.start))
+			data = append(data, b[:]...)
+			binary.LittleEndian.PutUint64(b[:], uint64(iv.end))
+			data = append(data, b[:]...)
+		}
+	}
+	return data
+}
+
+func decodeGTIDSet(data []byte) (*GTIDSet, error) {
+	set := &GTIDSet{sids: make(map[string][]gtidInterval)}
+	if len(data) < 8 {
+		return nil, ErrMalformPkt
+	}
+	n := binary.LittleEndian.Uint64(data)
+	pos := 8
+	for i := uint64(0); i < n; i++ {
+		if len(data) < pos+16+8 {
+			return nil, ErrMalformPkt
+		}
+		var uuid [16]byte
+		copy(uuid[:], data[pos:])
+		sid := formatUUID(uuid)
+		count := binary.LittleEndian.Uint64(data[pos+16:])
+		pos += 16 + 8
+		for j := uint64(0); j < count; j++ {
+			if len(data) < pos+16 {
+				return nil, ErrMalformPkt
+			}
+			set.addInterval(sid, gtidInterval{
+				int64(binary.LittleEndian.Uint64(data[pos:])),
+				int64(binary.LittleEndian.Uint64(data[pos+8:])),
+			})
+			pos += 16
+		}
+	}
+	return set, nil
+}
+
+func parseUUID(s string) (uuid [16]byte, err error) {
+	b, err := hex.DecodeString(strings.Replace(s, "-", "", -1))
+	if err == nil && len(b) != len(uuid) {
+		err = fmt.Errorf("invalid UUID %q", s)
+	}
+	copy(uuid[:], b)
+	return
+}
+
+func formatUUID(uuid [16]byte) string {
+	s := hex.EncodeToString(uuid[:])
+	return s[:8] + "-" + s[8:12] + "-" + s[12:16] + "-" + s[16:20] + "-" + s[20:]
+}
@@ -0,0 +1,1130 @@
+// Go MySQL Driver - A MySQL-Driver for Go's database/sql package
+//
+// Copyright 2017 The Go-MySQL-Driver Authors. All rights reserved.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package mysql
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// BinlogEventType is the type of a binlog event.
+type BinlogEventType byte
+
+// https://dev.mysql.com/doc/internals/en/binlog-event-type.html
+const (
+	BinlogUnknownEvent BinlogEventType = iota
+	BinlogStartEventV3
+	BinlogQueryEvent
+	BinlogStopEvent
+	BinlogRotateEvent
+	BinlogIntvarEvent
+	BinlogLoadEvent
+	BinlogSlaveEvent
+	BinlogCreateFileEvent
+	BinlogAppendBlockEvent
+	BinlogExecLoadEvent
+	BinlogDeleteFileEvent
+	BinlogNewLoadEvent
+	BinlogRandEvent
+	BinlogUserVarEvent
+	BinlogFormatDescriptionEvent
+	BinlogXIDEvent
+	BinlogBeginLoadQueryEvent
+	BinlogExecuteLoadQueryEvent
+	BinlogTableMapEvent
+	BinlogWriteRowsEventV0
+	BinlogUpdateRowsEventV0
+	BinlogDeleteRowsEventV0
+	BinlogWriteRowsEventV1
+	BinlogUpdateRowsEventV1
+	BinlogDeleteRowsEventV1
+	BinlogIncidentEvent
+	BinlogHeartbeatEvent
+	BinlogIgnorableEvent
+	BinlogRowsQueryEvent
+	BinlogWriteRowsEventV2
+	BinlogUpdateRowsEventV2
+	BinlogDeleteRowsEventV2
+	BinlogGTIDEvent
+	BinlogAnonymousGTIDEvent
+	BinlogPreviousGTIDsEvent
+)
+
+const (
+	binlogEventHeaderLength        = 19
+	binlogArtificialFlag    uint16 = 0x20
+	binlogChecksumAlgCRC32         = 1
+	binlogChecksumLength           = 4
+)
+
+// table_map optional metadata
+// https://dev.mysql.com/doc/dev/mysql-server/latest/classbinary__log_1_1Table__map__event.html
+const (
+	tableMapSignedness byte = 1
+	tableMapColumnName byte = 4
+)
+
+// BinlogEventHeader is the common header of binlog events.
+type BinlogEventHeader struct {
+	Timestamp uint32
+	Type      BinlogEventType
+	ServerID  uint32
+	EventSize uint32
+	LogPos    uint32 // Position of the next event in the binlog file
+	Flags     uint16
+}
+
+// BinlogEvent is an event of the binary log.
+type BinlogEvent struct {
+	Header BinlogEventHeader
+
+	// Data is the decoded event: a *FormatDescriptionEvent, *RotateEvent,
+	// *QueryEvent, *XIDEvent, *GTIDEvent, *PreviousGTIDsEvent,
+	// *TableMapEvent or *RowsEvent. It is nil for other event types.
+	Data interface{}
+
+	// RawData is the body of the event, without header and checksum.
+	RawData []byte
+}
+
+// FormatDescriptionEvent starts every binlog file.
+type FormatDescriptionEvent struct {
+	BinlogVersion uint16
+	ServerVersion string
+	ChecksumAlg   byte // 0 if events have no checksum, 1 for CRC32
+}
+
+// RotateEvent gives the next binlog file.
+type RotateEvent struct {
+	Position uint64
+	NextFile string
+}
+
+// QueryEvent is a statement, such as BEGIN, COMMIT or DDL.
+type QueryEvent struct {
+	ThreadID      uint32
+	ExecutionTime uint32
+	ErrorCode     uint16
+	Schema        string
+	Query         string
+}
+
+// XIDEvent commits a transaction.
+type XIDEvent struct {
+	XID uint64
+}
+
+// GTIDEvent starts a transaction with the identifier SID:GNO.
+type GTIDEvent struct {
+	SID string // UUID of the originating server
+	GNO int64  // Transaction number
+}
+
+// GTID returns the identifier of the transaction, e.g.
+// "3e11fa47-71ca-11e1-9e33-c80aa9429562:23".
+func (e *GTIDEvent) GTID() string {
+	return e.SID + ":" + strconv.FormatInt(e.GNO, 10)
+}
+
+// PreviousGTIDsEvent gives the transactions of the previous binlog files.
+type PreviousGTIDsEvent struct {
+	GTIDSet *GTIDSet
+}
+
+// TableMapEvent describes the table of the following rows events.
+type TableMapEvent struct {
+	TableID     uint64
+	Schema      string
+	Table       string
+	ColumnTypes []byte   // Column types, as in the protocol
+	ColumnMeta  []uint16 // Type specific metadata, such as lengths
+	Nullable    []bool
+
+	// ColumnNames and Unsigned are only known if the server logs them,
+	// e.g. with binlog_row_metadata=FULL (MySQL 8.0.1+). Without signedness,
+	// integers are assumed to be signed.
+	ColumnNames []string
+	Unsigned    []bool
+}
+
+// RowsEvent holds the rows changed by a statement. Whether they were inserted,
+// updated or deleted is given by the type of the event.
+//
+// Column values are nil for NULL, int64 for signed integers and YEAR, uint64
+// for unsigned integers, BIT and SET (bitmask), int64 for ENUM (1-based
+// index), float32 for FLOAT, float64 for DOUBLE, and []byte for strings,
+// blobs, decimals, temporal types and JSON (as text).
+type RowsEvent struct {
+	Table *TableMapEvent
+	Flags uint16
+
+	// Rows holds the inserted rows, the deleted rows, or the rows after an
+	// update. Present tells which columns are part of the row images; others
+	// are nil (binlog_row_image=MINIMAL).
+	Rows    [][]interface{}
+	Present []bool
+
+	// Before holds the rows before an update, matching Rows.
+	Before        [][]interface{}
+	BeforePresent []bool
+}
+
+// binlogParser decodes events. It keeps track of the state needed to decode
+// later events: checksums and table maps.
+type binlogParser struct {
+	checksum   bool
+	postHeader []byte // post-header lengths by event type - 1
+	tables     map[uint64]*TableMapEvent
+
+	parseTime bool
+	loc       *time.Location
+}
+
+func (p *binlogParser) parse(data []byte) (*BinlogEvent, error) {
+	if len(data) < binlogEventHeaderLength {
+		return nil, ErrMalformPkt
+	}
+	ev := &BinlogEvent{Header: BinlogEventHeader{
+		Timestamp: binary.LittleEndian.Uint32(data[0:]),
+		Type:      BinlogEventType(data[4]),
+		ServerID:  binary.LittleEndian.Uint32(data[5:]),
+		EventSize: binary.LittleEndian.Uint32(data[9:]),
+		LogPos:    binary.LittleEndian.Uint32(data[13:]),
+		Flags:     binary.LittleEndian.Uint16(data[17:]),
+	}}
+
+	body := data[binlogEventHeaderLength:]
+	if ev.Header.Type == BinlogFormatDescriptionEvent {
+		fde, err := p.parseFormatDescription(body)
+		if err != nil {
+			return nil, err
+		}
+		ev.Data = fde
+	}
+	if p.checksum {
+		if len(body) < binlogChecksumLength {
+			return nil, ErrMalformPkt
+		}
+		if !hasChecksum(data) {
+			return nil, errors.New("binlog: event checksum mismatch")
+		}
+		body = body[:len(body)-binlogChecksumLength]
+	} else if p.postHeader == nil && ev.Header.Type == BinlogRotateEvent && hasChecksum(data) {
+		// The artificial rotate event sent before the format description
+		// has a checksum if the server uses them.
+		body = body[:len(body)-binlogChecksumLength]
+	}
+	ev.RawData = body
+
+	var err error
+	switch ev.Header.Type {
+	case BinlogRotateEvent:
+		ev.Data, err = parseRotateEvent(body)
+	case BinlogQueryEvent:
+		ev.Data, err = parseQueryEvent(body)
+	case BinlogXIDEvent:
+		if len(body) < 8 {
+			return nil, ErrMalformPkt
+		}
+		ev.Data = &XIDEvent{XID: binary.LittleEndian.Uint64(body)}
+	case BinlogGTIDEvent:
+		if len(body) < 1+16+8 {
+			return nil, ErrMalformPkt
+		}
+		var sid [16]byte
+		copy(sid[:], body[1:])
+		ev.Data = &GTIDEvent{SID: formatUUID(sid), GNO: int64(binary.LittleEndian.Uint64(body[17:]))}
+	case BinlogPreviousGTIDsEvent:
+		var set *GTIDSet
+		if set, err = decodeGTIDSet(body); err == nil {
+			ev.Data = &PreviousGTIDsEvent{GTIDSet: set}
+		}
+	case BinlogTableMapEvent:
+		ev.Data, err = p.parseTableMapEvent(body)
+	case BinlogWriteRowsEventV1, BinlogUpdateRowsEventV1, BinlogDeleteRowsEventV1,
+		BinlogWriteRowsEventV2, BinlogUpdateRowsEventV2, BinlogDeleteRowsEventV2:
+		ev.Data, err = p.parseRowsEvent(ev.Header.Type, body)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return ev, nil
+}
+
+// Reports whether the event ends with its CRC32 checksum
+func hasChecksum(data []byte) bool {
+	n := len(data) - binlogChecksumLength
+	if n < binlogEventHeaderLength {
+		return false
+	}
+	return crc32.ChecksumIEEE(data[:n]) == binary.LittleEndian.Uint32(data[n:])
+}
+
+// https://dev.mysql.com/doc/internals/en/format-description-event.html
+func (p *binlogParser) parseFormatDescription(body []byte) (*FormatDescriptionEvent, error) {
+	if len(body) < 2+50+4+1 {
+		return nil, ErrMalformPkt
+	}
+	fde := &FormatDescriptionEvent{
+		BinlogVersion: binary.LittleEndian.Uint16(body),
+		ServerVersion: string(bytes.TrimRight(body[2:52], "\x00")),
+	}
+	postHeader := body[2+50+4+1:]
+
+	// Since 5.6.1, the event ends with the checksum algorithm and a
+	// checksum
+	if serverVersionProduct(fde.ServerVersion) >= 50601 {
+		if len(postHeader) < 1+binlogChecksumLength {
+			return nil, ErrMalformPkt
+		}
+		fde.ChecksumAlg = postHeader[len(postHeader)-5]
+		postHeader = postHeader[:len(postHeader)-5]
+	}
+	p.checksum = fde.ChecksumAlg == binlogChecksumAlgCRC32
+	p.postHeader = append([]byte{}, postHeader...)
+	return fde, nil
+}
+
+func serverVersionProduct(version string) int {
+	if i := strings.IndexFunc(version, func(r rune) bool { return r != '.' && (r < '0' || r > '9') }); i >= 0 {
+		version = version[:i]
+	}
+	product := 0
+	parts := strings.SplitN(version, ".", 3)
+	for i := 0; i < 3; i++ {
+		product *= 100
+		if i < len(parts) {
+			n, _ := strconv.Atoi(parts[i])
+			product += n
+		}
+	}
+	return product
+}
+
+// Size of table ids in table map and rows events
+func (p *binlogParser) tableIDSize(typ BinlogEventType) int {
+	if int(typ) <= len(p.postHeader) && p.postHeader[typ-1] == 6 {
+		return 4
+	}
+	return 6
+}
+
+// https://dev.mysql.com/doc/internals/en/rotate-event.html
+func parseRotateEvent(body []byte) (*RotateEvent, error) {
+	if len(body) < 8 {
+		return nil, ErrMalformPkt
+	}
+	return &RotateEvent{
+		Position: binary.LittleEndian.Uint64(body),
+		NextFile: string(body[8:]),
+	}, nil
+}
+
+// https://dev.mysql.com/doc/internals/en/query-event.html
+func parseQueryEvent(body []byte) (*QueryEvent, error) {
+	if len(body) < 4+4+1+2+2 {
+		return nil, ErrMalformPkt
+	}
+	e := &QueryEvent{
+		ThreadID:      binary.LittleEndian.Uint32(body),
+		ExecutionTime: binary.LittleEndian.Uint32(body[4:]),
+		ErrorCode:     binary.LittleEndian.Uint16(body[9:]),
+	}
+	schemaLen := int(body[8])
+	pos := 13 + int(binary.LittleEndian.Uint16(body[11:]))
+	if len(body) < pos+schemaLen+1 {
+		return nil, ErrMalformPkt
+	}
+	e.Schema = string(body[pos : pos+schemaLen])
+	e.Query = string(body[pos+schemaLen+1:])
+	return e, nil
+}
+
+func readTableID(data []byte, size int) uint64 {
+	var b [8]byte
+	copy(b[:], data[:size])
+	return binary.LittleEndian.Uint64(b[:])
+}
+
+// https://dev.mysql.com/doc/internals/en/table-map-event.html
+func (p *binlogParser) parseTableMapEvent(body []byte) (*TableMapEvent, error) {
+	idSize := p.tableIDSize(BinlogTableMapEvent)
+	if len(body) < idSize+2+1 {
+		return nil, ErrMalformPkt
+	}
+	e := &TableMapEvent{TableID: readTableID(body, idSize)}
+	pos := idSize + 2
+
+	// schema and table [length prefixed, null terminated string]
+	for _, name := range []*string{&e.Schema, &e.Table} {
+		if len(body) < pos+1 {
+			return nil, ErrMalformPkt
+		}
+		n := int(body[pos])
+		if len(body) < pos+1+n+1 {
+			return nil, ErrMalformPkt
+		}
+		*name = string(body[pos+1 : pos+1+n])
+		pos += 1 + n + 1
+	}
+
+	count, _, n := readLengthEncodedInteger(body[pos:])
+	pos += n
+	if uint64(len(body)) < uint64(pos)+count {
+		return nil, ErrMalformPkt
+	}
+	e.ColumnTypes = body[pos : pos+int(count)]
+	pos += int(count)
+
+	meta, _, n, err := readLengthEncodedString(body[pos:])
+	if err != nil {
+		return nil, err
+	}
+	pos += n
+	if e.ColumnMeta, err = parseColumnMeta(e.ColumnTypes, meta); err != nil {
+		return nil, err
+	}
+
+	nullBitmap := (len(e.ColumnTypes) + 7) / 8
+	if len(body) < pos+nullBitmap {
+		return nil, ErrMalformPkt
+	}
+	e.Nullable = readBitmap(body[pos:], len(e.ColumnTypes))
+	pos += nullBitmap
+
+	// optional metadata [type, length encoded value]
+	for pos < len(body) {
+		typ := body[pos]
+		value, _, n, err := readLengthEncodedString(body[pos+1:])
+		if err != nil {
+			return nil, err
+		}
+		pos += 1 + n
+		switch typ {
+		case tableMapSignedness:
+			e.Unsigned = make([]bool, len(e.ColumnTypes))
+			i := 0
+			for col, t := range e.ColumnTypes {
+				if isNumericType(t) {
+					if i/8 >= len(value) {
+						return nil, ErrMalformPkt
+					}
+					e.Unsigned[col] = value[i/8]&(0x80>>uint(i%8)) != 0
+					i++
+				}
+			}
+		case tableMapColumnName:
+			for len(value) > 0 {
+				name, _, n, err := readLengthEncodedString(value)
+				if err != nil {
+					return nil, err
+				}
+				e.ColumnNames = append(e.ColumnNames, string(name))
+				value = value[n:]
+			}
+		}
+	}
+
+	if p.tables == nil {
+		p.tables = make(map[uint64]*TableMapEvent)
+	}
+	p.tables[e.TableID] = e
+	return e, nil
+}
+
+func isNumericType(t byte) bool {
+	switch t {
+	case fieldTypeTiny, fieldTypeShort, fieldTypeInt24, fieldTypeLong, fieldTypeLongLong,
+		fieldTypeFloat, fieldTypeDouble, fieldTypeDecimal, fieldTypeNewDecimal:
+		return true
+	}
+	return false
+}
+
+func parseColumnMeta(types []byte, data []byte) ([]uint16, error) {
+	meta := make([]uint16, len(types))
+	pos := 0
+	for i, t := range types {
+		var n int
+		switch t {
+		case fieldTypeFloat, fieldTypeDouble, fieldTypeBLOB, fieldTypeGeometry, fieldTypeJSON,
+			fieldTypeTimestamp2, fieldTypeDateTime2, fieldTypeTime2:
+			n = 1
+			if len(data) >= pos+n {
+				meta[i] = uint16(data[pos])
+			}
+		case fieldTypeVarChar, fieldTypeVarString, fieldTypeBit:
+			n = 2
+			if len(data) >= pos+n {
+				meta[i] = binary.LittleEndian.Uint16(data[pos:])
+			}
+		case fieldTypeString, fieldTypeNewDecimal, fieldTypeEnum, fieldTypeSet:
+			// real type or precision first
+			n = 2
+			if len(data) >= pos+n {
+				meta[i] = uint16(data[pos])<<8 | uint16(data[pos+1])
+			}
+		}
+		pos += n
+		if pos > len(data) {
+			return nil, ErrMalformPkt
+		}
+	}
+	return meta, nil
+}
+
+// Reads a bitmap of n bits, least significant bit first
+func readBitmap(data []byte, n int) []bool {
+	bits := make([]bool, n)
+	for i := range bits {
+		bits[i] = data[i/8]&(1<<uint(i%8)) != 0
+	}
+	return bits
+}
+
+// https://dev.mysql.com/doc/internals/en/rows-event.html
+func (p *binlogParser) parseRowsEvent(typ BinlogEventType, body []byte) (*RowsEvent, error) {
+	idSize := p.tableIDSize(typ)
+	if len(body) < idSize+2 {
+		return nil, ErrMalformPkt
+	}
+	tableID := readTableID(body, idSize)
+	e := &RowsEvent{Flags: binary.LittleEndian.Uint16(body[idSize:])}
+	pos := idSize + 2
+
+	if typ >= BinlogWriteRowsEventV2 {
+		// extra data, its length includes the length itself
+		if len(body) < pos+2 {
+			return nil, ErrMalformPkt
+		}
+		pos += int(binary.LittleEndian.Uint16(body[pos:]))
+	}
+
+	if len(body) <= pos {
+		return nil, ErrMalformPkt
+	}
+	count, _, n := readLengthEncodedInteger(body[pos:])
+	pos += n
+	bitmapLen := (int(count) + 7) / 8
+
+	update := typ == BinlogUpdateRowsEventV1 || typ == BinlogUpdateRowsEventV2
+	if len(body) < pos+bitmapLen {
+		return nil, ErrMalformPkt
+	}
+	present := readBitmap(body[pos:], int(count))
+	pos += bitmapLen
+	if update {
+		if len(body) < pos+bitmapLen {
+			return nil, ErrMalformPkt
+		}
+		e.BeforePresent = present
+		present = readBitmap(body[pos:], int(count))
+		pos += bitmapLen
+	}
+	e.Present = present
+
+	e.Table = p.tables[tableID]
+	if e.Table == nil {
+		if pos == len(body) {
+			// dummy end of statement event
+			return e, nil
+		}
+		return nil, fmt.Errorf("binlog: rows event for unknown table id %d", tableID)
+	}
+	if int(count) != len(e.Table.ColumnTypes) {
+		return nil, fmt.Errorf("binlog: rows event with %d columns for table %s.%s with %d columns",
+			count, e.Table.Schema, e.Table.Table, len(e.Table.ColumnTypes))
+	}
+
+	for pos < len(body) {
+		if update {
+			row, n, err := p.readRow(e.Table, e.BeforePresent, body[pos:])
+			if err != nil {
+				return nil, err
+			}
+			e.Before = append(e.Before, row)
+			pos += n
+		}
+		row, n, err := p.readRow(e.Table, e.Present, body[pos:])
+		if err != nil {
+			return nil, err
+		}
+		e.Rows = append(e.Rows, row)
+		pos += n
+	}
+	return e, nil
+}
+
+func (p *binlogParser) readRow(table *TableMapEvent, present []bool, data []byte) ([]interface{}, int, error) {
+	n := 0
+	for _, ok := range present {
+		if ok {
+			n++
+		}
+	}
+	bitmapLen := (n + 7) / 8
+	if len(data) < bitmapLen {
+		return nil, 0, ErrMalformPkt
+	}
+	nulls := readBitmap(data, n)
+	pos := bitmapLen
+
+	row := make([]interface{}, len(present))
+	i := 0
+	for col, ok := range present {
+		if !ok {
+			continue
+		}
+		if nulls[i] {
+			i++
+			continue
+		}
+		i++
+
+		unsigned := table.Unsigned != nil && table.Unsigned[col]
+		v, n, err := p.readValue(data[pos:], table.ColumnTypes[col], table.ColumnMeta[col], unsigned)
+		if err != nil {
+			return nil, 0, fmt.Errorf("binlog: column %d of %s.%s: %v", col, table.Schema, table.Table, err)
+		}
+		row[col] = v
+		pos += n
+	}
+	return row, pos, nil
+}
+
+/******************************************************************************
+*                              Column Values                                  *
+******************************************************************************/
+
+func readUintLE(data []byte, n int) uint64 {
+	var v uint64
+	for i := n - 1; i >= 0; i-- {
+		v = v<<8 | uint64(data[i])
+	}
+	return v
+}
+
+func readUintBE(data []byte, n int) uint64 {
+	var v uint64
+	for i := 0; i < n; i++ {
+		v = v<<8 | uint64(data[i])
+	}
+	return v
+}
+
+// Reads an integer of n bytes, sign-extending it unless it is unsigned
+func readInt(data []byte, n int, unsigned bool) interface{} {
+	v := readUintLE(data, n)
+	if unsigned {
+		return v
+	}
+	shift := uint(64 - 8*n)
+	return int64(v<<shift) >> shift
+}
+
+// https://dev.mysql.com/doc/internals/en/binary-protocol-value.html
+func (p *binlogParser) readValue(data []byte, typ byte, meta uint16, unsigned bool) (interface{}, int, error) {
+	// the length of the value, if it is fixed
+	var n int
+	switch typ {
+	case fieldTypeTiny, fieldTypeYear:
+		n = 1
+	case fieldTypeShort:
+		n = 2
+	case fieldTypeInt24, fieldTypeDate, fieldTypeTime:
+		n = 3
+	case fieldTypeLong, fieldTypeFloat, fieldTypeTimestamp:
+		n = 4
+	case fieldTypeLongLong, fieldTypeDouble, fieldTypeDateTime:
+		n = 8
+	case fieldTypeTimestamp2:
+		n = 4 + (int(meta)+1)/2
+	case fieldTypeDateTime2:
+		n = 5 + (int(meta)+1)/2
+	case fieldTypeTime2:
+		n = 3 + (int(meta)+1)/2
+	}
+	if len(data) < n {
+		return nil, 0, ErrMalformPkt
+	}
+
+	switch typ {
+	case fieldTypeTiny, fieldTypeShort, fieldTypeInt24, fieldTypeLong, fieldTypeLongLong:
+		return readInt(data, n, unsigned), n, nil
+
+	case fieldTypeYear:
+		if data[0] == 0 {
+			return int64(0), n, nil
+		}
+		return int64(data[0]) + 1900, n, nil
+
+	case fieldTypeFloat:
+		return math.Float32frombits(binary.LittleEndian.Uint32(data)), n, nil
+
+	case fieldTypeDouble:
+		return math.Float64frombits(binary.LittleEndian.Uint64(data)), n, nil
+
+	case fieldTypeNewDecimal:
+		return decodeDecimal(data, int(meta>>8), int(meta&0xff))
+
+	case fieldTypeDate:
+		v := readUintLE(data, 3)
+		s := fmt.Sprintf("%04d-%02d-%02d", v>>9, (v>>5)&15, v&31)
+		return p.temporal(s), n, nil
+
+	case fieldTypeTime:
+		v := readInt(data, 3, false).(int64)
+		sign := ""
+		if v < 0 {
+			sign, v = "-", -v
+		}
+		return []byte(fmt.Sprintf("%s%02d:%02d:%02d", sign, v/10000, v/100%100, v%100)), n, nil
+
+	case fieldTypeDateTime:
+		v := binary.LittleEndian.Uint64(data)
+		d, t := v/1000000, v%1000000
+		s := fmt.Sprintf("%04d-%02d-%02d %02d:%02d:%02d", d/10000, d/100%100, d%100, t/10000, t/100%100, t%100)
+		return p.temporal(s), n, nil
+
+	case fieldTypeTimestamp:
+		t := time.Unix(int64(binary.LittleEndian.Uint32(data)), 0)
+		return p.timestamp(t, 0), n, nil
+
+	case fieldTypeTimestamp2:
+		usec, _ := readFraction(data[4:], int(meta))
+		t := time.Unix(int64(binary.BigEndian.Uint32(data)), int64(usec)*1000)
+		return p.timestamp(t, int(meta)), n, nil
+
+	case fieldTypeDateTime2:
+		// sign (1 bit), year*13+month (17 bits), day (5 bits), hour (5 bits),
+		// minute (6 bits), second (6 bits)
+		v := int64(readUintBE(data, 5)) - 0x8000000000
+		usec, _ := readFraction(data[5:], int(meta))
+		ymd, hms := v>>17, v%(1<<17)
+		ym := ymd >> 5
+		s := fmt.Sprintf("%04d-%02d-%02d %02d:%02d:%02d", ym/13, ym%13, ymd%(1<<5),
+			hms>>12, (hms>>6)%(1<<6), hms%(1<<6))
+		return p.temporal(s + formatFraction(usec, int(meta))), n, nil
+
+	case fieldTypeTime2:
+		return decodeTime2(data, int(meta)), n, nil
+
+	case fieldTypeVarChar, fieldTypeVarString:
+		return readLengthPrefixed(data, lengthBytes(int(meta)))
+
+	case fieldTypeString, fieldTypeEnum, fieldTypeSet:
+		realType, length := byte(meta>>8), int(meta&0xff)
+		if typ != fieldTypeString {
+			realType = typ
+		} else if realType&0x30 != 0x30 {
+			// lengths over 255 are stored in the real type
+			length |= int((realType&0x30)^0x30) << 4
+			realType |= 0x30
+		}
+		switch realType {
+		case fieldTypeEnum:
+			if len(data) < length {
+				return nil, 0, ErrMalformPkt
+			}
+			return int64(readUintLE(data, length)), length, nil
+		case fieldTypeSet:
+			if len(data) < length || length > 8 {
+				return nil, 0, ErrMalformPkt
+			}
+			return readUintLE(data, length), length, nil
+		}
+		return readLengthPrefixed(data, lengthBytes(length))
+
+	case fieldTypeBit:
+		length := int(meta>>8) + (int(meta&0xff)+7)/8
+		if len(data) < length || length > 8 {
+			return nil, 0, ErrMalformPkt
+		}
+		return readUintBE(data, length), length, nil
+
+	case fieldTypeBLOB, fieldTypeTinyBLOB, fieldTypeMediumBLOB, fieldTypeLongBLOB, fieldTypeGeometry:
+		return readLengthPrefixed(data, int(meta))
+
+	case fieldTypeJSON:
+		b, n, err := readLengthPrefixed(data, int(meta))
+		if err != nil {
+			return nil, 0, err
+		}
+		doc, err := decodeJSON(b.([]byte))
+		return doc, n, err
+	}
+	return nil, 0, fmt.Errorf("unsupported column type %d", typ)
+}
+
+func lengthBytes(maxLength int) int {
+	if maxLength < 256 {
+		return 1
+	}
+	return 2
+}
+
+func readLengthPrefixed(data []byte, n int) (interface{}, int, error) {
+	if n < 1 || n > 4 || len(data) < n {
+		return nil, 0, ErrMalformPkt
+	}
+	length := int(readUintLE(data, n))
+	if len(data) < n+length {
+		return nil, 0, ErrMalformPkt
+	}
+	return data[n : n+length], n + length, nil
+}
+
+// Reads the fractional seconds of TIMESTAMP2, DATETIME2 and TIME2 values, and
+// returns them in microseconds.
+func readFraction(data []byte, fsp int) (int, int) {
+	switch (fsp + 1) / 2 {
+	case 1:
+		return int(data[0]) * 10000, 1
+	case 2:
+		return int(binary.BigEndian.Uint16(data)) * 100, 2
+	case 3:
+		return int(readUintBE(data, 3)), 3
+	}
+	return 0, 0
+}
+
+func formatFraction(usec, fsp int) string {
+	if fsp == 0 {
+		return ""
+	}
+	return "." + fmt.Sprintf("%06d", usec)[:fsp]
+}
+
+// Returns the value of a DATE or DATETIME column
+func (p *binlogParser) temporal(s string) interface{} {
+	if p.parseTime {
+		if t, err := parseDateTime(s, p.loc); err == nil {
+			return t
+		}
+	}
+	return []byte(s)
+}
+
+// Returns the value of a TIMESTAMP column, in the location of the connection
+func (p *binlogParser) timestamp(t time.Time, fsp int) interface{} {
+	if t.Unix() == 0 && t.Nanosecond() == 0 {
+		// zero timestamp
+		return p.temporal("0000-00-00 00:00:00" + formatFraction(0, fsp))
+	}
+	t = t.In(p.loc)
+	if p.parseTime {
+		return t
+	}
+	return []byte(t.Format("2006-01-02 15:04:05") + formatFraction(t.Nanosecond()/1000, fsp))
+}
+
+// https://github.com/mysql/mysql-server/blob/5.7/sql-common/my_time.c
+// (my_time_packed_from_binary)
+func decodeTime2(data []byte, fsp int) []byte {
+	intPart := int64(readUintBE(data, 3)) - 0x800000
+	packed := intPart << 24
+	switch (fsp + 1) / 2 {
+	case 1:
+		frac := int64(data[3])
+		if intPart < 0 && frac > 0 {
+			intPart++
+			frac -= 0x100
+		}
+		packed = intPart<<24 + frac*10000
+	case 2:
+		frac := int64(binary.BigEndian.Uint16(data[3:]))
+		if intPart < 0 && frac > 0 {
+			intPart++
+			frac -= 0x10000
+		}
+		packed = intPart<<24 + frac*100
+	case 3:
+		packed = int64(readUintBE(data, 6)) - 0x800000000000
+	}
+
+	sign := ""
+	if packed < 0 {
+		sign, packed = "-", -packed
+	}
+	intPart, frac := packed>>24, packed%(1<<24)
+	s := fmt.Sprintf("%s%02d:%02d:%02d", sign, (intPart>>12)%(1<<10), (intPart>>6)%(1<<6), intPart%(1<<6))
+	return []byte(s + formatFraction(int(frac), fsp))
+}
+
+var decimalDigitsBytes = [10]int{0, 1, 1, 2, 2, 3, 3, 4, 4, 4}
+
+// Decodes a DECIMAL value, stored as groups of 9 digits in 4 bytes (big
+// endian), with the remaining leading and trailing digits in fewer bytes.
+// https://dev.mysql.com/doc/refman/5.7/en/precision-math-decimal-characteristics.html
+func decodeDecimal(data []byte, precision, scale int) (interface{}, int, error) {
+	intg := precision - scale
+	intg0, intg0x := intg/9, intg%9
+	frac0, frac0x := scale/9, scale%9
+	size := intg0*4 + decimalDigitsBytes[intg0x] + frac0*4 + decimalDigitsBytes[frac0x]
+	if len(data) < size || size == 0 {
+		return nil, 0, ErrMalformPkt
+	}
+
+	buf := make([]byte, size)
+	copy(buf, data)
+	// the sign is stored in the highest bit, negative values are inverted
+	negative := buf[0]&0x80 == 0
+	buf[0] ^= 0x80
+	if negative {
+		for i := range buf {
+			buf[i] ^= 0xff
+		}
+	}
+
+	var digits bytes.Buffer
+	pos := 0
+	group := func(n, width int) {
+		fmt.Fprintf(&digits, "%0*d", width, readUintBE(buf[pos:], n))
+		pos += n
+	}
+	if intg0x > 0 {
+		group(decimalDigitsBytes[intg0x], intg0x)
+	}
+	for i := 0; i < intg0; i++ {
+		group(4, 9)
+	}
+	intPart := strings.TrimLeft(digits.String(), "0")
+	if intPart == "" {
+		intPart = "0"
+	}
+
+	digits.Reset()
+	for i := 0; i < frac0; i++ {
+		group(4, 9)
+	}
+	if frac0x > 0 {
+		group(decimalDigitsBytes[frac0x], frac0x)
+	}
+
+	s := intPart
+	if scale > 0 {
+		s += "." + digits.String()
+	}
+	if negative {
+		s = "-" + s
+	}
+	return []byte(s), size, nil
+}
+
+/******************************************************************************
+*                                Binary JSON                                  *
+******************************************************************************/
+
+// https://github.com/mysql/mysql-server/blob/5.7/sql/json_binary.h
+const (
+	jsonSmallObject byte = iota
+	jsonLargeObject
+	jsonSmallArray
+	jsonLargeArray
+	jsonLiteral
+	jsonInt16
+	jsonUint16
+	jsonInt32
+	jsonUint32
+	jsonInt64
+	jsonUint64
+	jsonDouble
+	jsonString
+	jsonOpaque byte = 0x0f
+)
+
+const (
+	jsonLiteralNull byte = iota
+	jsonLiteralTrue
+	jsonLiteralFalse
+)
+
+// Converts a JSON document from the binary format of MySQL to text
+func decodeJSON(data []byte) ([]byte, error) {
+	if len(data) == 0 {
+		return []byte("null"), nil
+	}
+	var buf bytes.Buffer
+	if err := writeJSONValue(&buf, data[0], data[1:]); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func writeJSONValue(buf *bytes.Buffer, typ byte, data []byte) error {
+	var n int
+	switch typ {
+	case jsonLiteral:
+		n = 1
+	case jsonInt16, jsonUint16:
+		n = 2
+	case jsonInt32, jsonUint32:
+		n = 4
+	case jsonInt64, jsonUint64, jsonDouble:
+		n = 8
+	}
+	if len(data) < n {
+		return ErrMalformPkt
+	}
+
+	switch typ {
+	case jsonSmallObject, jsonLargeObject, jsonSmallArray, jsonLargeArray:
+		return writeJSONContainer(buf, data, typ == jsonSmallObject || typ == jsonLargeObject,
+			typ == jsonLargeObject || typ == jsonLargeArray)
+
+	case jsonLiteral:
+		switch data[0] {
+		case jsonLiteralNull:
+			buf.WriteString("null")
+		case jsonLiteralTrue:
+			buf.WriteString("true")
+		case jsonLiteralFalse:
+			buf.WriteString("false")
+		default:
+			return ErrMalformPkt
+		}
+
+	case jsonInt16, jsonInt32, jsonInt64:
+		buf.WriteString(strconv.FormatInt(readInt(data, n, false).(int64), 10))
+
+	case jsonUint16, jsonUint32, jsonUint64:
+		buf.WriteString(strconv.FormatUint(readUintLE(data, n), 10))
+
+	case jsonDouble:
+		f := math.Float64frombits(binary.LittleEndian.Uint64(data))
+		buf.WriteString(strconv.FormatFloat(f, 'g', -1, 64))
+
+	case jsonString:
+		s, err := readJSONString(data)
+		if err != nil {
+			return err
+		}
+		writeJSONString(buf, s)
+
+	case jsonOpaque:
+		// like MySQL, opaque values (e.g. dates) are printed as base64
+		if len(data) < 1 {
+			return ErrMalformPkt
+		}
+		s, err := readJSONString(data[1:])
+		if err != nil {
+			return err
+		}
+		writeJSONString(buf, []byte(fmt.Sprintf("base64:type%d:%s", data[0], base64.StdEncoding.EncodeToString(s))))
+
+	default:
+		return fmt.Errorf("unknown JSON value type %d", typ)
+	}
+	return nil
+}
+
+func writeJSONString(buf *bytes.Buffer, s []byte) {
+	b, _ := json.Marshal(string(s))
+	buf.Write(b)
+}
+
+// Reads a string with a variable length prefix: 7 bits per byte, the highest
+// bit telling whether more bytes follow
+func readJSONString(data []byte) ([]byte, error) {
+	var length, n int
+	for n < 5 {
+		if n >= len(data) {
+			return nil, ErrMalformPkt
+		}
+		b := data[n]
+		length |= int(b&0x7f) << uint(7*n)
+		n++
+		if b&0x80 == 0 {
+			break
+		}
+	}
+	if len(data) < n+length {
+		return nil, ErrMalformPkt
+	}
+	return data[n : n+length], nil
+}
+
+func writeJSONContainer(buf *bytes.Buffer, data []byte, object, large bool) error {
+	offsetSize := 2
+	if large {
+		offsetSize = 4
+	}
+	if len(data) < 2*offsetSize {
+		return ErrMalformPkt
+	}
+	count := int(readUintLE(data, offsetSize))
+	size := int(readUintLE(data[offsetSize:], offsetSize))
+	if size > len(data) {
+		return ErrMalformPkt
+	}
+	data = data[:size]
+
+	keyEntries := 2 * offsetSize
+	valueEntries := keyEntries
+	if object {
+		valueEntries += count * (offsetSize + 2)
+	}
+	if valueEntries+count*(1+offsetSize) > size {
+		return ErrMalformPkt
+	}
+
+	if object {
+		buf.WriteByte('{')
+	} else {
+		buf.WriteByte('[')
+	}
+	for i := 0; i < count; i++ {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		if object {
+			e := keyEntries + i*(offsetSize+2)
+			off := int(readUintLE(data[e:], offsetSize))
+			length := int(binary.LittleEndian.Uint16(data[e+offsetSize:]))
+			if off+length > size {
+				return ErrMalformPkt
+			}
+			writeJSONString(buf, data[off:off+length])
+			buf.WriteByte(':')
+		}
+
+		e := valueEntries + i*(1+offsetSize)
+		typ := data[e]
+		inlined := typ == jsonLiteral || typ == jsonInt16 || typ == jsonUint16 ||
+			(large && (typ == jsonInt32 || typ == jsonUint32))
+		var err error
+		if inlined {
+			err = writeJSONValue(buf, typ, data[e+1:e+1+offsetSize])
+		} else {
+			off := int(readUintLE(data[e+1:], offsetSize))
+			if off >= size {
+				return ErrMalformPkt
+			}
+			err = writeJSONValue(buf, typ, data[off:])
+		}
+		if err != nil {
+			return err
+		}
+	}
+	if object {
+		buf.WriteByte('}')
+	} else {
+		buf.WriteByte(']')
+	}
+	return nil
+}
@@ -0,0 +1,447 @@
+// Go MySQL Driver - A MySQL-Driver for Go's database/sql package
+//
+// Copyright 2017 The Go-MySQL-Driver Authors. All rights reserved.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package mysql
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"io/ioutil"
+	"net"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testdata/binlog.000001 is a binlog file in the format of MySQL 8.0.11, with
+// binlog_row_metadata=FULL and binlog_checksum=CRC32, for the statements:
+//
+//  CREATE TABLE t (id INT PRIMARY KEY, name VARCHAR(32), price DECIMAL(10,2),
+//      created DATETIME(3), flags TINYINT UNSIGNED, doc JSON, note TEXT,
+//      born DATE, dur TIME, ts TIMESTAMP(6) NULL, score DOUBLE,
+//      color ENUM('red','green'), bits BIT(10), y YEAR, code CHAR(4));
+//  INSERT INTO t VALUES (1, ...), (-5, ...);
+//  UPDATE t SET name = 'bobby', note = 'hi' WHERE id = -5;
+//  DELETE FROM t WHERE id = 1;
+//  CREATE TABLE u (a INT);
+//  FLUSH BINARY LOGS;
+const (
+	binlogTestFile = "testdata/binlog.000001"
+	binlogTestSID  = "3e11fa47-71ca-11e1-9e33-c80aa9429562"
+)
+
+var binlogTestColumns = []string{"id", "name", "price", "created", "flags", "doc", "note",
+	"born", "dur", "ts", "score", "color", "bits", "y", "code"}
+
+var binlogTestRow1 = []interface{}{int64(1), []byte("alice"), []byte("12.50"),
+	[]byte("2017-05-01 12:34:56.789"), uint64(200), []byte(`{"a":1,"b":[true,"x"]}`), nil,
+	[]byte("1990-02-03"), []byte("-01:02:03"), []byte("2017-05-01 10:00:00.123456"), float64(3.25),
+	int64(2), uint64(513), int64(2017), []byte("ab")}
+
+var binlogTestRow2 = []interface{}{int64(-5), []byte("bob"), []byte("-3.07"),
+	[]byte("2000-01-01 00:00:00.000"), uint64(0), []byte(`[1,2.5,null]`), []byte("hello"),
+	nil, []byte("12:00:00"), nil, nil, int64(1), uint64(0), nil, []byte("xyz")}
+
+func readBinlogTestFile(t *testing.T) []*BinlogEvent {
+	data, err := ioutil.ReadFile(binlogTestFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err := NewBinlogReader(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var events []*BinlogEvent
+	for {
+		ev, err := r.Next()
+		if err == io.EOF {
+			return events
+		}
+		if err != nil {
+			t.Fatalf("event %d: %v", len(events), err)
+		}
+		events = append(events, ev)
+	}
+}
+
+func checkRows(t *testing.T, name string, rows, expected [][]interface{}) {
+	if !reflect.DeepEqual(rows, expected) {
+		t.Errorf("%s: expected rows\n%q\ngot\n%q", name, expected, rows)
+	}
+}
+
+func TestBinlogReader(t *testing.T) {
+	events := readBinlogTestFile(t)
+
+	var types []BinlogEventType
+	for _, ev := range events {
+		types = append(types, ev.Header.Type)
+	}
+	expected := []BinlogEventType{
+		BinlogFormatDescriptionEvent, BinlogPreviousGTIDsEvent,
+		BinlogGTIDEvent, BinlogQueryEvent, BinlogTableMapEvent, BinlogWriteRowsEventV2, BinlogXIDEvent,
+		BinlogGTIDEvent, BinlogQueryEvent, BinlogTableMapEvent, BinlogUpdateRowsEventV2, BinlogXIDEvent,
+		BinlogGTIDEvent, BinlogQueryEvent, BinlogTableMapEvent, BinlogDeleteRowsEventV2, BinlogXIDEvent,
+		BinlogGTIDEvent, BinlogQueryEvent,
+		BinlogRotateEvent,
+	}
+	if !reflect.DeepEqual(types, expected) {
+		t.Fatalf("expected events %v, got %v", expected, types)
+	}
+
+	fde := events[0].Data.(*FormatDescriptionEvent)
+	if fde.BinlogVersion != 4 || fde.ServerVersion != "8.0.11" || fde.ChecksumAlg != binlogChecksumAlgCRC32 {
+		t.Errorf("unexpected format description %+v", fde)
+	}
+	if prev := events[1].Data.(*PreviousGTIDsEvent); prev.GTIDSet.String() != "" {
+		t.Errorf("expected empty previous GTIDs, got %q", prev.GTIDSet)
+	}
+	if gtid := events[2].Data.(*GTIDEvent); gtid.GTID() != binlogTestSID+":1" {
+		t.Errorf("unexpected GTID %q", gtid.GTID())
+	}
+	if q := events[3].Data.(*QueryEvent); q.Query != "BEGIN" || q.Schema != "test" {
+		t.Errorf("unexpected query %+v", q)
+	}
+
+	table := events[4].Data.(*TableMapEvent)
+	if table.TableID != 108 || table.Schema != "test" || table.Table != "t" {
+		t.Errorf("unexpected table map %+v", table)
+	}
+	if !reflect.DeepEqual(table.ColumnNames, binlogTestColumns) {
+		t.Errorf("expected columns %q, got %q", binlogTestColumns, table.ColumnNames)
+	}
+	if table.Nullable[0] || !table.Nullable[1] {
+		t.Errorf("unexpected nullability %v", table.Nullable)
+	}
+	if !table.Unsigned[4] || table.Unsigned[0] {
+		t.Errorf("unexpected signedness %v", table.Unsigned)
+	}
+
+	rows := events[5].Data.(*RowsEvent)
+	if rows.Table != table || rows.Before != nil {
+		t.Errorf("unexpected write rows %+v", rows)
+	}
+	checkRows(t, "write", rows.Rows, [][]interface{}{binlogTestRow1, binlogTestRow2})
+
+	updated := append([]interface{}{}, binlogTestRow2...)
+	updated[1], updated[6] = []byte("bobby"), []byte("hi")
+	rows = events[10].Data.(*RowsEvent)
+	checkRows(t, "update before", rows.Before, [][]interface{}{binlogTestRow2})
+	checkRows(t, "update after", rows.Rows, [][]interface{}{updated})
+
+	rows = events[15].Data.(*RowsEvent)
+	checkRows(t, "delete", rows.Rows, [][]interface{}{binlogTestRow1})
+
+	if xid := events[16].Data.(*XIDEvent); xid.XID != 44 {
+		t.Errorf("expected XID 44, got %d", xid.XID)
+	}
+	if q := events[18].Data.(*QueryEvent); q.Query != "CREATE TABLE u (a INT)" {
+		t.Errorf("unexpected query %q", q.Query)
+	}
+	if rotate := events[19].Data.(*RotateEvent); rotate.NextFile != "binlog.000002" || rotate.Position != 4 {
+		t.Errorf("unexpected rotate %+v", rotate)
+	}
+}
+
+func TestBinlogReaderChecksumMismatch(t *testing.T) {
+	data, err := ioutil.ReadFile(binlogTestFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// corrupt the body of the previous GTIDs event
+	fdeSize := binary.LittleEndian.Uint32(data[4+9:])
+	data[4+fdeSize+binlogEventHeaderLength] ^= 0xff
+
+	r, err := NewBinlogReader(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = r.Next(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = r.Next(); err == nil || !strings.Contains(err.Error(), "checksum") {
+		t.Errorf("expected checksum error, got %v", err)
+	}
+}
+
+func TestBinlogReaderCorruptSize(t *testing.T) {
+	data, err := ioutil.ReadFile(binlogTestFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, size := range []uint32{0xffffffff, 1 << 29} {
+		binary.LittleEndian.PutUint32(data[4+9:], size)
+		r, err := NewBinlogReader(bytes.NewReader(data))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err = r.Next(); err != ErrMalformPkt && err != io.ErrUnexpectedEOF {
+			t.Errorf("size %d: expected a malformed or truncated event, got %v", size, err)
+		}
+	}
+}
+
+func TestDecodeDecimal(t *testing.T) {
+	tests := []struct {
+		data             []byte
+		precision, scale int
+		expected         string
+	}{
+		{[]byte{0x81, 0x0D, 0xFB, 0x38, 0xD2, 0x04, 0xD2}, 14, 4, "1234567890.1234"},
+		{[]byte{0x7E, 0xF2, 0x04, 0xC7, 0x2D, 0xFB, 0x2D}, 14, 4, "-1234567890.1234"},
+		{[]byte{0x80, 0x00, 0x00, 0x00, 0x00}, 10, 2, "0.00"},
+		{[]byte{0x80, 0x00, 0x00, 0x0C, 0x32}, 10, 2, "12.50"},
+		{[]byte{0x80, 0x07}, 3, 0, "7"},
+		{[]byte{0x80, 0x00, 0x00, 0x00, 0x01}, 10, 9, "0.000000001"},
+	}
+	for _, test := range tests {
+		v, n, err := decodeDecimal(test.data, test.precision, test.scale)
+		if err != nil {
+			t.Errorf("%x: %v", test.data, err)
+			continue
+		}
+		if string(v.([]byte)) != test.expected || n != len(test.data) {
+			t.Errorf("%x: expected %s, got %s (%d bytes)", test.data, test.expected, v, n)
+		}
+	}
+}
+
+func TestGTIDSet(t *testing.T) {
+	set, err := ParseGTIDSet(" 3E11FA47-71CA-11E1-9E33-C80AA9429562:7:1-3:5-6,\n" +
+		"aaaaaaaa-bbbb-cccc-dddd-eeeeeeeeeeee:10")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := binlogTestSID + ":1-3:5-7,aaaaaaaa-bbbb-cccc-dddd-eeeeeeeeeeee:10"
+	if set.String() != expected {
+		t.Errorf("expected %q, got %q", expected, set)
+	}
+
+	set.Add(binlogTestSID, 4)
+	set.Add(binlogTestSID, 8)
+	set.Add("aaaaaaaa-bbbb-cccc-dddd-eeeeeeeeeeee", 12)
+	expected = binlogTestSID + ":1-8,aaaaaaaa-bbbb-cccc-dddd-eeeeeeeeeeee:10:12"
+	if set.String() != expected {
+		t.Errorf("expected %q, got %q", expected, set)
+	}
+	if !set.Contains(binlogTestSID, 8) || set.Contains(binlogTestSID, 9) ||
+		set.Contains("aaaaaaaa-bbbb-cccc-dddd-eeeeeeeeeeee", 11) {
+		t.Errorf("unexpected membership in %q", set)
+	}
+
+	decoded, err := decodeGTIDSet(set.encode())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if decoded.String() != expected {
+		t.Errorf("expected %q after encoding, got %q", expected, decoded)
+	}
+
+	for _, s := range []string{"nope:1", binlogTestSID + ":0", binlogTestSID + ":3-2", binlogTestSID + ":x"} {
+		if _, err := ParseGTIDSet(s); err == nil {
+			t.Errorf("expected error parsing %q", s)
+		}
+	}
+}
+
+// Returns the events of the test binlog file, as sent by the server
+func binlogTestEvents(t *testing.T) [][]byte {
+	data, err := ioutil.ReadFile(binlogTestFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data = data[len(binlogFileMagic):]
+
+	// the server starts with an artificial rotate event to the file
+	rotate := make([]byte, binlogEventHeaderLength+8+len("binlog.000001")+binlogChecksumLength)
+	rotate[4] = byte(BinlogRotateEvent)
+	binary.LittleEndian.PutUint32(rotate[9:], uint32(len(rotate)))
+	binary.LittleEndian.PutUint16(rotate[17:], binlogArtificialFlag)
+	binary.LittleEndian.PutUint64(rotate[19:], 4)
+	copy(rotate[27:], "binlog.000001")
+	n := len(rotate) - binlogChecksumLength
+	binary.LittleEndian.PutUint32(rotate[n:], crc32.ChecksumIEEE(rotate[:n]))
+
+	events := [][]byte{rotate}
+	for len(data) > 0 {
+		size := binary.LittleEndian.Uint32(data[9:])
+		events = append(events, data[:size])
+		data = data[size:]
+	}
+	return events
+}
+
+// Reads a command packet, which starts a new sequence
+func (s *fakeServer) readCommand() ([]byte, error) {
+	s.mc.sequence = 0
+	return s.read()
+}
+
+func binlogTestServer(events [][]byte) func(s *fakeServer) error {
+	return func(s *fakeServer) error {
+		if err := s.writeInit("mysql_native_password"); err != nil {
+			return err
+		}
+		if _, _, err := s.readHandshake(); err != nil {
+			return err
+		}
+		if err := s.writeOK(); err != nil {
+			return err
+		}
+
+		for _, query := range []string{
+			"SET @master_binlog_checksum = @@global.binlog_checksum",
+			"SET @master_heartbeat_period = 30000000000",
+		} {
+			data, err := s.readCommand()
+			if err != nil {
+				return err
+			}
+			if data[0] != comQuery || string(data[1:]) != query {
+				return fmt.Errorf("expected query %q, got %q", query, data)
+			}
+			if err = s.writeOK(); err != nil {
+				return err
+			}
+		}
+
+		data, err := s.readCommand()
+		if err != nil {
+			return err
+		}
+		if data[0] != comRegisterSlave || len(data) != 18 || binary.LittleEndian.Uint32(data[1:]) != 1001 {
+			return fmt.Errorf("unexpected register slave packet %x", data)
+		}
+		if err = s.writeOK(); err != nil {
+			return err
+		}
+
+		data, err = s.readCommand()
+		if err != nil {
+			return err
+		}
+		if data[0] != comBinlogDumpGTID || binary.LittleEndian.Uint16(data[1:]) != binlogDumpNonBlock|binlogDumpThroughGTID ||
+			binary.LittleEndian.Uint32(data[3:]) != 1001 || binary.LittleEndian.Uint64(data[11:]) != 4 {
+			return fmt.Errorf("unexpected binlog dump packet %x", data)
+		}
+		set, err := decodeGTIDSet(data[23:])
+		if err != nil {
+			return err
+		}
+		if set.String() != "aaaaaaaa-bbbb-cccc-dddd-eeeeeeeeeeee:1-10" {
+			return fmt.Errorf("unexpected GTID set %q", set)
+		}
+
+		for _, ev := range events {
+			if err = s.write(append([]byte{iOK}, ev...)...); err != nil {
+				return err
+			}
+		}
+		return s.write(iEOF, 0, 0, 0, 0)
+	}
+}
+
+func TestBinlogStream(t *testing.T) {
+	events := binlogTestEvents(t)
+	cc, sc := net.Pipe()
+	defer cc.Close()
+	RegisterDial("binlogtest", func(addr string) (net.Conn, error) {
+		return cc, nil
+	})
+
+	s := &fakeServer{&mysqlConn{
+		netConn:          sc,
+		buf:              newBuffer(sc),
+		maxAllowedPacket: maxPacketSize,
+	}}
+	serverErr := make(chan error, 1)
+	go func() {
+		err := binlogTestServer(events)(s)
+		if err != nil {
+			sc.Close()
+		}
+		serverErr <- err
+	}()
+
+	stream, err := NewBinlogStream("repl:secret@binlogtest(db)/?maxAllowedPacket=16777216&parseTime=true", BinlogConfig{
+		ServerID:    1001,
+		Position:    BinlogPosition{GTIDSet: "aaaaaaaa-bbbb-cccc-dddd-eeeeeeeeeeee:1-10"},
+		Heartbeat:   30 * time.Second,
+		NonBlocking: true,
+	})
+	if err != nil {
+		sc.Close()
+		t.Fatalf("client: %v (server: %v)", err, <-serverErr)
+	}
+	defer stream.Close()
+
+	// the position after each event
+	var positions []BinlogPosition
+	var created interface{}
+	for {
+		ev, err := stream.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("event %d: %v (server: %v)", len(positions), err, <-serverErr)
+		}
+		if rows, ok := ev.Data.(*RowsEvent); ok && ev.Header.Type == BinlogWriteRowsEventV2 {
+			created = rows.Rows[0][3]
+		}
+		positions = append(positions, stream.Position())
+	}
+	if err = <-serverErr; err != nil {
+		t.Fatalf("server: %v", err)
+	}
+
+	if expected := time.Date(2017, 5, 1, 12, 34, 56, 789000000, time.UTC); created != expected {
+		t.Errorf("expected created %v, got %v", expected, created)
+	}
+
+	if len(positions) != len(events) {
+		t.Fatalf("expected %d events, got %d", len(events), len(positions))
+	}
+	const other = ",aaaaaaaa-bbbb-cccc-dddd-eeeeeeeeeeee:1-10"
+	pos := func(file string, p uint32, gtids string) BinlogPosition {
+		return BinlogPosition{File: file, Pos: p, GTIDSet: gtids + other}
+	}
+	// the end of the events of each transaction
+	end := make([]uint32, len(events))
+	for i, ev := range events {
+		end[i] = binary.LittleEndian.Uint32(ev[13:])
+	}
+	start := BinlogPosition{File: "binlog.000001", Pos: 4, GTIDSet: other[1:]}
+	tx1 := pos("binlog.000001", end[7], binlogTestSID+":1")
+	tx2 := pos("binlog.000001", end[12], binlogTestSID+":1-2")
+	tx3 := pos("binlog.000001", end[17], binlogTestSID+":1-3")
+	tx4 := pos("binlog.000001", end[19], binlogTestSID+":1-4")
+	expected := []BinlogPosition{
+		start, start, start, // rotate, format description, previous GTIDs
+		start, start, start, start, tx1,
+		tx1, tx1, tx1, tx1, tx2,
+		tx2, tx2, tx2, tx2, tx3,
+		tx3, tx4,
+		pos("binlog.000002", 4, binlogTestSID+":1-4"),
+	}
+	for i := range expected {
+		if positions[i] != expected[i] {
+			t.Errorf("event %d: expected position %+v, got %+v", i, expected[i], positions[i])
+		}
+	}
+}
+
+func TestBinlogStreamServerID(t *testing.T) {
+	if _, err := NewBinlogStream("repl@tcp(127.0.0.1:3306)/", BinlogConfig{}); err != errBinlogServerID {
+		t.Errorf("expected %v, got %v", errBinlogServerID, err)
+	}
+}
@@ -62,12 +62,15 @@ func (mc *mysqlConn) handleParams() (err error) {
 	return
 }
 
-func (mc *mysqlConn) Begin() (driver.Tx, error) {
+func (mc *mysqlConn) Begin() (tx driver.Tx, err error) {
 	if mc.netConn == nil {
 		errLog.Print(ErrInvalidConn)
 		return nil, driver.ErrBadConn
 	}
-	err := mc.exec("START TRANSACTION")
+	if interceptor := mc.cfg.interceptor; interceptor != nil {
+		defer intercept(interceptor, OpBegin, "START TRANSACTION", nil, time.Now(), nil, &err)
+	}
+	err = mc.exec("START TRANSACTION")
 	if err == nil {
 		return &mysqlTx{mc}, err
 	}
@@ -102,19 +105,23 @@ func (mc *mysqlConn) cleanup() {
 	mc.buf.nc = nil
 }
 
-func (mc *mysqlConn) Prepare(query string) (driver.Stmt, error) {
+func (mc *mysqlConn) Prepare(query string) (_ driver.Stmt, err error) {
 	if mc.netConn == nil {
 		errLog.Print(ErrInvalidConn)
 		return nil, driver.ErrBadConn
 	}
+	if interceptor := mc.cfg.interceptor; interceptor != nil {
+		defer intercept(interceptor, OpPrepare, query, nil, time.Now(), nil, &err)
+	}
 	// Send command
-	err := mc.writeCommandPacketStr(comStmtPrepare, query)
+	err = mc.writeCommandPacketStr(comStmtPrepare, query)
 	if err != nil {
 		return nil, err
 	}
 
 	stmt := &mysqlStmt{
-		mc: mc,
+		mc:    mc,
+		query: query,
 	}
 
 	// Read Result
@@ -256,11 +263,14 @@ func (mc *mysqlConn) interpolateParams(query string, args []driver.Value) (strin
 	return string(buf), nil
 }
 
-func (mc *mysqlConn) Exec(query string, args []driver.Value) (driver.Result, error) {
+func (mc *mysqlConn) Exec(query string, args []driver.Value) (res driver.Result, err error) {
 	if mc.netConn == nil {
 		errLog.Print(ErrInvalidConn)
 		return nil, driver.ErrBadConn
 	}
+	if interceptor := mc.cfg.interceptor; interceptor != nil {
+		defer intercept(interceptor, OpExec, query, args, time.Now(), &res, &err)
+	}
 	if len(args) != 0 {
 		if !mc.cfg.InterpolateParams {
 			return nil, driver.ErrSkip
@@ -276,7 +286,7 @@ func (mc *mysqlConn) Exec(query string, args []driver.Value) (driver.Result, err
 	mc.affectedRows = 0
 	mc.insertId = 0
 
-	err := mc.exec(query)
+	err = mc.exec(query)
 	if err == nil {
 		return &mysqlResult{
 			affectedRows: int64(mc.affectedRows),
@@ -307,11 +317,14 @@ func (mc *mysqlConn) exec(query string) error {
 	return err
 }
 
-func (mc *mysqlConn) Query(query string, args []driver.Value) (driver.Rows, error) {
+func (mc *mysqlConn) Query(query string, args []driver.Value) (_ driver.Rows, err error) {
 	if mc.netConn == nil {
 		errLog.Print(ErrInvalidConn)
 		return nil, driver.ErrBadConn
 	}
+	if interceptor := mc.cfg.interceptor; interceptor != nil {
+		defer intercept(interceptor, OpQuery, query, args, time.Now(), nil, &err)
+	}
 	if len(args) != 0 {
 		if !mc.cfg.InterpolateParams {
 			return nil, driver.ErrSkip
@@ -325,7 +338,7 @@ func (mc *mysqlConn) Query(query string, args []driver.Value) (driver.Rows, erro
 		args = nil
 	}
 	// Send command
-	err := mc.writeCommandPacketStr(comQuery, query)
+	err = mc.writeCommandPacketStr(comQuery, query)
 	if err == nil {
 		// Read Result
 		var resLen int
@@ -12,16 +12,26 @@ const (
 	minProtocolVersion byte = 10
 	maxPacketSize           = 1<<24 - 1
 	timeFormat              = "2006-01-02 15:04:05.999999"
+	defaultAuthPlugin       = "mysql_native_password"
 )
 
 // MySQL constants documentation:
 // http://dev.mysql.com/doc/internals/en/client-server-protocol.html
 
 const (
-	iOK          byte = 0x00
-	iLocalInFile byte = 0xfb
-	iEOF         byte = 0xfe
-	iERR         byte = 0xff
+	iOK           byte = 0x00
+	iAuthMoreData byte = 0x01
+	iLocalInFile  byte = 0xfb
+	iEOF          byte = 0xfe
+	iERR          byte = 0xff
+)
+
+// caching_sha2_password
+// https://dev.mysql.com/doc/dev/mysql-server/latest/page_caching_sha2_authentication_exchanges.html
+const (
+	cachingSha2PasswordRequestPublicKey          byte = 2
+	cachingSha2PasswordFastAuthSuccess           byte = 3
+	cachingSha2PasswordPerformFullAuthentication byte = 4
 )
 
 // https://dev.mysql.com/doc/internals/en/capability-flags.html#packet-Protocol::CapabilityFlags
@@ -84,6 +94,8 @@ const (
 	comStmtReset
 	comSetOption
 	comStmtFetch
+	comDaemon
+	comBinlogDumpGTID
 )
 
 // https://dev.mysql.com/doc/internals/en/com-query-response.html#packet-Protocol::ColumnType
@@ -105,6 +117,9 @@ const (
 	fieldTypeNewDate
 	fieldTypeVarChar
 	fieldTypeBit
+	fieldTypeTimestamp2
+	fieldTypeDateTime2
+	fieldTypeTime2
 )
 const (
 	fieldTypeJSON byte = iota + 0xf5
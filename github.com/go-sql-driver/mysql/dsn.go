@@ -10,6 +10,7 @@ package mysql
 
 import (
 	"bytes"
+	"crypto/rsa"
 	"crypto/tls"
 	"errors"
 	"fmt"
@@ -40,9 +41,15 @@ type Config struct {
 	MaxAllowedPacket int               // Max packet size allowed
 	TLSConfig        string            // TLS configuration name
 	tls              *tls.Config       // TLS configuration
+	ServerPubKey     string            // Server public key file
+	pubKey           *rsa.PublicKey    // Server public key
 	Timeout          time.Duration     // Dial timeout
 	ReadTimeout      time.Duration     // I/O read timeout
 	WriteTimeout     time.Duration     // I/O write timeout
+	Interceptor      string            // Interceptor name
+	interceptor      Interceptor       // Interceptor, including the slow query logger
+
+	SlowQueryThreshold time.Duration // Log operations taking longer
 
 	AllowAllFiles           bool // Allow all files to be used with LOAD DATA LOCAL INFILE
 	AllowCleartextPasswords bool // Allows the cleartext client side plugin
@@ -157,6 +164,16 @@ func (cfg *Config) FormatDSN() string {
 		}
 	}
 
+	if len(cfg.Interceptor) > 0 {
+		if hasParam {
+			buf.WriteString("&interceptor=")
+		} else {
+			hasParam = true
+			buf.WriteString("?interceptor=")
+		}
+		buf.WriteString(url.QueryEscape(cfg.Interceptor))
+	}
+
 	if cfg.Loc != time.UTC && cfg.Loc != nil {
 		if hasParam {
 			buf.WriteString("&loc=")
@@ -195,6 +212,26 @@ func (cfg *Config) FormatDSN() string {
 		buf.WriteString(cfg.ReadTimeout.String())
 	}
 
+	if len(cfg.ServerPubKey) > 0 {
+		if hasParam {
+			buf.WriteString("&serverPubKey=")
+		} else {
+			hasParam = true
+			buf.WriteString("?serverPubKey=")
+		}
+		buf.WriteString(url.QueryEscape(cfg.ServerPubKey))
+	}
+
+	if cfg.SlowQueryThreshold > 0 {
+		if hasParam {
+			buf.WriteString("&slowQueryThreshold=")
+		} else {
+			hasParam = true
+			buf.WriteString("?slowQueryThreshold=")
+		}
+		buf.WriteString(cfg.SlowQueryThreshold.String())
+	}
+
 	if cfg.Strict {
 		if hasParam {
 			buf.WriteString("&strict=true")
@@ -342,6 +379,15 @@ func ParseDSN(dsn string) (cfg *Config, err error) {
 		return nil, errInvalidDSNUnsafeCollation
 	}
 
+	if cfg.SlowQueryThreshold > 0 {
+		slowQueryLogger := NewSlowQueryLogger(cfg.SlowQueryThreshold, nil)
+		if cfg.interceptor != nil {
+			cfg.interceptor = interceptorChain{cfg.interceptor, slowQueryLogger}
+		} else {
+			cfg.interceptor = slowQueryLogger
+		}
+	}
+
 	// Set default network if empty
 	if cfg.Net == "" {
 		cfg.Net = "tcp"
@@ -431,6 +477,20 @@ func parseDSNParams(cfg *Config, params string) (err error) {
 		case "compress":
 			return errors.New("compression not implemented yet")
 
+		// Interceptor of the operations of connections
+		case "interceptor":
+			name, err := url.QueryUnescape(value)
+			if err != nil {
+				return fmt.Errorf("invalid value for interceptor name: %v", err)
+			}
+
+			interceptor, ok := getInterceptor(name)
+			if !ok {
+				return errors.New("invalid value / unknown interceptor name: " + name)
+			}
+			cfg.Interceptor = name
+			cfg.interceptor = interceptor
+
 		// Enable client side placeholder substitution
 		case "interpolateParams":
 			var isBool bool
@@ -472,6 +532,25 @@ func parseDSNParams(cfg *Config, params string) (err error) {
 				return
 			}
 
+		// Server public key for the SHA256 authentication plugins
+		case "serverPubKey":
+			name, err := url.QueryUnescape(value)
+			if err != nil {
+				return fmt.Errorf("invalid value for server pub key name: %v", err)
+			}
+
+			if cfg.pubKey, err = readPubKeyFile(name); err != nil {
+				return fmt.Errorf("could not read server pub key file: %v", err)
+			}
+			cfg.ServerPubKey = name
+
+		// Slow query logging
+		case "slowQueryThreshold":
+			cfg.SlowQueryThreshold, err = time.ParseDuration(value)
+			if err != nil {
+				return
+			}
+
 		// Strict mode
 		case "strict":
 			var isBool bool
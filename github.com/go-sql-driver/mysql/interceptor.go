@@ -0,0 +1,324 @@
+// Go MySQL Driver - A MySQL-Driver for Go's database/sql package
+//
+// Copyright 2017 The Go-MySQL-Driver Authors. All rights reserved.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package mysql
+
+import (
+	"bytes"
+	"database/sql/driver"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	interceptorRegister     map[string]Interceptor
+	interceptorRegisterLock sync.RWMutex
+)
+
+// Operations reported to interceptors
+const (
+	OpExec      = "exec"
+	OpQuery     = "query"
+	OpPrepare   = "prepare"
+	OpStmtExec  = "stmt.exec"
+	OpStmtQuery = "stmt.query"
+	OpBegin     = "begin"
+	OpCommit    = "commit"
+	OpRollback  = "rollback"
+)
+
+// QueryInfo describes an operation run by the driver.
+type QueryInfo struct {
+	Op    string // One of the Op constants
+	Query string // Query text, with placeholders; the statement for OpBegin etc.
+
+	// Args describes the arguments without revealing their values, e.g.
+	// "int64", "string(5)", "[]byte(12)" or "NULL".
+	Args []string
+
+	Duration time.Duration
+
+	// RowsAffected is the number of rows affected by an exec, or -1. The
+	// duration of queries does not include reading the rows.
+	RowsAffected int64
+
+	Err error
+}
+
+// Interceptor sees the operations run by the connections which use it, after
+// they completed. It is called by concurrent connections and must not block.
+type Interceptor interface {
+	Intercept(info *QueryInfo)
+}
+
+// InterceptorFunc adapts a function to an Interceptor.
+type InterceptorFunc func(info *QueryInfo)
+
+// Intercept calls f(info).
+func (f InterceptorFunc) Intercept(info *QueryInfo) {
+	f(info)
+}
+
+// RegisterInterceptor registers an Interceptor to be used with sql.Open. Use
+// the name as a value in the DSN where interceptor=name.
+//
+//  histograms := mysql.NewLatencyHistograms()
+//  mysql.RegisterInterceptor("metrics", histograms)
+//  db, err := sql.Open("mysql", "user@tcp(localhost:3306)/test?interceptor=metrics")
+//
+func RegisterInterceptor(name string, interceptor Interceptor) {
+	interceptorRegisterLock.Lock()
+	// lazy map init
+	if interceptorRegister == nil {
+		interceptorRegister = make(map[string]Interceptor)
+	}
+
+	interceptorRegister[name] = interceptor
+	interceptorRegisterLock.Unlock()
+}
+
+// DeregisterInterceptor removes the Interceptor with the given name from the
+// registry.
+func DeregisterInterceptor(name string) {
+	interceptorRegisterLock.Lock()
+	delete(interceptorRegister, name)
+	interceptorRegisterLock.Unlock()
+}
+
+func getInterceptor(name string) (Interceptor, bool) {
+	interceptorRegisterLock.RLock()
+	interceptor, ok := interceptorRegister[name]
+	interceptorRegisterLock.RUnlock()
+	return interceptor, ok
+}
+
+type interceptorChain []Interceptor
+
+func (c interceptorChain) Intercept(info *QueryInfo) {
+	for _, interceptor := range c {
+		interceptor.Intercept(info)
+	}
+}
+
+// Reports an operation started at start to the interceptor, once it returned
+// with the given result and error. Operations skipped with driver.ErrSkip are
+// retried differently by database/sql and not reported.
+func intercept(interceptor Interceptor, op, query string, args []driver.Value, start time.Time, res *driver.Result, err *error) {
+	if *err == driver.ErrSkip {
+		return
+	}
+	info := &QueryInfo{
+		Op:           op,
+		Query:        query,
+		Duration:     time.Since(start),
+		RowsAffected: -1,
+		Err:          *err,
+	}
+	if len(args) > 0 {
+		info.Args = make([]string, len(args))
+		for i, arg := range args {
+			info.Args[i] = redactArg(arg)
+		}
+	}
+	if res != nil && *res != nil {
+		if n, err := (*res).RowsAffected(); err == nil {
+			info.RowsAffected = n
+		}
+	}
+	interceptor.Intercept(info)
+}
+
+func redactArg(arg driver.Value) string {
+	switch v := arg.(type) {
+	case nil:
+		return "NULL"
+	case string:
+		return "string(" + strconv.Itoa(len(v)) + ")"
+	case []byte:
+		if v == nil {
+			return "NULL"
+		}
+		return "[]byte(" + strconv.Itoa(len(v)) + ")"
+	default:
+		return fmt.Sprintf("%T", v)
+	}
+}
+
+// NewSlowQueryLogger returns an Interceptor which logs the operations taking
+// at least threshold, and the failed ones, with the given logger. The logger
+// set with SetLogger is used if it is nil. The DSN parameter
+// slowQueryThreshold sets up such a logger.
+func NewSlowQueryLogger(threshold time.Duration, logger Logger) Interceptor {
+	return InterceptorFunc(func(info *QueryInfo) {
+		if info.Duration < threshold && info.Err == nil {
+			return
+		}
+		l := logger
+		if l == nil {
+			l = errLog
+		}
+		l.Print(formatQueryInfo(info))
+	})
+}
+
+func formatQueryInfo(info *QueryInfo) string {
+	var buf bytes.Buffer
+	if info.Err != nil {
+		buf.WriteString("failed ")
+	} else {
+		buf.WriteString("slow ")
+	}
+	buf.WriteString(info.Op)
+	buf.WriteString(" (")
+	buf.WriteString(info.Duration.String())
+	if info.RowsAffected >= 0 {
+		buf.WriteString(", ")
+		buf.WriteString(strconv.FormatInt(info.RowsAffected, 10))
+		buf.WriteString(" rows affected")
+	}
+	buf.WriteString("): ")
+	buf.WriteString(info.Query)
+	if len(info.Args) > 0 {
+		buf.WriteString(" [")
+		buf.WriteString(strings.Join(info.Args, ", "))
+		buf.WriteByte(']')
+	}
+	if info.Err != nil {
+		buf.WriteString(": ")
+		buf.WriteString(info.Err.Error())
+	}
+	return buf.String()
+}
+
+// DefaultLatencyBuckets are the upper bounds of the buckets of latency
+// histograms, unless others are given.
+var DefaultLatencyBuckets = []time.Duration{
+	time.Millisecond,
+	5 * time.Millisecond,
+	10 * time.Millisecond,
+	50 * time.Millisecond,
+	100 * time.Millisecond,
+	500 * time.Millisecond,
+	time.Second,
+	5 * time.Second,
+}
+
+// Statements beyond this number share a histogram, so that queries which do
+// not use placeholders don't grow the histograms without bounds
+const maxLatencyHistograms = 1000
+
+// LatencyHistogram is the latency distribution of an operation on a statement.
+type LatencyHistogram struct {
+	Op    string
+	Query string // Empty for the histogram shared by excess statements
+
+	Buckets []time.Duration // Upper bounds of the buckets
+	Counts  []uint64        // Counts of the buckets, followed by the count of slower operations
+	Count   uint64
+	Sum     time.Duration
+	Errors  uint64
+}
+
+// LatencyHistograms is an Interceptor keeping latency histograms by operation
+// and statement.
+type LatencyHistograms struct {
+	buckets []time.Duration
+
+	mu         sync.Mutex
+	histograms map[[2]string]*LatencyHistogram
+}
+
+// NewLatencyHistograms returns histograms with the given bucket upper bounds,
+// or DefaultLatencyBuckets if none are given.
+func NewLatencyHistograms(buckets ...time.Duration) *LatencyHistograms {
+	if len(buckets) == 0 {
+		buckets = DefaultLatencyBuckets
+	}
+	buckets = append([]time.Duration{}, buckets...)
+	sort.Sort(durations(buckets))
+	return &LatencyHistograms{
+		buckets:    buckets,
+		histograms: make(map[[2]string]*LatencyHistogram),
+	}
+}
+
+// Intercept records the duration of the operation.
+func (h *LatencyHistograms) Intercept(info *QueryInfo) {
+	bucket := sort.Search(len(h.buckets), func(i int) bool { return info.Duration <= h.buckets[i] })
+
+	h.mu.Lock()
+	key := [2]string{info.Op, info.Query}
+	hist := h.histograms[key]
+	if hist == nil {
+		if len(h.histograms) >= maxLatencyHistograms {
+			key[1] = ""
+			hist = h.histograms[key]
+		}
+		if hist == nil {
+			hist = &LatencyHistogram{
+				Op:      key[0],
+				Query:   key[1],
+				Buckets: h.buckets,
+				Counts:  make([]uint64, len(h.buckets)+1),
+			}
+			h.histograms[key] = hist
+		}
+	}
+	hist.Counts[bucket]++
+	hist.Count++
+	hist.Sum += info.Duration
+	if info.Err != nil {
+		hist.Errors++
+	}
+	h.mu.Unlock()
+}
+
+// Histograms returns a copy of the histograms, sorted by statement and
+// operation.
+func (h *LatencyHistograms) Histograms() []LatencyHistogram {
+	h.mu.Lock()
+	histograms := make([]LatencyHistogram, 0, len(h.histograms))
+	for _, hist := range h.histograms {
+		c := *hist
+		c.Counts = append([]uint64{}, hist.Counts...)
+		histograms = append(histograms, c)
+	}
+	h.mu.Unlock()
+
+	sort.Sort(byQuery(histograms))
+	return histograms
+}
+
+// Reset clears the histograms.
+func (h *LatencyHistograms) Reset() {
+	h.mu.Lock()
+	h.histograms = make(map[[2]string]*LatencyHistogram)
+	h.mu.Unlock()
+}
+
+type durations []time.Duration
+
+func (d durations) Len() int           { return len(d) }
+func (d durations) Less(i, j int) bool { return d[i] < d[j] }
+func (d durations) Swap(i, j int)      { d[i], d[j] = d[j], d[i] }
+
+// byQuery sorts histograms by statement and operation
+type byQuery []LatencyHistogram
+
+func (h byQuery) Len() int      { return len(h) }
+func (h byQuery) Swap(i, j int) { h[i], h[j] = h[j], h[i] }
+func (h byQuery) Less(i, j int) bool {
+	if h[i].Query != h[j].Query {
+		return h[i].Query < h[j].Query
+	}
+	return h[i].Op < h[j].Op
+}
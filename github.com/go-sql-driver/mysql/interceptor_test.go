@@ -0,0 +1,183 @@
+// Go MySQL Driver - A MySQL-Driver for Go's database/sql package
+//
+// Copyright 2017 The Go-MySQL-Driver Authors. All rights reserved.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package mysql
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+var (
+	// OK packet with 2 affected rows
+	interceptOKPacket = []byte{0x07, 0x00, 0x00, 0x01, iOK, 0x02, 0x00, 0x02, 0x00, 0x00, 0x00}
+	// error packet 1146 (42S02): Table 'test.t' doesn't exist
+	interceptErrPacket = append([]byte{0x25, 0x00, 0x00, 0x01, iERR, 0x7a, 0x04, '#', '4', '2', 'S', '0', '2'},
+		"Table 'test.t' doesn't exist"...)
+)
+
+type testLogger []string
+
+func (l *testLogger) Print(v ...interface{}) {
+	*l = append(*l, fmt.Sprint(v...))
+}
+
+// Returns a connection, the connection it reads from and its intercepted
+// operations
+func interceptConn() (*mysqlConn, *mockConn, *[]*QueryInfo) {
+	conn := new(mockConn)
+	infos := new([]*QueryInfo)
+	mc := &mysqlConn{
+		buf:              newBuffer(conn),
+		netConn:          conn,
+		maxAllowedPacket: maxPacketSize,
+		cfg: &Config{
+			InterpolateParams: true,
+			Loc:               time.UTC,
+			interceptor: InterceptorFunc(func(info *QueryInfo) {
+				*infos = append(*infos, info)
+			}),
+		},
+	}
+	return mc, conn, infos
+}
+
+func TestInterceptExec(t *testing.T) {
+	mc, conn, infos := interceptConn()
+
+	query := "UPDATE t SET a = ? WHERE b = ? AND c = ?"
+	conn.data = interceptOKPacket
+	if _, err := mc.Exec(query, []driver.Value{int64(1), "gopher", nil}); err != nil {
+		t.Fatal(err)
+	}
+	conn.data = interceptErrPacket
+	if _, err := mc.Exec("DELETE FROM t", nil); err == nil {
+		t.Fatal("expected error")
+	}
+	// unsupported argument types fall back to prepared statements
+	if _, err := mc.Exec(query, []driver.Value{int64(1), "gopher", struct{}{}}); err != driver.ErrSkip {
+		t.Fatalf("expected driver.ErrSkip, got %v", err)
+	}
+
+	if len(*infos) != 2 {
+		t.Fatalf("expected 2 intercepted operations, got %d", len(*infos))
+	}
+	info := (*infos)[0]
+	if info.Op != OpExec || info.Query != query || info.RowsAffected != 2 || info.Err != nil {
+		t.Errorf("unexpected info %+v", info)
+	}
+	if expected := []string{"int64", "string(6)", "NULL"}; !reflect.DeepEqual(info.Args, expected) {
+		t.Errorf("expected args %q, got %q", expected, info.Args)
+	}
+	info = (*infos)[1]
+	if me, ok := info.Err.(*MySQLError); !ok || me.Number != 1146 || info.RowsAffected != -1 || info.Args != nil {
+		t.Errorf("unexpected info %+v", info)
+	}
+}
+
+func TestInterceptTransaction(t *testing.T) {
+	mc, conn, infos := interceptConn()
+
+	conn.data = interceptOKPacket
+	tx, err := mc.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn.data = interceptOKPacket
+	if err = tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+
+	var ops []string
+	for _, info := range *infos {
+		ops = append(ops, info.Op+" "+info.Query)
+	}
+	if expected := []string{"begin START TRANSACTION", "commit COMMIT"}; !reflect.DeepEqual(ops, expected) {
+		t.Errorf("expected %q, got %q", expected, ops)
+	}
+}
+
+func TestSlowQueryLogger(t *testing.T) {
+	var logger testLogger
+	slow := NewSlowQueryLogger(100*time.Millisecond, &logger)
+
+	slow.Intercept(&QueryInfo{Op: OpExec, Query: "DO 1", Duration: time.Millisecond, RowsAffected: 0})
+	slow.Intercept(&QueryInfo{Op: OpExec, Query: "UPDATE t SET a = ?", Args: []string{"int64"},
+		Duration: 2 * time.Second, RowsAffected: 3})
+	slow.Intercept(&QueryInfo{Op: OpQuery, Query: "SELECT * FROM t", Duration: time.Millisecond,
+		RowsAffected: -1, Err: &MySQLError{1146, "Table 'test.t' doesn't exist"}})
+
+	expected := testLogger{
+		"slow exec (2s, 3 rows affected): UPDATE t SET a = ? [int64]",
+		"failed query (1ms): SELECT * FROM t: Error 1146: Table 'test.t' doesn't exist",
+	}
+	if !reflect.DeepEqual(logger, expected) {
+		t.Errorf("expected log\n%q\ngot\n%q", expected, logger)
+	}
+}
+
+func TestLatencyHistograms(t *testing.T) {
+	h := NewLatencyHistograms(10*time.Millisecond, time.Millisecond)
+	for _, d := range []time.Duration{time.Millisecond / 2, time.Millisecond, 5 * time.Millisecond, time.Second} {
+		h.Intercept(&QueryInfo{Op: OpStmtExec, Query: "INSERT INTO t VALUES (?)", Duration: d})
+	}
+	h.Intercept(&QueryInfo{Op: OpPrepare, Query: "INSERT INTO t VALUES (?)", Duration: time.Second,
+		Err: ErrInvalidConn})
+
+	buckets := []time.Duration{time.Millisecond, 10 * time.Millisecond}
+	expected := []LatencyHistogram{
+		{Op: OpPrepare, Query: "INSERT INTO t VALUES (?)", Buckets: buckets, Counts: []uint64{0, 0, 1},
+			Count: 1, Sum: time.Second, Errors: 1},
+		{Op: OpStmtExec, Query: "INSERT INTO t VALUES (?)", Buckets: buckets, Counts: []uint64{2, 1, 1},
+			Count: 4, Sum: time.Second + 6*time.Millisecond + time.Millisecond/2},
+	}
+	if histograms := h.Histograms(); !reflect.DeepEqual(histograms, expected) {
+		t.Errorf("expected %+v, got %+v", expected, histograms)
+	}
+
+	// excess statements share a histogram
+	h.Reset()
+	for i := 0; i < maxLatencyHistograms+10; i++ {
+		h.Intercept(&QueryInfo{Op: OpExec, Query: fmt.Sprintf("DO %d", i)})
+	}
+	histograms := h.Histograms()
+	if len(histograms) != maxLatencyHistograms+1 || histograms[0].Query != "" || histograms[0].Count != 10 {
+		t.Errorf("unexpected %d histograms, first %+v", len(histograms), histograms[0])
+	}
+}
+
+func TestDSNInterceptor(t *testing.T) {
+	h := NewLatencyHistograms()
+	RegisterInterceptor("interceptor_test", h)
+	defer DeregisterInterceptor("interceptor_test")
+
+	cfg, err := ParseDSN("user@tcp(localhost:3306)/?interceptor=interceptor_test&slowQueryThreshold=250ms")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Interceptor != "interceptor_test" || cfg.SlowQueryThreshold != 250*time.Millisecond {
+		t.Errorf("unexpected config %+v", cfg)
+	}
+	chain, ok := cfg.interceptor.(interceptorChain)
+	if !ok || len(chain) != 2 || chain[0] != h {
+		t.Errorf("unexpected interceptor %#v", cfg.interceptor)
+	}
+
+	dsn := cfg.FormatDSN()
+	if !strings.Contains(dsn, "interceptor=interceptor_test") || !strings.Contains(dsn, "slowQueryThreshold=250ms") {
+		t.Errorf("unexpected DSN %q", dsn)
+	}
+
+	if _, err = ParseDSN("/?interceptor=unknown"); err == nil {
+		t.Error("expected error for unknown interceptor")
+	}
+}
@@ -13,11 +13,13 @@ import (
 	"fmt"
 	"reflect"
 	"strconv"
+	"time"
 )
 
 type mysqlStmt struct {
 	mc         *mysqlConn
 	id         uint32
+	query      string
 	paramCount int
 	columns    []mysqlField // cached from the first query
 }
@@ -44,13 +46,16 @@ func (stmt *mysqlStmt) ColumnConverter(idx int) driver.ValueConverter {
 	return converter{}
 }
 
-func (stmt *mysqlStmt) Exec(args []driver.Value) (driver.Result, error) {
+func (stmt *mysqlStmt) Exec(args []driver.Value) (res driver.Result, err error) {
 	if stmt.mc.netConn == nil {
 		errLog.Print(ErrInvalidConn)
 		return nil, driver.ErrBadConn
 	}
+	if interceptor := stmt.mc.cfg.interceptor; interceptor != nil {
+		defer intercept(interceptor, OpStmtExec, stmt.query, args, time.Now(), &res, &err)
+	}
 	// Send command
-	err := stmt.writeExecutePacket(args)
+	err = stmt.writeExecutePacket(args)
 	if err != nil {
 		return nil, err
 	}
@@ -84,13 +89,16 @@ func (stmt *mysqlStmt) Exec(args []driver.Value) (driver.Result, error) {
 	return nil, err
 }
 
-func (stmt *mysqlStmt) Query(args []driver.Value) (driver.Rows, error) {
+func (stmt *mysqlStmt) Query(args []driver.Value) (_ driver.Rows, err error) {
 	if stmt.mc.netConn == nil {
 		errLog.Print(ErrInvalidConn)
 		return nil, driver.ErrBadConn
 	}
+	if interceptor := stmt.mc.cfg.interceptor; interceptor != nil {
+		defer intercept(interceptor, OpStmtQuery, stmt.query, args, time.Now(), nil, &err)
+	}
 	// Send command
-	err := stmt.writeExecutePacket(args)
+	err = stmt.writeExecutePacket(args)
 	if err != nil {
 		return nil, err
 	}
@@ -8,6 +8,8 @@
 
 package mysql
 
+import "time"
+
 type mysqlTx struct {
 	mc *mysqlConn
 }
@@ -16,6 +18,9 @@ func (tx *mysqlTx) Commit() (err error) {
 	if tx.mc == nil || tx.mc.netConn == nil {
 		return ErrInvalidConn
 	}
+	if interceptor := tx.mc.cfg.interceptor; interceptor != nil {
+		defer intercept(interceptor, OpCommit, "COMMIT", nil, time.Now(), nil, &err)
+	}
 	err = tx.mc.exec("COMMIT")
 	tx.mc = nil
 	return
@@ -25,6 +30,9 @@ func (tx *mysqlTx) Rollback() (err error) {
 	if tx.mc == nil || tx.mc.netConn == nil {
 		return ErrInvalidConn
 	}
+	if interceptor := tx.mc.cfg.interceptor; interceptor != nil {
+		defer intercept(interceptor, OpRollback, "ROLLBACK", nil, time.Now(), nil, &err)
+	}
 	err = tx.mc.exec("ROLLBACK")
 	tx.mc = nil
 	return
@@ -0,0 +1,410 @@
+package sqlx
+
+// Batch Insert Support
+//
+//  * Batch - build multi-row INSERT statements, or upserts, for a slice of
+//    structs or maps, chunked to respect placeholder and size limits
+//  * BatchInsert, BatchUpsert - run a batch w/ the defaults
+//
+import (
+	"bytes"
+	"database/sql"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/jmoiron/sqlx/reflectx"
+)
+
+// Default limits of the statements of a Batch.  MySQL and PostgreSQL accept
+// at most 65535 placeholders per statement, SQLite 999 unless compiled with a
+// higher SQLITE_MAX_VARIABLE_NUMBER.
+const (
+	DefaultBatchMaxPlaceholders = 65535
+	SqliteBatchMaxPlaceholders  = 999
+	DefaultBatchMaxBytes        = 1 << 20
+)
+
+// Conflict turns the statements of a Batch into upserts.  The syntax depends
+// on the bindtype of the driver: ON DUPLICATE KEY UPDATE for QUESTION (MySQL)
+// and ON CONFLICT for DOLLAR (PostgreSQL) and sqlite3.
+type Conflict struct {
+	// Columns of the unique key which may conflict.  Required by PostgreSQL
+	// and SQLite to update rows;  MySQL checks every unique key.
+	Columns []string
+
+	// Update lists the columns set to the inserted values on conflict.  It
+	// defaults to the inserted columns which are not in Columns.
+	Update []string
+
+	// DoNothing keeps the existing rows on conflict.
+	DoNothing bool
+}
+
+// Batch builds multi-row INSERT statements from a slice of structs or maps.
+// Rows are bound the same way as NamedExec binds a single struct, including
+// obeying the `db` struct tags.  Table and column names are used verbatim.
+//
+// Running a batch takes several statements when it exceeds the limits;  run it
+// in a transaction to make it atomic.
+type Batch struct {
+	Table string
+
+	// Columns to insert.  For structs, it defaults to the fields of the
+	// struct which map to a column, not including the fields of nested
+	// structs.  For maps, it defaults to the sorted keys of the first map.
+	Columns []string
+
+	// OnConflict, if set, makes the statements upserts.
+	OnConflict *Conflict
+
+	// MaxPlaceholders is the maximum number of placeholders per statement.
+	// It defaults to SqliteBatchMaxPlaceholders for sqlite3 and to
+	// DefaultBatchMaxPlaceholders otherwise.
+	MaxPlaceholders int
+
+	// MaxBytes is the maximum size of the query and arguments of a statement,
+	// e.g. to stay under max_allowed_packet on MySQL.  The size of arguments
+	// other than strings and []byte is estimated.  A statement holds at least
+	// one row.  It defaults to DefaultBatchMaxBytes.
+	MaxBytes int
+}
+
+// BatchQuery is one of the statements of a Batch.
+type BatchQuery struct {
+	Query string
+	Args  []interface{}
+	Rows  int // number of rows inserted by the statement
+}
+
+// Queries builds the statements inserting rows, a slice of structs, pointers
+// to structs or map[string]interface{}, for the given driver.
+func (b *Batch) Queries(driverName string, rows interface{}) ([]BatchQuery, error) {
+	return b.queries(driverName, rows, mapper())
+}
+
+// Exec runs the statements inserting rows using the provided Ext (sqlx.Tx,
+// sqlx.Db), and returns the total number of rows affected.  Note that MySQL
+// counts a row updated by an upsert twice.
+func (b *Batch) Exec(e Ext, rows interface{}) (int64, error) {
+	queries, err := b.queries(e.DriverName(), rows, mapperFor(e))
+	if err != nil {
+		return 0, err
+	}
+	var affected int64
+	for _, q := range queries {
+		res, err := e.Exec(q.Query, q.Args...)
+		if err != nil {
+			return affected, err
+		}
+		if affected, err = addRowsAffected(affected, res); err != nil {
+			return affected, err
+		}
+	}
+	return affected, nil
+}
+
+func addRowsAffected(affected int64, res sql.Result) (int64, error) {
+	n, err := res.RowsAffected()
+	if err != nil {
+		return affected, err
+	}
+	return affected + n, nil
+}
+
+// BatchInsert inserts rows, a slice of structs or maps, into table using the
+// provided Ext, with as few statements as the default limits allow.
+func BatchInsert(e Ext, table string, rows interface{}) (int64, error) {
+	b := &Batch{Table: table}
+	return b.Exec(e, rows)
+}
+
+// BatchUpsert is like BatchInsert, but updates the existing rows on conflict.
+func BatchUpsert(e Ext, table string, rows interface{}, conflict Conflict) (int64, error) {
+	b := &Batch{Table: table, OnConflict: &conflict}
+	return b.Exec(e, rows)
+}
+
+func (b *Batch) queries(driverName string, rows interface{}, m *reflectx.Mapper) ([]BatchQuery, error) {
+	v := reflect.ValueOf(rows)
+	for v.Kind() == reflect.Ptr {
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+		return nil, fmt.Errorf("expected a slice of rows, got %T", rows)
+	}
+	if v.Len() == 0 {
+		return nil, nil
+	}
+
+	columns, getArgs, err := b.binder(v, m)
+	if err != nil {
+		return nil, err
+	}
+	if len(columns) == 0 {
+		return nil, errors.New("no columns to insert")
+	}
+
+	bindType := BindType(driverName)
+	suffix, err := b.conflictClause(driverName, bindType, columns)
+	if err != nil {
+		return nil, err
+	}
+
+	maxPlaceholders := b.MaxPlaceholders
+	if maxPlaceholders <= 0 {
+		maxPlaceholders = DefaultBatchMaxPlaceholders
+		if driverName == "sqlite3" {
+			maxPlaceholders = SqliteBatchMaxPlaceholders
+		}
+	}
+	maxRows := maxPlaceholders / len(columns)
+	if maxRows == 0 {
+		return nil, fmt.Errorf("%d columns exceed the maximum of %d placeholders", len(columns), maxPlaceholders)
+	}
+	maxBytes := b.MaxBytes
+	if maxBytes <= 0 {
+		maxBytes = DefaultBatchMaxBytes
+	}
+
+	prefix := "INSERT INTO " + b.Table + " (" + strings.Join(columns, ", ") + ") VALUES "
+
+	var queries []BatchQuery
+	var query bytes.Buffer
+	var args []interface{}
+	var nrows, size int
+	flush := func() {
+		query.WriteString(suffix)
+		queries = append(queries, BatchQuery{Query: query.String(), Args: args, Rows: nrows})
+		query.Reset()
+		args, nrows = nil, 0
+	}
+
+	var row []byte
+	for i := 0; i < v.Len(); i++ {
+		rowArgs, err := getArgs(v.Index(i))
+		if err != nil {
+			return nil, err
+		}
+
+		rowSize := 0
+		for _, arg := range rowArgs {
+			rowSize += argSize(arg)
+		}
+		row = appendBatchRow(row[:0], bindType, len(args), len(columns))
+		if nrows > 0 && (nrows == maxRows || size+len(row)+1+rowSize+len(suffix) > maxBytes) {
+			flush()
+			// the placeholders of the row start over
+			row = appendBatchRow(row[:0], bindType, 0, len(columns))
+		}
+
+		if nrows == 0 {
+			query.WriteString(prefix)
+			size = len(prefix)
+		} else {
+			query.WriteByte(',')
+			size++
+		}
+		query.Write(row)
+		size += len(row) + rowSize
+		args = append(args, rowArgs...)
+		nrows++
+	}
+	flush()
+
+	return queries, nil
+}
+
+// Returns the columns of the batch, and a function returning the arguments of
+// a row for these columns
+func (b *Batch) binder(v reflect.Value, m *reflectx.Mapper) ([]string, func(reflect.Value) ([]interface{}, error), error) {
+	t := v.Type().Elem()
+	if t.Kind() == reflect.Map {
+		if t.Key().Kind() != reflect.String {
+			return nil, nil, fmt.Errorf("expected maps with string keys, got %s", t)
+		}
+		columns := b.Columns
+		if len(columns) == 0 {
+			for _, key := range v.Index(0).MapKeys() {
+				columns = append(columns, key.String())
+			}
+			sort.Strings(columns)
+		}
+		return columns, func(row reflect.Value) ([]interface{}, error) {
+			args := make([]interface{}, len(columns))
+			for i, column := range columns {
+				val := row.MapIndex(reflect.ValueOf(column).Convert(t.Key()))
+				if !val.IsValid() {
+					return nil, fmt.Errorf("could not find name %s in %#v", column, row.Interface())
+				}
+				args[i] = val.Interface()
+			}
+			return args, nil
+		}, nil
+	}
+
+	base := reflectx.Deref(t)
+	if base.Kind() != reflect.Struct {
+		return nil, nil, fmt.Errorf("expected a slice of structs or maps, got a slice of %s", t)
+	}
+	columns := b.Columns
+	if len(columns) == 0 {
+		columns = structColumns(m.TypeMap(base))
+	}
+	traversals := m.TraversalsByName(base, columns)
+	for i, traversal := range traversals {
+		if len(traversal) == 0 {
+			return nil, nil, fmt.Errorf("could not find name %s in %s", columns[i], base)
+		}
+	}
+	return columns, func(row reflect.Value) ([]interface{}, error) {
+		for row.Kind() == reflect.Ptr || row.Kind() == reflect.Interface {
+			if row.IsNil() {
+				return nil, errors.New("nil row in batch")
+			}
+			row = row.Elem()
+		}
+		args := make([]interface{}, len(traversals))
+		for i, traversal := range traversals {
+			args[i] = reflectx.FieldByIndexesReadOnly(row, traversal).Interface()
+		}
+		return args, nil
+	}, nil
+}
+
+// Returns the columns of a struct:  the fields which are not structs, or which
+// are valuers or scannable, including those of embedded structs
+func structColumns(sm *reflectx.StructMap) []string {
+	var columns []string
+	for _, fi := range sm.Index {
+		if fi.Embedded || strings.Contains(fi.Path, ".") || sm.Paths[fi.Path] != fi {
+			continue
+		}
+		ft := fi.Field.Type
+		if reflectx.Deref(ft).Kind() == reflect.Struct &&
+			!ft.Implements(_valuerInterface) && !reflect.PtrTo(ft).Implements(_valuerInterface) &&
+			!isScannable(reflectx.Deref(ft)) {
+			continue
+		}
+		columns = append(columns, fi.Path)
+	}
+	return columns
+}
+
+// Appends the placeholders of a row, following the first n arguments of the
+// statement
+func appendBatchRow(row []byte, bindType int, n, columns int) []byte {
+	row = append(row, '(')
+	for i := 1; i <= columns; i++ {
+		if i > 1 {
+			row = append(row, ", "...)
+		}
+		switch bindType {
+		case DOLLAR:
+			row = append(row, '$')
+			row = strconv.AppendInt(row, int64(n+i), 10)
+		case NAMED:
+			row = append(row, ":arg"...)
+			row = strconv.AppendInt(row, int64(n+i), 10)
+		default:
+			row = append(row, '?')
+		}
+	}
+	return append(row, ')')
+}
+
+// Estimates the size of an argument in a statement
+func argSize(arg interface{}) int {
+	switch v := arg.(type) {
+	case string:
+		return len(v)
+	case []byte:
+		return len(v)
+	}
+	return 8
+}
+
+func (b *Batch) conflictClause(driverName string, bindType int, columns []string) (string, error) {
+	c := b.OnConflict
+	if c == nil {
+		return "", nil
+	}
+
+	var update []string
+	if !c.DoNothing {
+		update = c.Update
+		if update == nil {
+			for _, column := range columns {
+				if !containsString(c.Columns, column) {
+					update = append(update, column)
+				}
+			}
+		}
+	}
+
+	var buf bytes.Buffer
+	switch {
+	case bindType == DOLLAR || driverName == "sqlite3":
+		buf.WriteString(" ON CONFLICT")
+		if len(c.Columns) > 0 {
+			buf.WriteString(" (")
+			buf.WriteString(strings.Join(c.Columns, ", "))
+			buf.WriteByte(')')
+		}
+		if len(update) == 0 {
+			buf.WriteString(" DO NOTHING")
+			break
+		}
+		if len(c.Columns) == 0 {
+			return "", errors.New("conflict columns are required to update rows on conflict")
+		}
+		buf.WriteString(" DO UPDATE SET ")
+		for i, column := range update {
+			if i > 0 {
+				buf.WriteString(", ")
+			}
+			buf.WriteString(column)
+			buf.WriteString(" = EXCLUDED.")
+			buf.WriteString(column)
+		}
+
+	case bindType == QUESTION:
+		buf.WriteString(" ON DUPLICATE KEY UPDATE ")
+		if len(update) == 0 {
+			// a no-op update keeps the existing row
+			column := columns[0]
+			if len(c.Columns) > 0 {
+				column = c.Columns[0]
+			}
+			buf.WriteString(column)
+			buf.WriteString(" = ")
+			buf.WriteString(column)
+			break
+		}
+		for i, column := range update {
+			if i > 0 {
+				buf.WriteString(", ")
+			}
+			buf.WriteString(column)
+			buf.WriteString(" = VALUES(")
+			buf.WriteString(column)
+			buf.WriteByte(')')
+		}
+
+	default:
+		return "", fmt.Errorf("upserts are not supported for driver %q", driverName)
+	}
+	return buf.String(), nil
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,26 @@
+// +build go1.8
+
+package sqlx
+
+import "context"
+
+// ExecContext runs the statements inserting rows using the provided
+// ExtContext (sqlx.Tx, sqlx.Db), and returns the total number of rows
+// affected.
+func (b *Batch) ExecContext(ctx context.Context, e ExtContext, rows interface{}) (int64, error) {
+	queries, err := b.queries(e.DriverName(), rows, mapperFor(e))
+	if err != nil {
+		return 0, err
+	}
+	var affected int64
+	for _, q := range queries {
+		res, err := e.ExecContext(ctx, q.Query, q.Args...)
+		if err != nil {
+			return affected, err
+		}
+		if affected, err = addRowsAffected(affected, res); err != nil {
+			return affected, err
+		}
+	}
+	return affected, nil
+}
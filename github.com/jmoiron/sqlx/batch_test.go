@@ -0,0 +1,197 @@
+package sqlx
+
+import (
+	"database/sql"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type BatchPerson struct {
+	ID        int    `db:"id"`
+	FirstName string `db:"first_name"`
+	LastName  string `db:"last_name"`
+	Email     sql.NullString
+	Place     Place `db:"-"`
+}
+
+var batchPeople = []BatchPerson{
+	{1, "Jason", "Moiron", sql.NullString{String: "jmoiron@jmoiron.net", Valid: true}, Place{}},
+	{2, "John", "Doe", sql.NullString{}, Place{}},
+	{3, "Jane", "Roe", sql.NullString{String: "jane@roe.net", Valid: true}, Place{}},
+}
+
+func TestBatchQueries(t *testing.T) {
+	b := &Batch{Table: "person"}
+	queries, err := b.Queries("postgres", batchPeople)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(queries) != 1 {
+		t.Fatalf("expected 1 query, got %d", len(queries))
+	}
+	expected := "INSERT INTO person (id, first_name, last_name, email) VALUES " +
+		"($1, $2, $3, $4),($5, $6, $7, $8),($9, $10, $11, $12)"
+	if queries[0].Query != expected {
+		t.Errorf("expected %q, got %q", expected, queries[0].Query)
+	}
+	if len(queries[0].Args) != 12 || queries[0].Args[4] != 2 || queries[0].Args[5] != "John" ||
+		queries[0].Args[7] != (sql.NullString{}) || queries[0].Rows != 3 {
+		t.Errorf("unexpected args %v", queries[0].Args)
+	}
+
+	// pointers to structs, with chosen columns, chunked by placeholders
+	b = &Batch{Table: "person", Columns: []string{"first_name", "email"}, MaxPlaceholders: 5}
+	queries, err = b.Queries("mysql", []*BatchPerson{&batchPeople[0], &batchPeople[1], &batchPeople[2]})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, q := range queries {
+		got = append(got, q.Query)
+	}
+	expectedQueries := []string{
+		"INSERT INTO person (first_name, email) VALUES (?, ?),(?, ?)",
+		"INSERT INTO person (first_name, email) VALUES (?, ?)",
+	}
+	if !reflect.DeepEqual(got, expectedQueries) {
+		t.Errorf("expected %q, got %q", expectedQueries, got)
+	}
+	if queries[1].Args[0] != "Jane" || queries[1].Rows != 1 {
+		t.Errorf("unexpected last query %+v", queries[1])
+	}
+}
+
+func TestBatchMaxBytes(t *testing.T) {
+	rows := []map[string]interface{}{
+		{"name": strings.Repeat("a", 100), "n": 1},
+		{"name": strings.Repeat("b", 100), "n": 2},
+		{"name": strings.Repeat("c", 100), "n": 3},
+	}
+	b := &Batch{Table: "t", MaxBytes: 300}
+	queries, err := b.Queries("postgres", rows)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(queries) != 2 || queries[0].Rows != 2 || queries[1].Rows != 1 {
+		t.Fatalf("unexpected queries %+v", queries)
+	}
+	// placeholders start over in each statement
+	if queries[1].Query != "INSERT INTO t (n, name) VALUES ($1, $2)" {
+		t.Errorf("unexpected query %q", queries[1].Query)
+	}
+	if queries[1].Args[0] != 3 {
+		t.Errorf("unexpected args %v", queries[1].Args)
+	}
+
+	// a statement holds at least one row
+	b.MaxBytes = 10
+	if queries, err = b.Queries("postgres", rows); err != nil || len(queries) != 3 {
+		t.Errorf("expected 3 queries, got %d (%v)", len(queries), err)
+	}
+}
+
+func TestBatchUpsert(t *testing.T) {
+	tests := []struct {
+		driver   string
+		conflict Conflict
+		suffix   string
+	}{
+		{"mysql", Conflict{}, " ON DUPLICATE KEY UPDATE id = VALUES(id), first_name = VALUES(first_name)"},
+		{"mysql", Conflict{Columns: []string{"id"}}, " ON DUPLICATE KEY UPDATE first_name = VALUES(first_name)"},
+		{"mysql", Conflict{DoNothing: true}, " ON DUPLICATE KEY UPDATE id = id"},
+		{"postgres", Conflict{Columns: []string{"id"}}, " ON CONFLICT (id) DO UPDATE SET first_name = EXCLUDED.first_name"},
+		{"pgx", Conflict{Columns: []string{"id"}, Update: []string{"id", "first_name"}},
+			" ON CONFLICT (id) DO UPDATE SET id = EXCLUDED.id, first_name = EXCLUDED.first_name"},
+		{"postgres", Conflict{DoNothing: true}, " ON CONFLICT DO NOTHING"},
+		{"sqlite3", Conflict{Columns: []string{"id"}, DoNothing: true}, " ON CONFLICT (id) DO NOTHING"},
+		{"sqlite3", Conflict{Columns: []string{"id", "first_name"}}, " ON CONFLICT (id, first_name) DO NOTHING"},
+	}
+	for _, test := range tests {
+		conflict := test.conflict
+		b := &Batch{Table: "person", Columns: []string{"id", "first_name"}, OnConflict: &conflict}
+		queries, err := b.Queries(test.driver, batchPeople[:1])
+		if err != nil {
+			t.Errorf("%s %+v: %v", test.driver, test.conflict, err)
+			continue
+		}
+		if !strings.HasSuffix(queries[0].Query, ")"+test.suffix) {
+			t.Errorf("%s %+v: expected suffix %q, got %q", test.driver, test.conflict, test.suffix, queries[0].Query)
+		}
+	}
+
+	for _, test := range []struct {
+		driver   string
+		conflict Conflict
+	}{
+		{"postgres", Conflict{}},
+		{"oci8", Conflict{DoNothing: true}},
+	} {
+		b := &Batch{Table: "person", OnConflict: &test.conflict}
+		if _, err := b.Queries(test.driver, batchPeople); err == nil {
+			t.Errorf("%s %+v: expected error", test.driver, test.conflict)
+		}
+	}
+}
+
+func TestBatchErrors(t *testing.T) {
+	b := &Batch{Table: "person"}
+	if queries, err := b.Queries("mysql", []BatchPerson{}); err != nil || queries != nil {
+		t.Errorf("expected no queries for no rows, got %v (%v)", queries, err)
+	}
+	for _, rows := range []interface{}{
+		batchPeople[0],
+		[]int{1, 2},
+		[]*BatchPerson{nil},
+		[]map[string]interface{}{{"id": 1}, {"name": "x"}},
+	} {
+		if _, err := b.Queries("mysql", rows); err == nil {
+			t.Errorf("expected error for %#v", rows)
+		}
+	}
+	b.Columns = []string{"nope"}
+	if _, err := b.Queries("mysql", batchPeople); err == nil {
+		t.Error("expected error for unknown column")
+	}
+}
+
+func TestBatchInsert(t *testing.T) {
+	RunWithSchema(defaultSchema, t, func(db *DB, t *testing.T) {
+		people := []Person2{
+			{FirstName: sql.NullString{String: "Ada", Valid: true}, LastName: sql.NullString{String: "Lovelace", Valid: true}},
+			{FirstName: sql.NullString{String: "Alan", Valid: true}, Email: sql.NullString{String: "alan@turing.net", Valid: true}},
+			{FirstName: sql.NullString{String: "Grace", Valid: true}},
+		}
+		b := &Batch{Table: "nullperson", MaxPlaceholders: 6}
+		n, err := b.Exec(db, people)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n != 3 {
+			t.Errorf("expected 3 rows affected, got %d", n)
+		}
+
+		var got []Person2
+		if err = db.Select(&got, "SELECT * FROM nullperson ORDER BY first_name"); err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(got, people) {
+			t.Errorf("expected %v, got %v", people, got)
+		}
+
+		tx := db.MustBegin()
+		if _, err = tx.BatchInsert("place", []map[string]interface{}{
+			{"country": "Norway", "telcode": 47},
+			{"country": "Peru", "telcode": 51},
+		}); err != nil {
+			t.Fatal(err)
+		}
+		if err = tx.Commit(); err != nil {
+			t.Fatal(err)
+		}
+		var count int
+		if err = db.Get(&count, "SELECT count(*) FROM place"); err != nil || count != 2 {
+			t.Errorf("expected 2 places, got %d (%v)", count, err)
+		}
+	})
+}
@@ -0,0 +1,129 @@
+// sqlx-migrate applies and reverts schema migrations from a directory.
+//
+// Usage:
+//     sqlx-migrate -driver mysql -dsn 'user:pass@/db?multiStatements=true' -dir migrations status
+//     sqlx-migrate -driver mysql -dsn ... up [n]
+//     sqlx-migrate -driver mysql -dsn ... down [n]
+//     sqlx-migrate -driver mysql -dsn ... redo
+//
+// The MySQL driver is always built in.  Build with the postgres and sqlite3
+// tags to add the lib/pq and go-sqlite3 drivers.
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	_ "github.com/go-sql-driver/mysql"
+	"github.com/jmoiron/sqlx"
+	"github.com/jmoiron/sqlx/migrate"
+)
+
+var (
+	flagDriver = flag.String("driver", "mysql", "database driver name")
+	flagDSN    = flag.String("dsn", "", "data source name")
+	flagDir    = flag.String("dir", "migrations", "directory of the migration files")
+	flagTable  = flag.String("table", migrate.DefaultTable, "table recording applied migrations")
+)
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s [flags] status|up [n]|down [n]|redo\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if err := start(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func start() error {
+	args := flag.Args()
+	if len(args) == 0 || len(args) > 2 {
+		flag.Usage()
+		return errors.New("expected a command")
+	}
+	switch args[0] {
+	case "status", "up", "down", "redo":
+	default:
+		flag.Usage()
+		return fmt.Errorf("unknown command %q", args[0])
+	}
+
+	// up applies all pending migrations by default, down reverts the latest one
+	n := 0
+	if args[0] == "down" {
+		n = 1
+	}
+	if len(args) == 2 {
+		var err error
+		if n, err = strconv.Atoi(args[1]); err != nil || n < 1 {
+			return fmt.Errorf("invalid number of migrations %q", args[1])
+		}
+	}
+
+	db, err := sqlx.Connect(*flagDriver, *flagDSN)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	m := &migrate.Migrator{DB: db, Source: migrate.Dir(*flagDir), Table: *flagTable}
+
+	switch args[0] {
+	case "status":
+		return status(m)
+	case "up":
+		applied, err := m.Up(n)
+		printMigrations("applied", applied)
+		return err
+	case "down":
+		reverted, err := m.Down(n)
+		printMigrations("reverted", reverted)
+		return err
+	case "redo":
+		redone, err := m.Redo()
+		if redone != nil {
+			printMigrations("redone", []*migrate.Migration{redone})
+		}
+		return err
+	}
+	return nil
+}
+
+func status(m *migrate.Migrator) error {
+	statuses, err := m.Status()
+	if err != nil {
+		return err
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(w, "VERSION\tNAME\tSTATUS\tAPPLIED AT")
+	for _, s := range statuses {
+		state, at := "pending", ""
+		if s.Applied {
+			state, at = "applied", s.AppliedAt.Format(time.RFC3339)
+		}
+		if s.Modified {
+			state = "modified"
+		} else if s.Missing {
+			state = "missing"
+		}
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\n", s.Version, s.Name, state, at)
+	}
+	return w.Flush()
+}
+
+func printMigrations(verb string, migrations []*migrate.Migration) {
+	if len(migrations) == 0 {
+		fmt.Println("no migrations " + verb)
+	}
+	for _, mig := range migrations {
+		fmt.Printf("%s %d_%s\n", verb, mig.Version, mig.Name)
+	}
+}
@@ -0,0 +1,118 @@
+package migrate
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// ErrLocked is returned when another process holds the migration lock.
+var ErrLocked = errors.New("migrate: migrations are locked by another process")
+
+// errSingleConn is returned when a session lock can't be taken as the
+// migrations would wait forever for a second connection.
+var errSingleConn = errors.New("migrate: the migration lock pins a connection, SetMaxOpenConns must allow at least 2")
+
+// A locker prevents concurrent runs of migrations against a database.
+type locker interface {
+	lock() error
+	unlock() error
+}
+
+// newLocker returns an advisory lock for PostgreSQL and MySQL, and a lock
+// table for other databases.
+func newLocker(db *sqlx.DB, table string) locker {
+	switch db.DriverName() {
+	case "postgres", "pgx":
+		return &sessionLock{
+			db:        db,
+			lockSQL:   "SELECT pg_try_advisory_lock($1)",
+			unlockSQL: "SELECT pg_advisory_unlock($1)",
+			arg:       int64(crc32.ChecksumIEEE([]byte(table))),
+		}
+	case "mysql":
+		// Lock names are global to the server, so they're scoped to the
+		// current database.
+		return &sessionLock{
+			db:        db,
+			lockSQL:   "SELECT GET_LOCK(CONCAT_WS('.', DATABASE(), ?), 0)",
+			unlockSQL: "SELECT RELEASE_LOCK(CONCAT_WS('.', DATABASE(), ?))",
+			arg:       table,
+		}
+	}
+	return &tableLock{db: db, table: table + "_lock"}
+}
+
+// sessionLock holds a lock bound to a database session, which the server
+// releases if the process dies.  The session is pinned by a transaction
+// which is left idle while migrations run on other connections, so the
+// database must allow at least two open connections.
+type sessionLock struct {
+	db                 *sqlx.DB
+	lockSQL, unlockSQL string
+	arg                interface{}
+
+	tx *sql.Tx
+}
+
+func (l *sessionLock) lock() error {
+	if err := checkMaxOpenConns(l.db); err != nil {
+		return err
+	}
+	tx, err := l.db.Begin()
+	if err != nil {
+		return err
+	}
+	var ok sql.NullBool
+	if err = tx.QueryRow(l.lockSQL, l.arg).Scan(&ok); err != nil {
+		tx.Rollback()
+		return err
+	}
+	if !ok.Bool {
+		tx.Rollback()
+		return ErrLocked
+	}
+	l.tx = tx
+	return nil
+}
+
+func (l *sessionLock) unlock() error {
+	_, err := l.tx.Exec(l.unlockSQL, l.arg)
+	if rerr := l.tx.Rollback(); err == nil {
+		err = rerr
+	}
+	l.tx = nil
+	return err
+}
+
+// tableLock is held while a row exists in a lock table.  It survives the
+// process, so the row has to be deleted by hand after a crash.
+type tableLock struct {
+	db    *sqlx.DB
+	table string
+}
+
+func (l *tableLock) lock() error {
+	_, err := l.db.Exec(fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (id INTEGER NOT NULL PRIMARY KEY, locked_at VARCHAR(32) NOT NULL)", l.table))
+	if err != nil {
+		return err
+	}
+	q := l.db.Rebind(fmt.Sprintf("INSERT INTO %s (id, locked_at) VALUES (1, ?)", l.table))
+	if _, err = l.db.Exec(q, time.Now().UTC().Format(time.RFC3339)); err != nil {
+		var n int
+		if l.db.Get(&n, fmt.Sprintf("SELECT count(*) FROM %s", l.table)) == nil && n > 0 {
+			return ErrLocked
+		}
+		return err
+	}
+	return nil
+}
+
+func (l *tableLock) unlock() error {
+	_, err := l.db.Exec(fmt.Sprintf("DELETE FROM %s", l.table))
+	return err
+}
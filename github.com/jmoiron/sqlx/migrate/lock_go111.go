@@ -0,0 +1,13 @@
+// +build go1.11
+
+package migrate
+
+import "github.com/jmoiron/sqlx"
+
+// checkMaxOpenConns fails if db allows a single open connection.
+func checkMaxOpenConns(db *sqlx.DB) error {
+	if db.Stats().MaxOpenConnections == 1 {
+		return errSingleConn
+	}
+	return nil
+}
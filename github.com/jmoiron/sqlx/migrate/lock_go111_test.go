@@ -0,0 +1,20 @@
+// +build go1.11
+
+package migrate
+
+import (
+	"os"
+	"testing"
+)
+
+func TestSessionLockSingleConn(t *testing.T) {
+	dir, db := setup(t, testFiles)
+	defer os.RemoveAll(dir)
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+
+	l := &sessionLock{db: db, lockSQL: "SELECT ?", unlockSQL: "SELECT ?", arg: DefaultTable}
+	if err := l.lock(); err != errSingleConn {
+		t.Errorf("expected errSingleConn, got %v", err)
+	}
+}
@@ -0,0 +1,10 @@
+// +build !go1.11
+
+package migrate
+
+import "github.com/jmoiron/sqlx"
+
+// checkMaxOpenConns can't tell the connection limit of db before Go 1.11.
+func checkMaxOpenConns(db *sqlx.DB) error {
+	return nil
+}
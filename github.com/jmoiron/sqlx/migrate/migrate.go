@@ -0,0 +1,330 @@
+// Package migrate runs versioned schema migrations against a sqlx.DB.
+//
+// Migrations are pairs of SQL files, <version>_<name>.up.sql and
+// <version>_<name>.down.sql, read from a directory or from assets embedded
+// with go-bindata.  Applied versions are recorded with the checksum of their
+// up file in a table, and runs take a lock on the database so that concurrent
+// deployments don't apply the same migrations twice.
+//
+//  m := migrate.New(db, migrate.Dir("migrations"))
+//  applied, err := m.Up(0)
+//
+package migrate
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// DefaultTable is the table recording applied migrations, unless the
+// Migrator sets another.
+const DefaultTable = "schema_migrations"
+
+// Migrator applies and reverts the migrations of a Source.
+type Migrator struct {
+	DB     *sqlx.DB
+	Source Source
+	Table  string
+}
+
+// New returns a Migrator for the given database and source, recording
+// migrations in DefaultTable.
+func New(db *sqlx.DB, source Source) *Migrator {
+	return &Migrator{DB: db, Source: source, Table: DefaultTable}
+}
+
+// Status describes a migration, known by the source or the database.
+type Status struct {
+	Version   int64
+	Name      string
+	Applied   bool
+	AppliedAt time.Time
+
+	// Modified is set when the up file changed since it was applied, and
+	// Missing when the source no longer has an applied migration.
+	Modified bool
+	Missing  bool
+}
+
+// ChecksumError is returned by Up when applied migrations were modified.
+type ChecksumError struct {
+	Version int64
+	Name    string
+}
+
+func (e *ChecksumError) Error() string {
+	return fmt.Sprintf("migrate: migration %d_%s was modified after it was applied", e.Version, e.Name)
+}
+
+type record struct {
+	Version   int64  `db:"version"`
+	Name      string `db:"name"`
+	Checksum  string `db:"checksum"`
+	AppliedAt string `db:"applied_at"`
+}
+
+func (m *Migrator) table() string {
+	if m.Table == "" {
+		return DefaultTable
+	}
+	return m.Table
+}
+
+// Status returns the status of the migrations by version.
+func (m *Migrator) Status() ([]Status, error) {
+	migrations, err := m.Source.Migrations()
+	if err != nil {
+		return nil, err
+	}
+	if err = m.createTable(); err != nil {
+		return nil, err
+	}
+	records, err := m.records()
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]Status, 0, len(migrations))
+	known := make(map[int64]bool, len(migrations))
+	for _, mig := range migrations {
+		known[mig.Version] = true
+		s := Status{Version: mig.Version, Name: mig.Name}
+		if r := records[mig.Version]; r != nil {
+			s.Applied = true
+			s.AppliedAt, _ = time.Parse(time.RFC3339, r.AppliedAt)
+			s.Modified = r.Checksum != mig.Checksum()
+		}
+		statuses = append(statuses, s)
+	}
+	for _, r := range records {
+		if !known[r.Version] {
+			at, _ := time.Parse(time.RFC3339, r.AppliedAt)
+			statuses = append(statuses, Status{Version: r.Version, Name: r.Name, Applied: true, AppliedAt: at, Missing: true})
+		}
+	}
+	sort.Sort(statusesByVersion(statuses))
+	return statuses, nil
+}
+
+// Up applies at most n pending migrations, or all of them if n <= 0, in order
+// of version.  It returns the migrations it applied.  Nothing is applied if a
+// migration was modified after it was applied.
+func (m *Migrator) Up(n int) ([]*Migration, error) {
+	var applied []*Migration
+	err := m.locked(func(migrations []*Migration, records map[int64]*record) error {
+		var err error
+		applied, err = m.up(n, migrations, records)
+		return err
+	})
+	return applied, err
+}
+
+// Down reverts at most n applied migrations, or all of them if n <= 0, from
+// the latest version.  It returns the migrations it reverted.
+func (m *Migrator) Down(n int) ([]*Migration, error) {
+	var reverted []*Migration
+	err := m.locked(func(migrations []*Migration, records map[int64]*record) error {
+		var err error
+		reverted, err = m.down(n, migrations, records)
+		return err
+	})
+	return reverted, err
+}
+
+// Redo reverts the latest applied migration and applies it again.  It returns
+// the migration, or nil if none was applied.
+func (m *Migrator) Redo() (*Migration, error) {
+	var redone *Migration
+	err := m.locked(func(migrations []*Migration, records map[int64]*record) error {
+		reverted, err := m.down(1, migrations, records)
+		if err != nil || len(reverted) == 0 {
+			return err
+		}
+		if err = m.apply(reverted[0]); err != nil {
+			return err
+		}
+		redone = reverted[0]
+		return nil
+	})
+	return redone, err
+}
+
+// locked calls fn with the migrations of the source and the records of the
+// applied ones, while holding the migration lock.
+func (m *Migrator) locked(fn func([]*Migration, map[int64]*record) error) (err error) {
+	migrations, err := m.Source.Migrations()
+	if err != nil {
+		return err
+	}
+	l := newLocker(m.DB, m.table())
+	if err = l.lock(); err != nil {
+		return err
+	}
+	defer func() {
+		if uerr := l.unlock(); err == nil {
+			err = uerr
+		}
+	}()
+
+	if err = m.createTable(); err != nil {
+		return err
+	}
+	records, err := m.records()
+	if err != nil {
+		return err
+	}
+	return fn(migrations, records)
+}
+
+func (m *Migrator) up(n int, migrations []*Migration, records map[int64]*record) ([]*Migration, error) {
+	var pending []*Migration
+	for _, mig := range migrations {
+		r := records[mig.Version]
+		if r == nil {
+			pending = append(pending, mig)
+		} else if r.Checksum != mig.Checksum() {
+			return nil, &ChecksumError{mig.Version, mig.Name}
+		}
+	}
+	if n > 0 && n < len(pending) {
+		pending = pending[:n]
+	}
+
+	var applied []*Migration
+	for _, mig := range pending {
+		if err := m.apply(mig); err != nil {
+			return applied, err
+		}
+		applied = append(applied, mig)
+	}
+	return applied, nil
+}
+
+func (m *Migrator) down(n int, migrations []*Migration, records map[int64]*record) ([]*Migration, error) {
+	byVersion := make(map[int64]*Migration, len(migrations))
+	for _, mig := range migrations {
+		byVersion[mig.Version] = mig
+	}
+	versions := make([]int64, 0, len(records))
+	for v := range records {
+		versions = append(versions, v)
+	}
+	sort.Sort(sort.Reverse(int64s(versions)))
+	if n > 0 && n < len(versions) {
+		versions = versions[:n]
+	}
+
+	var reverted []*Migration
+	for _, v := range versions {
+		mig := byVersion[v]
+		if mig == nil {
+			return reverted, fmt.Errorf("migrate: applied migration %d_%s is missing from the source", v, records[v].Name)
+		}
+		if err := m.revert(mig); err != nil {
+			return reverted, err
+		}
+		reverted = append(reverted, mig)
+	}
+	return reverted, nil
+}
+
+func (m *Migrator) apply(mig *Migration) error {
+	q := m.DB.Rebind(fmt.Sprintf("INSERT INTO %s (version, name, checksum, applied_at) VALUES (?, ?, ?, ?)", m.table()))
+	err := m.run(mig.Up, func(e sqlx.Execer) error {
+		_, err := e.Exec(q, mig.Version, mig.Name, mig.Checksum(), time.Now().UTC().Format(time.RFC3339))
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("migrate: applying %d_%s: %v", mig.Version, mig.Name, err)
+	}
+	return nil
+}
+
+func (m *Migrator) revert(mig *Migration) error {
+	if mig.Down == nil {
+		return fmt.Errorf("migrate: migration %d_%s has no down file", mig.Version, mig.Name)
+	}
+	q := m.DB.Rebind(fmt.Sprintf("DELETE FROM %s WHERE version = ?", m.table()))
+	err := m.run(mig.Down, func(e sqlx.Execer) error {
+		_, err := e.Exec(q, mig.Version)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("migrate: reverting %d_%s: %v", mig.Version, mig.Name, err)
+	}
+	return nil
+}
+
+// run executes a step and records it with the same transaction, unless the
+// step can't run in one.
+func (m *Migrator) run(step *Step, record func(sqlx.Execer) error) error {
+	empty := strings.TrimSpace(step.SQL) == ""
+	if step.NoTransaction {
+		if !empty {
+			if _, err := m.DB.Exec(step.SQL); err != nil {
+				return err
+			}
+		}
+		return record(m.DB)
+	}
+
+	tx, err := m.DB.Beginx()
+	if err != nil {
+		return err
+	}
+	if !empty {
+		if _, err = tx.Exec(step.SQL); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	if err = record(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+func (m *Migrator) createTable() error {
+	_, err := m.DB.Exec(fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+	version BIGINT NOT NULL PRIMARY KEY,
+	name VARCHAR(255) NOT NULL,
+	checksum CHAR(64) NOT NULL,
+	applied_at VARCHAR(32) NOT NULL
+)`, m.table()))
+	return err
+}
+
+func (m *Migrator) records() (map[int64]*record, error) {
+	var records []*record
+	if err := m.DB.Select(&records, fmt.Sprintf("SELECT version, name, checksum, applied_at FROM %s", m.table())); err != nil {
+		return nil, err
+	}
+	byVersion := make(map[int64]*record, len(records))
+	for _, r := range records {
+		byVersion[r.Version] = r
+	}
+	return byVersion, nil
+}
+
+type migrationsByVersion []*Migration
+
+func (s migrationsByVersion) Len() int           { return len(s) }
+func (s migrationsByVersion) Less(i, j int) bool { return s[i].Version < s[j].Version }
+func (s migrationsByVersion) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+
+type statusesByVersion []Status
+
+func (s statusesByVersion) Len() int           { return len(s) }
+func (s statusesByVersion) Less(i, j int) bool { return s[i].Version < s[j].Version }
+func (s statusesByVersion) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+
+type int64s []int64
+
+func (s int64s) Len() int           { return len(s) }
+func (s int64s) Less(i, j int) bool { return s[i] < s[j] }
+func (s int64s) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
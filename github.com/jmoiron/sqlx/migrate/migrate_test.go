@@ -0,0 +1,216 @@
+package migrate
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+var testFiles = map[string]string{
+	"0001_person.up.sql":   "CREATE TABLE person (name text);",
+	"0001_person.down.sql": "DROP TABLE person;",
+	"0002_place.up.sql":    "CREATE TABLE place (country text);\nINSERT INTO place VALUES ('Peru');",
+	"0002_place.down.sql":  "DROP TABLE place;",
+	"0010_index.up.sql":    "-- index without locking the table\n" + NoTransaction + "\nCREATE INDEX place_country ON place (country);",
+	"0010_index.down.sql":  NoTransaction + "\nDROP INDEX place_country;",
+	"README":               "not a migration",
+}
+
+// Returns a temporary directory with the given files, and an empty database
+func setup(t *testing.T, files map[string]string) (string, *sqlx.DB) {
+	dir, err := ioutil.TempDir("", "migrate")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range files {
+		if err = ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	db, err := sqlx.Connect("sqlite3", filepath.Join(dir, "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return dir, db
+}
+
+func versions(migrations []*Migration) []int64 {
+	var v []int64
+	for _, m := range migrations {
+		v = append(v, m.Version)
+	}
+	return v
+}
+
+func TestSource(t *testing.T) {
+	dir, db := setup(t, testFiles)
+	defer os.RemoveAll(dir)
+	defer db.Close()
+
+	migrations, err := Dir(dir).Migrations()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v := versions(migrations); !reflect.DeepEqual(v, []int64{1, 2, 10}) {
+		t.Fatalf("expected versions 1, 2, 10, got %v", v)
+	}
+	if m := migrations[2]; m.Name != "index" || !m.Up.NoTransaction || !m.Down.NoTransaction || migrations[0].Up.NoTransaction {
+		t.Errorf("unexpected migration %+v", m)
+	}
+
+	assets := map[string]string{
+		"db/1_a.up.sql":   "CREATE TABLE a (id int);",
+		"db/1_a.down.sql": "DROP TABLE a;",
+	}
+	src := Bindata("db", func(name string) ([]string, error) {
+		var names []string
+		for asset := range assets {
+			names = append(names, path.Base(asset))
+		}
+		return names, nil
+	}, func(name string) ([]byte, error) {
+		return []byte(assets[name]), nil
+	})
+	if migrations, err = src.Migrations(); err != nil {
+		t.Fatal(err)
+	}
+	if len(migrations) != 1 || migrations[0].Down.SQL != "DROP TABLE a;" {
+		t.Errorf("unexpected migrations %+v", migrations)
+	}
+
+	for _, files := range []map[string]string{
+		{"1_a.down.sql": ""},
+		{"1_a.up.sql": "", "1_b.up.sql": ""},
+	} {
+		if _, err = parseMigrations(keys(files), func(name string) ([]byte, error) { return nil, nil }); err == nil {
+			t.Errorf("expected error for %v", files)
+		}
+	}
+}
+
+func keys(m map[string]string) []string {
+	var k []string
+	for key := range m {
+		k = append(k, key)
+	}
+	return k
+}
+
+func TestMigrate(t *testing.T) {
+	dir, db := setup(t, testFiles)
+	defer os.RemoveAll(dir)
+	defer db.Close()
+	m := New(db, Dir(dir))
+
+	applied, err := m.Up(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v := versions(applied); !reflect.DeepEqual(v, []int64{1, 2}) {
+		t.Errorf("expected versions 1, 2 applied, got %v", v)
+	}
+	if applied, err = m.Up(0); err != nil || !reflect.DeepEqual(versions(applied), []int64{10}) {
+		t.Errorf("expected version 10 applied, got %v (%v)", versions(applied), err)
+	}
+	if applied, err = m.Up(0); err != nil || len(applied) != 0 {
+		t.Errorf("expected nothing applied, got %v (%v)", versions(applied), err)
+	}
+	var country string
+	if err = db.Get(&country, "SELECT country FROM place"); err != nil || country != "Peru" {
+		t.Errorf("expected Peru, got %q (%v)", country, err)
+	}
+
+	statuses, err := m.Status()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(statuses) != 3 || !statuses[0].Applied || statuses[0].AppliedAt.IsZero() || statuses[2].Name != "index" {
+		t.Errorf("unexpected statuses %+v", statuses)
+	}
+
+	redone, err := m.Redo()
+	if err != nil || redone == nil || redone.Version != 10 {
+		t.Errorf("expected version 10 redone, got %+v (%v)", redone, err)
+	}
+	reverted, err := m.Down(2)
+	if err != nil || !reflect.DeepEqual(versions(reverted), []int64{10, 2}) {
+		t.Errorf("expected versions 10, 2 reverted, got %v (%v)", versions(reverted), err)
+	}
+	if _, err = db.Exec("SELECT * FROM place"); err == nil {
+		t.Error("expected table place to be dropped")
+	}
+	if statuses, err = m.Status(); err != nil || !statuses[0].Applied || statuses[1].Applied || statuses[2].Applied {
+		t.Errorf("unexpected statuses %+v (%v)", statuses, err)
+	}
+	if reverted, err = m.Down(0); err != nil || !reflect.DeepEqual(versions(reverted), []int64{1}) {
+		t.Errorf("expected version 1 reverted, got %v (%v)", versions(reverted), err)
+	}
+}
+
+func TestMigrateErrors(t *testing.T) {
+	dir, db := setup(t, testFiles)
+	defer os.RemoveAll(dir)
+	defer db.Close()
+	m := New(db, Dir(dir))
+	if _, err := m.Up(1); err != nil {
+		t.Fatal(err)
+	}
+
+	// a failing migration is rolled back
+	ioutil.WriteFile(filepath.Join(dir, "0002_place.up.sql"), []byte("CREATE TABLE place (country text); SELECT * FROM nope;"), 0644)
+	if applied, err := m.Up(0); err == nil || len(applied) != 0 {
+		t.Errorf("expected error, got %v applied", versions(applied))
+	}
+	if _, err := db.Exec("SELECT * FROM place"); err == nil {
+		t.Error("expected table place not to be created")
+	}
+
+	// modified migrations are reported and nothing is applied
+	ioutil.WriteFile(filepath.Join(dir, "0001_person.up.sql"), []byte("CREATE TABLE person (name text, age int);"), 0644)
+	if _, err := m.Up(0); err == nil {
+		t.Error("expected checksum error")
+	} else if cerr, ok := err.(*ChecksumError); !ok || cerr.Version != 1 {
+		t.Errorf("expected checksum error, got %v", err)
+	}
+	statuses, err := m.Status()
+	if err != nil || !statuses[0].Modified {
+		t.Errorf("expected modified migration, got %+v (%v)", statuses, err)
+	}
+
+	// missing migrations can't be reverted
+	os.Remove(filepath.Join(dir, "0001_person.up.sql"))
+	os.Remove(filepath.Join(dir, "0001_person.down.sql"))
+	if statuses, err = m.Status(); err != nil || !statuses[0].Missing || statuses[0].Name != "person" {
+		t.Errorf("expected missing migration, got %+v (%v)", statuses, err)
+	}
+	if _, err = m.Down(1); err == nil {
+		t.Error("expected error for missing migration")
+	}
+}
+
+func TestLock(t *testing.T) {
+	dir, db := setup(t, testFiles)
+	defer os.RemoveAll(dir)
+	defer db.Close()
+	m := New(db, Dir(dir))
+
+	l := newLocker(db, DefaultTable)
+	if err := l.lock(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.Up(0); err != ErrLocked {
+		t.Errorf("expected ErrLocked, got %v", err)
+	}
+	if err := l.unlock(); err != nil {
+		t.Fatal(err)
+	}
+	if applied, err := m.Up(0); err != nil || len(applied) != 3 {
+		t.Errorf("expected 3 migrations applied, got %v (%v)", versions(applied), err)
+	}
+}
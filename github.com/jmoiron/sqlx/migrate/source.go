@@ -0,0 +1,167 @@
+package migrate
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// NoTransaction is the directive which makes a step run outside of a
+// transaction, for statements such as CREATE INDEX CONCURRENTLY which can't
+// run in one.  It must appear on a line of its own among the comments at the
+// top of the file.
+const NoTransaction = "-- +migrate notransaction"
+
+// Step is the SQL run to apply or to revert a migration.  It is executed in a
+// single call to Exec, so drivers must accept several statements per query
+// when a step has more than one (for MySQL, set multiStatements=true).
+type Step struct {
+	SQL           string
+	NoTransaction bool
+}
+
+// Migration is a versioned schema change, read from a pair of files named
+// after it: <version>_<name>.up.sql and <version>_<name>.down.sql.  The down
+// file is optional.
+type Migration struct {
+	Version int64
+	Name    string
+	Up      *Step
+	Down    *Step
+}
+
+// Checksum returns the hex encoded SHA-256 sum of the up step, which is
+// recorded when the migration is applied.
+func (m *Migration) Checksum() string {
+	sum := sha256.Sum256([]byte(m.Up.SQL))
+	return hex.EncodeToString(sum[:])
+}
+
+// A Source provides the migrations to run.
+type Source interface {
+	// Migrations returns the migrations, sorted by version.
+	Migrations() ([]*Migration, error)
+}
+
+// Dir returns a Source reading migration files from a directory.
+func Dir(dir string) Source {
+	return &dirSource{dir}
+}
+
+type dirSource struct {
+	dir string
+}
+
+func (s *dirSource) Migrations() ([]*Migration, error) {
+	infos, err := ioutil.ReadDir(s.dir)
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(infos))
+	for _, info := range infos {
+		if !info.IsDir() {
+			names = append(names, info.Name())
+		}
+	}
+	return parseMigrations(names, func(name string) ([]byte, error) {
+		return ioutil.ReadFile(filepath.Join(s.dir, name))
+	})
+}
+
+// Bindata returns a Source reading migration files embedded with go-bindata,
+// given the AssetDir and Asset functions it generated and the directory of
+// the files among the assets:
+//
+//  src := migrate.Bindata("migrations", migrations.AssetDir, migrations.Asset)
+//
+func Bindata(dir string, assetDir func(string) ([]string, error), asset func(string) ([]byte, error)) Source {
+	return &bindataSource{dir, assetDir, asset}
+}
+
+type bindataSource struct {
+	dir      string
+	assetDir func(string) ([]string, error)
+	asset    func(string) ([]byte, error)
+}
+
+func (s *bindataSource) Migrations() ([]*Migration, error) {
+	names, err := s.assetDir(s.dir)
+	if err != nil {
+		return nil, err
+	}
+	return parseMigrations(names, func(name string) ([]byte, error) {
+		return s.asset(path.Join(s.dir, name))
+	})
+}
+
+var fileRegexp = regexp.MustCompile(`^(\d+)_(.+)\.(up|down)\.sql$`)
+
+// parseMigrations builds the migrations from the files with the given names,
+// ignoring those which aren't migration files.
+func parseMigrations(names []string, read func(name string) ([]byte, error)) ([]*Migration, error) {
+	byVersion := make(map[int64]*Migration)
+	for _, name := range names {
+		match := fileRegexp.FindStringSubmatch(name)
+		if match == nil {
+			continue
+		}
+		version, err := strconv.ParseInt(match[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("migrate: invalid version in %s: %v", name, err)
+		}
+		m := byVersion[version]
+		if m == nil {
+			m = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = m
+		} else if m.Name != match[2] {
+			return nil, fmt.Errorf("migrate: migrations %s and %s have the same version %d", m.Name, match[2], version)
+		}
+
+		b, err := read(name)
+		if err != nil {
+			return nil, err
+		}
+		step := parseStep(string(b))
+		if match[3] == "up" {
+			m.Up = step
+		} else {
+			m.Down = step
+		}
+	}
+
+	migrations := make([]*Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == nil {
+			return nil, fmt.Errorf("migrate: migration %d_%s has no up file", m.Version, m.Name)
+		}
+		migrations = append(migrations, m)
+	}
+	sort.Sort(migrationsByVersion(migrations))
+	return migrations, nil
+}
+
+func parseStep(sql string) *Step {
+	step := &Step{SQL: sql}
+	scanner := bufio.NewScanner(strings.NewReader(sql))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		if !strings.HasPrefix(line, "--") {
+			break
+		}
+		if line == NoTransaction {
+			step.NoTransaction = true
+		}
+	}
+	return step
+}
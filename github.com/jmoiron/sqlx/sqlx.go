@@ -307,6 +307,18 @@ func (db *DB) NamedExec(query string, arg interface{}) (sql.Result, error) {
 	return NamedExec(db, query, arg)
 }
 
+// BatchInsert inserts rows, a slice of structs or maps, into table using this DB.
+// Rows are bound like NamedExec binds a single arg.
+func (db *DB) BatchInsert(table string, rows interface{}) (int64, error) {
+	return BatchInsert(db, table, rows)
+}
+
+// BatchUpsert inserts rows into table using this DB, updating existing rows
+// on conflict.
+func (db *DB) BatchUpsert(table string, rows interface{}, conflict Conflict) (int64, error) {
+	return BatchUpsert(db, table, rows, conflict)
+}
+
 // Select using this DB.
 // Any placeholder parameters are replaced with supplied args.
 func (db *DB) Select(dest interface{}, query string, args ...interface{}) error {
@@ -413,6 +425,18 @@ func (tx *Tx) NamedExec(query string, arg interface{}) (sql.Result, error) {
 	return NamedExec(tx, query, arg)
 }
 
+// BatchInsert inserts rows, a slice of structs or maps, into table within a
+// transaction.  Rows are bound like NamedExec binds a single arg.
+func (tx *Tx) BatchInsert(table string, rows interface{}) (int64, error) {
+	return BatchInsert(tx, table, rows)
+}
+
+// BatchUpsert inserts rows into table within a transaction, updating existing
+// rows on conflict.
+func (tx *Tx) BatchUpsert(table string, rows interface{}, conflict Conflict) (int64, error) {
+	return BatchUpsert(tx, table, rows, conflict)
+}
+
 // Select within a transaction.
 // Any placeholder parameters are replaced with supplied args.
 func (tx *Tx) Select(dest interface{}, query string, args ...interface{}) error {
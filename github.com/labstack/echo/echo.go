@@ -182,6 +182,7 @@ const (
 	HeaderXForwardedProto               = "X-Forwarded-Proto"
 	HeaderXHTTPMethodOverride           = "X-HTTP-Method-Override"
 	HeaderXForwardedFor                 = "X-Forwarded-For"
+	HeaderXForwardedHost                = "X-Forwarded-Host"
 	HeaderXRealIP                       = "X-Real-IP"
 	HeaderXRequestID                    = "X-Request-ID"
 	HeaderServer                        = "Server"
@@ -194,6 +195,12 @@ const (
 	HeaderAccessControlAllowCredentials = "Access-Control-Allow-Credentials"
 	HeaderAccessControlExposeHeaders    = "Access-Control-Expose-Headers"
 	HeaderAccessControlMaxAge           = "Access-Control-Max-Age"
+	HeaderRetryAfter                    = "Retry-After"
+
+	// Rate limiting
+	HeaderRateLimitLimit     = "RateLimit-Limit"
+	HeaderRateLimitRemaining = "RateLimit-Remaining"
+	HeaderRateLimitReset     = "RateLimit-Reset"
 
 	// Security
 	HeaderStrictTransportSecurity = "Strict-Transport-Security"
@@ -226,6 +233,7 @@ var (
 	ErrForbidden                   = NewHTTPError(http.StatusForbidden)
 	ErrMethodNotAllowed            = NewHTTPError(http.StatusMethodNotAllowed)
 	ErrStatusRequestEntityTooLarge = NewHTTPError(http.StatusRequestEntityTooLarge)
+	ErrTooManyRequests             = NewHTTPError(http.StatusTooManyRequests)
 	ErrValidatorNotRegistered      = errors.New("Validator not registered")
 	ErrRendererNotRegistered       = errors.New("Renderer not registered")
 	ErrInvalidRedirectCode         = errors.New("Invalid redirect status code")
@@ -296,6 +304,9 @@ func (e *Echo) DefaultHTTPErrorHandler(err error, c Context) {
 	if he, ok := err.(*HTTPError); ok {
 		code = he.Code
 		msg = he.Message
+	} else if ve, ok := err.(ValidationErrors); ok {
+		code = http.StatusUnprocessableEntity
+		msg = Map{"message": "Validation failed", "errors": ve}
 	} else if e.Debug {
 		msg = err.Error()
 	} else {
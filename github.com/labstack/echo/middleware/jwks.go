@@ -0,0 +1,242 @@
+package middleware
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+)
+
+type (
+	// JWTKeySet resolves the key used to validate a token from its "kid" header.
+	JWTKeySet interface {
+		// Key returns the key identified by kid and the algorithm it must be
+		// used with. An empty algorithm allows any algorithm compatible with the
+		// key type.
+		Key(kid string) (key interface{}, alg string, err error)
+	}
+
+	// JWTKeys is a static JWTKeySet mapping key ids to keys.
+	JWTKeys map[string]interface{}
+
+	// JWKSConfig defines the config for a JWKS key set.
+	JWKSConfig struct {
+		// URL of the JWKS document.
+		// Either URL or File is required.
+		URL string
+
+		// File is the path of a JWKS document.
+		File string
+
+		// RefreshInterval is the interval between two fetches of the URL.
+		// Optional. Default value 1 hour.
+		RefreshInterval time.Duration
+
+		// RefreshUnknownKID is the minimum interval between two fetches of the
+		// URL triggered by an unknown key id, i.e. a key rotation. Lookups of
+		// unknown key ids within that interval fail without fetching the URL.
+		// Optional. Default value 1 minute.
+		RefreshUnknownKID time.Duration
+
+		// Client is the HTTP client used to fetch the URL.
+		// Optional. Default value an http.Client with a 30 seconds timeout.
+		Client *http.Client
+	}
+
+	// JWKS is a JWTKeySet loaded from a JSON Web Key Set document (RFC 7517).
+	// Keys loaded from a URL are cached and refreshed in the background.
+	JWKS struct {
+		config    JWKSConfig
+		mu        sync.RWMutex
+		keys      map[string]jwt.JWK
+		fetched   time.Time
+		unknownAt time.Time
+		fetchMu   sync.Mutex
+		stop      chan struct{}
+	}
+)
+
+var (
+	// DefaultJWKSConfig is the default JWKS config.
+	DefaultJWKSConfig = JWKSConfig{
+		RefreshInterval:   time.Hour,
+		RefreshUnknownKID: time.Minute,
+	}
+
+	// ErrJWTKeyNotFound is returned when no key matches the token's key id.
+	ErrJWTKeyNotFound = errors.New("jwt key not found")
+)
+
+// Key implements `JWTKeySet.Key`.
+func (k JWTKeys) Key(kid string) (interface{}, string, error) {
+	if key, ok := k[kid]; ok {
+		return key, "", nil
+	}
+	return nil, "", ErrJWTKeyNotFound
+}
+
+// NewJWKS loads a JWKS document from a file or a URL. Keys loaded from a URL
+// are refreshed in the background until `Close()` is called.
+func NewJWKS(config JWKSConfig) (*JWKS, error) {
+	// Defaults
+	if config.RefreshInterval == 0 {
+		config.RefreshInterval = DefaultJWKSConfig.RefreshInterval
+	}
+	if config.RefreshUnknownKID == 0 {
+		config.RefreshUnknownKID = DefaultJWKSConfig.RefreshUnknownKID
+	}
+	if config.Client == nil {
+		config.Client = &http.Client{Timeout: 30 * time.Second}
+	}
+	if config.URL == "" && config.File == "" {
+		return nil, errors.New("jwks requires a url or a file")
+	}
+
+	s := &JWKS{config: config}
+	if err := s.Refresh(); err != nil {
+		return nil, err
+	}
+	if config.URL != "" {
+		s.stop = make(chan struct{})
+		go s.poll(s.stop)
+	}
+	return s, nil
+}
+
+// Key implements `JWTKeySet.Key`.
+// An unknown key id triggers a refresh of the key set if it was loaded from a
+// URL, so rotated keys are picked up without waiting for the next poll.
+func (s *JWKS) Key(kid string) (interface{}, string, error) {
+	s.mu.RLock()
+	k, ok := s.keys[kid]
+	s.mu.RUnlock()
+	if ok {
+		return k.Public().Key, k.Algorithm, nil
+	}
+	if s.config.URL == "" {
+		return nil, "", ErrJWTKeyNotFound
+	}
+
+	// Only the first unknown key id within RefreshUnknownKID triggers a
+	// fetch, other lookups fail right away.
+	s.mu.Lock()
+	now := time.Now()
+	if now.Sub(s.fetched) < s.config.RefreshUnknownKID || now.Sub(s.unknownAt) < s.config.RefreshUnknownKID {
+		s.mu.Unlock()
+		return nil, "", ErrJWTKeyNotFound
+	}
+	s.unknownAt = now
+	s.mu.Unlock()
+
+	if err := s.Refresh(); err != nil {
+		return nil, "", err
+	}
+	s.mu.RLock()
+	k, ok = s.keys[kid]
+	s.mu.RUnlock()
+	if !ok {
+		return nil, "", ErrJWTKeyNotFound
+	}
+	return k.Public().Key, k.Algorithm, nil
+}
+
+// Refresh reloads the key set from its source.
+func (s *JWKS) Refresh() error {
+	s.fetchMu.Lock()
+	defer s.fetchMu.Unlock()
+	var (
+		r   io.ReadCloser
+		err error
+	)
+	if s.config.URL != "" {
+		var res *http.Response
+		res, err = s.config.Client.Get(s.config.URL)
+		if err == nil && res.StatusCode != http.StatusOK {
+			res.Body.Close()
+			err = fmt.Errorf("jwks: unexpected status %d fetching %s", res.StatusCode, s.config.URL)
+		}
+		if err == nil {
+			r = res.Body
+		}
+	} else {
+		r, err = os.Open(s.config.File)
+	}
+	if err != nil {
+		// Still mark as fetched so unknown kids don't hammer the source
+		s.mu.Lock()
+		s.fetched = time.Now()
+		s.mu.Unlock()
+		return err
+	}
+	defer r.Close()
+	keys, err := parseJWKS(r)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.fetched = time.Now()
+	if err != nil {
+		return err
+	}
+	s.keys = keys
+	return nil
+}
+
+// Close stops the background refresh.
+func (s *JWKS) Close() {
+	s.fetchMu.Lock()
+	defer s.fetchMu.Unlock()
+	if s.stop != nil {
+		close(s.stop)
+		s.stop = nil
+	}
+}
+
+func (s *JWKS) poll(stop chan struct{}) {
+	t := time.NewTicker(s.config.RefreshInterval)
+	defer t.Stop()
+	for {
+		select {
+		case <-t.C:
+			// Keep serving the cached keys on error
+			s.Refresh()
+		case <-stop:
+			return
+		}
+	}
+}
+
+// parseJWKS parses a JWKS document, skipping the keys which aren't used for
+// signatures and those of unsupported types or curves.
+func parseJWKS(r io.Reader) (map[string]jwt.JWK, error) {
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	set := struct {
+		Keys []json.RawMessage `json:"keys"`
+	}{}
+	if err = json.Unmarshal(b, &set); err != nil {
+		return nil, err
+	}
+	keys := make(map[string]jwt.JWK, len(set.Keys))
+	for _, raw := range set.Keys {
+		var k jwt.JWK
+		if err = json.Unmarshal(raw, &k); err != nil {
+			if err == jwt.ErrUnsupportedJWK {
+				continue
+			}
+			return nil, err
+		}
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		keys[k.KeyID] = k
+	}
+	return keys, nil
+}
@@ -0,0 +1,286 @@
+package middleware
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/labstack/echo"
+	"github.com/stretchr/testify/assert"
+)
+
+func b64Int(i *big.Int) string {
+	return base64.RawURLEncoding.EncodeToString(i.Bytes())
+}
+
+func rsaJWK(kid string, k *rsa.PublicKey) map[string]string {
+	return map[string]string{
+		"kty": "RSA",
+		"kid": kid,
+		"n":   b64Int(k.N),
+		"e":   b64Int(big.NewInt(int64(k.E))),
+	}
+}
+
+func ecJWK(kid string, k *ecdsa.PublicKey) map[string]string {
+	return map[string]string{
+		"kty": "EC",
+		"kid": kid,
+		"crv": "P-256",
+		"x":   b64Int(k.X),
+		"y":   b64Int(k.Y),
+	}
+}
+
+type jwksServer struct {
+	sync.Mutex
+	keys    []map[string]string
+	fetches int
+}
+
+func (s *jwksServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.Lock()
+	defer s.Unlock()
+	s.fetches++
+	json.NewEncoder(w).Encode(map[string]interface{}{"keys": s.keys})
+}
+
+func signJWT(t *testing.T, m jwt.SigningMethod, kid string, key interface{}, claims jwt.MapClaims) string {
+	token := jwt.NewWithClaims(m, claims)
+	if kid != "" {
+		token.Header["kid"] = kid
+	}
+	s, err := token.SignedString(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestJWTKeySet(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rotated, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := &jwksServer{keys: []map[string]string{
+		rsaJWK("rsa", &rsaKey.PublicKey),
+		ecJWK("ec", &ecKey.PublicKey),
+	}}
+	ts := httptest.NewServer(srv)
+	defer ts.Close()
+	ks, err := NewJWKS(JWKSConfig{URL: ts.URL, RefreshUnknownKID: time.Nanosecond})
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer ks.Close()
+
+	e := echo.New()
+	handler := func(c echo.Context) error {
+		return c.String(http.StatusOK, "test")
+	}
+	h := JWTWithConfig(JWTConfig{
+		KeySet:   ks,
+		Audience: "api",
+		Issuer:   "https://idp.example.com",
+	})(handler)
+	claims := jwt.MapClaims{"sub": "1234", "aud": "api", "iss": "https://idp.example.com"}
+
+	for _, tc := range []struct {
+		token      string
+		expErrCode int
+		info       string
+	}{
+		{
+			token: signJWT(t, jwt.SigningMethodRS256, "rsa", rsaKey, claims),
+			info:  "Valid RS256",
+		},
+		{
+			token: signJWT(t, jwt.SigningMethodPS256, "rsa", rsaKey, claims),
+			info:  "Valid PS256",
+		},
+		{
+			token: signJWT(t, jwt.SigningMethodES256, "ec", ecKey, claims),
+			info:  "Valid ES256",
+		},
+		{
+			token: signJWT(t, jwt.SigningMethodES256, "ec", ecKey, jwt.MapClaims{
+				"aud": []string{"other", "api"},
+				"iss": "https://idp.example.com",
+			}),
+			info: "Valid audience array",
+		},
+		{
+			token:      signJWT(t, jwt.SigningMethodES256, "rsa", ecKey, claims),
+			expErrCode: http.StatusUnauthorized,
+			info:       "Method not matching key type",
+		},
+		{
+			token:      signJWT(t, jwt.SigningMethodHS256, "rsa", []byte("secret"), claims),
+			expErrCode: http.StatusUnauthorized,
+			info:       "HMAC with public key",
+		},
+		{
+			token:      signJWT(t, jwt.SigningMethodES256, "unknown", ecKey, claims),
+			expErrCode: http.StatusUnauthorized,
+			info:       "Unknown kid",
+		},
+		{
+			token: signJWT(t, jwt.SigningMethodES256, "ec", ecKey, jwt.MapClaims{
+				"aud": "other",
+				"iss": "https://idp.example.com",
+			}),
+			expErrCode: http.StatusUnauthorized,
+			info:       "Invalid audience",
+		},
+		{
+			token: signJWT(t, jwt.SigningMethodES256, "ec", ecKey, jwt.MapClaims{
+				"aud": "api",
+				"iss": "https://evil.example.com",
+			}),
+			expErrCode: http.StatusUnauthorized,
+			info:       "Invalid issuer",
+		},
+	} {
+		req := httptest.NewRequest(echo.GET, "/", nil)
+		res := httptest.NewRecorder()
+		req.Header.Set(echo.HeaderAuthorization, "Bearer "+tc.token)
+		c := e.NewContext(req, res)
+		err := h(c)
+		if tc.expErrCode != 0 {
+			if he, ok := err.(*echo.HTTPError); assert.True(t, ok, tc.info) {
+				assert.Equal(t, tc.expErrCode, he.Code, tc.info)
+			}
+			continue
+		}
+		assert.NoError(t, err, tc.info)
+	}
+
+	// Key rotation: the new key is fetched when an unknown kid shows up
+	srv.Lock()
+	srv.keys = append(srv.keys, ecJWK("rotated", &rotated.PublicKey))
+	fetches := srv.fetches
+	srv.Unlock()
+	req := httptest.NewRequest(echo.GET, "/", nil)
+	req.Header.Set(echo.HeaderAuthorization, "Bearer "+signJWT(t, jwt.SigningMethodES256, "rotated", rotated, claims))
+	assert.NoError(t, h(e.NewContext(req, httptest.NewRecorder())))
+	srv.Lock()
+	assert.Equal(t, fetches+1, srv.fetches)
+	srv.Unlock()
+}
+
+func TestJWKSFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "jwks")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "jwks.json")
+	ioutil.WriteFile(path, []byte(`{"keys":[
+		{"kty":"oct","kid":"hmac","alg":"HS256","k":"c2VjcmV0"},
+		{"kty":"oct","kid":"enc","use":"enc","k":"c2VjcmV0"},
+		{"kty":"OKP","kid":"unsupported"},
+		{"kty":"EC","kid":"secp256k1","crv":"secp256k1","x":"AQ","y":"AQ"}
+	]}`), 0644)
+	ks, err := NewJWKS(JWKSConfig{File: path})
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer ks.Close()
+	key, alg, err := ks.Key("hmac")
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("secret"), key)
+	assert.Equal(t, "HS256", alg)
+	_, _, err = ks.Key("enc")
+	assert.Equal(t, ErrJWTKeyNotFound, err)
+	_, _, err = ks.Key("unsupported")
+	assert.Equal(t, ErrJWTKeyNotFound, err)
+	_, _, err = ks.Key("secp256k1")
+	assert.Equal(t, ErrJWTKeyNotFound, err)
+
+	e := echo.New()
+	h := JWTWithConfig(JWTConfig{KeySet: ks})(func(c echo.Context) error {
+		return c.String(http.StatusOK, "test")
+	})
+	req := httptest.NewRequest(echo.GET, "/", nil)
+	req.Header.Set(echo.HeaderAuthorization, "Bearer "+signJWT(t, jwt.SigningMethodHS512, "hmac", []byte("secret"), jwt.MapClaims{}))
+	he, ok := h(e.NewContext(req, httptest.NewRecorder())).(*echo.HTTPError)
+	if assert.True(t, ok, "Algorithm not matching the key's alg") {
+		assert.Equal(t, http.StatusUnauthorized, he.Code)
+	}
+
+	_, err = NewJWKS(JWKSConfig{File: filepath.Join(dir, "missing.json")})
+	assert.Error(t, err)
+	ioutil.WriteFile(path, []byte(`{"keys":[{"kty":"EC","kid":"bad","crv":"P-256","x":"AQ","y":"AQ"}]}`), 0644)
+	_, err = NewJWKS(JWKSConfig{File: path})
+	assert.Error(t, err)
+	_, err = NewJWKS(JWKSConfig{})
+	assert.Error(t, err)
+}
+
+func TestJWKSUnknownKIDRateLimit(t *testing.T) {
+	srv := &jwksServer{keys: []map[string]string{}}
+	ts := httptest.NewServer(srv)
+	defer ts.Close()
+	ks, err := NewJWKS(JWKSConfig{URL: ts.URL, RefreshUnknownKID: 50 * time.Millisecond})
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer ks.Close()
+	_, _, err = ks.Key("bogus")
+	assert.Equal(t, ErrJWTKeyNotFound, err)
+
+	// Concurrent lookups of unknown kids trigger a single fetch
+	time.Sleep(60 * time.Millisecond)
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ks.Key("bogus")
+		}()
+	}
+	wg.Wait()
+	srv.Lock()
+	assert.Equal(t, 2, srv.fetches)
+	srv.Unlock()
+}
+
+func TestJWTKeys(t *testing.T) {
+	e := echo.New()
+	h := JWTWithConfig(JWTConfig{
+		KeySet: JWTKeys{"k1": []byte("secret")},
+	})(func(c echo.Context) error {
+		return c.String(http.StatusOK, "test")
+	})
+	req := httptest.NewRequest(echo.GET, "/", nil)
+	req.Header.Set(echo.HeaderAuthorization, "Bearer "+signJWT(t, jwt.SigningMethodHS384, "k1", []byte("secret"), jwt.MapClaims{}))
+	assert.NoError(t, h(e.NewContext(req, httptest.NewRecorder())))
+}
+
+func TestJWKSStatusError(t *testing.T) {
+	ts := httptest.NewServer(http.NotFoundHandler())
+	defer ts.Close()
+	_, err := NewJWKS(JWKSConfig{URL: ts.URL})
+	assert.Error(t, err)
+}
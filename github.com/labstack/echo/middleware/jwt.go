@@ -1,6 +1,8 @@
 package middleware
 
 import (
+	"crypto/ecdsa"
+	"crypto/rsa"
 	"errors"
 	"fmt"
 	"net/http"
@@ -18,13 +20,29 @@ type (
 		Skipper Skipper
 
 		// Signing key to validate token.
-		// Required.
+		// Required unless KeySet is set.
 		SigningKey interface{}
 
 		// Signing method, used to check token signing method.
 		// Optional. Default value HS256.
+		// Ignored when KeySet is set.
 		SigningMethod string
 
+		// KeySet resolves the key used to validate the token from its "kid"
+		// header, e.g. a `JWKS` loaded from an identity provider. The token's
+		// signing method must match the key type (HMAC, RSA, RSA-PSS or ECDSA)
+		// and the key algorithm if the key set defines one.
+		// Optional. Takes precedence over SigningKey.
+		KeySet JWTKeySet
+
+		// Audience the token's "aud" claim must contain.
+		// Optional. Not checked if empty.
+		Audience string
+
+		// Issuer the token's "iss" claim must be equal to.
+		// Optional. Not checked if empty.
+		Issuer string
+
 		// Context key to store user information from the token into context.
 		// Optional. Default value "user".
 		ContextKey string
@@ -55,6 +73,17 @@ type (
 // Algorithms
 const (
 	AlgorithmHS256 = "HS256"
+	AlgorithmHS384 = "HS384"
+	AlgorithmHS512 = "HS512"
+	AlgorithmRS256 = "RS256"
+	AlgorithmRS384 = "RS384"
+	AlgorithmRS512 = "RS512"
+	AlgorithmPS256 = "PS256"
+	AlgorithmPS384 = "PS384"
+	AlgorithmPS512 = "PS512"
+	AlgorithmES256 = "ES256"
+	AlgorithmES384 = "ES384"
+	AlgorithmES512 = "ES512"
 )
 
 var (
@@ -90,8 +119,8 @@ func JWTWithConfig(config JWTConfig) echo.MiddlewareFunc {
 	if config.Skipper == nil {
 		config.Skipper = DefaultJWTConfig.Skipper
 	}
-	if config.SigningKey == nil {
-		panic("jwt middleware requires signing key")
+	if config.SigningKey == nil && config.KeySet == nil {
+		panic("jwt middleware requires signing key or key set")
 	}
 	if config.SigningMethod == "" {
 		config.SigningMethod = DefaultJWTConfig.SigningMethod
@@ -115,6 +144,23 @@ func JWTWithConfig(config JWTConfig) echo.MiddlewareFunc {
 		}
 		return config.SigningKey, nil
 	}
+	if config.KeySet != nil {
+		config.keyFunc = func(t *jwt.Token) (interface{}, error) {
+			kid, _ := t.Header["kid"].(string)
+			key, alg, err := config.KeySet.Key(kid)
+			if err != nil {
+				return nil, err
+			}
+			// Check the signing method against the key
+			if alg != "" && t.Method.Alg() != alg {
+				return nil, fmt.Errorf("Unexpected jwt signing method=%v", t.Header["alg"])
+			}
+			if !jwtMethodMatchesKey(t.Method, key) {
+				return nil, fmt.Errorf("Unexpected jwt signing method=%v for key type %T", t.Header["alg"], key)
+			}
+			return key, nil
+		}
+	}
 
 	// Initialize
 	parts := strings.Split(config.TokenLookup, ":")
@@ -144,7 +190,7 @@ func JWTWithConfig(config JWTConfig) echo.MiddlewareFunc {
 				claims := reflect.ValueOf(config.Claims).Interface().(jwt.Claims)
 				token, err = jwt.ParseWithClaims(auth, claims, config.keyFunc)
 			}
-			if err == nil && token.Valid {
+			if err == nil && token.Valid && jwtVerifyClaims(token.Claims, config.Audience, config.Issuer) {
 				// Store user information from token into context.
 				c.Set(config.ContextKey, token)
 				return next(c)
@@ -154,6 +200,57 @@ func JWTWithConfig(config JWTConfig) echo.MiddlewareFunc {
 	}
 }
 
+// jwtMethodMatchesKey checks the signing method can be used with the key.
+func jwtMethodMatchesKey(m jwt.SigningMethod, key interface{}) bool {
+	switch key.(type) {
+	case []byte:
+		_, ok := m.(*jwt.SigningMethodHMAC)
+		return ok
+	case *rsa.PublicKey:
+		switch m.(type) {
+		case *jwt.SigningMethodRSA, *jwt.SigningMethodRSAPSS:
+			return true
+		}
+	case *ecdsa.PublicKey:
+		_, ok := m.(*jwt.SigningMethodECDSA)
+		return ok
+	}
+	return false
+}
+
+// jwtVerifyClaims checks the audience and issuer claims if required.
+func jwtVerifyClaims(claims jwt.Claims, aud, iss string) bool {
+	type verifier interface {
+		VerifyAudience(cmp string, req bool) bool
+		VerifyIssuer(cmp string, req bool) bool
+	}
+	if aud == "" && iss == "" {
+		return true
+	}
+	v, ok := claims.(verifier)
+	if !ok {
+		return false
+	}
+	if iss != "" && !v.VerifyIssuer(iss, true) {
+		return false
+	}
+	if aud != "" {
+		// The audience may be an array of strings
+		if m, ok := claims.(jwt.MapClaims); ok {
+			if auds, ok := m["aud"].([]interface{}); ok {
+				for _, a := range auds {
+					if a == aud {
+						return true
+					}
+				}
+				return false
+			}
+		}
+		return v.VerifyAudience(aud, true)
+	}
+	return true
+}
+
 // jwtFromHeader returns a `jwtExtractor` that extracts token from the request header.
 func jwtFromHeader(header string, authScheme string) jwtExtractor {
 	return func(c echo.Context) (string, error) {
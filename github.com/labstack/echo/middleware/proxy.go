@@ -0,0 +1,441 @@
+package middleware
+
+import (
+	"crypto/tls"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"math/rand"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/labstack/echo"
+)
+
+type (
+	// ProxyConfig defines the config for Proxy middleware.
+	ProxyConfig struct {
+		// Skipper defines a function to skip middleware.
+		Skipper Skipper
+
+		// Balancer defines a load balancing technique.
+		// Required.
+		Balancer ProxyBalancer
+
+		// Rewrite defines URL path rewrite rules. The values captured in asterisk
+		// can be retrieved by index e.g. $1, $2 and so on. Only the first
+		// matching rule is applied, trying the longest patterns first.
+		// Examples:
+		// "/old":              "/new",
+		// "/api/*":            "/$1",
+		// "/js/*":             "/public/javascripts/$1",
+		// "/users/*/orders/*": "/user/$1/order/$2",
+		Rewrite map[string]string
+
+		// HealthCheckPath is the path requested on each target to check its
+		// health. Targets answering with a status code of 400 or more, or not
+		// answering, don't receive requests until they are healthy again.
+		// Optional. Default value "" (no health check).
+		HealthCheckPath string `json:"health_check_path"`
+
+		// HealthCheckInterval is the interval between two health checks.
+		// Optional. Default value 10 seconds.
+		HealthCheckInterval time.Duration `json:"health_check_interval"`
+
+		// HealthCheckTimeout is the timeout of a health check request.
+		// Optional. Default value 2 seconds.
+		HealthCheckTimeout time.Duration `json:"health_check_timeout"`
+
+		// HealthCheckStop stops the health checks when closed.
+		// Optional. Default value nil (health checks never stop).
+		HealthCheckStop <-chan struct{}
+
+		// Transport is used to send requests to the targets.
+		// Optional. Default value http.DefaultTransport.
+		Transport http.RoundTripper
+	}
+
+	// ProxyTarget defines the upstream target.
+	ProxyTarget struct {
+		Name string
+		URL  *url.URL
+		down int32
+	}
+
+	// ProxyBalancer defines an interface to implement a load balancing technique.
+	ProxyBalancer interface {
+		// Next returns the target for the next request, or nil if no target is
+		// healthy.
+		Next() *ProxyTarget
+
+		// Targets returns all the targets.
+		Targets() []*ProxyTarget
+	}
+
+	commonBalancer struct {
+		targets []*ProxyTarget
+	}
+
+	// RandomBalancer implements a random load balancing technique.
+	RandomBalancer struct {
+		commonBalancer
+	}
+
+	// RoundRobinBalancer implements a round-robin load balancing technique.
+	RoundRobinBalancer struct {
+		commonBalancer
+		i uint32
+	}
+
+	proxyRewrite struct {
+		pattern     string
+		re          *regexp.Regexp
+		replacement string
+	}
+
+	// proxyRewrites sorts rewrite rules from the longest pattern to the
+	// shortest.
+	proxyRewrites []proxyRewrite
+
+	// proxyTransport records the error of the round trip, so that it is
+	// handled by echo rather than by the reverse proxy.
+	proxyTransport struct {
+		http.RoundTripper
+		err error
+	}
+
+	// proxyResponse exposes only the `http.ResponseWriter` and `http.Flusher`
+	// methods of the response to the reverse proxy, as `Response.CloseNotify()`
+	// panics if the underlying writer doesn't implement it. It drops the error
+	// response written by the reverse proxy when the round trip failed.
+	proxyResponse struct {
+		res       *echo.Response
+		transport *proxyTransport
+	}
+)
+
+var (
+	// DefaultProxyConfig is the default Proxy middleware config.
+	DefaultProxyConfig = ProxyConfig{
+		Skipper:             DefaultSkipper,
+		HealthCheckInterval: 10 * time.Second,
+		HealthCheckTimeout:  2 * time.Second,
+	}
+)
+
+// Healthy reports whether the target passed its last health check.
+func (t *ProxyTarget) Healthy() bool {
+	return atomic.LoadInt32(&t.down) == 0
+}
+
+func (t *ProxyTarget) setHealthy(healthy bool) {
+	if healthy {
+		atomic.StoreInt32(&t.down, 0)
+	} else {
+		atomic.StoreInt32(&t.down, 1)
+	}
+}
+
+// NewRandomBalancer returns a random proxy balancer.
+func NewRandomBalancer(targets []*ProxyTarget) ProxyBalancer {
+	return &RandomBalancer{commonBalancer{targets}}
+}
+
+// NewRoundRobinBalancer returns a round-robin proxy balancer.
+func NewRoundRobinBalancer(targets []*ProxyTarget) ProxyBalancer {
+	return &RoundRobinBalancer{commonBalancer: commonBalancer{targets}}
+}
+
+// Targets implements `ProxyBalancer.Targets`.
+func (b *commonBalancer) Targets() []*ProxyTarget {
+	return b.targets
+}
+
+// Next randomly returns a healthy target.
+func (b *RandomBalancer) Next() *ProxyTarget {
+	n := len(b.targets)
+	if n == 0 {
+		return nil
+	}
+	i := rand.Intn(n)
+	for j := 0; j < n; j++ {
+		if t := b.targets[(i+j)%n]; t.Healthy() {
+			return t
+		}
+	}
+	return nil
+}
+
+// Next returns the next healthy target in a round-robin manner.
+func (b *RoundRobinBalancer) Next() *ProxyTarget {
+	n := uint32(len(b.targets))
+	if n == 0 {
+		return nil
+	}
+	for j := uint32(0); j < n; j++ {
+		if t := b.targets[(atomic.AddUint32(&b.i, 1)-1)%n]; t.Healthy() {
+			return t
+		}
+	}
+	return nil
+}
+
+// Proxy returns a Proxy middleware.
+//
+// Proxy middleware forwards the request to upstream targets chosen by the
+// balancer. WebSocket requests are proxied over the hijacked connection.
+// If no target is healthy, it sends "503 - Service Unavailable" response.
+// If the target cannot be reached, it sends "502 - Bad Gateway" response.
+func Proxy(balancer ProxyBalancer) echo.MiddlewareFunc {
+	c := DefaultProxyConfig
+	c.Balancer = balancer
+	return ProxyWithConfig(c)
+}
+
+// ProxyWithConfig returns a Proxy middleware with config.
+// See: `Proxy()`
+func ProxyWithConfig(config ProxyConfig) echo.MiddlewareFunc {
+	// Defaults
+	if config.Skipper == nil {
+		config.Skipper = DefaultProxyConfig.Skipper
+	}
+	if config.HealthCheckInterval == 0 {
+		config.HealthCheckInterval = DefaultProxyConfig.HealthCheckInterval
+	}
+	if config.HealthCheckTimeout == 0 {
+		config.HealthCheckTimeout = DefaultProxyConfig.HealthCheckTimeout
+	}
+	if config.Transport == nil {
+		config.Transport = http.DefaultTransport
+	}
+	if config.Balancer == nil {
+		panic("proxy middleware requires a balancer")
+	}
+
+	// Initialize
+	rewrites := make(proxyRewrites, 0, len(config.Rewrite))
+	for k, v := range config.Rewrite {
+		re := regexp.QuoteMeta(k)
+		re = strings.Replace(re, `\*`, "(.*)", -1)
+		rewrites = append(rewrites, proxyRewrite{
+			pattern:     k,
+			re:          regexp.MustCompile("^" + re + "$"),
+			replacement: v,
+		})
+	}
+	sort.Sort(rewrites)
+	if config.HealthCheckPath != "" {
+		go proxyHealthCheck(config)
+	}
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			if config.Skipper(c) {
+				return next(c)
+			}
+
+			req := c.Request()
+			tgt := config.Balancer.Next()
+			if tgt == nil {
+				return echo.NewHTTPError(http.StatusServiceUnavailable, "no healthy upstream target")
+			}
+
+			// Rewrite
+			for _, r := range rewrites {
+				if m := r.re.FindStringSubmatch(req.URL.Path); m != nil {
+					path := r.replacement
+					for i, v := range m[1:] {
+						path = strings.Replace(path, fmt.Sprintf("$%d", i+1), v, -1)
+					}
+					req.URL.Path = path
+					req.URL.RawPath = ""
+					break
+				}
+			}
+
+			// Forwarded headers
+			if req.Header.Get(echo.HeaderXRealIP) == "" {
+				req.Header.Set(echo.HeaderXRealIP, c.RealIP())
+			}
+			if req.Header.Get(echo.HeaderXForwardedProto) == "" {
+				req.Header.Set(echo.HeaderXForwardedProto, c.Scheme())
+			}
+			if req.Header.Get(echo.HeaderXForwardedHost) == "" {
+				req.Header.Set(echo.HeaderXForwardedHost, req.Host)
+			}
+
+			if strings.EqualFold(req.Header.Get(echo.HeaderUpgrade), "websocket") {
+				return proxyRaw(tgt, c)
+			}
+			return proxyHTTP(tgt, c, config.Transport)
+		}
+	}
+}
+
+func proxyHTTP(t *ProxyTarget, c echo.Context, transport http.RoundTripper) error {
+	tr := &proxyTransport{RoundTripper: transport}
+	p := httputil.NewSingleHostReverseProxy(t.URL)
+	p.Transport = tr
+	// Handled by the echo error handler
+	p.ErrorLog = log.New(ioutil.Discard, "", 0)
+	p.ServeHTTP(&proxyResponse{res: c.Response(), transport: tr}, c.Request())
+	if tr.err != nil {
+		return echo.NewHTTPError(http.StatusBadGateway, fmt.Sprintf("proxy to %s failed: %v", t.URL, tr.err))
+	}
+	return nil
+}
+
+// proxyRaw forwards the request over a raw connection to the target and
+// copies data both ways until one side closes its connection.
+func proxyRaw(t *ProxyTarget, c echo.Context) error {
+	req := c.Request()
+	addr := t.URL.Host
+	if _, _, err := net.SplitHostPort(addr); err != nil {
+		if t.URL.Scheme == "https" {
+			addr += ":443"
+		} else {
+			addr += ":80"
+		}
+	}
+	var (
+		out net.Conn
+		err error
+	)
+	if t.URL.Scheme == "https" {
+		out, err = tls.Dial("tcp", addr, nil)
+	} else {
+		out, err = net.Dial("tcp", addr)
+	}
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadGateway, fmt.Sprintf("proxy to %s failed: %v", t.URL, err))
+	}
+	defer out.Close()
+
+	// Forward the handshake
+	if prior := req.Header.Get(echo.HeaderXForwardedFor); prior != "" {
+		req.Header.Set(echo.HeaderXForwardedFor, prior+", "+c.RealIP())
+	} else {
+		req.Header.Set(echo.HeaderXForwardedFor, c.RealIP())
+	}
+	outReq := *req
+	outReq.URL = &url.URL{Path: singleJoiningSlash(t.URL.Path, req.URL.Path), RawQuery: req.URL.RawQuery}
+	if err = outReq.Write(out); err != nil {
+		return echo.NewHTTPError(http.StatusBadGateway, fmt.Sprintf("proxy to %s failed: %v", t.URL, err))
+	}
+
+	in, brw, err := c.Response().Hijack()
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	errc := make(chan error, 2)
+	cp := func(dst io.Writer, src io.Reader) {
+		_, err := io.Copy(dst, src)
+		errc <- err
+	}
+	// Data the client sent after the handshake may already be buffered
+	go cp(out, brw.Reader)
+	go cp(in, out)
+	// The connection is hijacked, the error can't be sent to the client
+	<-errc
+	return nil
+}
+
+func proxyHealthCheck(config ProxyConfig) {
+	client := &http.Client{
+		Transport: config.Transport,
+		Timeout:   config.HealthCheckTimeout,
+	}
+	check := func() {
+		for _, t := range config.Balancer.Targets() {
+			u := *t.URL
+			u.Path = singleJoiningSlash(u.Path, config.HealthCheckPath)
+			res, err := client.Get(u.String())
+			if err != nil {
+				t.setHealthy(false)
+				continue
+			}
+			io.Copy(ioutil.Discard, res.Body)
+			res.Body.Close()
+			t.setHealthy(res.StatusCode < http.StatusBadRequest)
+		}
+	}
+	check()
+	t := time.NewTicker(config.HealthCheckInterval)
+	defer t.Stop()
+	for {
+		select {
+		case <-t.C:
+			check()
+		case <-config.HealthCheckStop:
+			return
+		}
+	}
+}
+
+func (r proxyRewrites) Len() int {
+	return len(r)
+}
+
+func (r proxyRewrites) Less(i, j int) bool {
+	if len(r[i].pattern) != len(r[j].pattern) {
+		return len(r[i].pattern) > len(r[j].pattern)
+	}
+	return r[i].pattern < r[j].pattern
+}
+
+func (r proxyRewrites) Swap(i, j int) {
+	r[i], r[j] = r[j], r[i]
+}
+
+// RoundTrip implements `http.RoundTripper.RoundTrip`.
+func (t *proxyTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	res, err := t.RoundTripper.RoundTrip(r)
+	if err != nil {
+		t.err = err
+	}
+	return res, err
+}
+
+func (w *proxyResponse) Header() http.Header {
+	return w.res.Header()
+}
+
+func (w *proxyResponse) WriteHeader(code int) {
+	if w.transport.err != nil {
+		return
+	}
+	w.res.WriteHeader(code)
+}
+
+func (w *proxyResponse) Write(b []byte) (int, error) {
+	if w.transport.err != nil {
+		return len(b), nil
+	}
+	return w.res.Write(b)
+}
+
+func (w *proxyResponse) Flush() {
+	w.res.Flush()
+}
+
+func singleJoiningSlash(a, b string) string {
+	aslash := strings.HasSuffix(a, "/")
+	bslash := strings.HasPrefix(b, "/")
+	switch {
+	case aslash && bslash:
+		return a + b[1:]
+	case !aslash && !bslash:
+		return a + "/" + b
+	}
+	return a + b
+}
@@ -0,0 +1,222 @@
+package middleware
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo"
+	"github.com/stretchr/testify/assert"
+)
+
+func proxyTarget(t *testing.T, name string, h http.HandlerFunc) (*ProxyTarget, *httptest.Server) {
+	if h == nil {
+		h = func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, name)
+		}
+	}
+	srv := httptest.NewServer(h)
+	u, err := url.Parse(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &ProxyTarget{Name: name, URL: u}, srv
+}
+
+func proxyRequest(e *echo.Echo, target string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(echo.GET, target, nil)
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestProxyBalancers(t *testing.T) {
+	t1, srv1 := proxyTarget(t, "target 1", nil)
+	defer srv1.Close()
+	t2, srv2 := proxyTarget(t, "target 2", nil)
+	defer srv2.Close()
+	targets := []*ProxyTarget{t1, t2}
+
+	// Round-robin
+	e := echo.New()
+	e.Use(Proxy(NewRoundRobinBalancer(targets)))
+	assert.Equal(t, "target 1", proxyRequest(e, "/").Body.String())
+	assert.Equal(t, "target 2", proxyRequest(e, "/").Body.String())
+	assert.Equal(t, "target 1", proxyRequest(e, "/").Body.String())
+
+	// Random
+	e = echo.New()
+	e.Use(Proxy(NewRandomBalancer(targets)))
+	body := proxyRequest(e, "/").Body.String()
+	assert.Contains(t, []string{"target 1", "target 2"}, body)
+
+	// Unhealthy targets are skipped
+	t1.setHealthy(false)
+	for i := 0; i < 4; i++ {
+		assert.Equal(t, "target 2", proxyRequest(e, "/").Body.String())
+	}
+	t2.setHealthy(false)
+	assert.Equal(t, http.StatusServiceUnavailable, proxyRequest(e, "/").Code)
+
+	// Unreachable target
+	srv3 := httptest.NewServer(http.NotFoundHandler())
+	t3, _ := url.Parse(srv3.URL)
+	srv3.Close()
+	e = echo.New()
+	e.Use(Proxy(NewRoundRobinBalancer([]*ProxyTarget{{URL: t3}})))
+	assert.Equal(t, http.StatusBadGateway, proxyRequest(e, "/").Code)
+
+	assert.Panics(t, func() { Proxy(nil) })
+}
+
+func TestProxyRewriteAndHeaders(t *testing.T) {
+	var got *http.Request
+	tgt, srv := proxyTarget(t, "", func(w http.ResponseWriter, r *http.Request) {
+		got = r
+	})
+	defer srv.Close()
+	e := echo.New()
+	e.Use(ProxyWithConfig(ProxyConfig{
+		Balancer: NewRoundRobinBalancer([]*ProxyTarget{tgt}),
+		Rewrite: map[string]string{
+			"/old":              "/new",
+			"/api/*":            "/$1",
+			"/api/v1/*":         "/v1/$1",
+			"/users/*/orders/*": "/user/$1/order/$2",
+		},
+	}))
+
+	for _, data := range []struct {
+		path, expected string
+	}{
+		{"/old", "/new"},
+		{"/api/users", "/users"},
+		{"/api/v1/users", "/v1/users"},
+		{"/users/jack/orders/1", "/user/jack/order/1"},
+		{"/other", "/other"},
+	} {
+		proxyRequest(e, data.path)
+		if assert.NotNil(t, got, data.path) {
+			assert.Equal(t, data.expected, got.URL.Path, data.path)
+		}
+	}
+
+	req := httptest.NewRequest(echo.GET, "/?q=1", nil)
+	req.RemoteAddr = "10.0.0.1:1234"
+	e.ServeHTTP(httptest.NewRecorder(), req)
+	assert.Equal(t, "q=1", got.URL.RawQuery)
+	assert.Equal(t, "10.0.0.1", got.Header.Get(echo.HeaderXRealIP))
+	assert.Equal(t, "10.0.0.1", got.Header.Get(echo.HeaderXForwardedFor))
+	assert.Equal(t, "http", got.Header.Get(echo.HeaderXForwardedProto))
+	assert.Equal(t, "example.com", got.Header.Get(echo.HeaderXForwardedHost))
+}
+
+func TestProxyHealthCheck(t *testing.T) {
+	healthy := make(chan bool, 1)
+	healthy <- false
+	t1, srv1 := proxyTarget(t, "target 1", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/health" {
+			h := <-healthy
+			healthy <- h
+			if !h {
+				w.WriteHeader(http.StatusServiceUnavailable)
+			}
+			return
+		}
+		fmt.Fprint(w, "target 1")
+	})
+	defer srv1.Close()
+	t2, srv2 := proxyTarget(t, "target 2", nil)
+	defer srv2.Close()
+
+	stop := make(chan struct{})
+	e := echo.New()
+	e.Use(ProxyWithConfig(ProxyConfig{
+		Balancer:            NewRoundRobinBalancer([]*ProxyTarget{t1, t2}),
+		HealthCheckPath:     "/health",
+		HealthCheckInterval: 10 * time.Millisecond,
+		HealthCheckStop:     stop,
+	}))
+	waitFor := func(cond func() bool) {
+		for i := 0; i < 500 && !cond(); i++ {
+			time.Sleep(5 * time.Millisecond)
+		}
+		assert.True(t, cond())
+	}
+	waitFor(func() bool { return !t1.Healthy() })
+	assert.True(t, t2.Healthy())
+	assert.Equal(t, "target 2", proxyRequest(e, "/").Body.String())
+	assert.Equal(t, "target 2", proxyRequest(e, "/").Body.String())
+
+	<-healthy
+	healthy <- true
+	waitFor(t1.Healthy)
+
+	// Down targets are marked unhealthy
+	srv2.Close()
+	waitFor(func() bool { return !t2.Healthy() })
+	assert.Equal(t, "target 1", proxyRequest(e, "/").Body.String())
+
+	// Stopped health checks leave the targets as they are
+	close(stop)
+	time.Sleep(20 * time.Millisecond)
+	<-healthy
+	healthy <- false
+	time.Sleep(50 * time.Millisecond)
+	assert.True(t, t1.Healthy())
+}
+
+func TestProxyWebSocket(t *testing.T) {
+	tgt, upstream := proxyTarget(t, "", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get(echo.HeaderUpgrade) != "websocket" || r.URL.Path != "/ws" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		conn, brw, err := w.(http.Hijacker).Hijack()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		brw.WriteString("HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n\r\n")
+		brw.Flush()
+		// Echo frames back
+		io.Copy(conn, brw)
+	})
+	defer upstream.Close()
+
+	e := echo.New()
+	e.Use(ProxyWithConfig(ProxyConfig{
+		Balancer: NewRoundRobinBalancer([]*ProxyTarget{tgt}),
+		Rewrite:  map[string]string{"/socket": "/ws"},
+	}))
+	srv := httptest.NewServer(e)
+	defer srv.Close()
+
+	conn, err := net.Dial("tcp", srv.Listener.Addr().String())
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+	fmt.Fprint(conn, "GET /socket HTTP/1.1\r\nHost: example.com\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n\r\nhello")
+	r := bufio.NewReader(conn)
+	res, err := http.ReadResponse(r, nil)
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, http.StatusSwitchingProtocols, res.StatusCode)
+	b := make([]byte, 5)
+	_, err = io.ReadFull(r, b)
+	assert.NoError(t, err)
+	assert.Equal(t, "hello", string(b))
+	fmt.Fprint(conn, "world")
+	_, err = io.ReadFull(r, b)
+	assert.NoError(t, err)
+	assert.Equal(t, "world", string(b))
+}
@@ -0,0 +1,433 @@
+package middleware
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/labstack/echo"
+)
+
+type (
+	// RateLimitConfig defines the config for RateLimit middleware.
+	RateLimitConfig struct {
+		// Skipper defines a function to skip middleware.
+		Skipper Skipper
+
+		// Limit is the number of requests allowed per period for each key.
+		// Required.
+		Limit int `json:"limit"`
+
+		// Period is the duration of the rate limit window.
+		// Optional. Default value 1 minute.
+		Period time.Duration `json:"period"`
+
+		// Burst is the number of requests that can be made at once with the
+		// token bucket algorithm, the bucket capacity.
+		// Optional. Default value Limit.
+		Burst int `json:"burst"`
+
+		// Algorithm is the rate limiting algorithm.
+		// Optional. Default value "token-bucket".
+		// Possible values:
+		// - "token-bucket"
+		// - "sliding-window"
+		Algorithm string `json:"algorithm"`
+
+		// KeyLookup is a string in the form of "<source>" or "<source>:<name>"
+		// that is used to extract the key identifying a client.
+		// Optional. Default value "ip".
+		// Possible values:
+		// - "ip" (remote address of the connection, see TrustProxyHeaders)
+		// - "header:<name>"
+		// - "jwt" (subject of the token set by the JWT middleware)
+		// - "jwt:<context key>"
+		KeyLookup string `json:"key_lookup"`
+
+		// TrustProxyHeaders makes the "ip" key lookup use the client IP from
+		// the "X-Forwarded-For" and "X-Real-IP" headers. Only enable it behind
+		// a proxy setting these headers, as clients can forge them.
+		// Optional. Default value false.
+		TrustProxyHeaders bool `json:"trust_proxy_headers"`
+
+		// Name namespaces the keys in the store, so that middlewares sharing a
+		// store don't share their limits.
+		// Optional. Default value derived from the algorithm, limit, burst and
+		// period.
+		Name string `json:"name"`
+
+		// KeyExtractor is a function to extract the key identifying a client.
+		// It takes precedence over KeyLookup.
+		// Optional.
+		KeyExtractor RateLimitKeyExtractor
+
+		// Store keeps the rate limit state of each key.
+		// Optional. Default value a new memory store.
+		Store RateLimitStore
+	}
+
+	// RateLimitKeyExtractor defines a function to extract the rate limit key
+	// from the request.
+	RateLimitKeyExtractor func(echo.Context) (string, error)
+
+	// RateLimitRule defines the limit applied by a store.
+	RateLimitRule struct {
+		Algorithm string
+		Limit     int
+		Burst     int
+		Period    time.Duration
+	}
+
+	// RateLimitResult is the outcome of a request against the limit.
+	RateLimitResult struct {
+		// Allowed is true if the request is within the limit.
+		Allowed bool
+
+		// Remaining is the number of requests left in the current window.
+		Remaining int
+
+		// Reset is the time left until the limit is fully reset.
+		Reset time.Duration
+
+		// RetryAfter is the time left until the next request is allowed,
+		// for requests which are not allowed.
+		RetryAfter time.Duration
+	}
+
+	// RateLimitStore defines the interface for rate limit stores.
+	RateLimitStore interface {
+		// Take counts a request for key and reports whether it is allowed by
+		// rule.
+		Take(key string, rule RateLimitRule) (RateLimitResult, error)
+	}
+
+	// MemoryRateLimitStore is a RateLimitStore keeping its state in memory.
+	MemoryRateLimitStore struct {
+		mu      sync.Mutex
+		buckets map[string]*rateLimitBucket
+		swept   time.Time
+		now     func() time.Time
+	}
+
+	rateLimitBucket struct {
+		// Token bucket
+		tokens float64
+		last   time.Time
+
+		// Sliding window
+		window int64
+		count  int64
+		prev   int64
+
+		expires time.Time
+	}
+)
+
+// Rate limiting algorithms
+const (
+	RateLimitTokenBucket   = "token-bucket"
+	RateLimitSlidingWindow = "sliding-window"
+)
+
+var (
+	// DefaultRateLimitConfig is the default RateLimit middleware config.
+	DefaultRateLimitConfig = RateLimitConfig{
+		Skipper:   DefaultSkipper,
+		Period:    time.Minute,
+		Algorithm: RateLimitTokenBucket,
+		KeyLookup: "ip",
+	}
+)
+
+// RateLimit returns a RateLimit middleware allowing limit requests per minute
+// for each client IP.
+//
+// Each response has the "RateLimit-Limit", "RateLimit-Remaining" and
+// "RateLimit-Reset" headers set. For requests over the limit, it sends
+// "429 - Too Many Requests" response with a "Retry-After" header.
+func RateLimit(limit int) echo.MiddlewareFunc {
+	c := DefaultRateLimitConfig
+	c.Limit = limit
+	return RateLimitWithConfig(c)
+}
+
+// RateLimitWithConfig returns a RateLimit middleware with config.
+// See `RateLimit()`.
+func RateLimitWithConfig(config RateLimitConfig) echo.MiddlewareFunc {
+	// Defaults
+	if config.Skipper == nil {
+		config.Skipper = DefaultRateLimitConfig.Skipper
+	}
+	if config.Period == 0 {
+		config.Period = DefaultRateLimitConfig.Period
+	}
+	if config.Algorithm == "" {
+		config.Algorithm = DefaultRateLimitConfig.Algorithm
+	}
+	if config.KeyLookup == "" {
+		config.KeyLookup = DefaultRateLimitConfig.KeyLookup
+	}
+	if config.Burst == 0 {
+		config.Burst = config.Limit
+	}
+	if config.Store == nil {
+		config.Store = NewMemoryRateLimitStore()
+	}
+	if config.Limit <= 0 {
+		panic("rate-limit middleware requires a limit")
+	}
+	if config.Algorithm != RateLimitTokenBucket && config.Algorithm != RateLimitSlidingWindow {
+		panic("rate-limit middleware: unknown algorithm " + config.Algorithm)
+	}
+
+	// Initialize
+	ipExtractor := rateLimitKeyFromRemoteAddr
+	if config.TrustProxyHeaders {
+		ipExtractor = rateLimitKeyFromRealIP
+	}
+	extractor := config.KeyExtractor
+	if extractor == nil {
+		parts := strings.SplitN(config.KeyLookup, ":", 2)
+		switch parts[0] {
+		case "ip":
+			extractor = ipExtractor
+		case "header":
+			if len(parts) != 2 || parts[1] == "" {
+				panic("rate-limit middleware: key lookup header requires a name, e.g. \"header:X-API-Key\"")
+			}
+			extractor = rateLimitKeyFromHeader(parts[1])
+		case "jwt":
+			key := "user"
+			if len(parts) == 2 {
+				key = parts[1]
+			}
+			extractor = rateLimitKeyFromJWT(key, ipExtractor)
+		default:
+			panic("rate-limit middleware: unknown key lookup " + config.KeyLookup)
+		}
+	}
+	rule := RateLimitRule{
+		Algorithm: config.Algorithm,
+		Limit:     config.Limit,
+		Burst:     config.Burst,
+		Period:    config.Period,
+	}
+	prefix := config.Name
+	if prefix == "" {
+		prefix = fmt.Sprintf("%s/%d/%d/%s", rule.Algorithm, rule.Limit, rule.Burst, rule.Period)
+	}
+	prefix += ":"
+	limit := strconv.Itoa(config.Limit)
+	if config.Algorithm == RateLimitTokenBucket {
+		limit = strconv.Itoa(config.Burst)
+	}
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			if config.Skipper(c) {
+				return next(c)
+			}
+
+			key, err := extractor(c)
+			if err != nil {
+				return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+			}
+			res, err := config.Store.Take(prefix+key, rule)
+			if err != nil {
+				return err
+			}
+
+			h := c.Response().Header()
+			h.Set(echo.HeaderRateLimitLimit, limit)
+			h.Set(echo.HeaderRateLimitRemaining, strconv.Itoa(res.Remaining))
+			h.Set(echo.HeaderRateLimitReset, strconv.Itoa(ceilSeconds(res.Reset)))
+			if !res.Allowed {
+				h.Set(echo.HeaderRetryAfter, strconv.Itoa(ceilSeconds(res.RetryAfter)))
+				return echo.ErrTooManyRequests
+			}
+			return next(c)
+		}
+	}
+}
+
+func rateLimitKeyFromRemoteAddr(c echo.Context) (string, error) {
+	ra := c.Request().RemoteAddr
+	if ip, _, err := net.SplitHostPort(ra); err == nil {
+		ra = ip
+	}
+	return "ip:" + ra, nil
+}
+
+func rateLimitKeyFromRealIP(c echo.Context) (string, error) {
+	return "ip:" + c.RealIP(), nil
+}
+
+// rateLimitKeyFromHeader returns a `RateLimitKeyExtractor` that extracts the key
+// from the request header.
+func rateLimitKeyFromHeader(header string) RateLimitKeyExtractor {
+	return func(c echo.Context) (string, error) {
+		v := c.Request().Header.Get(header)
+		if v == "" {
+			return "", errors.New("Missing rate limit key in request header")
+		}
+		return "header:" + v, nil
+	}
+}
+
+// rateLimitKeyFromJWT returns a `RateLimitKeyExtractor` that extracts the key
+// from the subject of the token set in the context by the JWT middleware.
+// Requests without a token are limited by IP.
+func rateLimitKeyFromJWT(contextKey string, ipExtractor RateLimitKeyExtractor) RateLimitKeyExtractor {
+	return func(c echo.Context) (string, error) {
+		token, ok := c.Get(contextKey).(*jwt.Token)
+		if !ok {
+			return ipExtractor(c)
+		}
+		var sub string
+		switch claims := token.Claims.(type) {
+		case jwt.MapClaims:
+			sub, _ = claims["sub"].(string)
+		case *jwt.StandardClaims:
+			sub = claims.Subject
+		}
+		if sub == "" {
+			return ipExtractor(c)
+		}
+		return "jwt:" + sub, nil
+	}
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
+
+// NewMemoryRateLimitStore returns a new `MemoryRateLimitStore`.
+func NewMemoryRateLimitStore() *MemoryRateLimitStore {
+	return &MemoryRateLimitStore{
+		buckets: map[string]*rateLimitBucket{},
+		now:     time.Now,
+	}
+}
+
+// Take implements `RateLimitStore.Take`.
+func (s *MemoryRateLimitStore) Take(key string, rule RateLimitRule) (RateLimitResult, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.now()
+	s.sweep(now, rule.Period)
+	b := s.buckets[key]
+	if b == nil {
+		b = &rateLimitBucket{tokens: float64(rule.Burst), last: now}
+		s.buckets[key] = b
+	}
+	b.expires = now.Add(rateLimitTTL(rule))
+
+	if rule.Algorithm == RateLimitSlidingWindow {
+		window := now.UnixNano() / int64(rule.Period)
+		switch window - b.window {
+		case 0:
+		case 1:
+			b.prev, b.count = b.count, 0
+		default:
+			b.prev, b.count = 0, 0
+		}
+		b.window = window
+		elapsed := time.Duration(now.UnixNano() % int64(rule.Period))
+		allowed := slidingWindowCount(rule, elapsed, b.count, b.prev) < float64(rule.Limit)
+		if allowed {
+			b.count++
+		}
+		return slidingWindowResult(rule, elapsed, b.count, b.prev, allowed), nil
+	}
+
+	b.tokens = math.Min(float64(rule.Burst), b.tokens+now.Sub(b.last).Seconds()*tokenRate(rule))
+	b.last = now
+	allowed := b.tokens >= 1
+	if allowed {
+		b.tokens--
+	}
+	return tokenBucketResult(rule, b.tokens, allowed), nil
+}
+
+// sweep removes idle keys, at most once per period.
+func (s *MemoryRateLimitStore) sweep(now time.Time, period time.Duration) {
+	if now.Sub(s.swept) < period {
+		return
+	}
+	s.swept = now
+	for k, b := range s.buckets {
+		if now.After(b.expires) {
+			delete(s.buckets, k)
+		}
+	}
+}
+
+// rateLimitTTL returns how long the state of an idle key is kept: two periods
+// for the sliding window, and at least until an empty token bucket is full
+// again, so that idling doesn't reset the bucket early.
+func rateLimitTTL(rule RateLimitRule) time.Duration {
+	ttl := 2 * rule.Period
+	refill := time.Duration(math.Ceil(float64(rule.Burst)/float64(rule.Limit))) * rule.Period
+	if refill > ttl {
+		ttl = refill
+	}
+	return ttl
+}
+
+// tokenRate returns the number of tokens added to the bucket per second.
+func tokenRate(rule RateLimitRule) float64 {
+	return float64(rule.Limit) / rule.Period.Seconds()
+}
+
+func tokenBucketResult(rule RateLimitRule, tokens float64, allowed bool) RateLimitResult {
+	rate := tokenRate(rule)
+	res := RateLimitResult{
+		Allowed:   allowed,
+		Remaining: int(tokens),
+		Reset:     time.Duration((float64(rule.Burst) - tokens) / rate * float64(time.Second)),
+	}
+	if !allowed {
+		res.RetryAfter = time.Duration((1 - tokens) / rate * float64(time.Second))
+	}
+	return res
+}
+
+// slidingWindowCount estimates the number of requests in the last period from
+// the counts of the current and previous fixed windows.
+func slidingWindowCount(rule RateLimitRule, elapsed time.Duration, count, prev int64) float64 {
+	weight := float64(rule.Period-elapsed) / float64(rule.Period)
+	return float64(prev)*weight + float64(count)
+}
+
+func slidingWindowResult(rule RateLimitRule, elapsed time.Duration, count, prev int64, allowed bool) RateLimitResult {
+	estimate := slidingWindowCount(rule, elapsed, count, prev)
+	res := RateLimitResult{
+		Allowed:   allowed,
+		Remaining: int(math.Max(0, float64(rule.Limit)-math.Ceil(estimate))),
+		Reset:     rule.Period - elapsed,
+	}
+	if !allowed {
+		// Wait for enough of the previous window to slide out to allow one
+		// request, or else for enough of the current window to slide out of
+		// the next one.
+		res.RetryAfter = res.Reset
+		if prev > 0 {
+			excess := estimate - float64(rule.Limit) + 1
+			if wait := time.Duration(excess / float64(prev) * float64(rule.Period)); wait < res.Reset {
+				res.RetryAfter = wait
+				return res
+			}
+		}
+		if count >= int64(rule.Limit) {
+			res.RetryAfter += time.Duration((1 - float64(rule.Limit-1)/float64(count)) * float64(rule.Period))
+		}
+	}
+	return res
+}
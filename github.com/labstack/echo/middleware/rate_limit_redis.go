@@ -0,0 +1,118 @@
+package middleware
+
+import (
+	"errors"
+	"strconv"
+	"time"
+
+	"gopkg.in/redis.v5"
+)
+
+type (
+	// RedisRateLimitStore is a RateLimitStore keeping its state in Redis, so
+	// limits are shared between servers.
+	RedisRateLimitStore struct {
+		client redis.Cmdable
+		prefix string
+		now    func() time.Time
+	}
+)
+
+var (
+	// KEYS: bucket
+	// ARGV: burst, tokens per millisecond, now (ms), ttl (ms)
+	redisTokenBucket = redis.NewScript(`
+local burst = tonumber(ARGV[1])
+local now = tonumber(ARGV[3])
+local b = redis.call('HMGET', KEYS[1], 'tokens', 'last')
+local tokens = tonumber(b[1]) or burst
+local last = tonumber(b[2]) or now
+tokens = math.min(burst, tokens + math.max(0, now - last) * tonumber(ARGV[2]))
+local allowed = 0
+if tokens >= 1 then
+	tokens = tokens - 1
+	allowed = 1
+end
+redis.call('HMSET', KEYS[1], 'tokens', tostring(tokens), 'last', ARGV[3])
+redis.call('PEXPIRE', KEYS[1], ARGV[4])
+return {allowed, tostring(tokens)}
+`)
+
+	// KEYS: current window, previous window
+	// ARGV: limit, weight of the previous window, ttl (ms)
+	redisSlidingWindow = redis.NewScript(`
+local count = tonumber(redis.call('GET', KEYS[1]) or '0')
+local prev = tonumber(redis.call('GET', KEYS[2]) or '0')
+local allowed = 0
+if prev * tonumber(ARGV[2]) + count < tonumber(ARGV[1]) then
+	count = redis.call('INCR', KEYS[1])
+	redis.call('PEXPIRE', KEYS[1], ARGV[3])
+	allowed = 1
+end
+return {allowed, count, prev}
+`)
+
+	errRedisRateLimitReply = errors.New("rate-limit: unexpected redis reply")
+)
+
+// NewRedisRateLimitStore returns a new `RedisRateLimitStore` using client. Keys
+// are prefixed with prefix, e.g. "ratelimit:".
+func NewRedisRateLimitStore(client redis.Cmdable, prefix string) *RedisRateLimitStore {
+	return &RedisRateLimitStore{
+		client: client,
+		prefix: prefix,
+		now:    time.Now,
+	}
+}
+
+// Take implements `RateLimitStore.Take`.
+func (s *RedisRateLimitStore) Take(key string, rule RateLimitRule) (RateLimitResult, error) {
+	now := s.now()
+	ttl := int64(rateLimitTTL(rule) / time.Millisecond)
+	// Hash tag so all the keys of a client are in the same cluster slot
+	key = s.prefix + "{" + key + "}"
+
+	if rule.Algorithm == RateLimitSlidingWindow {
+		window := now.UnixNano() / int64(rule.Period)
+		elapsed := time.Duration(now.UnixNano() % int64(rule.Period))
+		weight := float64(rule.Period-elapsed) / float64(rule.Period)
+		keys := []string{
+			key + ":" + strconv.FormatInt(window, 10),
+			key + ":" + strconv.FormatInt(window-1, 10),
+		}
+		res, err := redisReply(redisSlidingWindow.Run(s.client, keys, rule.Limit, weight, ttl), 3)
+		if err != nil {
+			return RateLimitResult{}, err
+		}
+		allowed, _ := res[0].(int64)
+		count, _ := res[1].(int64)
+		prev, _ := res[2].(int64)
+		return slidingWindowResult(rule, elapsed, count, prev, allowed == 1), nil
+	}
+
+	rate := tokenRate(rule) / 1000
+	ms := now.UnixNano() / int64(time.Millisecond)
+	res, err := redisReply(redisTokenBucket.Run(s.client, []string{key}, rule.Burst, rate, ms, ttl), 2)
+	if err != nil {
+		return RateLimitResult{}, err
+	}
+	allowed, _ := res[0].(int64)
+	str, _ := res[1].(string)
+	tokens, err := strconv.ParseFloat(str, 64)
+	if err != nil {
+		return RateLimitResult{}, errRedisRateLimitReply
+	}
+	return tokenBucketResult(rule, tokens, allowed == 1), nil
+}
+
+func redisReply(cmd *redis.Cmd, n int) ([]interface{}, error) {
+	v, err := cmd.Result()
+	if err != nil {
+		return nil, err
+	}
+	res, ok := v.([]interface{})
+	if !ok || len(res) != n {
+		return nil, errRedisRateLimitReply
+	}
+	return res, nil
+}
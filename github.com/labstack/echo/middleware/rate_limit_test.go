@@ -0,0 +1,268 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/labstack/echo"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/redis.v5"
+)
+
+type rateLimitClock struct {
+	now time.Time
+}
+
+func (c *rateLimitClock) Now() time.Time {
+	return c.now
+}
+
+func rateLimitRequest(h echo.HandlerFunc, ip string) (*httptest.ResponseRecorder, error) {
+	e := echo.New()
+	req := httptest.NewRequest(echo.GET, "/", nil)
+	req.RemoteAddr = ip + ":1234"
+	res := httptest.NewRecorder()
+	return res, h(e.NewContext(req, res))
+}
+
+func TestRateLimitTokenBucket(t *testing.T) {
+	clock := &rateLimitClock{now: time.Unix(1000, 0)}
+	store := NewMemoryRateLimitStore()
+	store.now = clock.Now
+	h := RateLimitWithConfig(RateLimitConfig{
+		Limit:  2,
+		Period: time.Second,
+		Burst:  3,
+		Store:  store,
+	})(func(c echo.Context) error {
+		return c.String(http.StatusOK, "test")
+	})
+
+	// Burst
+	for i := 2; i >= 0; i-- {
+		res, err := rateLimitRequest(h, "1.1.1.1")
+		assert.NoError(t, err)
+		assert.Equal(t, "3", res.Header().Get(echo.HeaderRateLimitLimit))
+		assert.Equal(t, strconv.Itoa(i), res.Header().Get(echo.HeaderRateLimitRemaining))
+	}
+	res, err := rateLimitRequest(h, "1.1.1.1")
+	if he, ok := err.(*echo.HTTPError); assert.True(t, ok) {
+		assert.Equal(t, http.StatusTooManyRequests, he.Code)
+	}
+	assert.Equal(t, "1", res.Header().Get(echo.HeaderRetryAfter))
+	assert.Equal(t, "2", res.Header().Get(echo.HeaderRateLimitReset))
+
+	// Other clients are not limited
+	_, err = rateLimitRequest(h, "2.2.2.2")
+	assert.NoError(t, err)
+
+	// Refill at 2 tokens per second
+	clock.now = clock.now.Add(500 * time.Millisecond)
+	_, err = rateLimitRequest(h, "1.1.1.1")
+	assert.NoError(t, err)
+	_, err = rateLimitRequest(h, "1.1.1.1")
+	assert.Error(t, err)
+}
+
+func TestRateLimitTokenBucketLargeBurst(t *testing.T) {
+	clock := &rateLimitClock{now: time.Unix(1000, 0)}
+	store := NewMemoryRateLimitStore()
+	store.now = clock.Now
+	rule := RateLimitRule{Algorithm: RateLimitTokenBucket, Limit: 1, Burst: 5, Period: time.Second}
+	for i := 0; i < 5; i++ {
+		res, err := store.Take("1.1.1.1", rule)
+		assert.NoError(t, err)
+		assert.True(t, res.Allowed)
+	}
+
+	// The bucket is kept while it refills, past two periods
+	clock.now = clock.now.Add(2500 * time.Millisecond)
+	res, err := store.Take("1.1.1.1", rule)
+	assert.NoError(t, err)
+	assert.True(t, res.Allowed)
+	assert.Equal(t, 1, res.Remaining)
+	assert.Equal(t, 5*time.Second, rateLimitTTL(rule))
+}
+
+func TestRateLimitSlidingWindow(t *testing.T) {
+	clock := &rateLimitClock{now: time.Unix(1000, 0)}
+	store := NewMemoryRateLimitStore()
+	store.now = clock.Now
+	h := RateLimitWithConfig(RateLimitConfig{
+		Limit:     4,
+		Period:    10 * time.Second,
+		Algorithm: RateLimitSlidingWindow,
+		Store:     store,
+	})(func(c echo.Context) error {
+		return c.String(http.StatusOK, "test")
+	})
+
+	for i := 0; i < 4; i++ {
+		_, err := rateLimitRequest(h, "1.1.1.1")
+		assert.NoError(t, err)
+	}
+	res, err := rateLimitRequest(h, "1.1.1.1")
+	assert.Error(t, err)
+	assert.Equal(t, "0", res.Header().Get(echo.HeaderRateLimitRemaining))
+	assert.Equal(t, "10", res.Header().Get(echo.HeaderRateLimitReset))
+	// The 4 requests only slide out 2.5s into the next window
+	assert.Equal(t, "13", res.Header().Get(echo.HeaderRetryAfter))
+
+	// Half of the previous window still counts: 4*0.5 = 2 requests
+	clock.now = clock.now.Add(15 * time.Second)
+	for i := 0; i < 2; i++ {
+		_, err = rateLimitRequest(h, "1.1.1.1")
+		assert.NoError(t, err)
+	}
+	res, err = rateLimitRequest(h, "1.1.1.1")
+	assert.Error(t, err)
+	assert.Equal(t, "3", res.Header().Get(echo.HeaderRetryAfter))
+
+	// Two windows later everything slid out
+	clock.now = clock.now.Add(20 * time.Second)
+	res, err = rateLimitRequest(h, "1.1.1.1")
+	assert.NoError(t, err)
+	assert.Equal(t, "3", res.Header().Get(echo.HeaderRateLimitRemaining))
+}
+
+func TestRateLimitKeyLookup(t *testing.T) {
+	handler := func(c echo.Context) error {
+		return c.String(http.StatusOK, "test")
+	}
+	e := echo.New()
+
+	// Header
+	h := RateLimitWithConfig(RateLimitConfig{Limit: 1, KeyLookup: "header:X-API-Key"})(handler)
+	req := httptest.NewRequest(echo.GET, "/", nil)
+	he, ok := h(e.NewContext(req, httptest.NewRecorder())).(*echo.HTTPError)
+	if assert.True(t, ok) {
+		assert.Equal(t, http.StatusBadRequest, he.Code)
+	}
+	req.Header.Set("X-API-Key", "a")
+	assert.NoError(t, h(e.NewContext(req, httptest.NewRecorder())))
+	assert.Error(t, h(e.NewContext(req, httptest.NewRecorder())))
+	req.Header.Set("X-API-Key", "b")
+	assert.NoError(t, h(e.NewContext(req, httptest.NewRecorder())))
+
+	// JWT subject, falling back to the IP
+	h = RateLimitWithConfig(RateLimitConfig{Limit: 1, KeyLookup: "jwt"})(handler)
+	token := func(sub string) *jwt.Token {
+		return &jwt.Token{Claims: jwt.MapClaims{"sub": sub}}
+	}
+	req = httptest.NewRequest(echo.GET, "/", nil)
+	req.RemoteAddr = "1.1.1.1:1234"
+	c := e.NewContext(req, httptest.NewRecorder())
+	c.Set("user", token("john"))
+	assert.NoError(t, h(c))
+	assert.Error(t, h(c))
+	c = e.NewContext(req, httptest.NewRecorder())
+	c.Set("user", token("jane"))
+	assert.NoError(t, h(c))
+	c = e.NewContext(req, httptest.NewRecorder())
+	assert.NoError(t, h(c))
+	assert.Error(t, h(c))
+
+	// Custom extractor and skipper
+	h = RateLimitWithConfig(RateLimitConfig{
+		Limit: 1,
+		KeyExtractor: func(c echo.Context) (string, error) {
+			return c.QueryParam("tenant"), nil
+		},
+		Skipper: func(c echo.Context) bool {
+			return c.QueryParam("admin") != ""
+		},
+	})(handler)
+	for _, data := range []struct {
+		target string
+		err    bool
+	}{
+		{"/?tenant=a", false},
+		{"/?tenant=a", true},
+		{"/?tenant=b", false},
+		{"/?tenant=a&admin=1", false},
+	} {
+		req = httptest.NewRequest(echo.GET, data.target, nil)
+		err := h(e.NewContext(req, httptest.NewRecorder()))
+		assert.Equal(t, data.err, err != nil, data.target)
+	}
+
+	assert.Panics(t, func() { RateLimit(0) })
+	assert.Panics(t, func() { RateLimitWithConfig(RateLimitConfig{Limit: 1, Algorithm: "fixed"}) })
+	assert.Panics(t, func() { RateLimitWithConfig(RateLimitConfig{Limit: 1, KeyLookup: "header"}) })
+	assert.Panics(t, func() { RateLimitWithConfig(RateLimitConfig{Limit: 1, KeyLookup: "query:key"}) })
+}
+
+func TestRateLimitProxyHeaders(t *testing.T) {
+	handler := func(c echo.Context) error {
+		return c.String(http.StatusOK, "test")
+	}
+	e := echo.New()
+	request := func(h echo.HandlerFunc, forwardedFor string) error {
+		req := httptest.NewRequest(echo.GET, "/", nil)
+		req.RemoteAddr = "10.0.0.1:1234"
+		req.Header.Set(echo.HeaderXForwardedFor, forwardedFor)
+		return h(e.NewContext(req, httptest.NewRecorder()))
+	}
+
+	// Forged headers don't escape the limit by default
+	h := RateLimitWithConfig(RateLimitConfig{Limit: 1})(handler)
+	assert.NoError(t, request(h, "1.1.1.1"))
+	assert.Error(t, request(h, "2.2.2.2"))
+
+	h = RateLimitWithConfig(RateLimitConfig{Limit: 1, TrustProxyHeaders: true})(handler)
+	assert.NoError(t, request(h, "1.1.1.1"))
+	assert.NoError(t, request(h, "2.2.2.2"))
+	assert.Error(t, request(h, "1.1.1.1, 10.0.0.1"))
+}
+
+func TestRateLimitSharedStore(t *testing.T) {
+	handler := func(c echo.Context) error {
+		return c.String(http.StatusOK, "test")
+	}
+	store := NewMemoryRateLimitStore()
+	strict := RateLimitWithConfig(RateLimitConfig{Limit: 1, Store: store})(handler)
+	loose := RateLimitWithConfig(RateLimitConfig{Limit: 2, Store: store})(handler)
+	_, err := rateLimitRequest(strict, "1.1.1.1")
+	assert.NoError(t, err)
+	for i := 0; i < 2; i++ {
+		_, err = rateLimitRequest(loose, "1.1.1.1")
+		assert.NoError(t, err)
+	}
+
+	// Same rule, distinct names
+	a := RateLimitWithConfig(RateLimitConfig{Limit: 1, Store: store, Name: "a"})(handler)
+	b := RateLimitWithConfig(RateLimitConfig{Limit: 1, Store: store, Name: "b"})(handler)
+	_, err = rateLimitRequest(a, "1.1.1.1")
+	assert.NoError(t, err)
+	_, err = rateLimitRequest(b, "1.1.1.1")
+	assert.NoError(t, err)
+	_, err = rateLimitRequest(a, "1.1.1.1")
+	assert.Error(t, err)
+}
+
+func TestRedisRateLimitStore(t *testing.T) {
+	client := redis.NewClient(&redis.Options{Addr: ":6379"})
+	defer client.Close()
+	if err := client.Ping().Err(); err != nil {
+		t.Skipf("Redis is not available: %v", err)
+	}
+	clock := &rateLimitClock{now: time.Now()}
+	store := NewRedisRateLimitStore(client, "echo-test:"+clock.now.Format(time.RFC3339Nano)+":")
+	store.now = clock.Now
+	for _, algorithm := range []string{RateLimitTokenBucket, RateLimitSlidingWindow} {
+		rule := RateLimitRule{Algorithm: algorithm, Limit: 2, Burst: 2, Period: time.Minute}
+		for i := 0; i < 2; i++ {
+			res, err := store.Take(algorithm, rule)
+			assert.NoError(t, err)
+			assert.True(t, res.Allowed, algorithm)
+		}
+		res, err := store.Take(algorithm, rule)
+		assert.NoError(t, err)
+		assert.False(t, res.Allowed, algorithm)
+		assert.True(t, res.RetryAfter > 0, algorithm)
+	}
+}
@@ -0,0 +1,455 @@
+package middleware
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/gob"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/labstack/echo"
+)
+
+type (
+	// SessionConfig defines the config for Session middleware.
+	SessionConfig struct {
+		// Skipper defines a function to skip middleware.
+		Skipper Skipper
+
+		// Store keeps the session data.
+		// Optional. Default value a new memory store.
+		Store SessionStore
+
+		// IdleTimeout is the time after which a session expires if it was not
+		// used.
+		// Optional. Default value 30 minutes.
+		IdleTimeout time.Duration `json:"idle_timeout"`
+
+		// AbsoluteTimeout is the time after which a session expires, even if
+		// it was used.
+		// Optional. Default value 24 hours.
+		AbsoluteTimeout time.Duration `json:"absolute_timeout"`
+
+		// Name of the session cookie.
+		// Optional. Default value "session".
+		CookieName string `json:"cookie_name"`
+
+		// Domain of the session cookie.
+		// Optional. Default value none.
+		CookieDomain string `json:"cookie_domain"`
+
+		// Path of the session cookie.
+		// Optional. Default value "/".
+		CookiePath string `json:"cookie_path"`
+
+		// Indicates if the session cookie is secure.
+		// Optional. Default value false.
+		CookieSecure bool `json:"cookie_secure"`
+	}
+
+	// SessionStore defines the interface for session stores. Session data is
+	// opaque to the stores.
+	SessionStore interface {
+		// Load returns the data of the session referenced by the cookie value,
+		// or nil if there is no such session.
+		Load(cookie string) ([]byte, error)
+
+		// Save saves the data of the session id for ttl and returns the
+		// cookie value referencing it.
+		Save(id string, data []byte, ttl time.Duration) (string, error)
+
+		// Delete deletes the session id.
+		Delete(id string) error
+	}
+
+	// SessionData is the session of a client. Values are encoded with
+	// encoding/gob: types other than the basic types must be registered with
+	// `gob.Register()`.
+	SessionData struct {
+		data      sessionPayload
+		isNew     bool
+		oldID     string
+		destroyed bool
+	}
+
+	sessionPayload struct {
+		ID       string
+		Values   map[string]interface{}
+		Flashes  []interface{}
+		Created  time.Time
+		Accessed time.Time
+	}
+
+	// MemorySessionStore is a SessionStore keeping sessions in memory.
+	MemorySessionStore struct {
+		mu       sync.Mutex
+		sessions map[string]memorySession
+		swept    time.Time
+	}
+
+	memorySession struct {
+		data    []byte
+		expires time.Time
+	}
+
+	// CookieSessionStore is a SessionStore keeping sessions in the cookie
+	// itself, encrypted and authenticated with AES-GCM. Sessions can't be
+	// deleted before they expire, but the session cookie is removed from the
+	// client.
+	CookieSessionStore struct {
+		aeads []cipher.AEAD
+	}
+)
+
+const sessionContextKey = "_session"
+
+var (
+	// DefaultSessionConfig is the default Session middleware config.
+	DefaultSessionConfig = SessionConfig{
+		Skipper:         DefaultSkipper,
+		IdleTimeout:     30 * time.Minute,
+		AbsoluteTimeout: 24 * time.Hour,
+		CookieName:      "session",
+		CookiePath:      "/",
+	}
+
+	// ErrSessionTooLarge is returned when a session does not fit in a cookie.
+	ErrSessionTooLarge = errors.New("session is too large for a cookie")
+
+	// sessionNow is overridden in tests.
+	sessionNow = time.Now
+)
+
+// Session returns a Session middleware keeping sessions in memory.
+//
+// The session of the request is returned by `GetSession()`. It is saved
+// just before the response is written, and its cookie is only sent once
+// something was stored in it.
+func Session() echo.MiddlewareFunc {
+	return SessionWithConfig(DefaultSessionConfig)
+}
+
+// SessionWithConfig returns a Session middleware with config.
+// See `Session()`.
+func SessionWithConfig(config SessionConfig) echo.MiddlewareFunc {
+	// Defaults
+	if config.Skipper == nil {
+		config.Skipper = DefaultSessionConfig.Skipper
+	}
+	if config.Store == nil {
+		config.Store = NewMemorySessionStore()
+	}
+	if config.IdleTimeout == 0 {
+		config.IdleTimeout = DefaultSessionConfig.IdleTimeout
+	}
+	if config.AbsoluteTimeout == 0 {
+		config.AbsoluteTimeout = DefaultSessionConfig.AbsoluteTimeout
+	}
+	if config.CookieName == "" {
+		config.CookieName = DefaultSessionConfig.CookieName
+	}
+	if config.CookiePath == "" {
+		config.CookiePath = DefaultSessionConfig.CookiePath
+	}
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			if config.Skipper(c) {
+				return next(c)
+			}
+
+			now := sessionNow()
+			s, err := loadSession(c, config, now)
+			if err != nil {
+				return err
+			}
+			c.Set(sessionContextKey, s)
+
+			saved := false
+			save := func() {
+				if saved {
+					return
+				}
+				saved = true
+				cookie, err := s.save(config, now)
+				if err != nil {
+					c.Logger().Error(err)
+					return
+				}
+				if cookie != nil {
+					cookie.Path = config.CookiePath
+					cookie.Domain = config.CookieDomain
+					cookie.Secure = config.CookieSecure
+					// Session cookies are never needed by scripts
+					cookie.HttpOnly = true
+					c.SetCookie(cookie)
+				}
+			}
+			c.Response().Before(save)
+			err = next(c)
+			if !c.Response().Committed {
+				save()
+			}
+			return err
+		}
+	}
+}
+
+// GetSession returns the session of the request set by the Session
+// middleware, or nil if there is none.
+func GetSession(c echo.Context) *SessionData {
+	s, _ := c.Get(sessionContextKey).(*SessionData)
+	return s
+}
+
+func loadSession(c echo.Context, config SessionConfig, now time.Time) (*SessionData, error) {
+	if cookie, err := c.Cookie(config.CookieName); err == nil && cookie.Value != "" {
+		b, err := config.Store.Load(cookie.Value)
+		if err != nil {
+			return nil, err
+		}
+		s := &SessionData{}
+		if b != nil && gob.NewDecoder(bytes.NewReader(b)).Decode(&s.data) == nil {
+			if now.Sub(s.data.Accessed) <= config.IdleTimeout && now.Sub(s.data.Created) <= config.AbsoluteTimeout {
+				s.data.Accessed = now
+				return s, nil
+			}
+			// Expired
+			if err = config.Store.Delete(s.data.ID); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return newSession(now)
+}
+
+func newSession(now time.Time) (*SessionData, error) {
+	id, err := newSessionID()
+	if err != nil {
+		return nil, err
+	}
+	return &SessionData{
+		data: sessionPayload{
+			ID:       id,
+			Values:   map[string]interface{}{},
+			Created:  now,
+			Accessed: now,
+		},
+		isNew: true,
+	}, nil
+}
+
+func newSessionID() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// save saves the session in the store and returns the cookie to send, if any.
+func (s *SessionData) save(config SessionConfig, now time.Time) (*http.Cookie, error) {
+	if s.oldID != "" {
+		if err := config.Store.Delete(s.oldID); err != nil {
+			return nil, err
+		}
+	}
+	if s.destroyed {
+		if s.isNew {
+			return nil, nil
+		}
+		if err := config.Store.Delete(s.data.ID); err != nil {
+			return nil, err
+		}
+		return &http.Cookie{Name: config.CookieName, MaxAge: -1}, nil
+	}
+	if s.isNew && len(s.data.Values) == 0 && len(s.data.Flashes) == 0 {
+		// Don't start sessions for every client
+		return nil, nil
+	}
+
+	buf := new(bytes.Buffer)
+	if err := gob.NewEncoder(buf).Encode(&s.data); err != nil {
+		return nil, err
+	}
+	ttl := config.IdleTimeout
+	if left := config.AbsoluteTimeout - now.Sub(s.data.Created); left < ttl {
+		ttl = left
+	}
+	value, err := config.Store.Save(s.data.ID, buf.Bytes(), ttl)
+	if err != nil {
+		return nil, err
+	}
+	return &http.Cookie{Name: config.CookieName, Value: value}, nil
+}
+
+// ID returns the session id.
+func (s *SessionData) ID() string {
+	return s.data.ID
+}
+
+// IsNew returns true if the session was created by this request.
+func (s *SessionData) IsNew() bool {
+	return s.isNew
+}
+
+// Get returns the value of key or nil.
+func (s *SessionData) Get(key string) interface{} {
+	return s.data.Values[key]
+}
+
+// Set sets the value of key.
+func (s *SessionData) Set(key string, value interface{}) {
+	s.data.Values[key] = value
+}
+
+// Delete deletes key.
+func (s *SessionData) Delete(key string) {
+	delete(s.data.Values, key)
+}
+
+// Clear deletes all the values.
+func (s *SessionData) Clear() {
+	s.data.Values = map[string]interface{}{}
+}
+
+// AddFlash adds a flash message, which is kept until it is read with
+// `Flashes()`, usually by the next request.
+func (s *SessionData) AddFlash(value interface{}) {
+	s.data.Flashes = append(s.data.Flashes, value)
+}
+
+// Flashes returns and deletes the flash messages.
+func (s *SessionData) Flashes() []interface{} {
+	f := s.data.Flashes
+	s.data.Flashes = nil
+	return f
+}
+
+// RotateID gives the session a new id, keeping its values. It should be
+// called when the privileges of the session change, e.g. on login, to
+// prevent session fixation.
+func (s *SessionData) RotateID() error {
+	id, err := newSessionID()
+	if err != nil {
+		return err
+	}
+	if s.oldID == "" && !s.isNew {
+		s.oldID = s.data.ID
+	}
+	s.data.ID = id
+	return nil
+}
+
+// Destroy deletes the session, e.g. on logout.
+func (s *SessionData) Destroy() {
+	s.destroyed = true
+	s.data.Values = map[string]interface{}{}
+	s.data.Flashes = nil
+}
+
+// NewMemorySessionStore returns a new `MemorySessionStore`.
+func NewMemorySessionStore() *MemorySessionStore {
+	return &MemorySessionStore{sessions: map[string]memorySession{}}
+}
+
+// Load implements `SessionStore.Load`.
+func (s *MemorySessionStore) Load(id string) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ms, ok := s.sessions[id]
+	if !ok || sessionNow().After(ms.expires) {
+		return nil, nil
+	}
+	return ms.data, nil
+}
+
+// Save implements `SessionStore.Save`.
+func (s *MemorySessionStore) Save(id string, data []byte, ttl time.Duration) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := sessionNow()
+	if now.Sub(s.swept) > time.Minute {
+		s.swept = now
+		for k, ms := range s.sessions {
+			if now.After(ms.expires) {
+				delete(s.sessions, k)
+			}
+		}
+	}
+	s.sessions[id] = memorySession{data: data, expires: now.Add(ttl)}
+	return id, nil
+}
+
+// Delete implements `SessionStore.Delete`.
+func (s *MemorySessionStore) Delete(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.sessions, id)
+	return nil
+}
+
+// NewCookieSessionStore returns a new `CookieSessionStore`. Keys must be 16,
+// 24 or 32 bytes long, to select AES-128, AES-192 or AES-256. The first key
+// encrypts the sessions, all the keys are tried to decrypt them, so keys can
+// be rotated.
+func NewCookieSessionStore(keys ...[]byte) (*CookieSessionStore, error) {
+	if len(keys) == 0 {
+		return nil, errors.New("cookie session store requires a key")
+	}
+	s := &CookieSessionStore{}
+	for _, k := range keys {
+		block, err := aes.NewCipher(k)
+		if err != nil {
+			return nil, err
+		}
+		aead, err := cipher.NewGCM(block)
+		if err != nil {
+			return nil, err
+		}
+		s.aeads = append(s.aeads, aead)
+	}
+	return s, nil
+}
+
+// Load implements `SessionStore.Load`.
+func (s *CookieSessionStore) Load(cookie string) ([]byte, error) {
+	b, err := base64.RawURLEncoding.DecodeString(cookie)
+	if err != nil {
+		return nil, nil
+	}
+	for _, aead := range s.aeads {
+		if len(b) < aead.NonceSize() {
+			continue
+		}
+		if data, err := aead.Open(nil, b[:aead.NonceSize()], b[aead.NonceSize():], nil); err == nil {
+			return data, nil
+		}
+	}
+	// Tampered or encrypted with an old key
+	return nil, nil
+}
+
+// Save implements `SessionStore.Save`.
+func (s *CookieSessionStore) Save(id string, data []byte, ttl time.Duration) (string, error) {
+	aead := s.aeads[0]
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(data)+aead.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	value := base64.RawURLEncoding.EncodeToString(aead.Seal(nonce, nonce, data, nil))
+	if len(value) > 4000 {
+		return "", ErrSessionTooLarge
+	}
+	return value, nil
+}
+
+// Delete implements `SessionStore.Delete`. Cookie sessions can't be deleted.
+func (s *CookieSessionStore) Delete(id string) error {
+	return nil
+}
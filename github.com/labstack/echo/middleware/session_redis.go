@@ -0,0 +1,41 @@
+package middleware
+
+import (
+	"time"
+
+	"gopkg.in/redis.v5"
+)
+
+type (
+	// RedisSessionStore is a SessionStore keeping sessions in Redis, so they
+	// are shared between servers.
+	RedisSessionStore struct {
+		client redis.Cmdable
+		prefix string
+	}
+)
+
+// NewRedisSessionStore returns a new `RedisSessionStore` using client. Keys are
+// prefixed with prefix, e.g. "session:".
+func NewRedisSessionStore(client redis.Cmdable, prefix string) *RedisSessionStore {
+	return &RedisSessionStore{client: client, prefix: prefix}
+}
+
+// Load implements `SessionStore.Load`.
+func (s *RedisSessionStore) Load(id string) ([]byte, error) {
+	b, err := s.client.Get(s.prefix + id).Bytes()
+	if err == redis.Nil {
+		return nil, nil
+	}
+	return b, err
+}
+
+// Save implements `SessionStore.Save`.
+func (s *RedisSessionStore) Save(id string, data []byte, ttl time.Duration) (string, error) {
+	return id, s.client.Set(s.prefix+id, data, ttl).Err()
+}
+
+// Delete implements `SessionStore.Delete`.
+func (s *RedisSessionStore) Delete(id string) error {
+	return s.client.Del(s.prefix + id).Err()
+}
@@ -0,0 +1,207 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/redis.v5"
+)
+
+// sessionClient sends requests to e, keeping the session cookie.
+type sessionClient struct {
+	e      *echo.Echo
+	cookie *http.Cookie
+}
+
+func (sc *sessionClient) get(t *testing.T, path string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(echo.GET, path, nil)
+	if sc.cookie != nil {
+		req.AddCookie(sc.cookie)
+	}
+	rec := httptest.NewRecorder()
+	sc.e.ServeHTTP(rec, req)
+	for _, c := range rec.Result().Cookies() {
+		if c.Name == DefaultSessionConfig.CookieName {
+			if c.MaxAge < 0 {
+				sc.cookie = nil
+			} else {
+				sc.cookie = c
+			}
+		}
+	}
+	return rec
+}
+
+func sessionEcho(config SessionConfig) *echo.Echo {
+	e := echo.New()
+	e.Use(SessionWithConfig(config))
+	e.GET("/set", func(c echo.Context) error {
+		GetSession(c).Set("user", c.QueryParam("user"))
+		return c.NoContent(http.StatusOK)
+	})
+	e.GET("/get", func(c echo.Context) error {
+		user, _ := GetSession(c).Get("user").(string)
+		return c.String(http.StatusOK, user)
+	})
+	e.GET("/login", func(c echo.Context) error {
+		s := GetSession(c)
+		if err := s.RotateID(); err != nil {
+			return err
+		}
+		s.Set("user", "admin")
+		s.AddFlash("Welcome")
+		return c.Redirect(http.StatusFound, "/flash")
+	})
+	e.GET("/flash", func(c echo.Context) error {
+		f := GetSession(c).Flashes()
+		if len(f) == 0 {
+			return c.NoContent(http.StatusOK)
+		}
+		return c.String(http.StatusOK, f[0].(string))
+	})
+	e.GET("/logout", func(c echo.Context) error {
+		GetSession(c).Destroy()
+		return c.NoContent(http.StatusOK)
+	})
+	e.GET("/error", func(c echo.Context) error {
+		GetSession(c).Set("user", "error")
+		return echo.ErrForbidden
+	})
+	return e
+}
+
+func testSessionStore(t *testing.T, store SessionStore) {
+	sc := &sessionClient{e: sessionEcho(SessionConfig{Store: store})}
+
+	// No session is started for anonymous clients
+	sc.get(t, "/get")
+	assert.Nil(t, sc.cookie)
+
+	sc.get(t, "/set?user=jon")
+	if assert.NotNil(t, sc.cookie) {
+		assert.True(t, sc.cookie.HttpOnly)
+		assert.Equal(t, "/", sc.cookie.Path)
+	}
+	assert.Equal(t, "jon", sc.get(t, "/get").Body.String())
+
+	// Login rotates the session id and sets a flash message
+	before := sc.cookie.Value
+	assert.Equal(t, http.StatusFound, sc.get(t, "/login").Code)
+	assert.NotEqual(t, before, sc.cookie.Value)
+	assert.Equal(t, "admin", sc.get(t, "/get").Body.String())
+	assert.Equal(t, "Welcome", sc.get(t, "/flash").Body.String())
+	assert.Equal(t, "", sc.get(t, "/flash").Body.String())
+
+	// Sessions are saved when the handler returns an error
+	sc.get(t, "/error")
+	assert.Equal(t, "error", sc.get(t, "/get").Body.String())
+
+	// Logout
+	last := sc.cookie
+	sc.get(t, "/logout")
+	assert.Nil(t, sc.cookie)
+	assert.Equal(t, "", sc.get(t, "/get").Body.String())
+	if _, ok := store.(*CookieSessionStore); !ok {
+		// Server side sessions can't be replayed
+		sc.cookie = last
+		assert.Equal(t, "", sc.get(t, "/get").Body.String())
+	}
+}
+
+func TestSessionMemoryStore(t *testing.T) {
+	store := NewMemorySessionStore()
+	testSessionStore(t, store)
+
+	// The old session is deleted on rotation
+	sc := &sessionClient{e: sessionEcho(SessionConfig{Store: store})}
+	sc.get(t, "/set?user=jon")
+	old := sc.cookie
+	sc.get(t, "/login")
+	sc.cookie = old
+	assert.Equal(t, "", sc.get(t, "/get").Body.String())
+}
+
+func TestSessionCookieStore(t *testing.T) {
+	key1 := []byte("0123456789abcdef0123456789abcdef")
+	key2 := []byte("fedcba9876543210")
+	store, err := NewCookieSessionStore(key1)
+	if !assert.NoError(t, err) {
+		return
+	}
+	testSessionStore(t, store)
+
+	sc := &sessionClient{e: sessionEcho(SessionConfig{Store: store})}
+	sc.get(t, "/set?user=jon")
+	cookie := *sc.cookie
+
+	// Tampered cookies are ignored
+	b := []byte(sc.cookie.Value)
+	if b[10] == 'A' {
+		b[10] = 'B'
+	} else {
+		b[10] = 'A'
+	}
+	sc.cookie.Value = string(b)
+	assert.Equal(t, "", sc.get(t, "/get").Body.String())
+
+	// Keys can be rotated
+	rotated, err := NewCookieSessionStore(key2, key1)
+	if !assert.NoError(t, err) {
+		return
+	}
+	sc = &sessionClient{e: sessionEcho(SessionConfig{Store: rotated}), cookie: &cookie}
+	assert.Equal(t, "jon", sc.get(t, "/get").Body.String())
+	// Re-encrypted with the new key
+	other, _ := NewCookieSessionStore(key2)
+	sc.e = sessionEcho(SessionConfig{Store: other})
+	assert.Equal(t, "jon", sc.get(t, "/get").Body.String())
+
+	_, err = NewCookieSessionStore()
+	assert.Error(t, err)
+	_, err = NewCookieSessionStore([]byte("short"))
+	assert.Error(t, err)
+	_, err = store.Save("id", make([]byte, 4096), time.Hour)
+	assert.Equal(t, ErrSessionTooLarge, err)
+}
+
+func TestSessionTimeouts(t *testing.T) {
+	now := time.Now()
+	sessionNow = func() time.Time { return now }
+	defer func() { sessionNow = time.Now }()
+
+	store, _ := NewCookieSessionStore([]byte("0123456789abcdef"))
+	for _, store := range []SessionStore{NewMemorySessionStore(), store} {
+		sc := &sessionClient{e: sessionEcho(SessionConfig{
+			Store:           store,
+			IdleTimeout:     10 * time.Minute,
+			AbsoluteTimeout: time.Hour,
+		})}
+		sc.get(t, "/set?user=jon")
+
+		// Activity extends the idle timeout up to the absolute timeout
+		for i := 0; i < 6; i++ {
+			now = now.Add(9 * time.Minute)
+			assert.Equal(t, "jon", sc.get(t, "/get").Body.String())
+		}
+		now = now.Add(9 * time.Minute)
+		assert.Equal(t, "", sc.get(t, "/get").Body.String())
+
+		// Idle timeout
+		sc.get(t, "/set?user=jon")
+		now = now.Add(11 * time.Minute)
+		assert.Equal(t, "", sc.get(t, "/get").Body.String())
+	}
+}
+
+func TestSessionRedisStore(t *testing.T) {
+	client := redis.NewClient(&redis.Options{Addr: ":6379"})
+	defer client.Close()
+	if err := client.Ping().Err(); err != nil {
+		t.Skipf("Redis is not available: %v", err)
+	}
+	testSessionStore(t, NewRedisSessionStore(client, "echo-test:session:"))
+}
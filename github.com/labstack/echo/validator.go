@@ -0,0 +1,447 @@
+package echo
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+type (
+	// DefaultValidator is a `Validator` driven by `validate` struct tags.
+	//
+	// Rules are separated by commas:
+	// - required: the value is not the zero value, or not empty for slices and maps
+	// - omitempty: skips the other rules if the value is the zero value
+	// - min=<n>, max=<n>: bounds of numbers, or of the length of strings, slices and maps
+	// - len=<n>: exact value of numbers, or exact length of strings, slices and maps
+	// - email: the string is an email address
+	// - oneof=<a> <b> ...: the value is one of the space separated values
+	// - eqfield=<field>: the value is equal to the value of another field of the struct
+	// - regexp=<regexp>: the string matches the regexp, it must be the last rule
+	// - dive: the following rules apply to the elements of a slice, array or map
+	//
+	// Nested structs are validated, as are structs in slices and maps after dive.
+	// The fields of embedded structs without a JSON name are reported as fields
+	// of the embedding struct.
+	//
+	// Example:
+	//
+	//   type User struct {
+	//     Email    string   `json:"email" validate:"required,email"`
+	//     Password string   `json:"password" validate:"min=8"`
+	//     Confirm  string   `json:"confirm" validate:"eqfield=Password"`
+	//     Tags     []string `json:"tags" validate:"max=5,dive,required"`
+	//   }
+	DefaultValidator struct {
+		mu    sync.RWMutex
+		cache map[reflect.Type][]validatorField
+	}
+
+	// ValidationErrors is returned by `DefaultValidator` when validation fails.
+	// `DefaultHTTPErrorHandler` renders it as a "422 - Unprocessable Entity"
+	// response listing the fields which failed.
+	ValidationErrors []*FieldError
+
+	// FieldError describes a field which failed validation.
+	FieldError struct {
+		// Field is the path of the field, using JSON names, e.g. "items[0].name".
+		Field string `json:"field"`
+
+		// Rule is the rule which failed, e.g. "required".
+		Rule string `json:"rule"`
+
+		// Param is the parameter of the rule, e.g. "8" for "min=8".
+		Param string `json:"param,omitempty"`
+
+		// Message is a human readable description of the error.
+		Message string `json:"message"`
+	}
+
+	validatorField struct {
+		index     int
+		name      string
+		rules     []validatorRule
+		elemRules []validatorRule
+		dive      bool
+		inline    bool
+	}
+
+	validatorRule struct {
+		name  string
+		param string
+		num   float64
+		re    *regexp.Regexp
+	}
+)
+
+var (
+	emailRegexp = regexp.MustCompile(`^[a-zA-Z0-9.!#$%&'*+/=?^_{|}~-]+@[a-zA-Z0-9](?:[a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?(?:\.[a-zA-Z0-9](?:[a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?)+$`)
+	timeType    = reflect.TypeOf(time.Time{})
+)
+
+// Error implements the `error` interface.
+func (e ValidationErrors) Error() string {
+	msgs := make([]string, len(e))
+	for i, fe := range e {
+		msgs[i] = fe.Error()
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// Error implements the `error` interface.
+func (e *FieldError) Error() string {
+	return e.Field + " " + e.Message
+}
+
+// Validate implements the `Validator#Validate` function. It returns
+// `ValidationErrors` if i is not valid, and other errors if i is not a struct
+// or its tags are invalid.
+func (v *DefaultValidator) Validate(i interface{}) error {
+	rv := reflect.ValueOf(i)
+	for rv.Kind() == reflect.Ptr && !rv.IsNil() {
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return errors.New("validator: validated value must be a struct")
+	}
+	var errs ValidationErrors
+	if err := v.validateStruct(rv, "", &errs); err != nil {
+		return err
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+func (v *DefaultValidator) validateStruct(rv reflect.Value, path string, errs *ValidationErrors) error {
+	fields, err := v.fields(rv.Type())
+	if err != nil {
+		return err
+	}
+	for _, f := range fields {
+		fv := rv.Field(f.index)
+		fpath := f.name
+		if path != "" {
+			fpath = path + "." + f.name
+		}
+		ok, err := v.validateValue(fv, rv, fpath, f.rules, errs)
+		if err != nil || !ok {
+			if err != nil {
+				return err
+			}
+			continue
+		}
+		if f.dive {
+			for fv.Kind() == reflect.Ptr && !fv.IsNil() {
+				fv = fv.Elem()
+			}
+			switch fv.Kind() {
+			case reflect.Slice, reflect.Array:
+				for j := 0; j < fv.Len(); j++ {
+					if err = v.validateElem(fv.Index(j), rv, fmt.Sprintf("%s[%d]", fpath, j), f.elemRules, errs); err != nil {
+						return err
+					}
+				}
+			case reflect.Map:
+				for _, k := range fv.MapKeys() {
+					if err = v.validateElem(fv.MapIndex(k), rv, fmt.Sprintf("%s[%v]", fpath, k.Interface()), f.elemRules, errs); err != nil {
+						return err
+					}
+				}
+			default:
+				return fmt.Errorf("validator: dive on field %s which is not a slice, array or map", fpath)
+			}
+		} else if f.inline {
+			if err = v.validateNested(fv, path, errs); err != nil {
+				return err
+			}
+		} else if err = v.validateNested(fv, fpath, errs); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (v *DefaultValidator) validateElem(ev, parent reflect.Value, path string, rules []validatorRule, errs *ValidationErrors) error {
+	ok, err := v.validateValue(ev, parent, path, rules, errs)
+	if err != nil || !ok {
+		return err
+	}
+	return v.validateNested(ev, path, errs)
+}
+
+// validateNested validates the struct, or the pointer to a struct, rv.
+func (v *DefaultValidator) validateNested(rv reflect.Value, path string, errs *ValidationErrors) error {
+	for (rv.Kind() == reflect.Ptr || rv.Kind() == reflect.Interface) && !rv.IsNil() {
+		rv = rv.Elem()
+	}
+	if rv.Kind() == reflect.Struct && rv.Type() != timeType {
+		return v.validateStruct(rv, path, errs)
+	}
+	return nil
+}
+
+// validateValue applies rules to rv and reports whether all of them passed.
+func (v *DefaultValidator) validateValue(rv, parent reflect.Value, path string, rules []validatorRule, errs *ValidationErrors) (bool, error) {
+	for _, r := range rules {
+		switch r.name {
+		case "omitempty":
+			if isZero(rv) {
+				return false, nil
+			}
+			continue
+		case "required":
+			if isZero(rv) {
+				errs.add(path, r, "is required")
+				return false, nil
+			}
+			continue
+		}
+		ev := rv
+		for (ev.Kind() == reflect.Ptr || ev.Kind() == reflect.Interface) && !ev.IsNil() {
+			ev = ev.Elem()
+		}
+		if ev.Kind() == reflect.Ptr || ev.Kind() == reflect.Interface {
+			// nil: only required applies
+			return true, nil
+		}
+		msg, err := checkRule(r, ev, parent)
+		if err != nil {
+			return false, fmt.Errorf("validator: field %s: %v", path, err)
+		}
+		if msg != "" {
+			errs.add(path, r, msg)
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// checkRule returns the error message if rv doesn't pass r.
+func checkRule(r validatorRule, rv, parent reflect.Value) (string, error) {
+	switch r.name {
+	case "min", "max", "len":
+		var n float64
+		var unit string
+		switch rv.Kind() {
+		case reflect.String:
+			n, unit = float64(utf8.RuneCountInString(rv.String())), " characters"
+		case reflect.Slice, reflect.Array, reflect.Map:
+			n, unit = float64(rv.Len()), " items"
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			n = float64(rv.Int())
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+			n = float64(rv.Uint())
+		case reflect.Float32, reflect.Float64:
+			n = rv.Float()
+		default:
+			return "", fmt.Errorf("%s does not apply to %s", r.name, rv.Kind())
+		}
+		switch {
+		case r.name == "min" && n < r.num:
+			if unit == "" {
+				return "must be " + r.param + " or greater", nil
+			}
+			return "must contain at least " + r.param + unit, nil
+		case r.name == "max" && n > r.num:
+			if unit == "" {
+				return "must be " + r.param + " or less", nil
+			}
+			return "must contain at most " + r.param + unit, nil
+		case r.name == "len" && n != r.num:
+			if unit == "" {
+				return "must be " + r.param, nil
+			}
+			return "must contain " + r.param + unit, nil
+		}
+	case "email":
+		if rv.Kind() != reflect.String {
+			return "", fmt.Errorf("email does not apply to %s", rv.Kind())
+		}
+		if !emailRegexp.MatchString(rv.String()) {
+			return "must be a valid email address", nil
+		}
+	case "regexp":
+		if rv.Kind() != reflect.String {
+			return "", fmt.Errorf("regexp does not apply to %s", rv.Kind())
+		}
+		if !r.re.MatchString(rv.String()) {
+			return "must match " + r.param, nil
+		}
+	case "oneof":
+		s := fmt.Sprint(rv.Interface())
+		values := strings.Fields(r.param)
+		for _, v := range values {
+			if s == v {
+				return "", nil
+			}
+		}
+		return "must be one of " + strings.Join(values, ", "), nil
+	case "eqfield":
+		other := parent.FieldByName(r.param)
+		if !other.IsValid() {
+			return "", fmt.Errorf("eqfield: unknown field %s", r.param)
+		}
+		for other.Kind() == reflect.Ptr && !other.IsNil() {
+			other = other.Elem()
+		}
+		if !other.CanInterface() || !reflect.DeepEqual(rv.Interface(), other.Interface()) {
+			f, _ := parent.Type().FieldByName(r.param)
+			return "must be equal to " + fieldName(f), nil
+		}
+	}
+	return "", nil
+}
+
+func (errs *ValidationErrors) add(path string, r validatorRule, msg string) {
+	*errs = append(*errs, &FieldError{
+		Field:   path,
+		Rule:    r.name,
+		Param:   r.param,
+		Message: msg,
+	})
+}
+
+func isZero(rv reflect.Value) bool {
+	switch rv.Kind() {
+	case reflect.Slice, reflect.Map, reflect.String:
+		return rv.Len() == 0
+	case reflect.Invalid:
+		return true
+	case reflect.Bool:
+		return !rv.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return rv.Int() == 0
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return rv.Uint() == 0
+	case reflect.Float32, reflect.Float64:
+		return rv.Float() == 0
+	case reflect.Complex64, reflect.Complex128:
+		return rv.Complex() == 0
+	case reflect.Ptr, reflect.Interface, reflect.Func, reflect.Chan, reflect.UnsafePointer:
+		return rv.IsNil()
+	case reflect.Array:
+		for i := 0; i < rv.Len(); i++ {
+			if !isZero(rv.Index(i)) {
+				return false
+			}
+		}
+		return true
+	case reflect.Struct:
+		for i := 0; i < rv.NumField(); i++ {
+			if f := rv.Field(i); f.Kind() == reflect.Slice || f.Kind() == reflect.Map {
+				if !f.IsNil() {
+					return false
+				}
+			} else if !isZero(f) {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// fields returns the validated fields of t, parsing their tags once.
+func (v *DefaultValidator) fields(t reflect.Type) ([]validatorField, error) {
+	v.mu.RLock()
+	f, ok := v.cache[t]
+	v.mu.RUnlock()
+	if ok {
+		return f, nil
+	}
+	var fields []validatorField
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if sf.PkgPath != "" && !sf.Anonymous {
+			// Unexported
+			continue
+		}
+		f := validatorField{index: i, name: fieldName(sf)}
+		if sf.Anonymous && strings.Split(sf.Tag.Get("json"), ",")[0] == "" {
+			ft := sf.Type
+			if ft.Kind() == reflect.Ptr {
+				ft = ft.Elem()
+			}
+			f.inline = ft.Kind() == reflect.Struct
+		}
+		tag := sf.Tag.Get("validate")
+		if tag == "-" {
+			continue
+		}
+		for tag != "" {
+			var part string
+			if strings.HasPrefix(tag, "regexp=") {
+				// The regexp may contain commas
+				part, tag = tag, ""
+			} else if i := strings.IndexByte(tag, ','); i >= 0 {
+				part, tag = tag[:i], tag[i+1:]
+			} else {
+				part, tag = tag, ""
+			}
+			if part == "dive" {
+				f.dive = true
+				continue
+			}
+			r, err := parseValidatorRule(part)
+			if err != nil {
+				return nil, fmt.Errorf("validator: field %s.%s: %v", t.Name(), sf.Name, err)
+			}
+			if f.dive {
+				f.elemRules = append(f.elemRules, r)
+			} else {
+				f.rules = append(f.rules, r)
+			}
+		}
+		fields = append(fields, f)
+	}
+	v.mu.Lock()
+	if v.cache == nil {
+		v.cache = map[reflect.Type][]validatorField{}
+	}
+	v.cache[t] = fields
+	v.mu.Unlock()
+	return fields, nil
+}
+
+func parseValidatorRule(s string) (r validatorRule, err error) {
+	r.name = s
+	if i := strings.IndexByte(s, '='); i >= 0 {
+		r.name, r.param = s[:i], s[i+1:]
+	}
+	switch r.name {
+	case "required", "omitempty", "email":
+	case "min", "max", "len":
+		if r.num, err = strconv.ParseFloat(r.param, 64); err != nil {
+			return r, fmt.Errorf("invalid %s parameter %q", r.name, r.param)
+		}
+	case "regexp":
+		if r.re, err = regexp.Compile(r.param); err != nil {
+			return r, err
+		}
+	case "oneof", "eqfield":
+		if r.param == "" {
+			return r, fmt.Errorf("%s requires a parameter", r.name)
+		}
+	default:
+		return r, fmt.Errorf("unknown rule %q", r.name)
+	}
+	return r, nil
+}
+
+// fieldName returns the JSON name of a struct field.
+func fieldName(sf reflect.StructField) string {
+	if tag := sf.Tag.Get("json"); tag != "" && tag != "-" {
+		if name := strings.Split(tag, ",")[0]; name != "" {
+			return name
+		}
+	}
+	return sf.Name
+}
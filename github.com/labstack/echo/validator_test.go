@@ -0,0 +1,246 @@
+package echo
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type (
+	validatorAddress struct {
+		City string `json:"city" validate:"required"`
+		Zip  string `json:"zip" validate:"omitempty,len=5"`
+	}
+
+	validatorItem struct {
+		Name string `json:"name" validate:"required"`
+		Qty  int    `json:"qty" validate:"min=1,max=10"`
+	}
+
+	validatorUser struct {
+		Name     string                   `json:"name" validate:"required,max=8"`
+		Email    string                   `json:"email" validate:"required,email"`
+		Password string                   `json:"password" validate:"min=8"`
+		Confirm  string                   `json:"confirm" validate:"eqfield=Password"`
+		Role     string                   `json:"role" validate:"omitempty,oneof=admin user"`
+		Code     string                   `json:"code" validate:"omitempty,regexp=^[a-z]{2,3}$"`
+		Address  *validatorAddress        `json:"address" validate:"required"`
+		Items    []validatorItem          `json:"items" validate:"max=3,dive"`
+		Tags     map[string]string        `json:"tags" validate:"dive,required"`
+		Extra    map[string]validatorItem `json:"extra" validate:"dive"`
+		Born     time.Time                `json:"born"`
+		secret   string                   `validate:"required"`
+	}
+)
+
+func validUser() *validatorUser {
+	return &validatorUser{
+		Name:     "jon",
+		Email:    "jon@labstack.com",
+		Password: "password",
+		Confirm:  "password",
+		Role:     "admin",
+		Code:     "abc",
+		Address:  &validatorAddress{City: "Amsterdam"},
+		Items:    []validatorItem{{Name: "book", Qty: 1}},
+		Tags:     map[string]string{"a": "b"},
+	}
+}
+
+func validate(t *testing.T, i interface{}) ValidationErrors {
+	err := new(DefaultValidator).Validate(i)
+	if err == nil {
+		return nil
+	}
+	errs, ok := err.(ValidationErrors)
+	if assert.True(t, ok, err.Error()) {
+		return errs
+	}
+	return nil
+}
+
+func TestDefaultValidator(t *testing.T) {
+	assert.Nil(t, validate(t, validUser()))
+
+	u := validUser()
+	u.Name = ""
+	u.Email = "jon@"
+	u.Password = "pass"
+	u.Role = "root"
+	u.Code = "a,b"
+	u.Address.Zip = "123"
+	errs := validate(t, u)
+	if assert.Len(t, errs, 7) {
+		assert.Equal(t, &FieldError{Field: "name", Rule: "required", Message: "is required"}, errs[0])
+		assert.Equal(t, &FieldError{Field: "email", Rule: "email", Message: "must be a valid email address"}, errs[1])
+		assert.Equal(t, &FieldError{Field: "password", Rule: "min", Param: "8", Message: "must contain at least 8 characters"}, errs[2])
+		assert.Equal(t, "confirm", errs[3].Field)
+		assert.Equal(t, "eqfield", errs[3].Rule)
+		assert.Equal(t, "must be equal to password", errs[3].Message)
+		assert.Equal(t, "must be one of admin, user", errs[4].Message)
+		assert.Equal(t, "regexp", errs[5].Rule)
+		assert.Equal(t, "^[a-z]{2,3}$", errs[5].Param)
+		assert.Equal(t, "address.zip", errs[6].Field)
+	}
+}
+
+func TestDefaultValidatorNested(t *testing.T) {
+	u := validUser()
+	u.Address = nil
+	errs := validate(t, u)
+	if assert.Len(t, errs, 1) {
+		assert.Equal(t, "address", errs[0].Field)
+	}
+
+	u = validUser()
+	u.Address.City = ""
+	errs = validate(t, u)
+	if assert.Len(t, errs, 1) {
+		assert.Equal(t, "address.city", errs[0].Field)
+	}
+}
+
+func TestDefaultValidatorDive(t *testing.T) {
+	u := validUser()
+	u.Items = []validatorItem{{Name: "a", Qty: 1}, {Qty: 11}, {}, {}}
+	errs := validate(t, u)
+	if assert.Len(t, errs, 1) {
+		assert.Equal(t, "items", errs[0].Field)
+		assert.Equal(t, "must contain at most 3 items", errs[0].Message)
+	}
+
+	u.Items = u.Items[:2]
+	errs = validate(t, u)
+	if assert.Len(t, errs, 2) {
+		assert.Equal(t, "items[1].name", errs[0].Field)
+		assert.Equal(t, &FieldError{Field: "items[1].qty", Rule: "max", Param: "10", Message: "must be 10 or less"}, errs[1])
+	}
+
+	u = validUser()
+	u.Tags = map[string]string{"empty": ""}
+	u.Extra = map[string]validatorItem{"x": {Name: "x"}}
+	errs = validate(t, u)
+	if assert.Len(t, errs, 2) {
+		assert.Equal(t, "tags[empty]", errs[0].Field)
+		assert.Equal(t, "extra[x].qty", errs[1].Field)
+	}
+}
+
+func TestDefaultValidatorEmail(t *testing.T) {
+	for _, data := range []struct {
+		email string
+		valid bool
+	}{
+		{"jon@labstack.com", true},
+		{"jon.snow+echo@mail.labstack.com", true},
+		{"jon@invalid", false},
+		{"jon@", false},
+		{"@labstack.com", false},
+		{"jon@labstack.", false},
+	} {
+		u := validUser()
+		u.Email = data.email
+		assert.Equal(t, data.valid, validate(t, u) == nil, data.email)
+	}
+}
+
+func TestDefaultValidatorEmbedded(t *testing.T) {
+	type (
+		Base struct {
+			ID string `json:"id" validate:"required"`
+		}
+		Meta struct {
+			Owner string `json:"owner" validate:"required"`
+		}
+		Document struct {
+			Base
+			*Meta `json:"meta" validate:"required"`
+			Title string `json:"title" validate:"required"`
+		}
+	)
+	errs := validate(t, &Document{})
+	if assert.Len(t, errs, 3) {
+		assert.Equal(t, "id", errs[0].Field)
+		assert.Equal(t, "meta", errs[1].Field)
+		assert.Equal(t, "title", errs[2].Field)
+	}
+
+	errs = validate(t, &Document{Base: Base{ID: "1"}, Meta: &Meta{}, Title: "a"})
+	if assert.Len(t, errs, 1) {
+		assert.Equal(t, "meta.owner", errs[0].Field)
+	}
+}
+
+func TestIsZero(t *testing.T) {
+	var p *int
+	for _, data := range []struct {
+		v    interface{}
+		zero bool
+	}{
+		{0, true},
+		{1, false},
+		{"", true},
+		{"a", false},
+		{false, true},
+		{0.0, true},
+		{p, true},
+		{[2]int{}, true},
+		{[2]int{0, 1}, false},
+		{time.Time{}, true},
+		{time.Now(), false},
+		{validatorItem{}, true},
+		{validatorItem{Qty: 1}, false},
+		{[]int{}, true},
+		{map[string]int{}, true},
+	} {
+		assert.Equal(t, data.zero, isZero(reflect.ValueOf(data.v)), "%#v", data.v)
+	}
+}
+
+func TestDefaultValidatorInvalid(t *testing.T) {
+	v := new(DefaultValidator)
+	assert.Error(t, v.Validate("string"))
+
+	err := v.Validate(&struct {
+		Name string `validate:"unknown"`
+	}{})
+	if assert.Error(t, err) {
+		_, ok := err.(ValidationErrors)
+		assert.False(t, ok)
+	}
+
+	err = v.Validate(&struct {
+		Name string `validate:"dive"`
+	}{Name: "a"})
+	assert.Error(t, err)
+}
+
+func TestValidationErrorsHandler(t *testing.T) {
+	e := New()
+	e.Validator = &DefaultValidator{}
+	req := httptest.NewRequest(POST, "/", nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	u := validUser()
+	u.Email = ""
+	u.Items[0].Qty = 0
+	e.HTTPErrorHandler(c.Validate(u), c)
+
+	assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+	body := struct {
+		Message string
+		Errors  []*FieldError
+	}{}
+	if assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body)) {
+		assert.Equal(t, "Validation failed", body.Message)
+		assert.Equal(t, []*FieldError{
+			{Field: "email", Rule: "required", Message: "is required"},
+			{Field: "items[0].qty", Rule: "min", Param: "1", Message: "must be 1 or greater"},
+		}, body.Errors)
+	}
+}
@@ -0,0 +1,305 @@
+package z
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"math"
+	"net"
+	"os"
+	"sync"
+	"time"
+
+	"golang.org/x/net/icmp"
+	"golang.org/x/net/ipv4"
+	"golang.org/x/net/ipv6"
+)
+
+const (
+	protocolICMP     = 1
+	protocolIPv6ICMP = 58
+)
+
+// Ping 的参数
+type PingOptions struct {
+	// 网络类型: "ip" (默认), "ip4" 或 "ip6"
+	Network string
+	// 发送探测包的次数, 默认为 4
+	Count int
+	// 两次探测之间的间隔, 默认为 1 秒
+	Interval time.Duration
+	// 每个探测包等待回复的超时时间, 默认为 1 秒
+	Timeout time.Duration
+	// 探测包数据长度, 默认为 56 字节, 最少为 16 字节
+	Size int
+	// 为 true 时使用 raw socket (需要 root 或 CAP_NET_RAW 权限),
+	// 否则使用非特权的 datagram socket (Linux 下需要设置 net.ipv4.ping_group_range)
+	Privileged bool
+}
+
+// 单个探测包的结果
+type PingProbe struct {
+	// 序号
+	Seq int
+	// 往返时间, 丢包时为 0
+	RTT time.Duration
+	// 是否丢包
+	Lost bool
+}
+
+// Ping 的统计结果
+type PingStats struct {
+	// 目标地址
+	Addr string
+	// 解析后的 IP
+	IP net.IP
+	// 发送和接收的包数
+	Sent, Received int
+	// 每个探测包的结果
+	Probes []PingProbe
+	// 丢包率, 百分比
+	Loss float64
+	// 往返时间的最小值, 平均值, 最大值和标准差
+	Min, Avg, Max, StdDev time.Duration
+}
+
+// 批量 Ping 中某个地址的结果
+type PingResult struct {
+	Stats *PingStats
+	Err   error
+}
+
+func (opt *PingOptions) setDefaults() {
+	if opt.Network == "" {
+		opt.Network = "ip"
+	}
+	if opt.Count <= 0 {
+		opt.Count = 4
+	}
+	if opt.Interval <= 0 {
+		opt.Interval = time.Second
+	}
+	if opt.Timeout <= 0 {
+		opt.Timeout = time.Second
+	}
+	if opt.Size <= 0 {
+		opt.Size = 56
+	}
+	if opt.Size < 16 {
+		opt.Size = 16
+	}
+}
+
+// 向 addr 发送 ICMP Echo 请求并统计结果, 支持 IPv4 和 IPv6.
+// ctx 被取消时停止发送和等待回复, 返回已有的统计结果和 ctx 的错误.
+// 发送或接收出错时也会返回已有的统计结果.
+func PingContext(ctx context.Context, addr string, opt PingOptions) (*PingStats, error) {
+	opt.setDefaults()
+	ipaddr, err := net.ResolveIPAddr(opt.Network, addr)
+	if err != nil {
+		return nil, err
+	}
+	stats := &PingStats{Addr: addr, IP: ipaddr.IP}
+	if err = ctx.Err(); err != nil {
+		return stats, err
+	}
+
+	// 根据地址类型选择协议
+	var network, laddr string
+	var proto int
+	var typ icmp.Type
+	var replyType icmp.Type
+	if ipaddr.IP.To4() != nil {
+		network, laddr, proto = "udp4", "0.0.0.0", protocolICMP
+		if opt.Privileged {
+			network = "ip4:icmp"
+		}
+		typ, replyType = ipv4.ICMPTypeEcho, ipv4.ICMPTypeEchoReply
+	} else {
+		network, laddr, proto = "udp6", "::", protocolIPv6ICMP
+		if opt.Privileged {
+			network = "ip6:ipv6-icmp"
+		}
+		typ, replyType = ipv6.ICMPTypeEchoRequest, ipv6.ICMPTypeEchoReply
+	}
+	conn, err := icmp.ListenPacket(network, laddr)
+	if err != nil {
+		// raw socket 的错误包在 net.OpError 里, os.IsPermission 无法识别
+		if op, ok := err.(*net.OpError); ok {
+			err = op.Err
+		}
+		return nil, err
+	}
+	defer conn.Close()
+
+	// ctx 被取消时关闭连接, 以中断正在等待的读取
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			conn.Close()
+		case <-done:
+		}
+	}()
+	// 返回已有的统计结果, ctx 被取消时返回 ctx 的错误
+	fail := func(err error) (*PingStats, error) {
+		stats.compute()
+		if ctx.Err() != nil {
+			return stats, ctx.Err()
+		}
+		return stats, err
+	}
+
+	var dst net.Addr = ipaddr
+	if !opt.Privileged {
+		dst = &net.UDPAddr{IP: ipaddr.IP, Zone: ipaddr.Zone}
+	}
+
+	// 每个 Ping 使用随机的 token 来识别自己的回复,
+	// 因为 raw socket 会收到所有的 ICMP 回复
+	token := make([]byte, 8)
+	if _, err = rand.Read(token); err != nil {
+		return nil, err
+	}
+	id := os.Getpid() & 0xffff
+	buf := make([]byte, 1500)
+
+	for seq := 1; seq <= opt.Count; seq++ {
+		if seq > 1 {
+			t := time.NewTimer(opt.Interval)
+			select {
+			case <-ctx.Done():
+				t.Stop()
+				return fail(ctx.Err())
+			case <-t.C:
+			}
+		}
+
+		data := make([]byte, opt.Size)
+		copy(data, token)
+		sent := time.Now()
+		binary.BigEndian.PutUint64(data[8:], uint64(sent.UnixNano()))
+		msg := icmp.Message{
+			Type: typ,
+			Body: &icmp.Echo{ID: id, Seq: seq & 0xffff, Data: data},
+		}
+		b, err := msg.Marshal(nil)
+		if err != nil {
+			return nil, err
+		}
+		if _, err = conn.WriteTo(b, dst); err != nil {
+			return fail(err)
+		}
+		stats.Sent++
+
+		probe := PingProbe{Seq: seq, Lost: true}
+		deadline := sent.Add(opt.Timeout)
+		if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+			deadline = d
+		}
+		conn.SetReadDeadline(deadline)
+		for {
+			n, _, err := conn.ReadFrom(buf)
+			if err != nil {
+				if ne, ok := err.(net.Error); ok && ne.Timeout() || ctx.Err() != nil {
+					break
+				}
+				return fail(err)
+			}
+			rm, err := icmp.ParseMessage(proto, buf[:n])
+			if err != nil || rm.Type != replyType {
+				continue
+			}
+			echo, ok := rm.Body.(*icmp.Echo)
+			// 非特权模式下内核会修改 ID, 所以只校验序号和 token
+			if !ok || echo.Seq != seq&0xffff || len(echo.Data) < 16 || string(echo.Data[:8]) != string(token) {
+				continue
+			}
+			probe.RTT = time.Since(sent)
+			probe.Lost = false
+			stats.Received++
+			break
+		}
+		stats.Probes = append(stats.Probes, probe)
+		if ctx.Err() != nil {
+			return fail(ctx.Err())
+		}
+	}
+	stats.compute()
+	return stats, nil
+}
+
+// 计算丢包率和往返时间的统计值
+func (s *PingStats) compute() {
+	if s.Sent > 0 {
+		s.Loss = float64(s.Sent-s.Received) / float64(s.Sent) * 100
+	}
+	if s.Received == 0 {
+		return
+	}
+	var sum float64
+	for _, p := range s.Probes {
+		if p.Lost {
+			continue
+		}
+		if s.Min == 0 || p.RTT < s.Min {
+			s.Min = p.RTT
+		}
+		if p.RTT > s.Max {
+			s.Max = p.RTT
+		}
+		sum += float64(p.RTT)
+	}
+	avg := sum / float64(s.Received)
+	var variance float64
+	for _, p := range s.Probes {
+		if !p.Lost {
+			d := float64(p.RTT) - avg
+			variance += d * d
+		}
+	}
+	s.Avg = time.Duration(avg)
+	s.StdDev = time.Duration(math.Sqrt(variance / float64(s.Received)))
+}
+
+// 并发 Ping 多个地址, 返回的结果以地址为 key
+func PingAll(ctx context.Context, addrs []string, opt PingOptions) map[string]PingResult {
+	results := make(map[string]PingResult, len(addrs))
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, addr := range addrs {
+		wg.Add(1)
+		go func(addr string) {
+			defer wg.Done()
+			stats, err := PingContext(ctx, addr, opt)
+			mu.Lock()
+			results[addr] = PingResult{Stats: stats, Err: err}
+			mu.Unlock()
+		}(addr)
+	}
+	wg.Wait()
+	return results
+}
+
+// 无法发送 Ping 时的错误
+var ErrPingUnavailable = errors.New("ping: no ICMP socket available")
+
+// 先尝试非特权 socket, 失败时使用 raw socket
+func pingAny(ctx context.Context, addr string, opt PingOptions) (*PingStats, error) {
+	opt.Privileged = false
+	stats, err := PingContext(ctx, addr, opt)
+	if err == nil {
+		return stats, nil
+	}
+	opt.Privileged = true
+	stats, err2 := PingContext(ctx, addr, opt)
+	if err2 == nil {
+		return stats, nil
+	}
+	if os.IsPermission(err) || os.IsPermission(err2) {
+		return nil, ErrPingUnavailable
+	}
+	return nil, err2
+}
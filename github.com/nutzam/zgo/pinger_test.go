@@ -0,0 +1,104 @@
+package z_test
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	z "github.com/nutzam/zgo"
+)
+
+// 在回环地址上测试, 没有权限时跳过
+func pingLoopback(t *testing.T, addr string) {
+	for _, privileged := range []bool{false, true} {
+		opt := z.PingOptions{Count: 3, Interval: 10 * time.Millisecond, Privileged: privileged}
+		stats, err := z.PingContext(context.Background(), addr, opt)
+		if err != nil {
+			if os.IsPermission(err) {
+				t.Logf("%s privileged=%v: %v", addr, privileged, err)
+				continue
+			}
+			t.Skipf("%s privileged=%v: %v", addr, privileged, err)
+		}
+		if stats.Sent != 3 || stats.Received != 3 || len(stats.Probes) != 3 {
+			t.Fatalf("%s privileged=%v: unexpected stats %+v", addr, privileged, stats)
+		}
+		if stats.Loss != 0 {
+			t.Errorf("loss should be 0, got %v", stats.Loss)
+		}
+		if stats.Min <= 0 || stats.Min > stats.Avg || stats.Avg > stats.Max {
+			t.Errorf("invalid rtt stats %+v", stats)
+		}
+		return
+	}
+	t.Skip("no ICMP socket available")
+}
+
+func Test_PingContext_IPv4(t *testing.T) {
+	pingLoopback(t, "127.0.0.1")
+}
+
+func Test_PingContext_IPv6(t *testing.T) {
+	pingLoopback(t, "::1")
+}
+
+func Test_PingContext_Cancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	opt := z.PingOptions{Count: 3, Privileged: true}
+	stats, err := z.PingContext(ctx, "127.0.0.1", opt)
+	if err != context.Canceled {
+		t.Fatalf("should be canceled, got %v", err)
+	}
+	if stats == nil || stats.Sent != 0 {
+		t.Errorf("no probe should be sent, got %+v", stats)
+	}
+}
+
+// 等待回复或间隔时取消, 应立即返回已有的统计结果
+func Test_PingContext_CancelWhileWaiting(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	time.AfterFunc(50*time.Millisecond, cancel)
+	// 192.0.2.1 是文档保留地址, 通常不会有回复
+	opt := z.PingOptions{Count: 3, Timeout: 10 * time.Second, Privileged: true}
+	start := time.Now()
+	stats, err := z.PingContext(ctx, "192.0.2.1", opt)
+	if err != nil && err != context.Canceled {
+		t.Skip(err)
+	}
+	if err != context.Canceled {
+		t.Fatalf("should be canceled, got %v", err)
+	}
+	if d := time.Since(start); d > time.Second {
+		t.Errorf("should return once canceled, took %v", d)
+	}
+	if stats.Sent != 1 || len(stats.Probes) != 1 {
+		t.Errorf("unexpected stats %+v", stats)
+	}
+}
+
+func Test_PingAll(t *testing.T) {
+	opt := z.PingOptions{Count: 2, Interval: 10 * time.Millisecond, Privileged: true}
+	results := z.PingAll(context.Background(), []string{"127.0.0.1", "::1", "invalid.invalid"}, opt)
+	if len(results) != 3 {
+		t.Fatalf("should have 3 results, got %d", len(results))
+	}
+	if results["invalid.invalid"].Err == nil {
+		t.Error("invalid host should fail")
+	}
+	r := results["127.0.0.1"]
+	if r.Err != nil {
+		t.Skip(r.Err)
+	}
+	if r.Stats.Received != 2 {
+		t.Errorf("should receive 2 replies, got %d", r.Stats.Received)
+	}
+}
+
+func Test_Ping(t *testing.T) {
+	if !z.Ping("127.0.0.1", 1) {
+		t.Skip("no ICMP socket available")
+	}
+}
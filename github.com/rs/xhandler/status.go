@@ -0,0 +1,76 @@
+package xhandler
+
+import "net/http"
+
+// StatusWriter records the status code sent to the wrapped response writer.
+// Use NewStatusWriter to create one.
+type StatusWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+// Status returns the status code sent with WriteHeader, or http.StatusOK if
+// none was sent.
+func (w *StatusWriter) Status() int {
+	return w.status
+}
+
+// WriteHeader implements http.ResponseWriter interface
+func (w *StatusWriter) WriteHeader(status int) {
+	w.status = status
+	w.ResponseWriter.WriteHeader(status)
+}
+
+// NewStatusWriter wraps w into a StatusWriter, and returns it along with the
+// response writer to pass down to handlers. The latter implements the same
+// optional http.Flusher, http.Hijacker and http.CloseNotifier interfaces as
+// w, so that streaming and websocket handlers keep working.
+func NewStatusWriter(w http.ResponseWriter) (*StatusWriter, http.ResponseWriter) {
+	sw := &StatusWriter{ResponseWriter: w, status: http.StatusOK}
+	f, isFlusher := w.(http.Flusher)
+	h, isHijacker := w.(http.Hijacker)
+	cn, isCloseNotifier := w.(http.CloseNotifier)
+	switch {
+	case isFlusher && isHijacker && isCloseNotifier:
+		return sw, struct {
+			*StatusWriter
+			http.Flusher
+			http.Hijacker
+			http.CloseNotifier
+		}{sw, f, h, cn}
+	case isFlusher && isHijacker:
+		return sw, struct {
+			*StatusWriter
+			http.Flusher
+			http.Hijacker
+		}{sw, f, h}
+	case isFlusher && isCloseNotifier:
+		return sw, struct {
+			*StatusWriter
+			http.Flusher
+			http.CloseNotifier
+		}{sw, f, cn}
+	case isHijacker && isCloseNotifier:
+		return sw, struct {
+			*StatusWriter
+			http.Hijacker
+			http.CloseNotifier
+		}{sw, h, cn}
+	case isFlusher:
+		return sw, struct {
+			*StatusWriter
+			http.Flusher
+		}{sw, f}
+	case isHijacker:
+		return sw, struct {
+			*StatusWriter
+			http.Hijacker
+		}{sw, h}
+	case isCloseNotifier:
+		return sw, struct {
+			*StatusWriter
+			http.CloseNotifier
+		}{sw, cn}
+	}
+	return sw, sw
+}
@@ -0,0 +1,38 @@
+package xhandler
+
+import (
+	"bufio"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type hijackWriter struct {
+	http.ResponseWriter
+}
+
+func (hijackWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return nil, nil, nil
+}
+
+func TestNewStatusWriter(t *testing.T) {
+	rec := httptest.NewRecorder()
+	sw, w := NewStatusWriter(rec)
+	assert.Equal(t, http.StatusOK, sw.Status())
+	_, isFlusher := w.(http.Flusher)
+	_, isHijacker := w.(http.Hijacker)
+	assert.True(t, isFlusher)
+	assert.False(t, isHijacker)
+	w.WriteHeader(http.StatusTeapot)
+	assert.Equal(t, http.StatusTeapot, sw.Status())
+	assert.Equal(t, http.StatusTeapot, rec.Code)
+
+	_, w = NewStatusWriter(hijackWriter{httptest.NewRecorder()})
+	_, isFlusher = w.(http.Flusher)
+	_, isHijacker = w.(http.Hijacker)
+	assert.False(t, isFlusher)
+	assert.True(t, isHijacker)
+}
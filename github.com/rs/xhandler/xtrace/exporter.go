@@ -0,0 +1,87 @@
+package xtrace
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"sync"
+)
+
+// Exporter receives finished spans.
+type Exporter interface {
+	Export(d SpanData) error
+}
+
+// ExporterFunc is an adapter to allow the use of ordinary functions as Exporter.
+type ExporterFunc func(d SpanData) error
+
+// Export calls f(d).
+func (f ExporterFunc) Export(d SpanData) error {
+	return f(d)
+}
+
+// JSONExporter writes spans as JSON lines to a writer.
+type JSONExporter struct {
+	mu  sync.Mutex
+	w   io.Writer
+	enc *json.Encoder
+}
+
+// NewJSONExporter creates an exporter writing one JSON document per span to w.
+func NewJSONExporter(w io.Writer) *JSONExporter {
+	return &JSONExporter{w: w, enc: json.NewEncoder(w)}
+}
+
+// NewFileExporter creates a JSONExporter appending to the file at path.
+func NewFileExporter(path string) (*JSONExporter, error) {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, err
+	}
+	return NewJSONExporter(f), nil
+}
+
+// Export implements Exporter interface
+func (e *JSONExporter) Export(d SpanData) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.enc.Encode(d)
+}
+
+// Close closes the underlying writer if it implements io.Closer.
+func (e *JSONExporter) Close() error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if c, ok := e.w.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
+
+// MemoryExporter stores spans in memory. This exporter is useful for testing.
+type MemoryExporter struct {
+	mu    sync.Mutex
+	spans []SpanData
+}
+
+// Export implements Exporter interface
+func (e *MemoryExporter) Export(d SpanData) error {
+	e.mu.Lock()
+	e.spans = append(e.spans, d)
+	e.mu.Unlock()
+	return nil
+}
+
+// Spans returns the exported spans in the order they finished.
+func (e *MemoryExporter) Spans() []SpanData {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return append([]SpanData(nil), e.spans...)
+}
+
+// Reset removes all stored spans.
+func (e *MemoryExporter) Reset() {
+	e.mu.Lock()
+	e.spans = nil
+	e.mu.Unlock()
+}
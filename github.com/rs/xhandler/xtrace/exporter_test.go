@@ -0,0 +1,51 @@
+package xtrace
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJSONExporter(t *testing.T) {
+	buf := &bytes.Buffer{}
+	e := NewJSONExporter(buf)
+	assert.NoError(t, e.Export(SpanData{TraceID: "t", SpanID: "s", Name: "foo"}))
+	assert.NoError(t, e.Export(SpanData{TraceID: "t", SpanID: "s2", ParentID: "s", Name: "bar", Error: "err"}))
+	assert.Equal(t, `{"trace_id":"t","span_id":"s","name":"foo","start":"0001-01-01T00:00:00Z","end":"0001-01-01T00:00:00Z","duration":0}
+{"trace_id":"t","span_id":"s2","parent_id":"s","name":"bar","start":"0001-01-01T00:00:00Z","end":"0001-01-01T00:00:00Z","duration":0,"error":"err"}
+`, buf.String())
+	assert.NoError(t, e.Close())
+}
+
+func TestFileExporter(t *testing.T) {
+	dir, err := ioutil.TempDir("", "xtrace")
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "spans.json")
+	e, err := NewFileExporter(path)
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.NoError(t, e.Export(SpanData{Name: "foo"}))
+	assert.NoError(t, e.Close())
+	b, err := ioutil.ReadFile(path)
+	assert.NoError(t, err)
+	assert.Contains(t, string(b), `"name":"foo"`)
+
+	_, err = NewFileExporter(filepath.Join(dir, "missing", "spans.json"))
+	assert.Error(t, err)
+}
+
+func TestMemoryExporter(t *testing.T) {
+	e := &MemoryExporter{}
+	e.Export(SpanData{Name: "foo"})
+	assert.Equal(t, []SpanData{{Name: "foo"}}, e.Spans())
+	e.Reset()
+	assert.Len(t, e.Spans(), 0)
+}
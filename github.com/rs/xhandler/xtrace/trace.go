@@ -0,0 +1,232 @@
+// Package xtrace provides distributed tracing for xhandler middleware chains.
+//
+// A span is started for each request going thru the Tracer's Handler and is
+// stored in the request's context. Any code getting this context can record
+// child spans using StartSpan. The trace is propagated to other services using
+// the W3C traceparent header: it is read from incoming requests by the handler
+// and written on outgoing requests by Transport.
+//
+// Finished spans are sent to a pluggable Exporter.
+package xtrace // import "github.com/rs/xhandler/xtrace"
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"sync"
+	"time"
+)
+
+// TraceID is the identifier of a trace.
+type TraceID [16]byte
+
+// String returns the hex encoded trace id.
+func (id TraceID) String() string {
+	return hex.EncodeToString(id[:])
+}
+
+// IsZero returns true if the id is not set.
+func (id TraceID) IsZero() bool {
+	return id == TraceID{}
+}
+
+// SpanID is the identifier of a span.
+type SpanID [8]byte
+
+// String returns the hex encoded span id.
+func (id SpanID) String() string {
+	return hex.EncodeToString(id[:])
+}
+
+// IsZero returns true if the id is not set.
+func (id SpanID) IsZero() bool {
+	return id == SpanID{}
+}
+
+// SpanData is the record of a finished span sent to exporters.
+type SpanData struct {
+	TraceID    string                 `json:"trace_id"`
+	SpanID     string                 `json:"span_id"`
+	ParentID   string                 `json:"parent_id,omitempty"`
+	Name       string                 `json:"name"`
+	Start      time.Time              `json:"start"`
+	End        time.Time              `json:"end"`
+	Duration   time.Duration          `json:"duration"`
+	Attributes map[string]interface{} `json:"attributes,omitempty"`
+	Error      string                 `json:"error,omitempty"`
+}
+
+// Span is a timed operation of a trace.
+//
+// All methods are safe to call on a nil span so instrumented code does not
+// need to check if tracing is enabled.
+type Span struct {
+	tracer   *Tracer
+	traceID  TraceID
+	spanID   SpanID
+	parentID SpanID
+	sampled  bool
+	name     string
+	start    time.Time
+
+	mu    sync.Mutex
+	attrs map[string]interface{}
+	err   string
+	ended bool
+}
+
+type key int
+
+const spanKey key = 0
+
+var now = time.Now
+
+// NewContext returns a copy of the parent context and associates it with the
+// provided span.
+func NewContext(ctx context.Context, s *Span) context.Context {
+	return context.WithValue(ctx, spanKey, s)
+}
+
+// FromContext gets the current span out of the context. It returns nil if no
+// span is stored in the context.
+func FromContext(ctx context.Context) *Span {
+	if ctx == nil {
+		return nil
+	}
+	s, _ := ctx.Value(spanKey).(*Span)
+	return s
+}
+
+// StartSpan starts a child span of the span stored in ctx and returns a copy of
+// ctx holding the new span. If ctx holds no span, the returned span is nil and
+// nothing is recorded.
+func StartSpan(ctx context.Context, name string) (context.Context, *Span) {
+	parent := FromContext(ctx)
+	if parent == nil {
+		return ctx, nil
+	}
+	s := parent.tracer.newSpan(name, parent.traceID, parent.spanID, parent.sampled)
+	return NewContext(ctx, s), s
+}
+
+// TraceID returns the trace id of the span.
+func (s *Span) TraceID() TraceID {
+	if s == nil {
+		return TraceID{}
+	}
+	return s.traceID
+}
+
+// SpanID returns the id of the span.
+func (s *Span) SpanID() SpanID {
+	if s == nil {
+		return SpanID{}
+	}
+	return s.spanID
+}
+
+// Sampled returns true if the span will be exported.
+func (s *Span) Sampled() bool {
+	return s != nil && s.sampled
+}
+
+// SetAttribute sets a key/value attribute on the span.
+func (s *Span) SetAttribute(key string, value interface{}) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	if s.attrs == nil {
+		s.attrs = map[string]interface{}{}
+	}
+	s.attrs[key] = value
+	s.mu.Unlock()
+}
+
+// SetError marks the span as failed with the given error.
+func (s *Span) SetError(err error) {
+	if s == nil || err == nil {
+		return
+	}
+	s.mu.Lock()
+	s.err = err.Error()
+	s.mu.Unlock()
+}
+
+// End finishes the span and sends it to the tracer's exporter. Calling End more
+// than once has no effect.
+func (s *Span) End() {
+	if s == nil {
+		return
+	}
+	end := now()
+	s.mu.Lock()
+	if s.ended {
+		s.mu.Unlock()
+		return
+	}
+	s.ended = true
+	d := SpanData{
+		TraceID:  s.traceID.String(),
+		SpanID:   s.spanID.String(),
+		Name:     s.name,
+		Start:    s.start,
+		End:      end,
+		Duration: end.Sub(s.start),
+		Error:    s.err,
+	}
+	if !s.parentID.IsZero() {
+		d.ParentID = s.parentID.String()
+	}
+	if len(s.attrs) > 0 {
+		d.Attributes = make(map[string]interface{}, len(s.attrs))
+		for k, v := range s.attrs {
+			d.Attributes[k] = v
+		}
+	}
+	s.mu.Unlock()
+	if s.sampled {
+		s.tracer.export(d)
+	}
+}
+
+// traceparent returns the W3C traceparent header value for the span.
+func (s *Span) traceparent() string {
+	flags := "00"
+	if s.sampled {
+		flags = "01"
+	}
+	return "00-" + s.traceID.String() + "-" + s.spanID.String() + "-" + flags
+}
+
+// parseTraceparent parses a W3C traceparent header value.
+func parseTraceparent(h string) (traceID TraceID, parentID SpanID, sampled bool, ok bool) {
+	// version-traceid-parentid-flags
+	if len(h) < 55 || h[2] != '-' || h[35] != '-' || h[52] != '-' {
+		return
+	}
+	version := h[0:2]
+	if version == "ff" || (version == "00" && len(h) != 55) {
+		return
+	}
+	if _, err := hex.Decode(make([]byte, 1), []byte(version)); err != nil {
+		return
+	}
+	if _, err := hex.Decode(traceID[:], []byte(h[3:35])); err != nil || traceID.IsZero() {
+		return
+	}
+	if _, err := hex.Decode(parentID[:], []byte(h[36:52])); err != nil || parentID.IsZero() {
+		return
+	}
+	flags := make([]byte, 1)
+	if _, err := hex.Decode(flags, []byte(h[53:55])); err != nil {
+		return
+	}
+	return traceID, parentID, flags[0]&1 == 1, true
+}
+
+func randomID(b []byte) {
+	if _, err := rand.Read(b); err != nil {
+		panic("xtrace: cannot generate random id: " + err.Error())
+	}
+}
@@ -0,0 +1,100 @@
+package xtrace
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStartSpan(t *testing.T) {
+	e := &MemoryExporter{}
+	tr := NewTracer(e)
+	ctx, root := tr.StartSpan(context.Background(), "root")
+	assert.Equal(t, root, FromContext(ctx))
+	cctx, child := StartSpan(ctx, "child")
+	assert.Equal(t, child, FromContext(cctx))
+	assert.Equal(t, root.TraceID(), child.TraceID())
+	assert.NotEqual(t, root.SpanID(), child.SpanID())
+	child.SetAttribute("foo", "bar")
+	child.SetError(errors.New("some error"))
+	child.End()
+	child.End()
+	root.End()
+	spans := e.Spans()
+	if assert.Len(t, spans, 2) {
+		assert.Equal(t, "child", spans[0].Name)
+		assert.Equal(t, root.SpanID().String(), spans[0].ParentID)
+		assert.Equal(t, root.TraceID().String(), spans[0].TraceID)
+		assert.Equal(t, map[string]interface{}{"foo": "bar"}, spans[0].Attributes)
+		assert.Equal(t, "some error", spans[0].Error)
+		assert.Equal(t, "root", spans[1].Name)
+		assert.Equal(t, "", spans[1].ParentID)
+	}
+}
+
+func TestStartSpanNoParent(t *testing.T) {
+	ctx, s := StartSpan(context.Background(), "orphan")
+	assert.Nil(t, s)
+	assert.Nil(t, FromContext(ctx))
+	assert.Nil(t, FromContext(nil))
+	// Nil spans are safe to use
+	s.SetAttribute("foo", "bar")
+	s.SetError(errors.New("some error"))
+	s.End()
+	assert.True(t, s.TraceID().IsZero())
+	assert.True(t, s.SpanID().IsZero())
+	assert.False(t, s.Sampled())
+}
+
+func TestSpanDuration(t *testing.T) {
+	oldNow := now
+	defer func() { now = oldNow }()
+	t0 := time.Date(2000, 1, 2, 3, 4, 5, 0, time.UTC)
+	now = func() time.Time { return t0 }
+	e := &MemoryExporter{}
+	_, s := NewTracer(e).StartSpan(context.Background(), "root")
+	now = func() time.Time { return t0.Add(time.Second) }
+	s.End()
+	if spans := e.Spans(); assert.Len(t, spans, 1) {
+		assert.Equal(t, t0, spans[0].Start)
+		assert.Equal(t, t0.Add(time.Second), spans[0].End)
+		assert.Equal(t, time.Second, spans[0].Duration)
+	}
+}
+
+func TestParseTraceparent(t *testing.T) {
+	traceID, parentID, sampled, ok := parseTraceparent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	assert.True(t, ok)
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", traceID.String())
+	assert.Equal(t, "00f067aa0ba902b7", parentID.String())
+	assert.True(t, sampled)
+
+	_, _, sampled, ok = parseTraceparent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00")
+	assert.True(t, ok)
+	assert.False(t, sampled)
+
+	for _, h := range []string{
+		"",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7",
+		"00-00000000000000000000000000000000-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01",
+		"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e473g-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra",
+	} {
+		_, _, _, ok = parseTraceparent(h)
+		assert.False(t, ok, h)
+	}
+}
+
+func TestTraceparent(t *testing.T) {
+	s := &Span{sampled: true}
+	s.traceID[0] = 1
+	s.spanID[0] = 2
+	assert.Equal(t, "00-01000000000000000000000000000000-0200000000000000-01", s.traceparent())
+	s.sampled = false
+	assert.Equal(t, "00-01000000000000000000000000000000-0200000000000000-00", s.traceparent())
+}
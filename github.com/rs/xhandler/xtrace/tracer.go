@@ -0,0 +1,147 @@
+package xtrace
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/rs/xhandler"
+)
+
+// TraceparentHeader is the W3C trace context propagation header.
+const TraceparentHeader = "Traceparent"
+
+// Tracer creates spans and sends them to its exporter once finished.
+type Tracer struct {
+	// Exporter receives finished spans. Spans are discarded if nil.
+	Exporter Exporter
+	// Sample decides if a new trace must be exported. Traces started from an
+	// incoming traceparent header follow the sampling decision of the caller.
+	// All traces are sampled if nil.
+	Sample func(r *http.Request) bool
+	// ErrorLog logs the errors returned by the exporter. The standard logger is
+	// used if nil.
+	ErrorLog *log.Logger
+}
+
+// NewTracer creates a tracer exporting all spans to e.
+func NewTracer(e Exporter) *Tracer {
+	return &Tracer{Exporter: e}
+}
+
+func (t *Tracer) newSpan(name string, traceID TraceID, parentID SpanID, sampled bool) *Span {
+	s := &Span{
+		tracer:   t,
+		traceID:  traceID,
+		parentID: parentID,
+		sampled:  sampled,
+		name:     name,
+		start:    now(),
+	}
+	if s.traceID.IsZero() {
+		randomID(s.traceID[:])
+	}
+	randomID(s.spanID[:])
+	return s
+}
+
+// StartSpan starts a new root span. Use the package level StartSpan function to
+// create child spans.
+func (t *Tracer) StartSpan(ctx context.Context, name string) (context.Context, *Span) {
+	s := t.newSpan(name, TraceID{}, SpanID{}, true)
+	return NewContext(ctx, s), s
+}
+
+func (t *Tracer) export(d SpanData) {
+	if t.Exporter == nil {
+		return
+	}
+	if err := t.Exporter.Export(d); err != nil {
+		if t.ErrorLog != nil {
+			t.ErrorLog.Print("xtrace: cannot export span: ", err)
+		} else {
+			log.Print("xtrace: cannot export span: ", err)
+		}
+	}
+}
+
+// Handler returns a handler starting a span for each request. The span continues
+// the trace described by the request's traceparent header if any. The span is
+// named after the request's method and path and records the response status.
+func (t *Tracer) Handler(next xhandler.HandlerC) xhandler.HandlerC {
+	return xhandler.HandlerFuncC(func(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+		traceID, parentID, sampled, ok := parseTraceparent(r.Header.Get(TraceparentHeader))
+		if !ok {
+			sampled = t.Sample == nil || t.Sample(r)
+		}
+		s := t.newSpan(r.Method+" "+r.URL.Path, traceID, parentID, sampled)
+		s.SetAttribute("http.method", r.Method)
+		s.SetAttribute("http.url", r.URL.String())
+		sw, ww := xhandler.NewStatusWriter(w)
+		defer func() {
+			status := sw.Status()
+			s.SetAttribute("http.status_code", status)
+			if status >= http.StatusInternalServerError {
+				s.SetError(errStatus(status))
+			}
+			s.End()
+		}()
+		next.ServeHTTPC(NewContext(ctx, s), ww, r)
+	})
+}
+
+// Transport is an http.RoundTripper recording a child span for each request and
+// propagating the trace to the server thru the traceparent header. The parent
+// span is taken from the request's context.
+type Transport struct {
+	// Base is the underlying round tripper. http.DefaultTransport is used if nil.
+	Base http.RoundTripper
+}
+
+// RoundTrip implements http.RoundTripper interface
+func (t *Transport) RoundTrip(r *http.Request) (*http.Response, error) {
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	_, s := StartSpan(r.Context(), "HTTP "+r.Method)
+	if s == nil {
+		return base.RoundTrip(r)
+	}
+	defer s.End()
+	s.SetAttribute("http.method", r.Method)
+	s.SetAttribute("http.url", r.URL.String())
+	// RoundTrippers must not modify the request
+	r2 := new(http.Request)
+	*r2 = *r
+	r2.Header = make(http.Header, len(r.Header)+1)
+	for k, v := range r.Header {
+		r2.Header[k] = v
+	}
+	r2.Header.Set(TraceparentHeader, s.traceparent())
+	res, err := base.RoundTrip(r2)
+	if err != nil {
+		s.SetError(err)
+		return nil, err
+	}
+	s.SetAttribute("http.status_code", res.StatusCode)
+	if res.StatusCode >= http.StatusInternalServerError {
+		s.SetError(errStatus(res.StatusCode))
+	}
+	return res, nil
+}
+
+// Inject sets the traceparent header of h from the span stored in ctx.
+// It is useful to propagate the trace thru clients not using Transport.
+func Inject(ctx context.Context, h http.Header) {
+	if s := FromContext(ctx); s != nil {
+		h.Set(TraceparentHeader, s.traceparent())
+	}
+}
+
+type errStatus int
+
+func (e errStatus) Error() string {
+	return "HTTP status " + strconv.Itoa(int(e))
+}
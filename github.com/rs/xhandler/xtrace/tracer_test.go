@@ -0,0 +1,135 @@
+package xtrace
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/rs/xhandler"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHandler(t *testing.T) {
+	e := &MemoryExporter{}
+	tr := NewTracer(e)
+	c := xhandler.Chain{}
+	c.UseC(tr.Handler)
+	h := c.HandlerFC(func(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+		_, s := StartSpan(ctx, "db")
+		s.End()
+		w.WriteHeader(http.StatusBadGateway)
+	})
+	w := httptest.NewRecorder()
+	r, _ := http.NewRequest("GET", "http://example.com/foo?bar=baz", nil)
+	r.Header.Set("Traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	h.ServeHTTP(w, r)
+	spans := e.Spans()
+	if assert.Len(t, spans, 2) {
+		assert.Equal(t, "db", spans[0].Name)
+		assert.Equal(t, spans[1].SpanID, spans[0].ParentID)
+		assert.Equal(t, "GET /foo", spans[1].Name)
+		assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", spans[1].TraceID)
+		assert.Equal(t, "00f067aa0ba902b7", spans[1].ParentID)
+		assert.Equal(t, "HTTP status 502", spans[1].Error)
+		assert.Equal(t, map[string]interface{}{
+			"http.method":      "GET",
+			"http.url":         "http://example.com/foo?bar=baz",
+			"http.status_code": 502,
+		}, spans[1].Attributes)
+	}
+}
+
+func TestHandlerNotSampled(t *testing.T) {
+	e := &MemoryExporter{}
+	tr := NewTracer(e)
+	tr.Sample = func(r *http.Request) bool { return r.URL.Path != "/health" }
+	h := tr.Handler(xhandler.HandlerFuncC(func(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+		assert.False(t, FromContext(ctx).Sampled())
+	}))
+	r, _ := http.NewRequest("GET", "/health", nil)
+	h.ServeHTTPC(context.Background(), httptest.NewRecorder(), r)
+	r, _ = http.NewRequest("GET", "/", nil)
+	r.Header.Set("Traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00")
+	h.ServeHTTPC(context.Background(), httptest.NewRecorder(), r)
+	assert.Len(t, e.Spans(), 0)
+}
+
+func TestTransport(t *testing.T) {
+	e := &MemoryExporter{}
+	tr := NewTracer(e)
+	var traceparent string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		traceparent = r.Header.Get("Traceparent")
+	}))
+	defer ts.Close()
+	client := &http.Client{Transport: &Transport{}}
+
+	// Without a span in the context, nothing is recorded nor propagated
+	r, _ := http.NewRequest("GET", ts.URL, nil)
+	res, err := client.Do(r)
+	if assert.NoError(t, err) {
+		res.Body.Close()
+	}
+	assert.Equal(t, "", traceparent)
+	assert.Len(t, e.Spans(), 0)
+
+	ctx, root := tr.StartSpan(context.Background(), "root")
+	r, _ = http.NewRequest("GET", ts.URL, nil)
+	res, err = client.Do(r.WithContext(ctx))
+	if assert.NoError(t, err) {
+		res.Body.Close()
+	}
+	assert.Equal(t, "", r.Header.Get("Traceparent"))
+	spans := e.Spans()
+	if assert.Len(t, spans, 1) {
+		assert.Equal(t, "HTTP GET", spans[0].Name)
+		assert.Equal(t, root.SpanID().String(), spans[0].ParentID)
+		assert.Equal(t, 200, spans[0].Attributes["http.status_code"])
+		assert.Equal(t, "00-"+spans[0].TraceID+"-"+spans[0].SpanID+"-01", traceparent)
+	}
+}
+
+func TestTransportError(t *testing.T) {
+	e := &MemoryExporter{}
+	ctx, _ := NewTracer(e).StartSpan(context.Background(), "root")
+	client := &http.Client{Transport: &Transport{Base: roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+		return nil, errors.New("some error")
+	})}}
+	r, _ := http.NewRequest("GET", "http://example.com", nil)
+	_, err := client.Do(r.WithContext(ctx))
+	assert.Error(t, err)
+	if spans := e.Spans(); assert.Len(t, spans, 1) {
+		assert.Equal(t, "some error", spans[0].Error)
+	}
+}
+
+func TestInject(t *testing.T) {
+	h := http.Header{}
+	Inject(context.Background(), h)
+	assert.Equal(t, "", h.Get("Traceparent"))
+	ctx, s := NewTracer(nil).StartSpan(context.Background(), "root")
+	Inject(ctx, h)
+	assert.Equal(t, s.traceparent(), h.Get("Traceparent"))
+	s.End()
+}
+
+func TestExportError(t *testing.T) {
+	buf := &bytes.Buffer{}
+	tr := &Tracer{
+		Exporter: ExporterFunc(func(d SpanData) error { return errors.New("some error") }),
+		ErrorLog: log.New(buf, "", 0),
+	}
+	_, s := tr.StartSpan(context.Background(), "root")
+	s.End()
+	assert.Equal(t, "xtrace: cannot export span: some error\n", buf.String())
+}
+
+type roundTripperFunc func(r *http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(r *http.Request) (*http.Response, error) {
+	return f(r)
+}
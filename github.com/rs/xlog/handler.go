@@ -7,6 +7,7 @@ import (
 	"net"
 	"net/http"
 
+	"github.com/rs/xhandler"
 	"github.com/rs/xid"
 )
 
@@ -187,3 +188,27 @@ func RequestIDHandler(name, headerName string) func(next http.Handler) http.Hand
 		})
 	}
 }
+
+// TailSampleHandler returns a handler releasing the debug messages buffered by o
+// for the request at the end of the request. Buffered messages are forwarded if
+// the response status is 500 or higher. It must be installed after RequestIDHandler.
+func TailSampleHandler(o *TailSampleOutput) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			id, ok := IDFromContext(r.Context())
+			if !ok {
+				next.ServeHTTP(w, r)
+				return
+			}
+			sw, ww := xhandler.NewStatusWriter(w)
+			// A panicking handler is considered failed, and its buffered
+			// messages must be released either way.
+			failed := true
+			defer func() {
+				o.Done(id, failed)
+			}()
+			next.ServeHTTP(ww, r)
+			failed = sw.Status() >= http.StatusInternalServerError
+		})
+	}
+}
@@ -160,3 +160,27 @@ func RequestIDHandler(name, headerName string) func(next xhandler.HandlerC) xhan
 		})
 	}
 }
+
+// TailSampleHandler returns a handler releasing the debug messages buffered by o
+// for the request at the end of the request. Buffered messages are forwarded if
+// the response status is 500 or higher. It must be installed after RequestIDHandler.
+func TailSampleHandler(o *TailSampleOutput) func(next xhandler.HandlerC) xhandler.HandlerC {
+	return func(next xhandler.HandlerC) xhandler.HandlerC {
+		return xhandler.HandlerFuncC(func(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+			id, ok := IDFromContext(ctx)
+			if !ok {
+				next.ServeHTTPC(ctx, w, r)
+				return
+			}
+			sw, ww := xhandler.NewStatusWriter(w)
+			// A panicking handler is considered failed, and its buffered
+			// messages must be released either way.
+			failed := true
+			defer func() {
+				o.Done(id, failed)
+			}()
+			next.ServeHTTPC(ctx, ww, r)
+			failed = sw.Status() >= http.StatusInternalServerError
+		})
+	}
+}
@@ -144,3 +144,41 @@ func TestRequestIDHandler(t *testing.T) {
 	w := httptest.NewRecorder()
 	h.ServeHTTP(w, r)
 }
+
+func TestTailSampleHandler(t *testing.T) {
+	o := &RecorderOutput{}
+	ts := NewTailSampleOutput("id", o)
+	status := http.StatusOK
+	h := TailSampleHandler(ts)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		FromRequest(r).Debug("some debug")
+		w.WriteHeader(status)
+	}))
+	h = RequestIDHandler("id", "")(h)
+	h = NewHandler(Config{Level: LevelDebug, Output: ts})(h)
+	h.ServeHTTP(httptest.NewRecorder(), &http.Request{})
+	assert.Len(t, o.Messages, 0)
+	status = http.StatusInternalServerError
+	h.ServeHTTP(httptest.NewRecorder(), &http.Request{})
+	if assert.Len(t, o.Messages, 1) {
+		assert.Equal(t, "some debug", o.Messages[0]["message"])
+	}
+	assert.Len(t, ts.requests, 0)
+}
+
+func TestTailSampleHandlerPanic(t *testing.T) {
+	o := &RecorderOutput{}
+	ts := NewTailSampleOutput("id", o)
+	h := TailSampleHandler(ts)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		FromRequest(r).Debug("some debug")
+		panic("boom")
+	}))
+	h = RequestIDHandler("id", "")(h)
+	h = NewHandler(Config{Level: LevelDebug, Output: ts})(h)
+	assert.Panics(t, func() {
+		h.ServeHTTP(httptest.NewRecorder(), &http.Request{})
+	})
+	if assert.Len(t, o.Messages, 1) {
+		assert.Equal(t, "some debug", o.Messages[0]["message"])
+	}
+	assert.Len(t, ts.requests, 0)
+}
@@ -0,0 +1,367 @@
+package xlog
+
+import (
+	"compress/gzip"
+	"errors"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// backupTimeFormat is the time format used to suffix rotated files.
+const backupTimeFormat = "20060102T150405.000000000"
+
+// ErrFileClosed is returned when writing to a closed FileWriter.
+var ErrFileClosed = errors.New("file closed")
+
+// FileConfig defines a rotating file's configuration.
+type FileConfig struct {
+	// Path is the path of the log file. Rotated files are stored next to it
+	// with a timestamp suffix.
+	Path string
+	// Mode is the permission used to create the file (default 0644).
+	Mode os.FileMode
+	// MaxSize is the size in bytes after which the file is rotated. If 0, the
+	// file is never rotated on size.
+	MaxSize int64
+	// Interval is the duration after which the file is rotated. If 0, the file
+	// is never rotated on time.
+	Interval time.Duration
+	// MaxBackups is the maximum number of rotated files to keep. If 0, all
+	// rotated files are kept.
+	MaxBackups int
+	// Compress enables gzip compression of rotated files. Compression happens in
+	// a dedicated go routine.
+	Compress bool
+	// NoSIGHUP disables the reopening of the file when the process receives a
+	// SIGHUP signal.
+	NoSIGHUP bool
+	// Format creates the output used to serialize messages into the file.
+	// NewJSONOutput is used if not set. This setting is only used by NewFileOutput.
+	Format func(w io.Writer) Output
+}
+
+// FileWriter is an io.Writer writing to a file rotated on size and/or time.
+//
+// Unless disabled, the file is reopened when the process receives SIGHUP so it
+// can be used with external log rotation tools.
+type FileWriter struct {
+	c  FileConfig
+	mu sync.Mutex
+	// f is nil if the file could not be reopened after a rotation, in which
+	// case it is opened again on the next write
+	f      *os.File
+	closed bool
+	size   int64
+	opened time.Time
+	// bg serializes compression and cleanup of rotated files
+	bg   sync.Mutex
+	wg   sync.WaitGroup
+	sig  chan os.Signal
+	stop chan struct{}
+}
+
+// NewFileWriter opens the file described by c for appending, creating it if needed.
+func NewFileWriter(c FileConfig) (*FileWriter, error) {
+	if c.Mode == 0 {
+		c.Mode = 0644
+	}
+	w := &FileWriter{c: c}
+	if err := w.open(); err != nil {
+		return nil, err
+	}
+	if !c.NoSIGHUP {
+		sig := make(chan os.Signal, 1)
+		stop := make(chan struct{})
+		w.sig, w.stop = sig, stop
+		signal.Notify(sig, syscall.SIGHUP)
+		go func() {
+			for {
+				select {
+				case <-sig:
+					if err := w.Reopen(); err != nil {
+						critialLogger.Print("cannot reopen log file: ", err.Error())
+					}
+				case <-stop:
+					return
+				}
+			}
+		}()
+	}
+	return w, nil
+}
+
+// open opens the file and initializes its size. Must be called with w.mu held.
+func (w *FileWriter) open() error {
+	f, err := os.OpenFile(w.c.Path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, w.c.Mode)
+	if err != nil {
+		return err
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+	w.f = f
+	w.size = fi.Size()
+	w.opened = now()
+	return nil
+}
+
+// Write implements io.Writer interface
+func (w *FileWriter) Write(p []byte) (n int, err error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed {
+		return 0, ErrFileClosed
+	}
+	if w.f != nil && w.shouldRotate(int64(len(p))) {
+		if err = w.rotate(); err != nil {
+			// Keep logging to whatever file could be reopened
+			critialLogger.Print("cannot rotate log file: ", err.Error())
+		}
+	}
+	if w.f == nil {
+		if err = w.open(); err != nil {
+			return 0, err
+		}
+	}
+	n, err = w.f.Write(p)
+	w.size += int64(n)
+	return
+}
+
+func (w *FileWriter) shouldRotate(n int64) bool {
+	if w.c.MaxSize > 0 && w.size > 0 && w.size+n > w.c.MaxSize {
+		return true
+	}
+	if w.c.Interval > 0 && now().Sub(w.opened) >= w.c.Interval {
+		return true
+	}
+	return false
+}
+
+// Rotate forces the rotation of the file.
+func (w *FileWriter) Rotate() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed {
+		return ErrFileClosed
+	}
+	if w.f == nil {
+		if err := w.open(); err != nil {
+			return err
+		}
+	}
+	return w.rotate()
+}
+
+// rotate moves the current file to its backup name and opens a new file. Must
+// be called with w.mu held and the file open.
+//
+// If the file cannot be moved, the original path is reopened so logging
+// continues, and the error is returned.
+func (w *FileWriter) rotate() error {
+	err := w.f.Close()
+	w.f = nil
+	var backup string
+	if err == nil {
+		// Make sure not to overwrite a previous backup if the clock did not move
+		t := now().UTC()
+		backup = w.c.Path + "." + t.Format(backupTimeFormat)
+		for fileExists(backup) || fileExists(backup+".gz") {
+			t = t.Add(time.Nanosecond)
+			backup = w.c.Path + "." + t.Format(backupTimeFormat)
+		}
+		if err = os.Rename(w.c.Path, backup); os.IsNotExist(err) {
+			err = nil
+		}
+	}
+	if openErr := w.open(); err == nil {
+		err = openErr
+	}
+	if err != nil {
+		return err
+	}
+	w.wg.Add(1)
+	go func() {
+		defer w.wg.Done()
+		w.bg.Lock()
+		defer w.bg.Unlock()
+		if w.c.Compress {
+			if err := compressFile(backup); err != nil {
+				critialLogger.Print("cannot compress log file: ", err.Error())
+			}
+		}
+		if err := w.removeBackups(); err != nil {
+			critialLogger.Print("cannot remove old log files: ", err.Error())
+		}
+	}()
+	return nil
+}
+
+// Reopen closes and reopens the file. It is used when the file has been moved
+// by an external tool.
+func (w *FileWriter) Reopen() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed {
+		return ErrFileClosed
+	}
+	if w.f != nil {
+		// The file is reopened even if closing failed, the old
+		// descriptor being unusable anyway.
+		err := w.f.Close()
+		w.f = nil
+		if openErr := w.open(); openErr != nil {
+			return openErr
+		}
+		return err
+	}
+	return w.open()
+}
+
+// Close closes the file and waits for pending compressions to complete.
+func (w *FileWriter) Close() (err error) {
+	w.mu.Lock()
+	w.closed = true
+	if w.f != nil {
+		err = w.f.Close()
+		w.f = nil
+	}
+	if w.stop != nil {
+		signal.Stop(w.sig)
+		close(w.stop)
+		w.stop = nil
+	}
+	w.mu.Unlock()
+	w.wg.Wait()
+	return
+}
+
+// backups returns the list of rotated files sorted from the oldest to the newest.
+func (w *FileWriter) backups() ([]string, error) {
+	names, err := filepath.Glob(w.c.Path + ".*")
+	if err != nil {
+		return nil, err
+	}
+	prefix := w.c.Path + "."
+	backups := names[:0]
+	for _, name := range names {
+		ts := strings.TrimSuffix(strings.TrimPrefix(name, prefix), ".gz")
+		if _, err := time.Parse(backupTimeFormat, ts); err == nil {
+			backups = append(backups, name)
+		}
+	}
+	sort.Sort(byBackupTime(backups))
+	return backups, nil
+}
+
+// byBackupTime sorts rotated file names by their timestamp, whether or not
+// they are compressed.
+type byBackupTime []string
+
+func (b byBackupTime) Len() int      { return len(b) }
+func (b byBackupTime) Swap(i, j int) { b[i], b[j] = b[j], b[i] }
+func (b byBackupTime) Less(i, j int) bool {
+	return strings.TrimSuffix(b[i], ".gz") < strings.TrimSuffix(b[j], ".gz")
+}
+
+func (w *FileWriter) removeBackups() error {
+	if w.c.MaxBackups <= 0 {
+		return nil
+	}
+	backups, err := w.backups()
+	if err != nil {
+		return err
+	}
+	for len(backups) > w.c.MaxBackups {
+		if err := os.Remove(backups[0]); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		backups = backups[1:]
+	}
+	return nil
+}
+
+func fileExists(name string) bool {
+	_, err := os.Stat(name)
+	return err == nil
+}
+
+// compressFile gzips name into name.gz and removes name.
+func compressFile(name string) (err error) {
+	src, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	fi, err := src.Stat()
+	if err != nil {
+		return err
+	}
+	dst, err := os.OpenFile(name+".gz", os.O_WRONLY|os.O_CREATE|os.O_TRUNC, fi.Mode())
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			os.Remove(name + ".gz")
+		}
+	}()
+	gz := gzip.NewWriter(dst)
+	if _, err = io.Copy(gz, src); err != nil {
+		dst.Close()
+		return err
+	}
+	if err = gz.Close(); err != nil {
+		dst.Close()
+		return err
+	}
+	if err = dst.Close(); err != nil {
+		return err
+	}
+	return os.Remove(name)
+}
+
+// FileOutput is an Output writing messages to a rotating file.
+//
+// Writing to a file may block during rotation, so FileOutput should be wrapped
+// in an OutputChannel:
+//
+//     o, err := xlog.NewFileOutput(xlog.FileConfig{Path: "/var/log/app.log", MaxSize: 100 << 20})
+//     if err != nil {
+//         log.Fatal(err)
+//     }
+//     conf := xlog.Config{
+//         Output: xlog.NewOutputChannel(o),
+//     }
+type FileOutput struct {
+	*FileWriter
+	output Output
+}
+
+// NewFileOutput creates an output writing messages serialized by c.Format to
+// the file described by c.
+func NewFileOutput(c FileConfig) (*FileOutput, error) {
+	w, err := NewFileWriter(c)
+	if err != nil {
+		return nil, err
+	}
+	format := c.Format
+	if format == nil {
+		format = NewJSONOutput
+	}
+	return &FileOutput{FileWriter: w, output: format(w)}, nil
+}
+
+// Write implements the Output interface
+func (o *FileOutput) Write(fields map[string]interface{}) error {
+	return o.output.Write(fields)
+}
@@ -0,0 +1,172 @@
+package xlog
+
+import (
+	"compress/gzip"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFileWriterSizeRotation(t *testing.T) {
+	dir, err := ioutil.TempDir("", "xlog")
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer os.RemoveAll(dir)
+	oldNow := now
+	defer func() { now = oldNow }()
+	t0 := time.Date(2000, 1, 2, 3, 4, 5, 0, time.UTC)
+	now = func() time.Time { return t0 }
+
+	path := filepath.Join(dir, "test.log")
+	w, err := NewFileWriter(FileConfig{Path: path, MaxSize: 10, MaxBackups: 2, NoSIGHUP: true})
+	if !assert.NoError(t, err) {
+		return
+	}
+	for _, s := range []string{"aaaaaa\n", "bbbbbb\n", "cccccc\n", "dddddd\n"} {
+		_, err = w.Write([]byte(s))
+		assert.NoError(t, err)
+	}
+	assert.NoError(t, w.Close())
+	b, err := ioutil.ReadFile(path)
+	assert.NoError(t, err)
+	assert.Equal(t, "dddddd\n", string(b))
+	backups, err := w.backups()
+	assert.NoError(t, err)
+	if assert.Len(t, backups, 2) {
+		b, _ = ioutil.ReadFile(backups[0])
+		assert.Equal(t, "bbbbbb\n", string(b))
+		b, _ = ioutil.ReadFile(backups[1])
+		assert.Equal(t, "cccccc\n", string(b))
+	}
+	_, err = w.Write([]byte("closed"))
+	assert.Equal(t, ErrFileClosed, err)
+}
+
+func TestFileWriterRotationFailure(t *testing.T) {
+	dir, err := ioutil.TempDir("", "xlog")
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	sub := filepath.Join(dir, "sub")
+	assert.NoError(t, os.Mkdir(sub, 0755))
+	path := filepath.Join(sub, "test.log")
+	w, err := NewFileWriter(FileConfig{Path: path, MaxSize: 10, NoSIGHUP: true})
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer w.Close()
+	_, err = w.Write([]byte("aaaaaa\n"))
+	assert.NoError(t, err)
+
+	// The file cannot be reopened while the directory is missing
+	assert.NoError(t, os.RemoveAll(sub))
+	_, err = w.Write([]byte("bbbbbb\n"))
+	assert.Error(t, err)
+
+	// Logging resumes once the file can be opened again
+	assert.NoError(t, os.Mkdir(sub, 0755))
+	_, err = w.Write([]byte("cccccc\n"))
+	assert.NoError(t, err)
+	b, err := ioutil.ReadFile(path)
+	assert.NoError(t, err)
+	assert.Equal(t, "cccccc\n", string(b))
+}
+
+func TestFileWriterTimeRotation(t *testing.T) {
+	dir, err := ioutil.TempDir("", "xlog")
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer os.RemoveAll(dir)
+	oldNow := now
+	defer func() { now = oldNow }()
+	t0 := time.Date(2000, 1, 2, 3, 4, 5, 0, time.UTC)
+	now = func() time.Time { return t0 }
+
+	path := filepath.Join(dir, "test.log")
+	w, err := NewFileWriter(FileConfig{Path: path, Interval: time.Hour, Compress: true, NoSIGHUP: true})
+	if !assert.NoError(t, err) {
+		return
+	}
+	w.Write([]byte("foo\n"))
+	now = func() time.Time { return t0.Add(time.Hour) }
+	w.Write([]byte("bar\n"))
+	assert.NoError(t, w.Close())
+
+	b, err := ioutil.ReadFile(path)
+	assert.NoError(t, err)
+	assert.Equal(t, "bar\n", string(b))
+	backup := path + "." + t0.Add(time.Hour).Format(backupTimeFormat)
+	_, err = os.Stat(backup)
+	assert.True(t, os.IsNotExist(err))
+	f, err := os.Open(backup + ".gz")
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer f.Close()
+	gz, err := gzip.NewReader(f)
+	if !assert.NoError(t, err) {
+		return
+	}
+	b, err = ioutil.ReadAll(gz)
+	assert.NoError(t, err)
+	assert.Equal(t, "foo\n", string(b))
+}
+
+func TestFileWriterReopen(t *testing.T) {
+	dir, err := ioutil.TempDir("", "xlog")
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "test.log")
+	w, err := NewFileWriter(FileConfig{Path: path})
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer w.Close()
+	w.Write([]byte("foo\n"))
+	assert.NoError(t, os.Rename(path, path+".old"))
+	assert.NoError(t, syscall.Kill(os.Getpid(), syscall.SIGHUP))
+	for i := 0; i < 100; i++ {
+		if _, err = os.Stat(path); err == nil {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	w.Write([]byte("bar\n"))
+	b, err := ioutil.ReadFile(path)
+	assert.NoError(t, err)
+	assert.Equal(t, "bar\n", string(b))
+}
+
+func TestFileOutput(t *testing.T) {
+	dir, err := ioutil.TempDir("", "xlog")
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "test.log")
+	o, err := NewFileOutput(FileConfig{Path: path, NoSIGHUP: true})
+	if !assert.NoError(t, err) {
+		return
+	}
+	oc := NewOutputChannel(o)
+	oc.Write(F{"message": "some message", "level": "info"})
+	oc.Close()
+	assert.NoError(t, o.Close())
+	b, err := ioutil.ReadFile(path)
+	assert.NoError(t, err)
+	assert.Equal(t, "{\"level\":\"info\",\"message\":\"some message\"}\n", string(b))
+
+	_, err = NewFileOutput(FileConfig{Path: filepath.Join(dir, "missing", "test.log")})
+	assert.Error(t, err)
+}
@@ -0,0 +1,253 @@
+package xlog
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// maxSampleKeys is the maximum number of distinct keys a sample output tracks
+// before its counters are reset.
+const maxSampleKeys = 10000
+
+// SampleByLevel is a sample key function grouping messages by level.
+func SampleByLevel(fields map[string]interface{}) string {
+	lvl, _ := fields[KeyLevel].(string)
+	return lvl
+}
+
+// SampleByMessage is a sample key function grouping messages by level and message.
+func SampleByMessage(fields map[string]interface{}) string {
+	lvl, _ := fields[KeyLevel].(string)
+	msg, _ := fields[KeyMessage].(string)
+	return lvl + "\x00" + msg
+}
+
+type sampleOutput struct {
+	n      uint64
+	key    func(fields map[string]interface{}) string
+	output Output
+	mu     sync.Mutex
+	counts map[string]uint64
+}
+
+// NewSampleOutput returns an output forwarding only one message out of n for
+// each key returned by the key function. The first message of each key is always
+// forwarded. If key is nil, messages are grouped using SampleByLevel.
+func NewSampleOutput(n int, key func(fields map[string]interface{}) string, o Output) Output {
+	if key == nil {
+		key = SampleByLevel
+	}
+	if n < 1 {
+		n = 1
+	}
+	return &sampleOutput{
+		n:      uint64(n),
+		key:    key,
+		output: o,
+		counts: map[string]uint64{},
+	}
+}
+
+func (s *sampleOutput) Write(fields map[string]interface{}) error {
+	k := s.key(fields)
+	s.mu.Lock()
+	if len(s.counts) >= maxSampleKeys {
+		if _, found := s.counts[k]; !found {
+			s.counts = map[string]uint64{}
+		}
+	}
+	c := s.counts[k]
+	s.counts[k] = c + 1
+	s.mu.Unlock()
+	if c%s.n != 0 {
+		return nil
+	}
+	return s.output.Write(fields)
+}
+
+type rateLimitOutput struct {
+	rate    float64
+	burst   float64
+	output  Output
+	mu      sync.Mutex
+	tokens  float64
+	last    time.Time
+	dropped int
+}
+
+// NewRateLimitOutput returns an output forwarding at most rate messages per second
+// to o with bursts of up to burst messages. Messages over the limit are discarded.
+// Once messages are allowed again, a warning message with the number of dropped
+// messages in the "dropped" field is sent before the next forwarded message.
+func NewRateLimitOutput(rate float64, burst int, o Output) Output {
+	if burst < 1 {
+		burst = 1
+	}
+	return &rateLimitOutput{
+		rate:   rate,
+		burst:  float64(burst),
+		output: o,
+		tokens: float64(burst),
+		last:   now(),
+	}
+}
+
+func (r *rateLimitOutput) Write(fields map[string]interface{}) error {
+	r.mu.Lock()
+	t := now()
+	if elapsed := t.Sub(r.last).Seconds(); elapsed > 0 {
+		r.tokens += elapsed * r.rate
+		if r.tokens > r.burst {
+			r.tokens = r.burst
+		}
+	}
+	r.last = t
+	if r.tokens < 1 {
+		r.dropped++
+		r.mu.Unlock()
+		return nil
+	}
+	r.tokens--
+	dropped := r.dropped
+	r.dropped = 0
+	r.mu.Unlock()
+	if dropped > 0 {
+		if err := r.output.Write(map[string]interface{}{
+			KeyTime:    t,
+			KeyLevel:   LevelWarn.String(),
+			KeyMessage: fmt.Sprintf("dropped %d messages", dropped),
+			"dropped":  dropped,
+		}); err != nil {
+			return err
+		}
+	}
+	return r.output.Write(fields)
+}
+
+// TailSampleOutput buffers debug messages per request and only forwards them if
+// the request ends in an error. Messages are attributed to a request using the
+// value of the Field field, as set by RequestIDHandler. Messages without this
+// field and messages with a level other than debug are forwarded immediately.
+//
+// A request is considered failed as soon as an error or fatal message is logged
+// for it or when it is marked as such with Done. Buffered messages are flushed
+// before the error message and further debug messages of the failed request are
+// forwarded directly.
+//
+// Because Done is called from the request's go routine, TailSampleOutput must
+// be placed in front of the OutputChannel, not behind it:
+//
+//     o := xlog.NewTailSampleOutput("req_id", xlog.NewOutputChannel(xlog.NewConsoleOutput()))
+type TailSampleOutput struct {
+	// Field is the name of the field holding the request id.
+	Field string
+	// MaxMessages is the maximum number of debug messages buffered per request.
+	// The oldest messages are discarded once this limit is reached.
+	MaxMessages int
+	// MaxRequests is the maximum number of requests tracked at once. Debug
+	// messages of requests above this limit are discarded.
+	MaxRequests int
+	// Output is the output to forward messages to.
+	Output Output
+
+	mu       sync.Mutex
+	requests map[interface{}]*tailRequest
+}
+
+type tailRequest struct {
+	failed   bool
+	messages []map[string]interface{}
+}
+
+// NewTailSampleOutput creates a TailSampleOutput using field as request id field
+// with a buffer of 100 messages per request and up to 10000 concurrent requests.
+func NewTailSampleOutput(field string, o Output) *TailSampleOutput {
+	return &TailSampleOutput{
+		Field:       field,
+		MaxMessages: 100,
+		MaxRequests: 10000,
+		Output:      o,
+	}
+}
+
+// Write implements the Output interface
+func (t *TailSampleOutput) Write(fields map[string]interface{}) error {
+	id, found := fields[t.Field]
+	if !found {
+		return t.Output.Write(fields)
+	}
+	switch fields[KeyLevel] {
+	case "debug":
+		t.mu.Lock()
+		req := t.request(id)
+		if req == nil || req.failed {
+			t.mu.Unlock()
+			if req == nil {
+				return nil
+			}
+			return t.Output.Write(fields)
+		}
+		if t.MaxMessages > 0 && len(req.messages) >= t.MaxMessages {
+			copy(req.messages, req.messages[1:])
+			req.messages = req.messages[:len(req.messages)-1]
+		}
+		req.messages = append(req.messages, fields)
+		t.mu.Unlock()
+		return nil
+	case "error", "fatal":
+		if err := t.fail(id); err != nil {
+			return err
+		}
+	}
+	return t.Output.Write(fields)
+}
+
+// request returns the buffer for id, creating it if necessary. It returns nil if
+// the maximum number of tracked requests is reached. Must be called with t.mu held.
+func (t *TailSampleOutput) request(id interface{}) *tailRequest {
+	if t.requests == nil {
+		t.requests = map[interface{}]*tailRequest{}
+	}
+	req := t.requests[id]
+	if req == nil {
+		if t.MaxRequests > 0 && len(t.requests) >= t.MaxRequests {
+			return nil
+		}
+		req = &tailRequest{}
+		t.requests[id] = req
+	}
+	return req
+}
+
+// fail marks the request as failed and flushes its buffered messages.
+func (t *TailSampleOutput) fail(id interface{}) (err error) {
+	t.mu.Lock()
+	req := t.request(id)
+	if req == nil || req.failed {
+		t.mu.Unlock()
+		return nil
+	}
+	req.failed = true
+	messages := req.messages
+	req.messages = nil
+	t.mu.Unlock()
+	for _, m := range messages {
+		if e := t.Output.Write(m); e != nil {
+			err = e
+		}
+	}
+	return
+}
+
+// Done releases the messages buffered for the request id. If failed is true,
+// the buffered messages are forwarded, otherwise they are discarded.
+func (t *TailSampleOutput) Done(id interface{}, failed bool) (err error) {
+	if failed {
+		err = t.fail(id)
+	}
+	t.mu.Lock()
+	delete(t.requests, id)
+	t.mu.Unlock()
+	return
+}
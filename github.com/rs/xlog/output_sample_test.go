@@ -0,0 +1,133 @@
+package xlog
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSampleOutput(t *testing.T) {
+	o := &RecorderOutput{}
+	s := NewSampleOutput(3, nil, o)
+	for i := 0; i < 7; i++ {
+		assert.NoError(t, s.Write(F{"level": "info", "i": i}))
+	}
+	assert.NoError(t, s.Write(F{"level": "error", "i": 7}))
+	assert.Equal(t, []F{
+		{"level": "info", "i": 0},
+		{"level": "info", "i": 3},
+		{"level": "info", "i": 6},
+		{"level": "error", "i": 7},
+	}, o.Messages)
+}
+
+func TestSampleOutputByMessage(t *testing.T) {
+	o := &RecorderOutput{}
+	s := NewSampleOutput(2, SampleByMessage, o)
+	s.Write(F{"level": "error", "message": "foo"})
+	s.Write(F{"level": "error", "message": "bar"})
+	s.Write(F{"level": "error", "message": "foo"})
+	s.Write(F{"level": "error", "message": "bar"})
+	s.Write(F{"level": "info", "message": "foo"})
+	assert.Equal(t, []F{
+		{"level": "error", "message": "foo"},
+		{"level": "error", "message": "bar"},
+		{"level": "info", "message": "foo"},
+	}, o.Messages)
+}
+
+func TestRateLimitOutput(t *testing.T) {
+	oldNow := now
+	defer func() { now = oldNow }()
+	t0 := time.Date(2000, 1, 2, 3, 4, 5, 0, time.UTC)
+	now = func() time.Time { return t0 }
+
+	o := &RecorderOutput{}
+	r := NewRateLimitOutput(1, 2, o)
+	for i := 0; i < 5; i++ {
+		assert.NoError(t, r.Write(F{"i": i}))
+	}
+	assert.Equal(t, []F{{"i": 0}, {"i": 1}}, o.Messages)
+
+	o.Reset()
+	now = func() time.Time { return t0.Add(time.Second) }
+	assert.NoError(t, r.Write(F{"i": 5}))
+	assert.NoError(t, r.Write(F{"i": 6}))
+	assert.Equal(t, []F{
+		{"time": t0.Add(time.Second), "level": "warn", "message": "dropped 3 messages", "dropped": 3},
+		{"i": 5},
+	}, o.Messages)
+}
+
+func TestRateLimitOutputError(t *testing.T) {
+	oldNow := now
+	defer func() { now = oldNow }()
+	t0 := time.Date(2000, 1, 2, 3, 4, 5, 0, time.UTC)
+	now = func() time.Time { return t0 }
+
+	o := newTestOutputErr(errors.New("some error"))
+	r := NewRateLimitOutput(1, 1, o)
+	assert.EqualError(t, r.Write(F{"i": 0}), "some error")
+	assert.NoError(t, r.Write(F{"i": 1}))
+	now = func() time.Time { return t0.Add(time.Second) }
+	assert.EqualError(t, r.Write(F{"i": 2}), "some error")
+	assert.Equal(t, 0, o.get()["i"])
+	// The summary failed so the message is not sent
+	assert.Equal(t, 1, o.get()["dropped"])
+	assert.True(t, o.empty())
+}
+
+func TestTailSampleOutput(t *testing.T) {
+	o := &RecorderOutput{}
+	ts := NewTailSampleOutput("id", o)
+	ts.MaxMessages = 2
+
+	// Messages without id and non-debug messages go thru
+	ts.Write(F{"level": "debug", "message": "no id"})
+	ts.Write(F{"level": "info", "id": 1, "message": "info"})
+	assert.Equal(t, []F{
+		{"level": "debug", "message": "no id"},
+		{"level": "info", "id": 1, "message": "info"},
+	}, o.Messages)
+
+	// Debug messages of successful requests are discarded
+	o.Reset()
+	ts.Write(F{"level": "debug", "id": 1, "message": "a"})
+	assert.NoError(t, ts.Done(1, false))
+	assert.Equal(t, []F{}, o.Messages)
+	assert.Len(t, ts.requests, 0)
+
+	// Debug messages are flushed before the error
+	ts.Write(F{"level": "debug", "id": 2, "message": "a"})
+	ts.Write(F{"level": "debug", "id": 2, "message": "b"})
+	ts.Write(F{"level": "debug", "id": 2, "message": "c"})
+	ts.Write(F{"level": "error", "id": 2, "message": "d"})
+	ts.Write(F{"level": "debug", "id": 2, "message": "e"})
+	assert.Equal(t, []F{
+		{"level": "debug", "id": 2, "message": "b"},
+		{"level": "debug", "id": 2, "message": "c"},
+		{"level": "error", "id": 2, "message": "d"},
+		{"level": "debug", "id": 2, "message": "e"},
+	}, o.Messages)
+	ts.Done(2, false)
+
+	// Requests marked as failed on Done are flushed
+	o.Reset()
+	ts.Write(F{"level": "debug", "id": 3, "message": "a"})
+	assert.NoError(t, ts.Done(3, true))
+	assert.Equal(t, []F{{"level": "debug", "id": 3, "message": "a"}}, o.Messages)
+	assert.Len(t, ts.requests, 0)
+}
+
+func TestTailSampleOutputMaxRequests(t *testing.T) {
+	o := &RecorderOutput{}
+	ts := NewTailSampleOutput("id", o)
+	ts.MaxRequests = 1
+	ts.Write(F{"level": "debug", "id": 1, "message": "a"})
+	ts.Write(F{"level": "debug", "id": 2, "message": "b"})
+	ts.Done(2, true)
+	ts.Done(1, true)
+	assert.Equal(t, []F{{"level": "debug", "id": 1, "message": "a"}}, o.Messages)
+}
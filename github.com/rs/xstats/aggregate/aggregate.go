@@ -0,0 +1,318 @@
+// Package aggregate is a dependency free xstats sender aggregating observations
+// in process and exposing them in the Prometheus text exposition format.
+package aggregate // import "github.com/rs/xstats/aggregate"
+
+import (
+	"bufio"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/xstats"
+)
+
+// DefaultBuckets are the default histogram buckets, tailored to measure
+// durations in seconds.
+var DefaultBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// DefaultMaxSeries is the default maximum number of tag sets tracked per stat.
+const DefaultMaxSeries = 1000
+
+// DroppedSeriesStat is the name of the counter tracking the observations
+// dropped because of the MaxSeries limit.
+const DroppedSeriesStat = "xstats_dropped_series_total"
+
+// Options defines the aggregator's configuration.
+type Options struct {
+	// Buckets defines the histogram buckets' upper bounds used by default.
+	// DefaultBuckets is used if not set.
+	Buckets []float64
+	// StatBuckets defines per stat histogram buckets.
+	StatBuckets map[string][]float64
+	// MaxSeries is the maximum number of distinct tag sets tracked for each stat.
+	// Observations for new tag sets above this limit are dropped and counted in
+	// the DroppedSeriesStat counter. DefaultMaxSeries is used if 0, -1 disables
+	// the limit.
+	MaxSeries int
+	// TagLabel translates a tag into a label name and value. Tags are split on the
+	// first ':' by default; tags without a value are set as label with an empty value.
+	// Returning an empty name drops the tag.
+	TagLabel func(tag string) (name, value string)
+}
+
+// Sender is an xstats.Sender aggregating counters, gauges and histograms
+// for each stat and tag set. Sender implements http.Handler to serve the
+// aggregated metrics in the Prometheus text format.
+type Sender struct {
+	opts     Options
+	mu       sync.Mutex
+	families map[string]*family
+	dropped  map[string]float64
+}
+
+const (
+	typeCounter   = "counter"
+	typeGauge     = "gauge"
+	typeHistogram = "histogram"
+)
+
+type family struct {
+	typ     string
+	buckets []float64
+	series  map[string]*series
+}
+
+type series struct {
+	labels string
+	value  float64
+	counts []uint64
+	sum    float64
+	count  uint64
+}
+
+// New creates a new aggregator sender.
+func New(opts Options) *Sender {
+	if opts.Buckets == nil {
+		opts.Buckets = DefaultBuckets
+	}
+	if opts.MaxSeries == 0 {
+		opts.MaxSeries = DefaultMaxSeries
+	}
+	if opts.TagLabel == nil {
+		opts.TagLabel = splitTag
+	}
+	return &Sender{
+		opts:     opts,
+		families: map[string]*family{},
+		dropped:  map[string]float64{},
+	}
+}
+
+// Gauge implements xstats.Sender interface
+func (s *Sender) Gauge(stat string, value float64, tags ...string) {
+	s.mu.Lock()
+	if m := s.series(typeGauge, stat, tags); m != nil {
+		m.value = value
+	}
+	s.mu.Unlock()
+}
+
+// Count implements xstats.Sender interface
+func (s *Sender) Count(stat string, count float64, tags ...string) {
+	s.mu.Lock()
+	if m := s.series(typeCounter, stat, tags); m != nil {
+		m.value += count
+	}
+	s.mu.Unlock()
+}
+
+// Histogram implements xstats.Sender interface
+func (s *Sender) Histogram(stat string, value float64, tags ...string) {
+	s.mu.Lock()
+	if m := s.series(typeHistogram, stat, tags); m != nil {
+		buckets := s.families[sanitizeName(stat)].buckets
+		if i := sort.SearchFloat64s(buckets, value); i < len(buckets) {
+			m.counts[i]++
+		}
+		m.sum += value
+		m.count++
+	}
+	s.mu.Unlock()
+}
+
+// Timing implements xstats.Sender interface
+//
+// Timings are recorded as histograms in seconds.
+func (s *Sender) Timing(stat string, duration time.Duration, tags ...string) {
+	s.Histogram(stat, duration.Seconds(), tags...)
+}
+
+// series returns the series of stat for tags, creating it if needed. It returns
+// nil if the stat is already registered with another type or if the MaxSeries
+// limit is reached. Must be called with s.mu held.
+func (s *Sender) series(typ, stat string, tags []string) *series {
+	name := sanitizeName(stat)
+	f, found := s.families[name]
+	if !found {
+		f = &family{typ: typ, series: map[string]*series{}}
+		if typ == typeHistogram {
+			f.buckets = s.opts.Buckets
+			if b, found := s.opts.StatBuckets[stat]; found {
+				f.buckets = b
+			}
+			f.buckets = append([]float64(nil), f.buckets...)
+			sort.Float64s(f.buckets)
+		}
+		s.families[name] = f
+	} else if f.typ != typ {
+		return nil
+	}
+	labels := s.labels(tags)
+	m, found := f.series[labels]
+	if !found {
+		if s.opts.MaxSeries > 0 && len(f.series) >= s.opts.MaxSeries {
+			s.dropped[name]++
+			return nil
+		}
+		m = &series{labels: labels}
+		if typ == typeHistogram {
+			m.counts = make([]uint64, len(f.buckets))
+		}
+		f.series[labels] = m
+	}
+	return m
+}
+
+// labels returns the canonical label pairs (sorted by name) for tags, formatted
+// in the exposition format without the surrounding braces.
+func (s *Sender) labels(tags []string) string {
+	if len(tags) == 0 {
+		return ""
+	}
+	pairs := make([]string, 0, len(tags))
+	seen := make(map[string]bool, len(tags))
+	// Last tag wins when a label is set several times
+	for i := len(tags) - 1; i >= 0; i-- {
+		name, value := s.opts.TagLabel(tags[i])
+		if name == "" {
+			continue
+		}
+		name = sanitizeName(name)
+		if name == "le" || seen[name] {
+			continue
+		}
+		seen[name] = true
+		pairs = append(pairs, name+`="`+escapeValue(value)+`"`)
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
+
+// ServeHTTP implements http.Handler interface
+func (s *Sender) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	s.WriteTo(w)
+}
+
+// WriteTo writes all aggregated metrics in the Prometheus text exposition
+// format to w.
+func (s *Sender) WriteTo(w io.Writer) (int64, error) {
+	cw := &countWriter{w: w}
+	bw := bufio.NewWriter(cw)
+	s.mu.Lock()
+	names := make([]string, 0, len(s.families))
+	for name := range s.families {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		f := s.families[name]
+		bw.WriteString("# TYPE " + name + " " + f.typ + "\n")
+		keys := make([]string, 0, len(f.series))
+		for k := range f.series {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			m := f.series[k]
+			if f.typ != typeHistogram {
+				writeSample(bw, name, m.labels, "", m.value)
+				continue
+			}
+			var cumul uint64
+			for i, b := range f.buckets {
+				cumul += m.counts[i]
+				writeSample(bw, name+"_bucket", m.labels, formatFloat(b), float64(cumul))
+			}
+			writeSample(bw, name+"_bucket", m.labels, "+Inf", float64(m.count))
+			writeSample(bw, name+"_sum", m.labels, "", m.sum)
+			writeSample(bw, name+"_count", m.labels, "", float64(m.count))
+		}
+	}
+	if len(s.dropped) > 0 {
+		bw.WriteString("# TYPE " + DroppedSeriesStat + " counter\n")
+		stats := make([]string, 0, len(s.dropped))
+		for stat := range s.dropped {
+			stats = append(stats, stat)
+		}
+		sort.Strings(stats)
+		for _, stat := range stats {
+			writeSample(bw, DroppedSeriesStat, `stat="`+escapeValue(stat)+`"`, "", s.dropped[stat])
+		}
+	}
+	s.mu.Unlock()
+	err := bw.Flush()
+	return cw.n, err
+}
+
+func writeSample(w *bufio.Writer, name, labels, le string, value float64) {
+	w.WriteString(name)
+	if labels != "" || le != "" {
+		w.WriteByte('{')
+		w.WriteString(labels)
+		if le != "" {
+			if labels != "" {
+				w.WriteByte(',')
+			}
+			w.WriteString(`le="` + le + `"`)
+		}
+		w.WriteByte('}')
+	}
+	w.WriteByte(' ')
+	w.WriteString(formatFloat(value))
+	w.WriteByte('\n')
+}
+
+func formatFloat(f float64) string {
+	switch {
+	case math.IsInf(f, 1):
+		return "+Inf"
+	case math.IsInf(f, -1):
+		return "-Inf"
+	case math.IsNaN(f):
+		return "NaN"
+	}
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
+
+// sanitizeName replaces all characters not allowed in Prometheus metric and
+// label names by underscores.
+func sanitizeName(name string) string {
+	return strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r == '_' || r == ':' || r >= '0' && r <= '9' {
+			return r
+		}
+		return '_'
+	}, name)
+}
+
+var valueEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+
+func escapeValue(v string) string {
+	return valueEscaper.Replace(v)
+}
+
+func splitTag(tag string) (string, string) {
+	if i := strings.IndexByte(tag, ':'); i >= 0 {
+		return tag[:i], tag[i+1:]
+	}
+	return tag, ""
+}
+
+type countWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (w *countWriter) Write(p []byte) (int, error) {
+	n, err := w.w.Write(p)
+	w.n += int64(n)
+	return n, err
+}
+
+var _ xstats.Sender = (*Sender)(nil)
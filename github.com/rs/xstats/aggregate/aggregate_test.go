@@ -0,0 +1,121 @@
+package aggregate
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func get(s *Sender) string {
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, &http.Request{Method: "GET"})
+	return w.Body.String()
+}
+
+func TestCounter(t *testing.T) {
+	s := New(Options{})
+	s.Count("metric.c", 1, "tag:1")
+	s.Count("metric.c", 2, "tag:1")
+	s.Count("metric.c", 2, "gat:2", "tag:2")
+	assert.Equal(t, `# TYPE metric_c counter
+metric_c{gat="2",tag="2"} 2
+metric_c{tag="1"} 3
+`, get(s))
+}
+
+func TestGauge(t *testing.T) {
+	s := New(Options{})
+	s.Gauge("metric_g", 1)
+	s.Gauge("metric_g", -2)
+	s.Gauge("metric_g", 3, "tag", "quote:a\"b")
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, &http.Request{Method: "GET"})
+	assert.Equal(t, "text/plain; version=0.0.4; charset=utf-8", w.Header().Get("Content-Type"))
+	assert.Equal(t, `# TYPE metric_g gauge
+metric_g -2
+metric_g{quote="a\"b",tag=""} 3
+`, w.Body.String())
+}
+
+func TestHistogram(t *testing.T) {
+	s := New(Options{Buckets: []float64{1, 5, 10}})
+	s.Histogram("metric_h", 0.5, "tag:1")
+	s.Histogram("metric_h", 5, "tag:1")
+	s.Histogram("metric_h", 7, "tag:1")
+	s.Histogram("metric_h", 100, "tag:1")
+	assert.Equal(t, `# TYPE metric_h histogram
+metric_h_bucket{tag="1",le="1"} 1
+metric_h_bucket{tag="1",le="5"} 2
+metric_h_bucket{tag="1",le="10"} 3
+metric_h_bucket{tag="1",le="+Inf"} 4
+metric_h_sum{tag="1"} 112.5
+metric_h_count{tag="1"} 4
+`, get(s))
+}
+
+func TestTiming(t *testing.T) {
+	s := New(Options{StatBuckets: map[string][]float64{"metric_t": {1, 0.1}}})
+	s.Timing("metric_t", 500*time.Millisecond)
+	assert.Equal(t, `# TYPE metric_t histogram
+metric_t_bucket{le="0.1"} 0
+metric_t_bucket{le="1"} 1
+metric_t_bucket{le="+Inf"} 1
+metric_t_sum 0.5
+metric_t_count 1
+`, get(s))
+}
+
+func TestTypeConflict(t *testing.T) {
+	s := New(Options{})
+	s.Count("metric", 1)
+	s.Gauge("metric", 2)
+	assert.Equal(t, "# TYPE metric counter\nmetric 1\n", get(s))
+}
+
+func TestMaxSeries(t *testing.T) {
+	s := New(Options{MaxSeries: 1})
+	s.Count("metric", 1, "tag:1")
+	s.Count("metric", 1, "tag:2")
+	s.Count("metric", 1, "tag:3")
+	s.Count("metric", 1, "tag:1")
+	assert.Equal(t, `# TYPE metric counter
+metric{tag="1"} 2
+# TYPE xstats_dropped_series_total counter
+xstats_dropped_series_total{stat="metric"} 2
+`, get(s))
+}
+
+func TestTagLabel(t *testing.T) {
+	s := New(Options{
+		TagLabel: func(tag string) (string, string) {
+			if strings.HasPrefix(tag, "drop") {
+				return "", ""
+			}
+			return "t", tag
+		},
+	})
+	s.Count("metric", 1, "drop:me", "v.1")
+	assert.Equal(t, "# TYPE metric counter\nmetric{t=\"v.1\"} 1\n", get(s))
+}
+
+func TestWriteTo(t *testing.T) {
+	s := New(Options{})
+	s.Count("metric", 1)
+	buf := &bytes.Buffer{}
+	n, err := s.WriteTo(buf)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(buf.Len()), n)
+}
+
+func TestBuckets(t *testing.T) {
+	assert.Equal(t, []float64{1, 3, 5}, LinearBuckets(1, 2, 3))
+	assert.Equal(t, []float64{1, 2, 4}, ExponentialBuckets(1, 2, 3))
+	assert.Equal(t, []float64{1.5, 2, 3, 4, 6, 8}, HDRBuckets(1, 8, 2))
+	assert.Equal(t, []float64{3, 3.5, 4, 5, 6, 7, 8}, HDRBuckets(3, 7.5, 4))
+	assert.Nil(t, HDRBuckets(0, 1, 1))
+}
@@ -0,0 +1,50 @@
+package aggregate
+
+import "math"
+
+// LinearBuckets returns count buckets, each width wide, the first one having
+// start as upper bound.
+func LinearBuckets(start, width float64, count int) []float64 {
+	buckets := make([]float64, count)
+	for i := range buckets {
+		buckets[i] = start + float64(i)*width
+	}
+	return buckets
+}
+
+// ExponentialBuckets returns count buckets, the first one having start as upper
+// bound and each following bound being factor times the previous one.
+func ExponentialBuckets(start, factor float64, count int) []float64 {
+	buckets := make([]float64, count)
+	for i := range buckets {
+		buckets[i] = start
+		start *= factor
+	}
+	return buckets
+}
+
+// HDRBuckets returns log-linear buckets covering the range from min to max in
+// the fashion of HDR histograms: each power of two is divided into subBuckets
+// linear buckets, bounding the relative error of any value to 1/subBuckets.
+func HDRBuckets(min, max float64, subBuckets int) []float64 {
+	if min <= 0 || max <= min || subBuckets < 1 {
+		return nil
+	}
+	buckets := []float64{}
+	lower := math.Pow(2, math.Floor(math.Log2(min)))
+	for lower < max {
+		width := lower / float64(subBuckets)
+		for i := 1; i <= subBuckets; i++ {
+			b := lower + float64(i)*width
+			if b < min {
+				continue
+			}
+			buckets = append(buckets, b)
+			if b >= max {
+				return buckets
+			}
+		}
+		lower *= 2
+	}
+	return buckets
+}
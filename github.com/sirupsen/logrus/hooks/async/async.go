@@ -0,0 +1,280 @@
+// Package async runs logrus hooks in the background, so that slow hooks don't
+// block the logging calls.
+package async
+
+import (
+	"fmt"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// DropPolicy decides what happens to an entry fired while the queue is full.
+type DropPolicy int
+
+const (
+	// Block waits for room in the queue.
+	Block DropPolicy = iota
+	// DropNewest discards the entry being fired.
+	DropNewest
+	// DropOldest discards the oldest entry in the queue to make room.
+	DropOldest
+)
+
+// BatchHook is a hook able to send several entries at once. Hooks implementing
+// it receive the entries in batches of up to `Options.BatchSize`.
+type BatchHook interface {
+	logrus.Hook
+	FireBatch([]*logrus.Entry) error
+}
+
+// Options configures a Hook.
+type Options struct {
+	// QueueSize is the number of entries waiting to be fired. Defaults to 1024.
+	QueueSize int
+
+	// Policy decides what happens to entries fired while the queue is full.
+	// Defaults to Block.
+	Policy DropPolicy
+
+	// BatchSize is the maximum number of entries sent at once to a BatchHook.
+	// Defaults to 100.
+	BatchSize int
+
+	// FlushInterval is the maximum time an entry waits for its batch to be
+	// full before it is sent to a BatchHook. Defaults to 1 second.
+	FlushInterval time.Duration
+
+	// FlushTimeout is the maximum time Flush and Close wait for the queued
+	// entries to be fired. Defaults to 5 seconds.
+	FlushTimeout time.Duration
+
+	// ErrorHandler is called with the errors returned by the wrapped hook.
+	// Defaults to printing them to stderr.
+	ErrorHandler func(error)
+}
+
+// Hook fires the wrapped hook from a background goroutine. Entries are queued
+// by Fire, and fired in the order they were logged.
+//
+// The queued entries are flushed when the program exits through logrus.Exit,
+// e.g. after a Fatal entry, and before a Panic entry panics.
+type Hook struct {
+	hook    logrus.Hook
+	batch   BatchHook
+	opts    Options
+	queue   chan *logrus.Entry
+	flush   chan chan struct{}
+	quit    chan struct{}
+	done    chan struct{}
+	dropped uint64
+
+	mu     sync.RWMutex
+	closed bool
+}
+
+// New wraps hook in a Hook and starts its goroutine. This is called with
+// `logger.Hooks.Add(async.New(hook, async.Options{}))`.
+func New(hook logrus.Hook, opts Options) *Hook {
+	if opts.QueueSize <= 0 {
+		opts.QueueSize = 1024
+	}
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = 100
+	}
+	if opts.FlushInterval <= 0 {
+		opts.FlushInterval = time.Second
+	}
+	if opts.FlushTimeout <= 0 {
+		opts.FlushTimeout = 5 * time.Second
+	}
+	if opts.ErrorHandler == nil {
+		opts.ErrorHandler = func(err error) {
+			fmt.Fprintf(os.Stderr, "Failed to fire hook: %v\n", err)
+		}
+	}
+	h := &Hook{
+		hook:  hook,
+		opts:  opts,
+		queue: make(chan *logrus.Entry, opts.QueueSize),
+		flush: make(chan chan struct{}),
+		quit:  make(chan struct{}),
+		done:  make(chan struct{}),
+	}
+	if b, ok := hook.(BatchHook); ok {
+		h.batch = b
+	} else {
+		// Entries are fired one by one, there's no point in waiting for more
+		h.opts.BatchSize = 1
+	}
+	logrus.RegisterExitHandler(func() { h.Flush() })
+	go h.run()
+	return h
+}
+
+// Levels returns the levels of the wrapped hook.
+func (h *Hook) Levels() []logrus.Level {
+	return h.hook.Levels()
+}
+
+// Fire queues a copy of entry. Once the hook is closed, entries are fired
+// synchronously.
+func (h *Hook) Fire(entry *logrus.Entry) error {
+	// The entry is reused by the logger once the hooks are fired
+	e := *entry
+	e.Buffer = nil
+
+	h.mu.RLock()
+	if h.closed {
+		h.mu.RUnlock()
+		return h.hook.Fire(&e)
+	}
+	h.enqueue(&e)
+	h.mu.RUnlock()
+
+	if e.Level == logrus.PanicLevel {
+		h.Flush()
+	}
+	return nil
+}
+
+func (h *Hook) enqueue(e *logrus.Entry) {
+	switch h.opts.Policy {
+	case DropNewest:
+		select {
+		case h.queue <- e:
+		default:
+			atomic.AddUint64(&h.dropped, 1)
+		}
+	case DropOldest:
+		for {
+			select {
+			case h.queue <- e:
+				return
+			default:
+			}
+			select {
+			case <-h.queue:
+				atomic.AddUint64(&h.dropped, 1)
+			default:
+			}
+		}
+	default:
+		h.queue <- e
+	}
+}
+
+// Dropped returns the number of entries dropped because the queue was full.
+func (h *Hook) Dropped() uint64 {
+	return atomic.LoadUint64(&h.dropped)
+}
+
+// Flush fires the queued entries and returns once they are fired, or after
+// FlushTimeout. It returns false on timeout.
+func (h *Hook) Flush() bool {
+	timeout := time.NewTimer(h.opts.FlushTimeout)
+	defer timeout.Stop()
+	ack := make(chan struct{})
+	select {
+	case h.flush <- ack:
+	case <-h.done:
+		return true
+	case <-timeout.C:
+		return false
+	}
+	select {
+	case <-ack:
+		return true
+	case <-timeout.C:
+		return false
+	}
+}
+
+// Close fires the queued entries and stops the goroutine. It returns false if
+// the entries could not be fired within FlushTimeout. Entries fired after
+// Close are passed synchronously to the wrapped hook.
+func (h *Hook) Close() bool {
+	h.mu.Lock()
+	if !h.closed {
+		h.closed = true
+		close(h.quit)
+	}
+	h.mu.Unlock()
+	select {
+	case <-h.done:
+		return true
+	case <-time.After(h.opts.FlushTimeout):
+		return false
+	}
+}
+
+func (h *Hook) run() {
+	defer close(h.done)
+	var (
+		pending []*logrus.Entry
+		ticker  *time.Ticker
+		tick    <-chan time.Time
+	)
+	if h.batch != nil {
+		ticker = time.NewTicker(h.opts.FlushInterval)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+	add := func(e *logrus.Entry) {
+		pending = append(pending, e)
+		if len(pending) >= h.opts.BatchSize {
+			h.fire(pending)
+			pending = pending[:0]
+		}
+	}
+	drain := func() {
+		for {
+			select {
+			case e := <-h.queue:
+				add(e)
+			default:
+				if len(pending) > 0 {
+					h.fire(pending)
+					pending = pending[:0]
+				}
+				return
+			}
+		}
+	}
+	for {
+		select {
+		case e := <-h.queue:
+			add(e)
+		case <-tick:
+			if len(pending) > 0 {
+				h.fire(pending)
+				pending = pending[:0]
+			}
+		case ack := <-h.flush:
+			drain()
+			close(ack)
+		case <-h.quit:
+			drain()
+			return
+		}
+	}
+}
+
+func (h *Hook) fire(entries []*logrus.Entry) {
+	if h.batch != nil {
+		batch := make([]*logrus.Entry, len(entries))
+		copy(batch, entries)
+		if err := h.batch.FireBatch(batch); err != nil {
+			h.opts.ErrorHandler(err)
+		}
+		return
+	}
+	for _, e := range entries {
+		if err := h.hook.Fire(e); err != nil {
+			h.opts.ErrorHandler(err)
+		}
+	}
+}
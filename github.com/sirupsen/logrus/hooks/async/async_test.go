@@ -0,0 +1,188 @@
+package async
+
+import (
+	"errors"
+	"io/ioutil"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+type recordHook struct {
+	mu      sync.Mutex
+	entries []*logrus.Entry
+	batches [][]*logrus.Entry
+	block   chan struct{}
+	err     error
+}
+
+func (h *recordHook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+func (h *recordHook) Fire(e *logrus.Entry) error {
+	if h.block != nil {
+		<-h.block
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.entries = append(h.entries, e)
+	return h.err
+}
+
+func (h *recordHook) messages() []string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	var msgs []string
+	for _, e := range h.entries {
+		msgs = append(msgs, e.Message)
+	}
+	return msgs
+}
+
+type batchHook struct {
+	recordHook
+}
+
+func (h *batchHook) FireBatch(entries []*logrus.Entry) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.batches = append(h.batches, entries)
+	h.entries = append(h.entries, entries...)
+	return nil
+}
+
+func newLogger(hook logrus.Hook) *logrus.Logger {
+	logger := logrus.New()
+	logger.Out = ioutil.Discard
+	logger.Hooks.Add(hook)
+	return logger
+}
+
+func TestAsync(t *testing.T) {
+	assert := assert.New(t)
+	rec := &recordHook{}
+	hook := New(rec, Options{})
+	logger := newLogger(hook)
+
+	logger.WithField("key", "value").Info("one")
+	logger.Warn("two")
+	assert.True(hook.Flush())
+	assert.Equal([]string{"one", "two"}, rec.messages())
+	assert.Equal("value", rec.entries[0].Data["key"])
+	assert.Equal(logrus.WarnLevel, rec.entries[1].Level)
+
+	assert.True(hook.Close())
+	logger.Info("three")
+	assert.Equal([]string{"one", "two", "three"}, rec.messages())
+}
+
+func TestAsyncDoesNotBlock(t *testing.T) {
+	assert := assert.New(t)
+	rec := &recordHook{block: make(chan struct{})}
+	hook := New(rec, Options{QueueSize: 2, Policy: DropNewest})
+	logger := newLogger(hook)
+
+	logger.Info("0")
+	// Wait for the worker to take the first entry
+	for len(hook.queue) > 0 {
+		time.Sleep(time.Millisecond)
+	}
+	done := make(chan struct{})
+	go func() {
+		for i := 1; i < 10; i++ {
+			logger.Info(i)
+		}
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("logging blocked on the hook")
+	}
+	close(rec.block)
+	assert.True(hook.Flush())
+	// One entry taken by the worker and two queued
+	assert.Equal([]string{"0", "1", "2"}, rec.messages())
+	assert.Equal(uint64(7), hook.Dropped())
+}
+
+func TestAsyncDropOldest(t *testing.T) {
+	assert := assert.New(t)
+	rec := &recordHook{block: make(chan struct{})}
+	hook := New(rec, Options{QueueSize: 2, Policy: DropOldest})
+	logger := newLogger(hook)
+
+	logger.Info("0")
+	// Wait for the worker to take the first entry
+	for len(hook.queue) > 0 {
+		time.Sleep(time.Millisecond)
+	}
+	for i := 1; i < 6; i++ {
+		logger.Info(i)
+	}
+	close(rec.block)
+	assert.True(hook.Flush())
+	assert.Equal([]string{"0", "4", "5"}, rec.messages())
+	assert.Equal(uint64(3), hook.Dropped())
+}
+
+func TestAsyncFlushTimeout(t *testing.T) {
+	rec := &recordHook{block: make(chan struct{})}
+	defer close(rec.block)
+	hook := New(rec, Options{FlushTimeout: 10 * time.Millisecond})
+	newLogger(hook).Info("stuck")
+	assert.False(t, hook.Flush())
+}
+
+func TestAsyncBatch(t *testing.T) {
+	assert := assert.New(t)
+	rec := &batchHook{}
+	hook := New(rec, Options{BatchSize: 2, FlushInterval: time.Hour})
+	logger := newLogger(hook)
+
+	for i := 0; i < 5; i++ {
+		logger.Info(i)
+	}
+	assert.True(hook.Close())
+	assert.Equal([]string{"0", "1", "2", "3", "4"}, rec.messages())
+	if assert.Len(rec.batches, 3) {
+		assert.Len(rec.batches[0], 2)
+		assert.Len(rec.batches[2], 1)
+	}
+}
+
+func TestAsyncBatchInterval(t *testing.T) {
+	rec := &batchHook{}
+	hook := New(rec, Options{FlushInterval: 10 * time.Millisecond})
+	defer hook.Close()
+	newLogger(hook).Info("tick")
+
+	deadline := time.Now().Add(time.Second)
+	for len(rec.messages()) == 0 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	assert.Equal(t, []string{"tick"}, rec.messages())
+}
+
+func TestAsyncErrorHandler(t *testing.T) {
+	errs := make(chan error, 1)
+	rec := &recordHook{err: errors.New("hook failed")}
+	hook := New(rec, Options{ErrorHandler: func(err error) { errs <- err }})
+	newLogger(hook).Error("oops")
+	hook.Flush()
+	assert.EqualError(t, <-errs, "hook failed")
+}
+
+func TestAsyncPanic(t *testing.T) {
+	rec := &recordHook{}
+	hook := New(rec, Options{})
+	logger := newLogger(hook)
+
+	assert.Panics(t, func() { logger.Panic("boom") })
+	// Flushed before panicking
+	assert.Equal(t, []string{"boom"}, rec.messages())
+}
@@ -0,0 +1,229 @@
+// Package rotate provides a file writer rotating on size and time, to be used
+// as the output of a logger:
+//
+//	logger.Out = &rotate.Writer{
+//	  Filename:   "/var/log/app.log",
+//	  MaxSize:    100 << 20,
+//	  Interval:   24 * time.Hour,
+//	  MaxBackups: 7,
+//	}
+package rotate
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// backupTimeFormat is the format of the time inserted in the backup names,
+// which sorts in chronological order.
+const backupTimeFormat = "20060102T150405.000"
+
+// Writer is an io.WriteCloser writing to Filename, rotating it when it grows
+// over MaxSize or when a new Interval starts. The current file is renamed with
+// the rotation time inserted before its extension, e.g. "app.log" becomes
+// "app-20171019T150405.000.log", and a new file is created.
+//
+// The file is opened, or created, on the first write. Writer is safe for
+// concurrent use.
+type Writer struct {
+	// Filename is the path of the file to write to. Required.
+	Filename string
+
+	// MaxSize is the size in bytes after which the file is rotated. Zero
+	// disables rotation on size.
+	MaxSize int64
+
+	// Interval rotates the file when the time, truncated to Interval, changes,
+	// e.g. 24 * time.Hour rotates at midnight UTC. Zero disables rotation on
+	// time.
+	Interval time.Duration
+
+	// MaxBackups is the number of rotated files to keep, the oldest ones
+	// are removed. Zero keeps all of them.
+	MaxBackups int
+
+	// Perm is the permission of created files. Defaults to 0644.
+	Perm os.FileMode
+
+	mu     sync.Mutex
+	file   *os.File
+	size   int64
+	period time.Time
+	now    func() time.Time
+	remove func(name string) error
+}
+
+// Write implements io.Writer. A write is never split between two files.
+func (w *Writer) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	now := w.clock()
+	if w.file == nil {
+		if err := w.open(now); err != nil {
+			return 0, err
+		}
+	}
+	if w.due(now, int64(len(p))) {
+		if err := w.rotate(now); err != nil {
+			return 0, err
+		}
+		// Removing old backups is best effort, it doesn't fail the write.
+		w.prune()
+	}
+	n, err := w.file.Write(p)
+	w.size += int64(n)
+	return n, err
+}
+
+// Rotate closes the current file, renames it and creates a new one, e.g. on
+// SIGHUP. It also returns the errors removing old backups, which happen once
+// the new file is created.
+func (w *Writer) Rotate() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	now := w.clock()
+	if w.file == nil {
+		if err := w.open(now); err != nil {
+			return err
+		}
+	}
+	if err := w.rotate(now); err != nil {
+		return err
+	}
+	return w.prune()
+}
+
+// Close closes the current file.
+func (w *Writer) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.file == nil {
+		return nil
+	}
+	err := w.file.Close()
+	w.file = nil
+	return err
+}
+
+func (w *Writer) clock() time.Time {
+	if w.now != nil {
+		return w.now()
+	}
+	return time.Now()
+}
+
+// due reports whether the file must be rotated before writing n bytes.
+func (w *Writer) due(now time.Time, n int64) bool {
+	if w.MaxSize > 0 && w.size > 0 && w.size+n > w.MaxSize {
+		return true
+	}
+	return w.Interval > 0 && !now.Truncate(w.Interval).Equal(w.period)
+}
+
+// open opens the file in append mode. An existing file is rotated by the next
+// write if it was last modified in a previous interval.
+func (w *Writer) open(now time.Time) error {
+	perm := w.Perm
+	if perm == 0 {
+		perm = 0644
+	}
+	if err := os.MkdirAll(filepath.Dir(w.Filename), 0755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(w.Filename, os.O_WRONLY|os.O_APPEND|os.O_CREATE, perm)
+	if err != nil {
+		return err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+	w.file = f
+	w.size = info.Size()
+	w.period = now
+	if w.size > 0 {
+		w.period = info.ModTime()
+	}
+	if w.Interval > 0 {
+		w.period = w.period.Truncate(w.Interval)
+	}
+	return nil
+}
+
+func (w *Writer) rotate(now time.Time) error {
+	if err := w.file.Close(); err != nil {
+		return err
+	}
+	w.file = nil
+	if err := os.Rename(w.Filename, w.backupName(now)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return w.open(now)
+}
+
+// backupName returns the name of the backup of a file rotated at t. If a
+// backup of the same millisecond exists, the time is moved forward until the
+// name is free, so that backups never overwrite each other.
+func (w *Writer) backupName(t time.Time) string {
+	ext := filepath.Ext(w.Filename)
+	prefix := strings.TrimSuffix(w.Filename, ext)
+	for {
+		name := fmt.Sprintf("%s-%s%s", prefix, t.Format(backupTimeFormat), ext)
+		if _, err := os.Lstat(name); os.IsNotExist(err) {
+			return name
+		}
+		t = t.Add(time.Millisecond)
+	}
+}
+
+// backups returns the rotated files, oldest first.
+func (w *Writer) backups() ([]string, error) {
+	dir := filepath.Dir(w.Filename)
+	ext := filepath.Ext(w.Filename)
+	prefix := strings.TrimSuffix(filepath.Base(w.Filename), ext) + "-"
+	infos, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var backups []string
+	for _, info := range infos {
+		name := info.Name()
+		if info.IsDir() || !strings.HasPrefix(name, prefix) || !strings.HasSuffix(name, ext) {
+			continue
+		}
+		ts := strings.TrimSuffix(strings.TrimPrefix(name, prefix), ext)
+		if _, err := time.Parse(backupTimeFormat, ts); err == nil {
+			backups = append(backups, filepath.Join(dir, name))
+		}
+	}
+	sort.Strings(backups)
+	return backups, nil
+}
+
+func (w *Writer) prune() error {
+	if w.MaxBackups <= 0 {
+		return nil
+	}
+	backups, err := w.backups()
+	if err != nil {
+		return err
+	}
+	remove := w.remove
+	if remove == nil {
+		remove = os.Remove
+	}
+	for len(backups) > w.MaxBackups {
+		if err := remove(backups[0]); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		backups = backups[1:]
+	}
+	return nil
+}
@@ -0,0 +1,184 @@
+package rotate
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func testWriter(t *testing.T) (*Writer, *time.Time, func()) {
+	dir, err := ioutil.TempDir("", "rotate")
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Date(2017, 10, 19, 10, 0, 0, 0, time.UTC)
+	w := &Writer{
+		Filename: filepath.Join(dir, "app.log"),
+		now:      func() time.Time { return now },
+	}
+	return w, &now, func() {
+		w.Close()
+		os.RemoveAll(dir)
+	}
+}
+
+func files(t *testing.T, w *Writer) []string {
+	infos, err := ioutil.ReadDir(filepath.Dir(w.Filename))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, info := range infos {
+		names = append(names, info.Name())
+	}
+	sort.Strings(names)
+	return names
+}
+
+func read(t *testing.T, w *Writer, name string) string {
+	b, err := ioutil.ReadFile(filepath.Join(filepath.Dir(w.Filename), name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b)
+}
+
+func TestRotateSize(t *testing.T) {
+	assert := assert.New(t)
+	w, now, cleanup := testWriter(t)
+	defer cleanup()
+	w.MaxSize = 10
+
+	w.Write([]byte("12345"))
+	w.Write([]byte("67890"))
+	assert.Equal([]string{"app.log"}, files(t, w))
+
+	*now = now.Add(time.Second)
+	w.Write([]byte("abc"))
+	assert.Equal([]string{"app-20171019T100001.000.log", "app.log"}, files(t, w))
+	assert.Equal("1234567890", read(t, w, "app-20171019T100001.000.log"))
+	assert.Equal("abc", read(t, w, "app.log"))
+
+	// Writes larger than MaxSize are not split
+	*now = now.Add(time.Second)
+	w.Write([]byte("0123456789abc"))
+	assert.Equal("0123456789abc", read(t, w, "app.log"))
+	assert.Len(files(t, w), 3)
+}
+
+func TestRotateInterval(t *testing.T) {
+	assert := assert.New(t)
+	w, now, cleanup := testWriter(t)
+	defer cleanup()
+	w.Interval = time.Hour
+
+	w.Write([]byte("a"))
+	*now = now.Add(59 * time.Minute)
+	w.Write([]byte("b"))
+	assert.Equal([]string{"app.log"}, files(t, w))
+
+	*now = now.Add(time.Minute)
+	w.Write([]byte("c"))
+	assert.Equal([]string{"app-20171019T110000.000.log", "app.log"}, files(t, w))
+	assert.Equal("ab", read(t, w, "app-20171019T110000.000.log"))
+	assert.Equal("c", read(t, w, "app.log"))
+}
+
+func TestRotateReopen(t *testing.T) {
+	assert := assert.New(t)
+	w, now, cleanup := testWriter(t)
+	defer cleanup()
+	w.Interval = time.Hour
+
+	assert.NoError(ioutil.WriteFile(w.Filename, []byte("old"), 0644))
+	mtime := now.Add(-2 * time.Hour)
+	assert.NoError(os.Chtimes(w.Filename, mtime, mtime))
+
+	w.Write([]byte("new"))
+	assert.Equal([]string{"app-20171019T100000.000.log", "app.log"}, files(t, w))
+	assert.Equal("old", read(t, w, "app-20171019T100000.000.log"))
+	assert.Equal("new", read(t, w, "app.log"))
+}
+
+func TestRotateMaxBackups(t *testing.T) {
+	assert := assert.New(t)
+	w, now, cleanup := testWriter(t)
+	defer cleanup()
+	w.MaxBackups = 2
+
+	for i := 0; i < 4; i++ {
+		*now = now.Add(time.Second)
+		w.Write([]byte("x"))
+		assert.NoError(w.Rotate())
+	}
+	assert.NoError(ioutil.WriteFile(filepath.Join(filepath.Dir(w.Filename), "app-other.log"), nil, 0644))
+	*now = now.Add(time.Second)
+	assert.NoError(w.Rotate())
+	assert.Equal([]string{
+		"app-20171019T100004.000.log",
+		"app-20171019T100005.000.log",
+		"app-other.log",
+		"app.log",
+	}, files(t, w))
+}
+
+func TestRotatePruneError(t *testing.T) {
+	assert := assert.New(t)
+	w, now, cleanup := testWriter(t)
+	defer cleanup()
+	w.MaxSize = 1
+	w.MaxBackups = 1
+	w.remove = func(string) error { return os.ErrPermission }
+
+	for _, s := range []string{"a", "b", "c"} {
+		*now = now.Add(time.Second)
+		n, err := w.Write([]byte(s))
+		assert.NoError(err)
+		assert.Equal(1, n)
+	}
+	assert.Equal("c", read(t, w, "app.log"))
+	assert.Equal(os.ErrPermission, w.Rotate())
+	assert.Equal([]string{
+		"app-20171019T100002.000.log",
+		"app-20171019T100003.000.log",
+		"app-20171019T100003.001.log",
+		"app.log",
+	}, files(t, w))
+}
+
+func TestLoggerOut(t *testing.T) {
+	w, _, cleanup := testWriter(t)
+	defer cleanup()
+
+	logger := logrus.New()
+	logger.Out = w
+	logger.Formatter = &logrus.JSONFormatter{}
+	logger.Info("hello")
+
+	assert.Contains(t, read(t, w, "app.log"), `"msg":"hello"`)
+}
+
+func TestRotateSameTime(t *testing.T) {
+	assert := assert.New(t)
+	w, _, cleanup := testWriter(t)
+	defer cleanup()
+
+	for _, s := range []string{"a", "b", "c"} {
+		w.Write([]byte(s))
+		assert.NoError(w.Rotate())
+	}
+	assert.Equal([]string{
+		"app-20171019T100000.000.log",
+		"app-20171019T100000.001.log",
+		"app-20171019T100000.002.log",
+		"app.log",
+	}, files(t, w))
+	assert.Equal("a", read(t, w, "app-20171019T100000.000.log"))
+	assert.Equal("c", read(t, w, "app-20171019T100000.002.log"))
+}
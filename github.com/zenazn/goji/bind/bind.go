@@ -10,7 +10,8 @@ it will be treated as a TCP address. If it begins with a "/" or a ".", it will
 be treated as a path to a UNIX socket. If it begins with the string "fd@", as in
 "fd@3", it will be treated as a file descriptor (useful for use with systemd,
 for instance). If it begins with the string "einhorn@", as in "einhorn@0", the
-corresponding einhorn socket will be used.
+corresponding einhorn socket will be used. TCP and UNIX sockets are opened with
+graceful.Listen, so that they are passed to the new process on upgrades.
 
 If an option is not explicitly passed, the implementation will automatically
 select between using "einhorn@0", "fd@3", and ":8000", depending on whether
@@ -32,6 +33,8 @@ import (
 	"strconv"
 	"strings"
 	"sync"
+
+	"github.com/zenazn/goji/graceful"
 )
 
 var bind string
@@ -98,9 +101,9 @@ func Sniff() string {
 
 func listenTo(bind string) (net.Listener, error) {
 	if strings.Contains(bind, ":") {
-		return net.Listen("tcp", bind)
+		return graceful.Listen("tcp", bind)
 	} else if strings.HasPrefix(bind, ".") || strings.HasPrefix(bind, "/") {
-		return net.Listen("unix", bind)
+		return graceful.Listen("unix", bind)
 	} else if strings.HasPrefix(bind, "fd@") {
 		fd, err := strconv.Atoi(bind[3:])
 		if err != nil {
@@ -100,3 +100,15 @@ func Trace(pattern web.PatternType, handler web.HandlerType) {
 func NotFound(handler web.HandlerType) {
 	DefaultMux.NotFound(handler)
 }
+
+// Routes returns the routes of the default Mux. See the documentation for
+// web.Mux.Routes for more information.
+func Routes() []web.Route {
+	return DefaultMux.Routes()
+}
+
+// URL builds the path of a named route of the default Mux. See the
+// documentation for web.Mux.URL for more information.
+func URL(name string, params map[string]string) (string, error) {
+	return DefaultMux.URL(name, params)
+}
@@ -4,6 +4,10 @@ connections after receiving a signal. By default, this package listens for
 interrupts (i.e., SIGINT), but when it detects that it is running under Einhorn
 it will additionally listen for SIGUSR2 as well, giving your application
 automatic support for graceful restarts/code upgrades.
+
+Without Einhorn, HandleUpgrades installs a SIGUSR2 handler which starts a new
+process running the same binary, passes it the sockets opened with Listen, and
+gracefully shuts down once the new process calls Ready.
 */
 package graceful
 
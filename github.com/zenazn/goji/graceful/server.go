@@ -37,7 +37,7 @@ func (srv *Server) ListenAndServe() error {
 	if addr == "" {
 		addr = ":http"
 	}
-	ln, err := net.Listen("tcp", addr)
+	ln, err := Listen("tcp", addr)
 	if err != nil {
 		return err
 	}
@@ -71,7 +71,7 @@ func (srv *Server) ListenAndServeTLS(certFile, keyFile string) error {
 		return err
 	}
 
-	ln, err := net.Listen("tcp", addr)
+	ln, err := Listen("tcp", addr)
 	if err != nil {
 		return err
 	}
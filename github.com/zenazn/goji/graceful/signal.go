@@ -127,18 +127,26 @@ func sigLoop() {
 		now := time.Now()
 		mu.Lock()
 		force := doubleKick != 0 && now.Sub(last) < doubleKick
-		if t := timeout; t != 0 && !force {
-			go func() {
-				time.Sleep(t)
-				shutdown(true)
-			}()
-		}
 		mu.Unlock()
-		go shutdown(force)
+		gracefulShutdown(force)
 		last = now
 	}
 }
 
+// gracefulShutdown starts a shutdown in the background, as if a signal was
+// received, forcing it after the Timeout.
+func gracefulShutdown(force bool) {
+	mu.Lock()
+	if t := timeout; t != 0 && !force {
+		go func() {
+			time.Sleep(t)
+			shutdown(true)
+		}()
+	}
+	mu.Unlock()
+	go shutdown(force)
+}
+
 var preOnce, closeOnce, forceOnce, postOnce, notifyOnce sync.Once
 
 func shutdown(force bool) {
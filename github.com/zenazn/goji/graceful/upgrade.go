@@ -0,0 +1,278 @@
+// +build !windows,go1.10
+
+package graceful
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"os/exec"
+	"os/signal"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+)
+
+// Environment passed to the new process by Upgrade. Listener i is passed as
+// file descriptor 3+i, and the readiness pipe as the descriptor following the
+// listeners.
+const (
+	upgradePPIDEnv      = "GOJI_UPGRADE_PPID"
+	upgradeListenersEnv = "GOJI_UPGRADE_LISTENERS"
+	upgradeReadyEnv     = "GOJI_UPGRADE_READY_FD"
+)
+
+// UpgradeTimeout is the maximum amount of time Upgrade waits for the new
+// process to call Ready. If it doesn't, the new process is killed and the
+// current one keeps serving.
+var UpgradeTimeout = time.Minute
+
+type fileListener interface {
+	net.Listener
+	syscall.Conn
+}
+
+type upgradeListener struct {
+	network, addr string
+	l             fileListener
+}
+
+var upgradeMu sync.Mutex // protects everything that follows
+var upgradeListeners []upgradeListener
+var inherited = make(map[string]*os.File)
+var readyPipe *os.File
+var upgrading bool
+
+// upgradeCommand describes the command starting the new process. It's a
+// variable so tests can replace the binary.
+var upgradeCommand = func() (*exec.Cmd, error) {
+	exe, err := os.Executable()
+	if err != nil {
+		return nil, err
+	}
+	return exec.Command(exe, os.Args[1:]...), nil
+}
+
+func init() {
+	ppid, err := strconv.Atoi(os.Getenv(upgradePPIDEnv))
+	if err != nil || ppid != os.Getppid() {
+		return
+	}
+	var specs []string
+	if s := os.Getenv(upgradeListenersEnv); s != "" {
+		specs = strings.Split(s, ",")
+	}
+	for i, spec := range specs {
+		fd := 3 + i
+		// Prevent the listeners from leaking to our children
+		syscall.CloseOnExec(fd)
+		inherited[spec] = os.NewFile(uintptr(fd), spec)
+	}
+	if fd, err := strconv.Atoi(os.Getenv(upgradeReadyEnv)); err == nil {
+		syscall.CloseOnExec(fd)
+		readyPipe = os.NewFile(uintptr(fd), "upgrade-ready")
+	}
+	for _, env := range []string{upgradePPIDEnv, upgradeListenersEnv, upgradeReadyEnv} {
+		os.Unsetenv(env)
+	}
+}
+
+// Listen announces on the local network address like net.Listen, and makes
+// the listener available to the process started by Upgrade. In that process,
+// Listen returns the listener inherited from its parent for the same network
+// and address instead of binding a new socket. Only "tcp" and "unix" listeners
+// can be passed.
+//
+// ListenAndServe and ListenAndServeTLS use Listen.
+func Listen(network, addr string) (net.Listener, error) {
+	upgradeMu.Lock()
+	defer upgradeMu.Unlock()
+
+	spec := network + ":" + addr
+	var l net.Listener
+	var err error
+	if f, ok := inherited[spec]; ok {
+		delete(inherited, spec)
+		l, err = net.FileListener(f)
+		f.Close()
+	} else {
+		l, err = net.Listen(network, addr)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if fl, ok := l.(fileListener); ok {
+		upgradeListeners = append(upgradeListeners, upgradeListener{network, addr, fl})
+	}
+	return l, nil
+}
+
+// HandleUpgrades installs a SIGUSR2 handler calling Upgrade. It should not be
+// used under Einhorn, which already uses SIGUSR2 to trigger a graceful
+// shutdown.
+func HandleUpgrades() {
+	c := make(chan os.Signal, 1)
+	signal.Notify(c, syscall.SIGUSR2)
+	go func() {
+		for range c {
+			if err := Upgrade(); err != nil {
+				log.Printf("graceful: upgrade failed: %v", err)
+			}
+		}
+	}()
+}
+
+// Upgrade starts a new process running the same binary with the same arguments,
+// and passes it the listeners created with Listen. Once the new process calls
+// Ready, the current process stops accepting connections and gracefully shuts
+// down, as if it received a signal.
+//
+// If the new process exits or doesn't call Ready within UpgradeTimeout, it is
+// killed, Upgrade returns an error and the current process keeps serving.
+func Upgrade() error {
+	upgradeMu.Lock()
+	defer upgradeMu.Unlock()
+
+	if upgrading || atomic.LoadInt32(&closing) != 0 {
+		return errors.New("graceful: already shutting down")
+	}
+
+	// The listeners are duplicated and passed with syscall.ForkExec rather
+	// than os/exec, which would put the sockets shared with this process in
+	// blocking mode.
+	var fds []int
+	defer func() {
+		for _, fd := range fds {
+			syscall.Close(fd)
+		}
+	}()
+	specs := make([]string, 0, len(upgradeListeners))
+	for _, ul := range upgradeListeners {
+		fd, err := dupListener(ul.l)
+		if err != nil {
+			return fmt.Errorf("graceful: could not pass %s listener on %s: %v", ul.network, ul.addr, err)
+		}
+		fds = append(fds, fd)
+		specs = append(specs, ul.network+":"+ul.addr)
+	}
+	r, w, err := os.Pipe()
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	defer w.Close()
+
+	cmd, err := upgradeCommand()
+	if err != nil {
+		return err
+	}
+	env := cmd.Env
+	if env == nil {
+		env = os.Environ()
+	}
+	env = append(env,
+		upgradePPIDEnv+"="+strconv.Itoa(os.Getpid()),
+		upgradeListenersEnv+"="+strings.Join(specs, ","),
+		upgradeReadyEnv+"="+strconv.Itoa(3+len(specs)),
+	)
+	files := []uintptr{0, 1, 2}
+	for _, fd := range fds {
+		files = append(files, uintptr(fd))
+	}
+	files = append(files, w.Fd())
+	pid, err := syscall.ForkExec(cmd.Path, cmd.Args, &syscall.ProcAttr{
+		Dir:   cmd.Dir,
+		Env:   env,
+		Files: files,
+	})
+	if err != nil {
+		return err
+	}
+	// Our end of the pipe must be closed to see EOF if the child exits
+	w.Close()
+	proc, err := os.FindProcess(pid)
+	if err != nil {
+		return err
+	}
+
+	exited := make(chan *os.ProcessState, 1)
+	go func() {
+		state, _ := proc.Wait()
+		exited <- state
+	}()
+	ready := make(chan bool, 1)
+	go func() {
+		buf := make([]byte, 1)
+		n, _ := r.Read(buf)
+		ready <- n == 1
+	}()
+
+	timer := time.NewTimer(UpgradeTimeout)
+	defer timer.Stop()
+	select {
+	case ok := <-ready:
+		if !ok {
+			return fmt.Errorf("graceful: new process exited before being ready: %v", <-exited)
+		}
+	case <-timer.C:
+		proc.Kill()
+		return errors.New("graceful: timed out waiting for the new process to be ready")
+	}
+
+	upgrading = true
+	for _, ul := range upgradeListeners {
+		// Closing our copy of a UNIX socket must not remove the socket file
+		// now used by the new process.
+		if ul, ok := ul.l.(*net.UnixListener); ok {
+			ul.SetUnlinkOnClose(false)
+		}
+	}
+	gracefulShutdown(false)
+	return nil
+}
+
+// dupListener returns a duplicate of the file descriptor of l.
+func dupListener(l fileListener) (int, error) {
+	rc, err := l.SyscallConn()
+	if err != nil {
+		return 0, err
+	}
+	var fd int
+	var derr error
+	err = rc.Control(func(s uintptr) {
+		syscall.ForkLock.RLock()
+		defer syscall.ForkLock.RUnlock()
+		if fd, derr = syscall.Dup(int(s)); derr == nil {
+			syscall.CloseOnExec(fd)
+		}
+	})
+	if err != nil {
+		return 0, err
+	}
+	return fd, derr
+}
+
+// Ready notifies the process which started this one through Upgrade that it is
+// ready to receive traffic, after which the parent process shuts down. It does
+// nothing if the process wasn't started by Upgrade.
+func Ready() {
+	upgradeMu.Lock()
+	defer upgradeMu.Unlock()
+
+	if readyPipe == nil {
+		return
+	}
+	readyPipe.Write([]byte{1})
+	readyPipe.Close()
+	readyPipe = nil
+	// Listeners which were not claimed are closed
+	for spec, f := range inherited {
+		f.Close()
+		delete(inherited, spec)
+	}
+}
@@ -0,0 +1,24 @@
+// +build windows !go1.10
+
+package graceful
+
+import (
+	"errors"
+	"net"
+)
+
+// Listen behaves like net.Listen. Upgrades are not supported on this platform.
+func Listen(network, addr string) (net.Listener, error) {
+	return net.Listen(network, addr)
+}
+
+// HandleUpgrades does nothing, upgrades are not supported on this platform.
+func HandleUpgrades() {}
+
+// Upgrade returns an error, upgrades are not supported on this platform.
+func Upgrade() error {
+	return errors.New("graceful: upgrades are not supported on this platform")
+}
+
+// Ready does nothing, upgrades are not supported on this platform.
+func Ready() {}
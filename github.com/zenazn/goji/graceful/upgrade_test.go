@@ -0,0 +1,107 @@
+// +build !windows,go1.10
+
+package graceful
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"os/exec"
+	"testing"
+	"time"
+)
+
+const upgradeChildEnv = "GOJI_TEST_UPGRADE_CHILD"
+
+// TestUpgradeChild is run as the new process by TestUpgrade.
+func TestUpgradeChild(t *testing.T) {
+	mode := os.Getenv(upgradeChildEnv)
+	if mode == "" {
+		t.Skip("only run by TestUpgrade")
+	}
+	if mode == "fail" {
+		os.Exit(1)
+	}
+	l, err := Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	inherited := l.Addr().String() == mode
+	served := make(chan struct{}, 1)
+	go Serve(l, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "child inherited=%v", inherited)
+		select {
+		case served <- struct{}{}:
+		default:
+		}
+	}))
+	Ready()
+	select {
+	case <-served:
+		// Let the response be written
+		time.Sleep(100 * time.Millisecond)
+	case <-time.After(10 * time.Second):
+	}
+}
+
+func get(t *testing.T, addr string) string {
+	client := &http.Client{Transport: &http.Transport{DisableKeepAlives: true}}
+	res, err := client.Get("http://" + addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+	b, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b)
+}
+
+func TestUpgrade(t *testing.T) {
+	if upgrading {
+		t.Skip("the process can only shut down once")
+	}
+	l, err := Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := l.Addr().String()
+	go Serve(l, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "parent")
+	}))
+
+	child := func(mode string) {
+		upgradeCommand = func() (*exec.Cmd, error) {
+			cmd := exec.Command(os.Args[0], "-test.run=^TestUpgradeChild$")
+			cmd.Env = append(os.Environ(), upgradeChildEnv+"="+mode)
+			return cmd, nil
+		}
+	}
+
+	// A failed upgrade keeps the current process serving
+	child("fail")
+	if err := Upgrade(); err == nil {
+		t.Fatal("expected upgrade to fail")
+	}
+	if body := get(t, addr); body != "parent" {
+		t.Fatalf("expected parent to serve, got %q", body)
+	}
+
+	child(addr)
+	if err := Upgrade(); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-wait:
+	case <-time.After(5 * time.Second):
+		t.Fatal("parent did not shut down")
+	}
+	if body := get(t, addr); body != "child inherited=true" {
+		t.Fatalf("expected child to serve, got %q", body)
+	}
+	if err := Upgrade(); err == nil {
+		t.Fatal("expected second upgrade to fail")
+	}
+}
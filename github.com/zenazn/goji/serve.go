@@ -51,6 +51,7 @@ func ServeListener(listener net.Listener) {
 
 	graceful.HandleSignals()
 	bind.Ready()
+	graceful.Ready()
 	graceful.PreHook(func() { log.Printf("Goji received signal, gracefully stopping") })
 	graceful.PostHook(func() { log.Printf("Goji stopped") })
 
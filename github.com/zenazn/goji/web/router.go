@@ -48,6 +48,7 @@ type route struct {
 	method  method
 	pattern Pattern
 	handler Handler
+	name    string
 }
 
 type router struct {
@@ -127,6 +128,12 @@ func (rt *router) handle(p Pattern, m method, h Handler) {
 	rt.lock.Lock()
 	defer rt.lock.Unlock()
 
+	var name string
+	if np, ok := p.(namedPattern); ok {
+		name, p = np.name, np.Pattern
+		rt.checkName(name)
+	}
+
 	// Calculate the sorted insertion point, because there's no reason to do
 	// swapping hijinks if we're already making a copy. We need to use
 	// bubble sort because we can only compare adjacent elements.
@@ -146,6 +153,7 @@ func (rt *router) handle(p Pattern, m method, h Handler) {
 		method:  m,
 		pattern: p,
 		handler: h,
+		name:    name,
 	}
 	copy(newRoutes[i+1:], rt.routes[i:])
 
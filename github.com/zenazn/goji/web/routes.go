@@ -0,0 +1,316 @@
+package web
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"reflect"
+	"regexp"
+	"runtime"
+	"sort"
+	"strings"
+	"text/tabwriter"
+)
+
+// namedPattern is a Pattern carrying the name of its route. The router unwraps
+// it when the route is added.
+type namedPattern struct {
+	Pattern
+	name string
+}
+
+/*
+Named names the route added with the given pattern, so that URLs can be built
+from it with Mux.URL:
+
+	m.Get(web.Named("user", "/users/:id"), showUser)
+	u, err := m.URL("user", map[string]string{"id": "42"}) // "/users/42"
+
+Route names must be unique within a Mux. Named accepts the same types as
+ParsePattern.
+*/
+func Named(name string, pattern PatternType) Pattern {
+	return namedPattern{ParsePattern(pattern), name}
+}
+
+// Route describes a route registered on a Mux, as returned by Mux.Routes.
+type Route struct {
+	// Name is the name given to the route with Named, or "".
+	Name string
+	// Methods is the sorted list of HTTP methods served by the route, or
+	// nil if it serves every method.
+	Methods []string
+	// Pattern is the PatternType the route was added with.
+	Pattern PatternType
+	// Handler is the HandlerType the route was added with.
+	Handler HandlerType
+	// Middleware is the middleware stack run before the handler, outermost
+	// first.
+	Middleware []MiddlewareType
+}
+
+func (m method) names() []string {
+	if m&mALL == mALL {
+		return nil
+	}
+	names := make([]string, 0)
+	for mname, meth := range validMethodsMap {
+		if m&meth != 0 {
+			names = append(names, mname)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (m *mStack) middleware() []MiddlewareType {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	mw := make([]MiddlewareType, len(m.stack))
+	for i, l := range m.stack {
+		mw[i] = l.orig
+	}
+	return mw
+}
+
+func (rt *router) snapshot() []route {
+	rt.lock.Lock()
+	defer rt.lock.Unlock()
+	return rt.routes
+}
+
+func (rt *router) byName(name string) (route, bool) {
+	rt.lock.Lock()
+	defer rt.lock.Unlock()
+	for _, r := range rt.routes {
+		if r.name == name {
+			return r, true
+		}
+	}
+	return route{}, false
+}
+
+func (r route) describe(mw []MiddlewareType) Route {
+	match := Match{Pattern: r.pattern, Handler: r.handler}
+	return Route{
+		Name:       r.name,
+		Methods:    r.method.names(),
+		Pattern:    match.RawPattern(),
+		Handler:    match.RawHandler(),
+		Middleware: mw,
+	}
+}
+
+/*
+Routes returns the routes of the Mux, in the order they are matched.
+
+The routes of Muxes added as handlers are listed in place of the route they
+were added with, restricted to the methods of that route, and with both
+middleware stacks.
+*/
+func (m *Mux) Routes() []Route {
+	return m.routes(nil, mALL)
+}
+
+func (m *Mux) routes(outer []MiddlewareType, methods method) []Route {
+	mw := append(append([]MiddlewareType{}, outer...), m.ms.middleware()...)
+	routes := make([]Route, 0)
+	for _, r := range m.rt.snapshot() {
+		meth := r.method & methods
+		if meth == 0 {
+			continue
+		}
+		if sub, ok := r.handler.(*Mux); ok {
+			routes = append(routes, sub.routes(mw, meth)...)
+			continue
+		}
+		r.method = meth
+		routes = append(routes, r.describe(mw))
+	}
+	return routes
+}
+
+/*
+Lookup returns the route which would serve a request with the given method and
+path, and the URL parameters it would be called with. It returns false if no
+route matches, in which case the NotFound handler would be called.
+
+Like Routes, Lookup descends into Muxes added as handlers.
+*/
+func (m *Mux) Lookup(method, path string) (Route, map[string]string, bool) {
+	r := &http.Request{Method: method, URL: &url.URL{Path: path}}
+	var c C
+	return m.lookup(nil, r, &c)
+}
+
+func (m *Mux) lookup(outer []MiddlewareType, r *http.Request, c *C) (Route, map[string]string, bool) {
+	rm := m.rt.getMachine()
+	if rm == nil {
+		rm = m.rt.compile()
+	}
+	mw := append(append([]MiddlewareType{}, outer...), m.ms.middleware()...)
+	_, rte := rm.route(c, nil, r)
+	if rte == nil {
+		return Route{}, nil, false
+	}
+	if sub, ok := rte.handler.(*Mux); ok {
+		return sub.lookup(mw, r, c)
+	}
+	return rte.describe(mw), c.URLParams, true
+}
+
+/*
+URL builds the path of the route with the given name, replacing its named
+parameters with the given values, e.g. "/users/:id" with {"id": "42"} gives
+"/users/42". The wildcard of patterns ending in "/*" is replaced with the value
+of "*", or removed if there's none. Values are escaped.
+
+Only string patterns can be reversed. Named routes of Muxes added as handlers
+are also found.
+*/
+func (m *Mux) URL(name string, params map[string]string) (string, error) {
+	r, ok := m.findNamed(name)
+	if !ok {
+		return "", fmt.Errorf("web: unknown route %q", name)
+	}
+	sp, ok := r.pattern.(stringPattern)
+	if !ok {
+		return "", fmt.Errorf("web: route %q does not have a string pattern", name)
+	}
+	var path string
+	for i, pat := range sp.pats {
+		v, ok := params[pat]
+		if !ok || v == "" {
+			return "", fmt.Errorf("web: missing parameter %q for route %q", pat, name)
+		}
+		if strings.ContainsAny(v, "/"+string(sp.breaks[i])) {
+			return "", fmt.Errorf("web: parameter %q of route %q cannot contain %q", pat, name, sp.breaks[i])
+		}
+		path += sp.literals[i] + v
+	}
+	tail := sp.literals[len(sp.pats)]
+	if sp.wildcard {
+		// The wildcard matches the tail's trailing slash
+		tail = tail[:len(tail)-1]
+		w := params["*"]
+		if !strings.HasPrefix(w, "/") {
+			w = "/" + w
+		}
+		tail += w
+	}
+	u := url.URL{Path: path + tail}
+	return u.String(), nil
+}
+
+func (m *Mux) findNamed(name string) (route, bool) {
+	if r, ok := m.rt.byName(name); ok {
+		return r, true
+	}
+	for _, r := range m.rt.snapshot() {
+		if sub, ok := r.handler.(*Mux); ok {
+			if r, ok := sub.findNamed(name); ok {
+				return r, true
+			}
+		}
+	}
+	return route{}, false
+}
+
+/*
+DebugRoutes is a handler printing the route table of the Mux as plain text. If
+the "path" query parameter is set, it also prints the route and middleware
+which would serve a request with that path, and with the method given by the
+"method" query parameter (GET by default).
+
+It is meant to be added on a protected route during development:
+
+	m.Get("/debug/routes", m.DebugRoutes)
+*/
+func (m *Mux) DebugRoutes(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+
+	if path := r.URL.Query().Get("path"); path != "" {
+		method := r.URL.Query().Get("method")
+		if method == "" {
+			method = "GET"
+		}
+		method = strings.ToUpper(method)
+		if rte, params, ok := m.Lookup(method, path); ok {
+			fmt.Fprintf(w, "%s %s matches %s\n", method, path, describePattern(rte.Pattern))
+			if rte.Name != "" {
+				fmt.Fprintf(w, "  name:       %s\n", rte.Name)
+			}
+			fmt.Fprintf(w, "  handler:    %s\n", describeFunc(rte.Handler))
+			fmt.Fprintf(w, "  middleware: %s\n", describeMiddleware(rte.Middleware))
+			keys := make([]string, 0, len(params))
+			for k := range params {
+				keys = append(keys, k)
+			}
+			sort.Strings(keys)
+			for _, k := range keys {
+				fmt.Fprintf(w, "  param:      %s=%q\n", k, params[k])
+			}
+		} else {
+			fmt.Fprintf(w, "%s %s matches no route\n", method, path)
+		}
+		fmt.Fprintln(w)
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, "METHODS\tPATTERN\tNAME\tHANDLER\tMIDDLEWARE")
+	for _, rte := range m.Routes() {
+		methods := "*"
+		if rte.Methods != nil {
+			methods = strings.Join(rte.Methods, ",")
+		}
+		name := rte.Name
+		if name == "" {
+			name = "-"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", methods, describePattern(rte.Pattern),
+			name, describeFunc(rte.Handler), describeMiddleware(rte.Middleware))
+	}
+	tw.Flush()
+}
+
+func describePattern(p PatternType) string {
+	switch v := p.(type) {
+	case string:
+		return v
+	case *regexp.Regexp:
+		return v.String()
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// describeFunc returns the name of functions, and the type of other values.
+func describeFunc(f interface{}) string {
+	v := reflect.ValueOf(f)
+	if v.Kind() == reflect.Func {
+		if fn := runtime.FuncForPC(v.Pointer()); fn != nil {
+			return fn.Name()
+		}
+	}
+	return fmt.Sprintf("%T", f)
+}
+
+func describeMiddleware(mw []MiddlewareType) string {
+	if len(mw) == 0 {
+		return "-"
+	}
+	names := make([]string, len(mw))
+	for i, f := range mw {
+		names[i] = describeFunc(f)
+	}
+	return strings.Join(names, ", ")
+}
+
+func (rt *router) checkName(name string) {
+	for _, r := range rt.routes {
+		if r.name == name {
+			log.Fatalf("web: duplicate route name %q", name)
+		}
+	}
+}
@@ -0,0 +1,169 @@
+package web
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func routesHandler(w http.ResponseWriter, r *http.Request) {}
+
+func routesMiddleware(h http.Handler) http.Handler { return h }
+
+func adminMiddleware(c *C, h http.Handler) http.Handler { return h }
+
+func routesMux() (*Mux, *Mux) {
+	m := New()
+	m.Use(routesMiddleware)
+	m.Get(Named("user", "/users/:id"), routesHandler)
+	m.Post("/users", routesHandler)
+	m.Get(Named("file", "/files/:name.:ext"), routesHandler)
+	m.Get(Named("static", "/static/*"), routesHandler)
+	m.Get(Named("re", regexp.MustCompile(`^/re/(?P<id>\d+)$`)), routesHandler)
+
+	admin := New()
+	admin.Use(adminMiddleware)
+	admin.Delete(Named("admin.user", "/admin/users/:id"), routesHandler)
+	admin.Get("/admin/", routesHandler)
+	m.Post("/admin/*", admin)
+	m.Handle("/admin/*", admin)
+	return m, admin
+}
+
+func TestRoutes(t *testing.T) {
+	t.Parallel()
+	m, _ := routesMux()
+	routes := m.Routes()
+
+	var got []string
+	for _, r := range routes {
+		methods := "*"
+		if r.Methods != nil {
+			methods = strings.Join(r.Methods, ",")
+		}
+		got = append(got, methods+" "+describePattern(r.Pattern)+" "+r.Name)
+	}
+	expected := []string{
+		"DELETE /admin/users/:id admin.user",
+		"GET,HEAD /admin/ ",
+		"GET,HEAD /files/:name.:ext file",
+		"GET,HEAD ^/re/(?P<id>\\d+)$ re",
+		"GET,HEAD /static/* static",
+		"GET,HEAD /users/:id user",
+		"POST /users ",
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected routes %q, got %q", expected, got)
+	}
+
+	if len(routes[0].Middleware) != 2 {
+		t.Fatalf("Expected 2 middleware, got %v", routes[0].Middleware)
+	}
+	if !funcEqual(routes[0].Middleware[0], routesMiddleware) ||
+		!funcEqual(routes[0].Middleware[1], adminMiddleware) {
+		t.Errorf("Unexpected middleware %v", routes[0].Middleware)
+	}
+	if len(routes[6].Middleware) != 1 {
+		t.Errorf("Expected 1 middleware, got %v", routes[6].Middleware)
+	}
+	if reflect.ValueOf(routes[6].Handler).Pointer() != reflect.ValueOf(routesHandler).Pointer() {
+		t.Errorf("Unexpected handler %v", routes[6].Handler)
+	}
+}
+
+func TestLookup(t *testing.T) {
+	t.Parallel()
+	m, _ := routesMux()
+
+	r, params, ok := m.Lookup("GET", "/users/42")
+	if !ok || r.Name != "user" || params["id"] != "42" {
+		t.Errorf("Unexpected lookup %v %v %v", r, params, ok)
+	}
+	r, params, ok = m.Lookup("DELETE", "/admin/users/7")
+	if !ok || r.Name != "admin.user" || params["id"] != "7" || len(r.Middleware) != 2 {
+		t.Errorf("Unexpected lookup %v %v %v", r, params, ok)
+	}
+	if _, _, ok = m.Lookup("PUT", "/users/42"); ok {
+		t.Error("Expected no route for PUT /users/42")
+	}
+	if _, _, ok = m.Lookup("GET", "/nope"); ok {
+		t.Error("Expected no route for /nope")
+	}
+}
+
+func TestURL(t *testing.T) {
+	t.Parallel()
+	m, _ := routesMux()
+
+	tests := []struct {
+		name   string
+		params map[string]string
+		url    string
+		err    bool
+	}{
+		{"user", map[string]string{"id": "42"}, "/users/42", false},
+		{"user", map[string]string{"id": "a b?"}, "/users/a%20b%3F", false},
+		{"user", map[string]string{"id": "a/b"}, "", true},
+		{"user", nil, "", true},
+		{"file", map[string]string{"name": "report", "ext": "pdf"}, "/files/report.pdf", false},
+		{"file", map[string]string{"name": "a.b", "ext": "pdf"}, "", true},
+		{"static", map[string]string{"*": "css/app.css"}, "/static/css/app.css", false},
+		{"static", map[string]string{"*": "/js/app.js"}, "/static/js/app.js", false},
+		{"static", nil, "/static/", false},
+		{"admin.user", map[string]string{"id": "7"}, "/admin/users/7", false},
+		{"re", nil, "", true},
+		{"unknown", nil, "", true},
+	}
+	for _, test := range tests {
+		u, err := m.URL(test.name, test.params)
+		if test.err {
+			if err == nil {
+				t.Errorf("Expected error for %q %v, got %q", test.name, test.params, u)
+			}
+			continue
+		}
+		if err != nil || u != test.url {
+			t.Errorf("Expected %q for %q %v, got %q (%v)", test.url, test.name, test.params, u, err)
+		}
+	}
+
+	// Reversed URLs are routed back to their route
+	u, _ := m.URL("file", map[string]string{"name": "report", "ext": "pdf"})
+	r, params, ok := m.Lookup("GET", u)
+	if !ok || r.Name != "file" || params["name"] != "report" || params["ext"] != "pdf" {
+		t.Errorf("Unexpected lookup of %q: %v %v", u, r, params)
+	}
+}
+
+func TestDebugRoutes(t *testing.T) {
+	t.Parallel()
+	m, _ := routesMux()
+	m.Get("/debug/routes", m.DebugRoutes)
+
+	r, _ := http.NewRequest("GET", "/debug/routes?method=delete&path=/admin/users/7", nil)
+	w := httptest.NewRecorder()
+	m.ServeHTTP(w, r)
+	body := w.Body.String()
+	for _, s := range []string{
+		"DELETE /admin/users/7 matches /admin/users/:id",
+		"name:       admin.user",
+		"middleware: github.com/zenazn/goji/web.routesMiddleware, github.com/zenazn/goji/web.adminMiddleware",
+		`param:      id="7"`,
+		"METHODS",
+		"GET,HEAD  /users/:id",
+	} {
+		if !strings.Contains(body, s) {
+			t.Errorf("Expected %q in:\n%s", s, body)
+		}
+	}
+
+	r, _ = http.NewRequest("GET", "/debug/routes?path=/nope", nil)
+	w = httptest.NewRecorder()
+	m.ServeHTTP(w, r)
+	if !strings.Contains(w.Body.String(), "GET /nope matches no route") {
+		t.Errorf("Unexpected body:\n%s", w.Body.String())
+	}
+}
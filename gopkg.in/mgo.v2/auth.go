@@ -29,6 +29,7 @@ package mgo
 import (
 	"crypto/md5"
 	"crypto/sha1"
+	"crypto/sha256"
 	"encoding/hex"
 	"errors"
 	"fmt"
@@ -87,6 +88,15 @@ type saslResult struct {
 	ErrMsg         string
 }
 
+type saslSupportedMechsCmd struct {
+	IsMaster           int    `bson:"ismaster"`
+	SaslSupportedMechs string `bson:"saslSupportedMechs"`
+}
+
+type saslSupportedMechsResult struct {
+	SaslSupportedMechs []string `bson:"saslSupportedMechs"`
+}
+
 type saslStepper interface {
 	Step(serverData []byte) (clientData []byte, done bool, err error)
 	Close()
@@ -160,8 +170,10 @@ func (socket *mongoSocket) resetNonce() {
 
 func (socket *mongoSocket) Login(cred Credential) error {
 	socket.Lock()
-	if cred.Mechanism == "" && socket.serverInfo.MaxWireVersion >= 3 {
-		cred.Mechanism = "SCRAM-SHA-1"
+	maxWireVersion := socket.serverInfo.MaxWireVersion
+	negotiate := cred.Mechanism == "" && maxWireVersion >= 3
+	if negotiate {
+		cred.Mechanism = socket.scramMechanism(cred)
 	}
 	for _, sockCred := range socket.creds {
 		if sockCred == cred {
@@ -178,6 +190,14 @@ func (socket *mongoSocket) Login(cred Credential) error {
 	}
 	socket.Unlock()
 
+	if negotiate && cred.Mechanism == "" {
+		var err error
+		cred.Mechanism, err = socket.negotiateMechanism(cred, maxWireVersion)
+		if err != nil {
+			return err
+		}
+	}
+
 	debugf("Socket %p to %s: login: db=%q user=%q", socket, socket.addr, cred.Source, cred.Username)
 
 	var err error
@@ -201,6 +221,50 @@ func (socket *mongoSocket) Login(cred Credential) error {
 	return err
 }
 
+// scramMechanism returns the SCRAM mechanism that cred, which has no
+// mechanism set, was last negotiated with on the socket, or "" if it is
+// neither logged in nor pending logout. This avoids a round trip to the
+// server for every login with the same credential.
+//
+// The socket must be locked.
+func (socket *mongoSocket) scramMechanism(cred Credential) string {
+	for _, creds := range [][]Credential{socket.creds, socket.logout} {
+		for _, sockCred := range creds {
+			if sockCred.Mechanism != "SCRAM-SHA-1" && sockCred.Mechanism != "SCRAM-SHA-256" {
+				continue
+			}
+			cred.Mechanism = sockCred.Mechanism
+			if sockCred == cred {
+				return cred.Mechanism
+			}
+		}
+	}
+	return ""
+}
+
+// negotiateMechanism picks the SCRAM mechanism for cred. Servers from 4.0 on
+// (wire version 7) list the mechanisms the user supports in response to
+// isMaster with saslSupportedMechs, and SCRAM-SHA-256 is preferred when
+// available. Older servers only support SCRAM-SHA-1.
+func (socket *mongoSocket) negotiateMechanism(cred Credential, maxWireVersion int) (string, error) {
+	if maxWireVersion < 7 {
+		return "SCRAM-SHA-1", nil
+	}
+	cmd := saslSupportedMechsCmd{IsMaster: 1, SaslSupportedMechs: cred.Source + "." + cred.Username}
+	res := saslSupportedMechsResult{}
+	err := socket.loginRun("admin", &cmd, &res, func() error { return nil })
+	if err != nil {
+		return "", err
+	}
+	debugf("Socket %p to %s: login: db=%q user=%q supports %v", socket, socket.addr, cred.Source, cred.Username, res.SaslSupportedMechs)
+	for _, mech := range res.SaslSupportedMechs {
+		if mech == "SCRAM-SHA-256" {
+			return mech, nil
+		}
+	}
+	return "SCRAM-SHA-1", nil
+}
+
 func (socket *mongoSocket) loginClassic(cred Credential) error {
 	// Note that this only works properly because this function is
 	// synchronous, which means the nonce won't get reset while we're
@@ -274,9 +338,9 @@ func (socket *mongoSocket) loginPlain(cred Credential) error {
 func (socket *mongoSocket) loginSASL(cred Credential) error {
 	var sasl saslStepper
 	var err error
-	if cred.Mechanism == "SCRAM-SHA-1" {
+	if cred.Mechanism == "SCRAM-SHA-1" || cred.Mechanism == "SCRAM-SHA-256" {
 		// SCRAM is handled without external libraries.
-		sasl = saslNewScram(cred)
+		sasl, err = saslNewScram(cred)
 	} else if len(cred.ServiceHost) > 0 {
 		sasl, err = saslNew(cred, cred.ServiceHost)
 	} else {
@@ -353,11 +417,22 @@ func (socket *mongoSocket) loginSASL(cred Credential) error {
 	return nil
 }
 
-func saslNewScram(cred Credential) *saslScram {
-	credsum := md5.New()
-	credsum.Write([]byte(cred.Username + ":mongo:" + cred.Password))
-	client := scram.NewClient(sha1.New, cred.Username, hex.EncodeToString(credsum.Sum(nil)))
-	return &saslScram{cred: cred, client: client}
+func saslNewScram(cred Credential) (*saslScram, error) {
+	var client *scram.Client
+	if cred.Mechanism == "SCRAM-SHA-256" {
+		// Unlike SCRAM-SHA-1, SCRAM-SHA-256 takes the password itself
+		// rather than its MONGODB-CR digest, normalized with SASLprep.
+		pass, err := saslPrep(cred.Password)
+		if err != nil {
+			return nil, fmt.Errorf("cannot prepare password for SCRAM-SHA-256: %v", err)
+		}
+		client = scram.NewClient(sha256.New, cred.Username, pass)
+	} else {
+		credsum := md5.New()
+		credsum.Write([]byte(cred.Username + ":mongo:" + cred.Password))
+		client = scram.NewClient(sha1.New, cred.Username, hex.EncodeToString(credsum.Sum(nil)))
+	}
+	return &saslScram{cred: cred, client: client}, nil
 }
 
 type saslScram struct {
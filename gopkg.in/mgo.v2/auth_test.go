@@ -40,6 +40,7 @@ import (
 
 	. "gopkg.in/check.v1"
 	"gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
 )
 
 func (s *S) TestAuthLoginDatabase(c *C) {
@@ -894,6 +895,87 @@ func (s *S) TestAuthScramSha1URL(c *C) {
 	c.Assert(err, Equals, mgo.ErrNotFound)
 }
 
+func (s *S) TestAuthScramSha256Cred(c *C) {
+	if !s.versionAtLeast(4, 0) {
+		c.Skip("SCRAM-SHA-256 tests depend on 4.0")
+	}
+	cred := &mgo.Credential{
+		Username:  "root",
+		Password:  "rapadura",
+		Mechanism: "SCRAM-SHA-256",
+		Source:    "admin",
+	}
+	host := "localhost:40002"
+	c.Logf("Connecting to %s...", host)
+	session, err := mgo.Dial(host)
+	c.Assert(err, IsNil)
+	defer session.Close()
+
+	mycoll := session.DB("admin").C("mycoll")
+
+	c.Logf("Connected! Testing the need for authentication...")
+	err = mycoll.Find(nil).One(nil)
+	c.Assert(err, ErrorMatches, "unauthorized|not authorized .*")
+
+	c.Logf("Authenticating...")
+	err = session.Login(cred)
+	c.Assert(err, IsNil)
+	c.Logf("Authenticated!")
+
+	c.Logf("Connected! Testing the need for authentication...")
+	err = mycoll.Find(nil).One(nil)
+	c.Assert(err, Equals, mgo.ErrNotFound)
+}
+
+func (s *S) TestAuthScramSha256URL(c *C) {
+	if !s.versionAtLeast(4, 0) {
+		c.Skip("SCRAM-SHA-256 tests depend on 4.0")
+	}
+	host := "localhost:40002"
+	c.Logf("Connecting to %s...", host)
+	session, err := mgo.Dial(fmt.Sprintf("root:rapadura@%s?authMechanism=SCRAM-SHA-256", host))
+	c.Assert(err, IsNil)
+	defer session.Close()
+
+	mycoll := session.DB("admin").C("mycoll")
+
+	c.Logf("Connected! Testing the need for authentication...")
+	err = mycoll.Find(nil).One(nil)
+	c.Assert(err, Equals, mgo.ErrNotFound)
+}
+
+func (s *S) TestAuthScramNegotiated(c *C) {
+	if !s.versionAtLeast(3, 0) {
+		c.Skip("SCRAM negotiation depends on 3.0")
+	}
+	session, err := mgo.Dial("localhost:40002")
+	c.Assert(err, IsNil)
+	defer session.Close()
+
+	admindb := session.DB("admin")
+	err = admindb.Login("root", "rapadura")
+	c.Assert(err, IsNil)
+
+	// Users limited to SCRAM-SHA-1 must still be able to log in with
+	// servers that support SCRAM-SHA-256.
+	createUser := bson.D{{"createUser", "sha1user"}, {"pwd", "pass"}, {"roles", []string{"read"}}}
+	if s.versionAtLeast(4, 0) {
+		createUser = append(createUser, bson.DocElem{"mechanisms", []string{"SCRAM-SHA-1"}})
+	}
+	err = admindb.Run(createUser, nil)
+	c.Assert(err, IsNil)
+	defer admindb.RemoveUser("sha1user")
+
+	for _, userinfo := range []string{"root:rapadura", "sha1user:pass"} {
+		c.Logf("Connecting as %s...", userinfo)
+		session, err := mgo.Dial(userinfo + "@localhost:40002/admin")
+		c.Assert(err, IsNil)
+		err = session.DB("admin").C("mycoll").Find(nil).One(nil)
+		c.Assert(err, Equals, mgo.ErrNotFound)
+		session.Close()
+	}
+}
+
 func (s *S) TestAuthX509Cred(c *C) {
 	session, err := mgo.Dial("localhost:40001")
 	c.Assert(err, IsNil)
@@ -963,6 +1045,65 @@ func (s *S) TestAuthX509Cred(c *C) {
 	c.Assert(len(names) > 0, Equals, true)
 }
 
+func (s *S) TestAuthX509DialInfo(c *C) {
+	session, err := mgo.Dial("localhost:40001")
+	c.Assert(err, IsNil)
+	defer session.Close()
+	binfo, err := session.BuildInfo()
+	c.Assert(err, IsNil)
+	if binfo.OpenSSLVersion == "" {
+		c.Skip("server does not support SSL")
+	}
+
+	clientCertPEM, err := ioutil.ReadFile("harness/certs/client.pem")
+	c.Assert(err, IsNil)
+
+	clientCert, err := tls.X509KeyPair(clientCertPEM, clientCertPEM)
+	c.Assert(err, IsNil)
+
+	tlsConfig := &tls.Config{
+		// Isolating tests to client certs, don't care about server validation.
+		InsecureSkipVerify: true,
+		Certificates:       []tls.Certificate{clientCert},
+	}
+
+	var host = "localhost:40003"
+	c.Logf("Connecting to %s...", host)
+	session, err = mgo.DialWithInfo(&mgo.DialInfo{
+		Addrs:     []string{host},
+		TLSConfig: tlsConfig,
+		Username:  "root",
+		Password:  "rapadura",
+	})
+	c.Assert(err, IsNil)
+	defer session.Close()
+
+	// This needs to be kept in sync with client.pem
+	x509Subject := "CN=localhost,OU=Client,O=MGO,L=MGO,ST=MGO,C=GO"
+
+	externalDB := session.DB("$external")
+	var x509User mgo.User = mgo.User{
+		Username:     x509Subject,
+		OtherDBRoles: map[string][]mgo.Role{"admin": []mgo.Role{mgo.RoleRoot}},
+	}
+	err = externalDB.UpsertUser(&x509User)
+	c.Assert(err, IsNil)
+
+	c.Logf("Authenticating with the client certificate...")
+	x509Session, err := mgo.DialWithInfo(&mgo.DialInfo{
+		Addrs:     []string{host},
+		TLSConfig: tlsConfig,
+		Mechanism: "MONGODB-X509",
+	})
+	c.Assert(err, IsNil)
+	defer x509Session.Close()
+	c.Logf("Authenticated!")
+
+	names, err := x509Session.DatabaseNames()
+	c.Assert(err, IsNil)
+	c.Assert(len(names) > 0, Equals, true)
+}
+
 var (
 	plainFlag = flag.String("plain", "", "Host to test PLAIN authentication against (depends on custom environment)")
 	plainUser = "einstein"
@@ -0,0 +1,406 @@
+package mgo
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"gopkg.in/mgo.v2/bson"
+)
+
+// FullDocument defines what the fullDocument field of update change events
+// holds. See ChangeStreamOptions.
+type FullDocument string
+
+const (
+	// FullDocumentDefault leaves out the full document from update
+	// events, which only include the changed fields.
+	FullDocumentDefault FullDocument = "default"
+
+	// FullDocumentUpdateLookup includes the most current majority-committed
+	// version of the updated document in update events.
+	FullDocumentUpdateLookup FullDocument = "updateLookup"
+)
+
+// ChangeStreamOptions holds the options for opening a change stream with
+// the Watch methods.
+type ChangeStreamOptions struct {
+	// FullDocument defines what update events hold in their fullDocument
+	// field. Defaults to FullDocumentDefault.
+	FullDocument FullDocument
+
+	// ResumeAfter, if set, starts the stream right after the change
+	// event with the given resume token. See ChangeStream.ResumeToken.
+	ResumeAfter *bson.Raw
+
+	// MaxAwaitTime is the maximum amount of time the server waits for new
+	// changes before replying to a request for more of them. If set, Next
+	// returns false with Timeout reporting true when no changes arrive in
+	// that time. Otherwise Next blocks until a change is available.
+	MaxAwaitTime time.Duration
+
+	// BatchSize is the number of changes requested per batch. Defaults to
+	// the server's choice.
+	BatchSize int
+
+	// Collation defines the collation used for string comparisons in the
+	// pipeline.
+	Collation *Collation
+}
+
+// ChangeStream iterates over the change events of a collection, database or
+// whole deployment, as opened by the Watch methods.
+//
+// A change stream remembers the resume token of the last event obtained
+// with Next, and automatically reopens itself right after that event when
+// the server connection is lost or the server steps down, so that no events
+// are missed.
+//
+// A ChangeStream must not be used concurrently by multiple goroutines,
+// except for Close which interrupts a blocked Next call.
+//
+// Relevant documentation:
+//
+//     https://docs.mongodb.com/manual/changeStreams/
+//
+type ChangeStream struct {
+	m           sync.Mutex // held by Next
+	session     *Session
+	db          string
+	collection  string
+	cluster     bool
+	pipeline    []interface{}
+	options     ChangeStreamOptions
+	iter        *Iter
+	resumeToken *bson.Raw
+	startAt     bson.MongoTimestamp
+	err         error
+	timedout    bool
+
+	// cm guards iter and closed, so that Close doesn't wait for Next.
+	cm     sync.Mutex
+	closed bool
+}
+
+type changeStreamCmd struct {
+	Aggregate interface{}    `bson:"aggregate"`
+	Pipeline  []interface{}  `bson:"pipeline"`
+	Cursor    *pipeCmdCursor `bson:"cursor"`
+	Collation *Collation     `bson:"collation,omitempty"`
+}
+
+type changeStreamStage struct {
+	FullDocument         FullDocument        `bson:"fullDocument,omitempty"`
+	ResumeAfter          *bson.Raw           `bson:"resumeAfter,omitempty"`
+	StartAtOperationTime bson.MongoTimestamp `bson:"startAtOperationTime,omitempty"`
+	AllChangesForCluster bool                `bson:"allChangesForCluster,omitempty"`
+}
+
+// Watch opens a change stream reporting the changes made to the collection.
+// The optional pipeline is appended to the $changeStream stage to filter or
+// transform the change events, and must be a slice of stages such as those
+// accepted by Pipe, or a Pipeline.
+//
+// For example:
+//
+//     stream, err := collection.Watch(nil, mgo.ChangeStreamOptions{})
+//     if err != nil {
+//         return err
+//     }
+//     defer stream.Close()
+//     var change bson.M
+//     for stream.Next(&change) {
+//         fmt.Printf("Change: %v\n", change)
+//     }
+//     if err := stream.Err(); err != nil {
+//         return err
+//     }
+//
+// Change streams require MongoDB 3.6 or later, and a replica set or
+// sharded cluster.
+func (c *Collection) Watch(pipeline interface{}, options ChangeStreamOptions) (*ChangeStream, error) {
+	return newChangeStream(c.Database.Session, c.Database.Name, c.Name, false, pipeline, options)
+}
+
+// Watch opens a change stream reporting the changes made to all the
+// collections in the database. See Collection.Watch for details.
+//
+// Watching a database requires MongoDB 4.0 or later.
+func (db *Database) Watch(pipeline interface{}, options ChangeStreamOptions) (*ChangeStream, error) {
+	return newChangeStream(db.Session, db.Name, "", false, pipeline, options)
+}
+
+// Watch opens a change stream reporting the changes made to all the
+// databases in the deployment, except for the admin, local and config
+// databases. See Collection.Watch for details.
+//
+// Watching a deployment requires MongoDB 4.0 or later.
+func (s *Session) Watch(pipeline interface{}, options ChangeStreamOptions) (*ChangeStream, error) {
+	return newChangeStream(s, "admin", "", true, pipeline, options)
+}
+
+func newChangeStream(session *Session, db, collection string, cluster bool, pipeline interface{}, options ChangeStreamOptions) (*ChangeStream, error) {
+	stages, err := pipelineStages(pipeline)
+	if err != nil {
+		return nil, err
+	}
+
+	// The stream gets its own session, as waiting for changes would
+	// otherwise hold the socket reserved by the given one.
+	scopy := session.Copy()
+	if scopy.Mode() == Eventual {
+		scopy.SetMode(Monotonic, false)
+	}
+
+	cs := &ChangeStream{
+		session:     scopy,
+		db:          db,
+		collection:  collection,
+		cluster:     cluster,
+		pipeline:    stages,
+		options:     options,
+		resumeToken: options.ResumeAfter,
+	}
+	cs.iter, err = cs.open()
+	if err != nil {
+		scopy.Close()
+		return nil, err
+	}
+	return cs, nil
+}
+
+// pipelineStages returns the stages of pipeline, which may be nil or any
+// slice of stage documents.
+func pipelineStages(pipeline interface{}) ([]interface{}, error) {
+	if pipeline == nil {
+		return nil, nil
+	}
+	v := reflect.ValueOf(pipeline)
+	if v.Kind() != reflect.Slice {
+		return nil, errors.New("change stream pipeline must be a slice of stages")
+	}
+	stages := make([]interface{}, v.Len())
+	for i := range stages {
+		stages[i] = v.Index(i).Interface()
+	}
+	return stages, nil
+}
+
+// open runs the aggregation for the change stream, resuming after the
+// last change event obtained, if any. Otherwise, when reopening the stream,
+// it starts at the operation time of the initial aggregation so that no
+// events are missed.
+func (cs *ChangeStream) open() (*Iter, error) {
+	stage := changeStreamStage{
+		FullDocument:         cs.options.FullDocument,
+		ResumeAfter:          cs.resumeToken,
+		AllChangesForCluster: cs.cluster,
+	}
+	if cs.resumeToken == nil {
+		stage.StartAtOperationTime = cs.startAt
+	}
+	cmd := changeStreamCmd{
+		Aggregate: 1,
+		Pipeline:  append([]interface{}{bson.M{"$changeStream": stage}}, cs.pipeline...),
+		Cursor:    &pipeCmdCursor{cs.options.BatchSize},
+		Collation: cs.options.Collation,
+	}
+	if cs.collection != "" {
+		cmd.Aggregate = cs.collection
+	}
+
+	var result struct {
+		Cursor        cursorData
+		OperationTime bson.MongoTimestamp `bson:"operationTime"`
+	}
+	err := cs.session.DB(cs.db).Run(cmd, &result)
+	if err != nil {
+		return nil, err
+	}
+	if cs.startAt == 0 {
+		// Servers before 4.0 don't report the operation time.
+		cs.startAt = result.OperationTime
+	}
+
+	// Database and deployment streams have cursors in namespaces such
+	// as "db.$cmd.aggregate", so more changes must be requested from
+	// the namespace reported by the server.
+	dot := strings.Index(result.Cursor.NS, ".")
+	if dot < 0 {
+		return nil, errors.New("invalid change stream cursor namespace: " + result.Cursor.NS)
+	}
+	c := cs.session.DB(result.Cursor.NS[:dot]).C(result.Cursor.NS[dot+1:])
+	iter := c.NewIter(nil, result.Cursor.FirstBatch, result.Cursor.Id, nil)
+	iter.findCmd = true
+	if cs.options.MaxAwaitTime > 0 {
+		iter.maxTimeMS = int64(cs.options.MaxAwaitTime / time.Millisecond)
+		iter.timeout = cs.options.MaxAwaitTime
+	}
+	return iter, nil
+}
+
+// Next retrieves the next change event from the stream, blocking until one
+// is available, and unmarshals it into result.
+//
+// Next returns false if an error happened, if the stream was invalidated
+// (by dropping the watched collection, for instance), or if no changes
+// arrived within the MaxAwaitTime option. In the latter case Timeout
+// returns true and Next may be called again to keep waiting for changes.
+// Errors that prevent the stream from resuming are reported by Err.
+func (cs *ChangeStream) Next(result interface{}) bool {
+	cs.m.Lock()
+	defer cs.m.Unlock()
+
+	cs.timedout = false
+	resumed := false
+	for cs.err == nil && !cs.isClosed() {
+		var raw bson.Raw
+		if cs.iter.Next(&raw) {
+			var event struct {
+				ResumeToken *bson.Raw `bson:"_id"`
+			}
+			if err := raw.Unmarshal(&event); err != nil {
+				cs.err = err
+				return false
+			}
+			if event.ResumeToken == nil {
+				cs.err = errors.New("change stream event has no resume token; the pipeline must not remove the _id field")
+				return false
+			}
+			cs.resumeToken = event.ResumeToken
+			if err := raw.Unmarshal(result); err != nil {
+				cs.err = err
+				return false
+			}
+			return true
+		}
+		if cs.isClosed() {
+			return false
+		}
+		if cs.iter.Timeout() {
+			cs.timedout = true
+			return false
+		}
+		err := cs.iter.Err()
+		if err == nil {
+			// The server closed the cursor after invalidating the stream.
+			return false
+		}
+		if resumed || !isResumableError(err) {
+			cs.err = err
+			return false
+		}
+
+		debugf("Change stream %p resuming after error: %v", cs, err)
+		cs.iter.Close()
+		cs.session.Refresh()
+		iter, err := cs.open()
+		if err != nil {
+			cs.err = err
+			return false
+		}
+		cs.cm.Lock()
+		closed := cs.closed
+		if !closed {
+			cs.iter = iter
+		}
+		cs.cm.Unlock()
+		if closed {
+			iter.Close()
+			return false
+		}
+		resumed = true
+	}
+	return false
+}
+
+func (cs *ChangeStream) isClosed() bool {
+	cs.cm.Lock()
+	defer cs.cm.Unlock()
+	return cs.closed
+}
+
+// isResumableError returns whether err, obtained while waiting for more
+// change events, may be recovered from by reopening the change stream.
+// That's the case for network errors and most server errors, such as those
+// caused by a primary stepping down.
+func isResumableError(err error) bool {
+	e, ok := err.(*QueryError)
+	if !ok {
+		return err != ErrCursor
+	}
+	switch e.Code {
+	case 11601, // Interrupted
+		136, // CappedPositionLost
+		237: // CursorKilled
+		return false
+	}
+	return true
+}
+
+// Err returns nil if no errors happened while watching for changes, or the
+// actual error otherwise.
+func (cs *ChangeStream) Err() error {
+	cs.m.Lock()
+	defer cs.m.Unlock()
+	if cs.err != nil || cs.isClosed() {
+		// Close recorded the error of the iterator before killing it.
+		return cs.err
+	}
+	return cs.iter.Err()
+}
+
+// Timeout returns true if Next returned false because no changes arrived
+// within the MaxAwaitTime option.
+func (cs *ChangeStream) Timeout() bool {
+	cs.m.Lock()
+	defer cs.m.Unlock()
+	return cs.timedout
+}
+
+// ResumeToken returns the resume token of the last change event obtained
+// with Next, or the ResumeAfter option if no events were obtained yet.
+// It may be provided as the ResumeAfter option to a later Watch call to
+// continue watching right after that event.
+func (cs *ChangeStream) ResumeToken() *bson.Raw {
+	cs.m.Lock()
+	defer cs.m.Unlock()
+	if cs.resumeToken == nil {
+		return nil
+	}
+	token := *cs.resumeToken
+	return &token
+}
+
+// Close kills the server cursor used by the change stream and releases its
+// resources. It returns nil if no errors happened while watching for
+// changes, or the actual error otherwise.
+//
+// Close may be called while another goroutine is blocked in Next, which
+// then returns false.  Close is idempotent.
+func (cs *ChangeStream) Close() error {
+	cs.cm.Lock()
+	closed := cs.closed
+	cs.closed = true
+	iter := cs.iter
+	cs.cm.Unlock()
+
+	// Killing the cursor interrupts the request for more changes that a
+	// blocked Next is waiting for, and the session is closed once Next
+	// returned.
+	var err error
+	if !closed {
+		err = iter.Close()
+	}
+	cs.m.Lock()
+	defer cs.m.Unlock()
+	if !closed {
+		cs.session.Close()
+		if cs.err == nil {
+			cs.err = err
+		}
+	}
+	return cs.err
+}
@@ -0,0 +1,267 @@
+package mgo_test
+
+import (
+	"time"
+
+	. "gopkg.in/check.v1"
+	"gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+)
+
+type changeEvent struct {
+	OperationType string `bson:"operationType"`
+	FullDocument  M      `bson:"fullDocument"`
+	DocumentKey   M      `bson:"documentKey"`
+	NS            struct {
+		DB   string `bson:"db"`
+		Coll string `bson:"coll"`
+	} `bson:"ns"`
+}
+
+func (s *S) TestChangeStreamCollection(c *C) {
+	if !s.versionAtLeast(3, 6) {
+		c.Skip("change streams depend on 3.6")
+	}
+	session, err := mgo.Dial("localhost:40011")
+	c.Assert(err, IsNil)
+	defer session.Close()
+
+	coll := session.DB("mydb").C("mycoll")
+	err = coll.Insert(M{"_id": 0})
+	c.Assert(err, IsNil)
+
+	stream, err := coll.Watch(nil, mgo.ChangeStreamOptions{FullDocument: mgo.FullDocumentUpdateLookup})
+	c.Assert(err, IsNil)
+	defer stream.Close()
+
+	c.Assert(stream.ResumeToken(), IsNil)
+
+	err = coll.Insert(M{"_id": 1, "n": 1})
+	c.Assert(err, IsNil)
+	err = coll.UpdateId(1, M{"$inc": M{"n": 1}})
+	c.Assert(err, IsNil)
+	err = coll.RemoveId(1)
+	c.Assert(err, IsNil)
+
+	var ops []string
+	var event changeEvent
+	for i := 0; i < 3; i++ {
+		c.Assert(stream.Next(&event), Equals, true)
+		c.Assert(event.NS.DB, Equals, "mydb")
+		c.Assert(event.NS.Coll, Equals, "mycoll")
+		c.Assert(event.DocumentKey["_id"], Equals, 1)
+		if event.OperationType == "update" {
+			c.Assert(event.FullDocument["n"], Equals, 2)
+		}
+		ops = append(ops, event.OperationType)
+	}
+	c.Assert(ops, DeepEquals, []string{"insert", "update", "delete"})
+	c.Assert(stream.ResumeToken(), NotNil)
+
+	c.Assert(stream.Close(), IsNil)
+	c.Assert(stream.Close(), IsNil)
+}
+
+func (s *S) TestChangeStreamPipeline(c *C) {
+	if !s.versionAtLeast(3, 6) {
+		c.Skip("change streams depend on 3.6")
+	}
+	session, err := mgo.Dial("localhost:40011")
+	c.Assert(err, IsNil)
+	defer session.Close()
+
+	coll := session.DB("mydb").C("mycoll")
+	err = coll.Insert(M{"_id": 0})
+	c.Assert(err, IsNil)
+
+	pipeline := mgo.Pipeline{}.Match(bson.M{"operationType": "insert", "fullDocument.n": bson.M{"$gte": 42}})
+	stream, err := coll.Watch(pipeline, mgo.ChangeStreamOptions{})
+	c.Assert(err, IsNil)
+	defer stream.Close()
+
+	for n := 40; n < 44; n++ {
+		err = coll.Insert(M{"_id": n, "n": n})
+		c.Assert(err, IsNil)
+	}
+
+	var event changeEvent
+	for _, n := range []int{42, 43} {
+		c.Assert(stream.Next(&event), Equals, true)
+		c.Assert(event.FullDocument["n"], Equals, n)
+	}
+
+	_, err = coll.Watch(M{"$match": M{}}, mgo.ChangeStreamOptions{})
+	c.Assert(err, ErrorMatches, "change stream pipeline must be a slice of stages")
+}
+
+func (s *S) TestChangeStreamTimeout(c *C) {
+	if !s.versionAtLeast(3, 6) {
+		c.Skip("change streams depend on 3.6")
+	}
+	session, err := mgo.Dial("localhost:40011")
+	c.Assert(err, IsNil)
+	defer session.Close()
+
+	coll := session.DB("mydb").C("mycoll")
+	err = coll.Insert(M{"_id": 0})
+	c.Assert(err, IsNil)
+
+	stream, err := coll.Watch(nil, mgo.ChangeStreamOptions{MaxAwaitTime: 500 * time.Millisecond})
+	c.Assert(err, IsNil)
+	defer stream.Close()
+
+	var event changeEvent
+	start := time.Now()
+	c.Assert(stream.Next(&event), Equals, false)
+	c.Assert(stream.Timeout(), Equals, true)
+	c.Assert(stream.Err(), IsNil)
+	c.Assert(time.Since(start) >= 500*time.Millisecond, Equals, true)
+
+	err = coll.Insert(M{"_id": 1})
+	c.Assert(err, IsNil)
+
+	c.Assert(stream.Next(&event), Equals, true)
+	c.Assert(stream.Timeout(), Equals, false)
+	c.Assert(event.DocumentKey["_id"], Equals, 1)
+}
+
+func (s *S) TestChangeStreamResumeAfter(c *C) {
+	if !s.versionAtLeast(3, 6) {
+		c.Skip("change streams depend on 3.6")
+	}
+	session, err := mgo.Dial("localhost:40011")
+	c.Assert(err, IsNil)
+	defer session.Close()
+
+	coll := session.DB("mydb").C("mycoll")
+	err = coll.Insert(M{"_id": 0})
+	c.Assert(err, IsNil)
+
+	stream, err := coll.Watch(nil, mgo.ChangeStreamOptions{})
+	c.Assert(err, IsNil)
+
+	for i := 1; i <= 3; i++ {
+		err = coll.Insert(M{"_id": i})
+		c.Assert(err, IsNil)
+	}
+
+	var event changeEvent
+	c.Assert(stream.Next(&event), Equals, true)
+	c.Assert(event.DocumentKey["_id"], Equals, 1)
+	token := stream.ResumeToken()
+	c.Assert(stream.Close(), IsNil)
+
+	stream, err = coll.Watch(nil, mgo.ChangeStreamOptions{ResumeAfter: token})
+	c.Assert(err, IsNil)
+	defer stream.Close()
+
+	for _, id := range []int{2, 3} {
+		c.Assert(stream.Next(&event), Equals, true)
+		c.Assert(event.DocumentKey["_id"], Equals, id)
+	}
+}
+
+func (s *S) TestChangeStreamResumesAfterCursorLoss(c *C) {
+	if !s.versionAtLeast(3, 6) {
+		c.Skip("change streams depend on 3.6")
+	}
+	session, err := mgo.Dial("localhost:40011")
+	c.Assert(err, IsNil)
+	defer session.Close()
+
+	coll := session.DB("mydb").C("mycoll")
+	err = coll.Insert(M{"_id": 0})
+	c.Assert(err, IsNil)
+
+	stream, err := coll.Watch(nil, mgo.ChangeStreamOptions{})
+	c.Assert(err, IsNil)
+	defer stream.Close()
+
+	err = coll.Insert(M{"_id": 1})
+	c.Assert(err, IsNil)
+
+	var event changeEvent
+	c.Assert(stream.Next(&event), Equals, true)
+	c.Assert(event.DocumentKey["_id"], Equals, 1)
+
+	// Kill the server cursor so that the next request for changes fails.
+	cursorId := mgo.HackChangeStreamCursorId(stream)
+	c.Assert(cursorId, Not(Equals), int64(0))
+	err = session.DB("mydb").Run(bson.D{{"killCursors", "mycoll"}, {"cursors", []int64{cursorId}}}, nil)
+	c.Assert(err, IsNil)
+
+	err = coll.Insert(M{"_id": 2})
+	c.Assert(err, IsNil)
+
+	c.Assert(stream.Next(&event), Equals, true)
+	c.Assert(event.DocumentKey["_id"], Equals, 2)
+	c.Assert(mgo.HackChangeStreamCursorId(stream), Not(Equals), cursorId)
+}
+
+func (s *S) TestChangeStreamCloseInterruptsNext(c *C) {
+	if !s.versionAtLeast(3, 6) {
+		c.Skip("change streams depend on 3.6")
+	}
+	session, err := mgo.Dial("localhost:40011")
+	c.Assert(err, IsNil)
+	defer session.Close()
+
+	coll := session.DB("mydb").C("mycoll")
+	err = coll.Insert(M{"_id": 0})
+	c.Assert(err, IsNil)
+
+	stream, err := coll.Watch(nil, mgo.ChangeStreamOptions{})
+	c.Assert(err, IsNil)
+
+	done := make(chan bool)
+	go func() {
+		var event changeEvent
+		done <- stream.Next(&event)
+	}()
+	time.Sleep(100 * time.Millisecond)
+
+	c.Assert(stream.Close(), IsNil)
+	select {
+	case next := <-done:
+		c.Assert(next, Equals, false)
+	case <-time.After(5 * time.Second):
+		c.Fatalf("Next wasn't interrupted by Close")
+	}
+	c.Assert(stream.Err(), IsNil)
+}
+
+func (s *S) TestChangeStreamDatabase(c *C) {
+	if !s.versionAtLeast(4, 0) {
+		c.Skip("database and deployment change streams depend on 4.0")
+	}
+	session, err := mgo.Dial("localhost:40011")
+	c.Assert(err, IsNil)
+	defer session.Close()
+
+	db := session.DB("mydb")
+	err = db.C("mycoll").Insert(M{"_id": 0})
+	c.Assert(err, IsNil)
+
+	dbStream, err := db.Watch(nil, mgo.ChangeStreamOptions{})
+	c.Assert(err, IsNil)
+	defer dbStream.Close()
+
+	clusterStream, err := session.Watch(nil, mgo.ChangeStreamOptions{})
+	c.Assert(err, IsNil)
+	defer clusterStream.Close()
+
+	err = db.C("othercoll").Insert(M{"_id": 1})
+	c.Assert(err, IsNil)
+	err = session.DB("otherdb").C("mycoll").Insert(M{"_id": 2})
+	c.Assert(err, IsNil)
+
+	var event changeEvent
+	c.Assert(dbStream.Next(&event), Equals, true)
+	c.Assert(event.NS.DB, Equals, "mydb")
+	c.Assert(event.NS.Coll, Equals, "othercoll")
+
+	for _, ns := range []string{"mydb.othercoll", "otherdb.mycoll"} {
+		c.Assert(clusterStream.Next(&event), Equals, true)
+		c.Assert(event.NS.DB+"."+event.NS.Coll, Equals, ns)
+	}
+}
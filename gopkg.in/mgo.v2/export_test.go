@@ -31,3 +31,12 @@ func HackSyncSocketTimeout(newTimeout time.Duration) (restore func()) {
 	syncSocketTimeout = newTimeout
 	return
 }
+
+func HackChangeStreamCursorId(cs *ChangeStream) int64 {
+	cs.m.Lock()
+	defer cs.m.Unlock()
+
+	cs.iter.m.Lock()
+	defer cs.iter.m.Unlock()
+	return cs.iter.op.cursorId
+}
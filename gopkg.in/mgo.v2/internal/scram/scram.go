@@ -58,6 +58,7 @@ import (
 //
 type Client struct {
 	newHash func() hash.Hash
+	name    string
 
 	user string
 	pass string
@@ -77,9 +78,16 @@ type Client struct {
 //
 //    client := scram.NewClient(sha1.New, user, pass)
 //
+// and for SCRAM-SHA-256:
+//
+//    client := scram.NewClient(sha256.New, user, pass)
+//
+// The password is used as provided; any SASLprep normalization required by
+// the mechanism must be done by the caller.
 func NewClient(newHash func() hash.Hash, user, pass string) *Client {
 	c := &Client{
 		newHash: newHash,
+		name:    mechanismName(newHash),
 		user:    user,
 		pass:    pass,
 	}
@@ -88,6 +96,18 @@ func NewClient(newHash func() hash.Hash, user, pass string) *Client {
 	return c
 }
 
+// mechanismName returns the SCRAM mechanism name for the hash algorithm,
+// used in error messages.
+func mechanismName(newHash func() hash.Hash) string {
+	switch newHash().Size() {
+	case 20:
+		return "SCRAM-SHA-1"
+	case 32:
+		return "SCRAM-SHA-256"
+	}
+	return "SCRAM"
+}
+
 // Out returns the data to be sent to the server in the current step.
 func (c *Client) Out() []byte {
 	if c.out.Len() == 0 {
@@ -135,7 +155,7 @@ func (c *Client) step1(in []byte) error {
 		const nonceLen = 6
 		buf := make([]byte, nonceLen + b64.EncodedLen(nonceLen))
 		if _, err := rand.Read(buf[:nonceLen]); err != nil {
-			return fmt.Errorf("cannot read random SCRAM-SHA-1 nonce from operating system: %v", err)
+			return fmt.Errorf("cannot read random %s nonce from operating system: %v", c.name, err)
 		}
 		c.clientNonce = buf[nonceLen:]
 		b64.Encode(c.clientNonce, buf[:nonceLen])
@@ -158,32 +178,32 @@ func (c *Client) step2(in []byte) error {
 
 	fields := bytes.Split(in, []byte(","))
 	if len(fields) != 3 {
-		return fmt.Errorf("expected 3 fields in first SCRAM-SHA-1 server message, got %d: %q", len(fields), in)
+		return fmt.Errorf("expected 3 fields in first %s server message, got %d: %q", c.name, len(fields), in)
 	}
 	if !bytes.HasPrefix(fields[0], []byte("r=")) || len(fields[0]) < 2 {
-		return fmt.Errorf("server sent an invalid SCRAM-SHA-1 nonce: %q", fields[0])
+		return fmt.Errorf("server sent an invalid %s nonce: %q", c.name, fields[0])
 	}
 	if !bytes.HasPrefix(fields[1], []byte("s=")) || len(fields[1]) < 6 {
-		return fmt.Errorf("server sent an invalid SCRAM-SHA-1 salt: %q", fields[1])
+		return fmt.Errorf("server sent an invalid %s salt: %q", c.name, fields[1])
 	}
 	if !bytes.HasPrefix(fields[2], []byte("i=")) || len(fields[2]) < 6 {
-		return fmt.Errorf("server sent an invalid SCRAM-SHA-1 iteration count: %q", fields[2])
+		return fmt.Errorf("server sent an invalid %s iteration count: %q", c.name, fields[2])
 	}
 
 	c.serverNonce = fields[0][2:]
 	if !bytes.HasPrefix(c.serverNonce, c.clientNonce) {
-		return fmt.Errorf("server SCRAM-SHA-1 nonce is not prefixed by client nonce: got %q, want %q+\"...\"", c.serverNonce, c.clientNonce)
+		return fmt.Errorf("server %s nonce is not prefixed by client nonce: got %q, want %q+\"...\"", c.name, c.serverNonce, c.clientNonce)
 	}
 
 	salt := make([]byte, b64.DecodedLen(len(fields[1][2:])))
 	n, err := b64.Decode(salt, fields[1][2:])
 	if err != nil {
-		return fmt.Errorf("cannot decode SCRAM-SHA-1 salt sent by server: %q", fields[1])
+		return fmt.Errorf("cannot decode %s salt sent by server: %q", c.name, fields[1])
 	}
 	salt = salt[:n]
 	iterCount, err := strconv.Atoi(string(fields[2][2:]))
 	if err != nil {
-		return fmt.Errorf("server sent an invalid SCRAM-SHA-1 iteration count: %q", fields[2])
+		return fmt.Errorf("server sent an invalid %s iteration count: %q", c.name, fields[2])
 	}
 	c.saltPassword(salt, iterCount)
 
@@ -205,12 +225,12 @@ func (c *Client) step3(in []byte) error {
 		ise = bytes.HasPrefix(fields[0], []byte("e="))
 	}
 	if ise {
-		return fmt.Errorf("SCRAM-SHA-1 authentication error: %s", fields[0][2:])
+		return fmt.Errorf("%s authentication error: %s", c.name, fields[0][2:])
 	} else if !isv {
-		return fmt.Errorf("unsupported SCRAM-SHA-1 final message from server: %q", in)
+		return fmt.Errorf("unsupported %s final message from server: %q", c.name, in)
 	}
 	if !bytes.Equal(c.serverSignature(), fields[0][2:]) {
-		return fmt.Errorf("cannot authenticate SCRAM-SHA-1 server signature: %q", fields[0][2:])
+		return fmt.Errorf("cannot authenticate %s server signature: %q", c.name, fields[0][2:])
 	}
 	return nil
 }
@@ -0,0 +1,163 @@
+package mgo
+
+import (
+	"gopkg.in/mgo.v2/bson"
+)
+
+// Pipeline is an aggregation pipeline built stage by stage, as an
+// alternative to writing the stage documents by hand. A Pipeline may be
+// provided anywhere a slice of stages is accepted, such as Collection.Pipe
+// and the Watch methods.
+//
+// Every method returns a new Pipeline with the stage appended, leaving the
+// original pipeline unchanged, so a common prefix may be shared by several
+// pipelines.
+//
+// For example, this pipeline:
+//
+//     pipeline := mgo.Pipeline{}.
+//             Match(bson.M{"status": "A"}).
+//             Group("$cust_id", bson.M{"total": bson.M{"$sum": "$amount"}}).
+//             Sort("-total")
+//     iter := collection.Pipe(pipeline).Iter()
+//
+// is equivalent to:
+//
+//     pipeline := []bson.M{
+//             {"$match": bson.M{"status": "A"}},
+//             {"$group": bson.M{"_id": "$cust_id", "total": bson.M{"$sum": "$amount"}}},
+//             {"$sort": bson.D{{"total", -1}}},
+//     }
+//
+// Relevant documentation:
+//
+//     https://docs.mongodb.com/manual/reference/operator/aggregation-pipeline/
+//
+type Pipeline []bson.M
+
+// UnwindOptions holds the optional settings of an $unwind stage.
+// See Pipeline.UnwindWith.
+type UnwindOptions struct {
+	// IncludeArrayIndex names a field to hold the array index of the
+	// unwound element.
+	IncludeArrayIndex string
+
+	// PreserveNullAndEmptyArrays outputs the document even if the path is
+	// missing, null or an empty array.
+	PreserveNullAndEmptyArrays bool
+}
+
+// stage returns a copy of p with the given stage appended.
+func (p Pipeline) stage(name string, value interface{}) Pipeline {
+	return append(p[:len(p):len(p)], bson.M{name: value})
+}
+
+// Match appends a $match stage filtering documents with query, which is
+// written in the same language accepted by Collection.Find.
+func (p Pipeline) Match(query interface{}) Pipeline {
+	return p.stage("$match", query)
+}
+
+// Group appends a $group stage grouping documents by the id expression,
+// and computing each of the given fields with an accumulator expression.
+//
+// For example:
+//
+//     pipeline.Group("$item", bson.M{"count": bson.M{"$sum": 1}})
+//
+func (p Pipeline) Group(id interface{}, fields bson.M) Pipeline {
+	group := make(bson.M, len(fields)+1)
+	for name, value := range fields {
+		group[name] = value
+	}
+	group["_id"] = id
+	return p.stage("$group", group)
+}
+
+// Lookup appends a $lookup stage joining documents with those in the from
+// collection whose foreignField equals their localField. The matching
+// documents are stored in an array field named as.
+func (p Pipeline) Lookup(from, localField, foreignField, as string) Pipeline {
+	return p.stage("$lookup", bson.M{
+		"from":         from,
+		"localField":   localField,
+		"foreignField": foreignField,
+		"as":           as,
+	})
+}
+
+// LookupPipeline appends a $lookup stage joining documents with the result
+// of running pipeline on the from collection. The variables in let are
+// available to the pipeline stages, and the results are stored in an
+// array field named as.
+//
+// Lookups with a pipeline require MongoDB 3.6 or later.
+func (p Pipeline) LookupPipeline(from string, let bson.M, pipeline Pipeline, as string) Pipeline {
+	if pipeline == nil {
+		pipeline = Pipeline{}
+	}
+	lookup := bson.M{
+		"from":     from,
+		"pipeline": pipeline,
+		"as":       as,
+	}
+	if let != nil {
+		lookup["let"] = let
+	}
+	return p.stage("$lookup", lookup)
+}
+
+// Unwind appends an $unwind stage outputting a document for each element of
+// the array at path, which must be prefixed by "$".
+func (p Pipeline) Unwind(path string) Pipeline {
+	return p.stage("$unwind", path)
+}
+
+// UnwindWith works like Unwind, but with the given options.
+func (p Pipeline) UnwindWith(path string, options UnwindOptions) Pipeline {
+	unwind := bson.M{"path": path}
+	if options.IncludeArrayIndex != "" {
+		unwind["includeArrayIndex"] = options.IncludeArrayIndex
+	}
+	if options.PreserveNullAndEmptyArrays {
+		unwind["preserveNullAndEmptyArrays"] = true
+	}
+	return p.stage("$unwind", unwind)
+}
+
+// Project appends a $project stage reshaping documents as defined by fields,
+// which is a document such as bson.M{"name": 1, "total": "$amount"}. Use a
+// bson.D to preserve the order of the fields in the output documents.
+func (p Pipeline) Project(fields interface{}) Pipeline {
+	return p.stage("$project", fields)
+}
+
+// Facet appends a $facet stage running each of the given pipelines on the
+// same input documents, and storing their results in an array field named
+// after the facet.
+func (p Pipeline) Facet(facets map[string]Pipeline) Pipeline {
+	facet := make(bson.M, len(facets))
+	for name, pipeline := range facets {
+		if pipeline == nil {
+			pipeline = Pipeline{}
+		}
+		facet[name] = pipeline
+	}
+	return p.stage("$facet", facet)
+}
+
+// Sort appends a $sort stage ordering documents by the provided field
+// names, in the format accepted by Query.Sort.
+func (p Pipeline) Sort(fields ...string) Pipeline {
+	return p.stage("$sort", sortOrder(fields))
+}
+
+// Skip appends a $skip stage skipping the first n documents.
+func (p Pipeline) Skip(n int) Pipeline {
+	return p.stage("$skip", n)
+}
+
+// Limit appends a $limit stage passing only the first n documents on.
+func (p Pipeline) Limit(n int) Pipeline {
+	return p.stage("$limit", n)
+}
@@ -0,0 +1,99 @@
+package mgo_test
+
+import (
+	. "gopkg.in/check.v1"
+	"gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+)
+
+func (s *S) TestPipelineStages(c *C) {
+	pipeline := mgo.Pipeline{}.
+		Match(bson.M{"status": "A"}).
+		Lookup("items", "item", "_id", "details").
+		Unwind("$details").
+		UnwindWith("$tags", mgo.UnwindOptions{IncludeArrayIndex: "idx", PreserveNullAndEmptyArrays: true}).
+		Group("$cust_id", bson.M{"total": bson.M{"$sum": "$amount"}}).
+		Project(bson.D{{"total", 1}, {"_id", 0}}).
+		Sort("-total", "name").
+		Skip(10).
+		Limit(5)
+
+	c.Assert([]bson.M(pipeline), DeepEquals, []bson.M{
+		{"$match": bson.M{"status": "A"}},
+		{"$lookup": bson.M{"from": "items", "localField": "item", "foreignField": "_id", "as": "details"}},
+		{"$unwind": "$details"},
+		{"$unwind": bson.M{"path": "$tags", "includeArrayIndex": "idx", "preserveNullAndEmptyArrays": true}},
+		{"$group": bson.M{"_id": "$cust_id", "total": bson.M{"$sum": "$amount"}}},
+		{"$project": bson.D{{"total", 1}, {"_id", 0}}},
+		{"$sort": bson.D{{"total", -1}, {"name", 1}}},
+		{"$skip": 10},
+		{"$limit": 5},
+	})
+}
+
+func (s *S) TestPipelineFacetAndLookupPipeline(c *C) {
+	pipeline := mgo.Pipeline{}.
+		LookupPipeline("stock", bson.M{"item": "$item"}, mgo.Pipeline{}.Match(bson.M{"$expr": bson.M{"$eq": []string{"$name", "$$item"}}}), "stock").
+		Facet(map[string]mgo.Pipeline{
+			"byStatus": mgo.Pipeline{}.Group("$status", bson.M{"n": bson.M{"$sum": 1}}),
+			"all":      nil,
+		})
+
+	c.Assert([]bson.M(pipeline), DeepEquals, []bson.M{
+		{"$lookup": bson.M{
+			"from":     "stock",
+			"let":      bson.M{"item": "$item"},
+			"pipeline": mgo.Pipeline{{"$match": bson.M{"$expr": bson.M{"$eq": []string{"$name", "$$item"}}}}},
+			"as":       "stock",
+		}},
+		{"$facet": bson.M{
+			"byStatus": mgo.Pipeline{{"$group": bson.M{"_id": "$status", "n": bson.M{"$sum": 1}}}},
+			"all":      mgo.Pipeline{},
+		}},
+	})
+}
+
+func (s *S) TestPipelineIsImmutable(c *C) {
+	base := make(mgo.Pipeline, 0, 4).Match(bson.M{"n": bson.M{"$gte": 42}})
+	asc := base.Sort("n")
+	desc := base.Sort("-n")
+
+	c.Assert(base, HasLen, 1)
+	c.Assert(asc[1], DeepEquals, bson.M{"$sort": bson.D{{"n", 1}}})
+	c.Assert(desc[1], DeepEquals, bson.M{"$sort": bson.D{{"n", -1}}})
+}
+
+func (s *S) TestPipelinePipe(c *C) {
+	if !s.versionAtLeast(2, 1) {
+		c.Skip("Pipe only works on 2.1+")
+	}
+
+	session, err := mgo.Dial("localhost:40001")
+	c.Assert(err, IsNil)
+	defer session.Close()
+
+	coll := session.DB("mydb").C("mycoll")
+
+	ns := []int{40, 41, 42, 43, 44, 45, 46}
+	for _, n := range ns {
+		err := coll.Insert(M{"n": n, "odd": n%2 == 1})
+		c.Assert(err, IsNil)
+	}
+
+	pipeline := mgo.Pipeline{}.
+		Match(bson.M{"n": bson.M{"$gte": 42}}).
+		Group("$odd", bson.M{"sum": bson.M{"$sum": "$n"}}).
+		Sort("_id")
+
+	var result []struct {
+		Odd bool `bson:"_id"`
+		Sum int
+	}
+	err = coll.Pipe(pipeline).All(&result)
+	c.Assert(err, IsNil)
+	c.Assert(result, HasLen, 2)
+	c.Assert(result[0].Odd, Equals, false)
+	c.Assert(result[0].Sum, Equals, 42+44+46)
+	c.Assert(result[1].Odd, Equals, true)
+	c.Assert(result[1].Sum, Equals, 43+45)
+}
@@ -0,0 +1,37 @@
+package mgo
+
+import (
+	"strings"
+
+	"golang.org/x/text/secure/precis"
+	"golang.org/x/text/unicode/norm"
+)
+
+// saslPrep prepares a SCRAM-SHA-256 password as the RFC 4013 SASLprep
+// profile does.  The characters commonly mapped to nothing are removed and
+// the password is normalized with NFKC before being checked with the PRECIS
+// OpaqueString profile, which replaced SASLprep and prohibits mostly the
+// same characters.  Unlike SASLprep, it rejects empty passwords.
+func saslPrep(password string) (string, error) {
+	password = strings.Map(func(r rune) rune {
+		if mappedToNothing(r) {
+			return -1
+		}
+		return r
+	}, password)
+	return precis.OpaqueString.String(norm.NFKC.String(password))
+}
+
+// mappedToNothing reports whether r is in table B.1 of RFC 3454.
+func mappedToNothing(r rune) bool {
+	switch {
+	case r == '\u00ad', r == '\u034f', r == '\u1806',
+		r >= '\u180b' && r <= '\u180d',
+		r >= '\u200b' && r <= '\u200d',
+		r == '\u2060',
+		r >= '\ufe00' && r <= '\ufe0f',
+		r == '\ufeff':
+		return true
+	}
+	return false
+}
@@ -0,0 +1,38 @@
+package mgo
+
+import (
+	. "gopkg.in/check.v1"
+)
+
+type SASLPrepS struct{}
+
+var _ = Suite(&SASLPrepS{})
+
+var saslPrepTests = []struct {
+	password, prepared, err string
+}{
+	{"password", "password", ""},
+	// RFC 4013 examples
+	{"I\u00adX", "IX", ""},
+	{"user", "user", ""},
+	{"USER", "USER", ""},
+	{"\u00aa", "a", ""},
+	{"\u2168", "IX", ""},
+	{"\u0007", "", "precis: disallowed rune encountered"},
+	// Non-ASCII spaces are mapped to spaces
+	{"pass\u00a0word", "pass word", ""},
+	{"\u200b", "", "precis: transformation resulted in empty string"},
+	{"", "", "precis: transformation resulted in empty string"},
+}
+
+func (s *SASLPrepS) TestSASLPrep(c *C) {
+	for _, t := range saslPrepTests {
+		prepared, err := saslPrep(t.password)
+		if t.err != "" {
+			c.Check(err, ErrorMatches, t.err, Commentf("%q", t.password))
+			continue
+		}
+		c.Check(err, IsNil, Commentf("%q", t.password))
+		c.Check(prepared, Equals, t.prepared, Commentf("%q", t.password))
+	}
+}
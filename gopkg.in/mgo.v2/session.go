@@ -27,7 +27,12 @@
 package mgo
 
 import (
+	"bytes"
 	"crypto/md5"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
 	"encoding/hex"
 	"errors"
 	"fmt"
@@ -139,6 +144,7 @@ type Iter struct {
 	timeout        time.Duration
 	timedout       bool
 	findCmd        bool
+	maxTimeMS      int64
 }
 
 var (
@@ -220,8 +226,11 @@ const (
 //
 //     authMechanism=<mechanism>
 //
-//        Defines the protocol for credential negotiation. Defaults to "MONGODB-CR",
-//        which is the default username/password challenge-response mechanism.
+//        Defines the protocol for credential negotiation. Supported mechanisms
+//        are "SCRAM-SHA-256", "SCRAM-SHA-1", "MONGODB-CR", "PLAIN", "GSSAPI"
+//        and "MONGODB-X509". Defaults to the strongest SCRAM mechanism the
+//        user supports on MongoDB 3.0 and later, and to "MONGODB-CR" on
+//        older servers.
 //
 //
 //     gssapiServiceName=<name>
@@ -372,7 +381,7 @@ type DialInfo struct {
 	ServiceHost string
 
 	// Mechanism defines the protocol for credential negotiation.
-	// Defaults to "MONGODB-CR".
+	// See Credential.Mechanism for the default.
 	Mechanism string
 
 	// Username and Password inform the credentials for the initial authentication
@@ -384,6 +393,15 @@ type DialInfo struct {
 	// See Session.SetPoolLimit for details.
 	PoolLimit int
 
+	// TLSConfig, if set, causes connections with the MongoDB servers to be
+	// established over TLS with the given configuration. It is ignored if
+	// DialServer or Dial are set.
+	//
+	// With the MONGODB-X509 mechanism and no Username, the first
+	// certificate in TLSConfig.Certificates is taken as the client
+	// certificate, and its subject is used as the username.
+	TLSConfig *tls.Config
+
 	// DialServer optionally specifies the dial function for establishing
 	// connections with the MongoDB servers.
 	DialServer func(addr *ServerAddr) (net.Conn, error)
@@ -422,7 +440,19 @@ func DialWithInfo(info *DialInfo) (*Session, error) {
 		}
 		addrs[i] = addr
 	}
-	cluster := newCluster(addrs, info.Direct, info.FailFast, dialer{info.Dial, info.DialServer}, info.ReplicaSetName)
+	username := info.Username
+	if username == "" && info.Mechanism == "MONGODB-X509" && info.TLSConfig != nil && len(info.TLSConfig.Certificates) > 0 {
+		var err error
+		username, err = x509Subject(&info.TLSConfig.Certificates[0])
+		if err != nil {
+			return nil, err
+		}
+	}
+	dial := dialer{info.Dial, info.DialServer}
+	if info.TLSConfig != nil && !dial.isSet() {
+		dial.new = tlsDialer(info.TLSConfig, info.Timeout)
+	}
+	cluster := newCluster(addrs, info.Direct, info.FailFast, dial, info.ReplicaSetName)
 	session := newSession(Eventual, cluster, info.Timeout)
 	session.defaultdb = info.Database
 	if session.defaultdb == "" {
@@ -435,14 +465,14 @@ func DialWithInfo(info *DialInfo) (*Session, error) {
 			session.sourcedb = "admin"
 		}
 	}
-	if info.Username != "" {
+	if username != "" {
 		source := session.sourcedb
 		if info.Source == "" &&
 			(info.Mechanism == "GSSAPI" || info.Mechanism == "PLAIN" || info.Mechanism == "MONGODB-X509") {
 			source = "$external"
 		}
 		session.dialCred = &Credential{
-			Username:    info.Username,
+			Username:    username,
 			Password:    info.Password,
 			Mechanism:   info.Mechanism,
 			Service:     info.Service,
@@ -468,6 +498,95 @@ func DialWithInfo(info *DialInfo) (*Session, error) {
 	return session, nil
 }
 
+// tlsDialer returns a DialServer function establishing TLS connections with
+// the given configuration.
+func tlsDialer(config *tls.Config, timeout time.Duration) func(addr *ServerAddr) (net.Conn, error) {
+	return func(addr *ServerAddr) (net.Conn, error) {
+		dialer := &net.Dialer{Timeout: timeout}
+		return tls.DialWithDialer(dialer, "tcp", addr.String(), config)
+	}
+}
+
+// x509Subject returns the subject of the client certificate in the RFC 2253
+// format MongoDB expects as the MONGODB-X509 username.
+func x509Subject(cert *tls.Certificate) (string, error) {
+	if len(cert.Certificate) == 0 {
+		return "", errors.New("TLS client certificate is empty")
+	}
+	leaf := cert.Leaf
+	if leaf == nil {
+		var err error
+		leaf, err = x509.ParseCertificate(cert.Certificate[0])
+		if err != nil {
+			return "", errors.New("cannot parse TLS client certificate: " + err.Error())
+		}
+	}
+	var subject pkix.RDNSequence
+	if _, err := asn1.Unmarshal(leaf.RawSubject, &subject); err != nil {
+		return "", errors.New("cannot parse TLS client certificate subject: " + err.Error())
+	}
+	return rdnString(subject), nil
+}
+
+var attributeTypeNames = map[string]string{
+	"2.5.4.6":  "C",
+	"2.5.4.10": "O",
+	"2.5.4.11": "OU",
+	"2.5.4.3":  "CN",
+	"2.5.4.5":  "SERIALNUMBER",
+	"2.5.4.7":  "L",
+	"2.5.4.8":  "ST",
+	"2.5.4.9":  "STREET",
+	"2.5.4.17": "POSTALCODE",
+}
+
+// rdnString formats the distinguished name rdns as described in RFC 2253,
+// like pkix.RDNSequence.String which is only available from Go 1.10.
+func rdnString(rdns pkix.RDNSequence) string {
+	var buf bytes.Buffer
+	for i := len(rdns) - 1; i >= 0; i-- {
+		if i < len(rdns)-1 {
+			buf.WriteByte(',')
+		}
+		for j, atv := range rdns[i] {
+			if j > 0 {
+				buf.WriteByte('+')
+			}
+			oid := atv.Type.String()
+			name, ok := attributeTypeNames[oid]
+			if !ok {
+				// Unknown attributes which aren't strings are written
+				// with their BER encoding.
+				if _, ok := atv.Value.(string); !ok {
+					if der, err := asn1.Marshal(atv.Value); err == nil {
+						buf.WriteString(oid + "=#" + hex.EncodeToString(der))
+						continue
+					}
+				}
+				name = oid
+			}
+			buf.WriteString(name + "=")
+			value := fmt.Sprint(atv.Value)
+			for k, c := range value {
+				switch c {
+				case ',', '+', '"', '\\', '<', '>', ';':
+					buf.WriteByte('\\')
+				case ' ':
+					if k == 0 || k == len(value)-1 {
+						buf.WriteByte('\\')
+					}
+				case '#':
+					if k == 0 {
+						buf.WriteByte('\\')
+					}
+				}
+				buf.WriteRune(c)
+			}
+		}
+	}
+	return buf.String()
+}
+
 func isOptSep(c rune) bool {
 	return c == ';' || c == '&'
 }
@@ -678,7 +797,10 @@ type Credential struct {
 	ServiceHost string
 
 	// Mechanism defines the protocol for credential negotiation.
-	// Defaults to "MONGODB-CR".
+	// With MongoDB 4.0 and later, it defaults to SCRAM-SHA-256 if the
+	// server reports it as supported for the user via saslSupportedMechs,
+	// and to SCRAM-SHA-1 otherwise. With MongoDB 3.0 and later it defaults
+	// to SCRAM-SHA-1, and to MONGODB-CR with older servers.
 	Mechanism string
 }
 
@@ -703,7 +825,7 @@ func (s *Session) Login(cred *Credential) error {
 
 	credCopy := *cred
 	if cred.Source == "" {
-		if cred.Mechanism == "GSSAPI" {
+		if cred.Mechanism == "GSSAPI" || cred.Mechanism == "MONGODB-X509" {
 			credCopy.Source = "$external"
 		} else {
 			credCopy.Source = s.sourcedb
@@ -2187,13 +2309,19 @@ type pipeCmdCursor struct {
 }
 
 // Pipe prepares a pipeline to aggregate. The pipeline document
-// must be a slice built in terms of the aggregation framework language.
+// must be a slice built in terms of the aggregation framework language,
+// or a Pipeline.
 //
 // For example:
 //
 //     pipe := collection.Pipe([]bson.M{{"$match": bson.M{"name": "Otavio"}}})
 //     iter := pipe.Iter()
 //
+// or, equivalently:
+//
+//     pipe := collection.Pipe(mgo.Pipeline{}.Match(bson.M{"name": "Otavio"}))
+//     iter := pipe.Iter()
+//
 // Relevant documentation:
 //
 //     http://docs.mongodb.org/manual/reference/aggregation
@@ -2823,7 +2951,17 @@ func (q *Query) Select(selector interface{}) *Query {
 //     http://www.mongodb.org/display/DOCS/Sorting+and+Natural+Order
 //
 func (q *Query) Sort(fields ...string) *Query {
+	order := sortOrder(fields)
 	q.m.Lock()
+	q.op.options.OrderBy = order
+	q.op.hasOptions = true
+	q.m.Unlock()
+	return q
+}
+
+// sortOrder returns the sort document for fields in the format accepted
+// by Query.Sort.
+func sortOrder(fields []string) bson.D {
 	var order bson.D
 	for _, field := range fields {
 		n := 1
@@ -2852,10 +2990,7 @@ func (q *Query) Sort(fields ...string) *Query {
 			order = append(order, bson.DocElem{field, n})
 		}
 	}
-	q.op.options.OrderBy = order
-	q.op.hasOptions = true
-	q.m.Unlock()
-	return q
+	return order
 }
 
 // Explain returns a number of details about how the MongoDB server would
@@ -3930,6 +4065,7 @@ func (iter *Iter) getMoreCmd() *queryOp {
 		CursorId:   iter.op.cursorId,
 		Collection: iter.op.collection[nameDot+1:],
 		BatchSize:  iter.op.limit,
+		MaxTimeMS:  iter.maxTimeMS,
 	}
 
 	var op queryOp
@@ -4524,7 +4660,12 @@ func (iter *Iter) replyFunc() replyFunc {
 			} else if !findReply.Ok && findReply.Errmsg != "" {
 				iter.err = &QueryError{Code: findReply.Code, Message: findReply.Errmsg}
 			} else if len(findReply.Cursor.FirstBatch) == 0 && len(findReply.Cursor.NextBatch) == 0 {
-				iter.err = ErrNotFound
+				if findReply.Cursor.Id != 0 {
+					// It's a tailable cursor.
+					iter.op.cursorId = findReply.Cursor.Id
+				} else {
+					iter.err = ErrNotFound
+				}
 			} else {
 				batch := findReply.Cursor.FirstBatch
 				if len(batch) == 0 {
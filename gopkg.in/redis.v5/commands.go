@@ -181,6 +181,28 @@ type Cmdable interface {
 	ZRevRank(key, member string) *IntCmd
 	ZScore(key, member string) *FloatCmd
 	ZUnionStore(dest string, store ZStore, keys ...string) *IntCmd
+	XAdd(a *XAddArgs) *StringCmd
+	XDel(stream string, ids ...string) *IntCmd
+	XLen(stream string) *IntCmd
+	XRange(stream, start, stop string) *XMessageSliceCmd
+	XRangeN(stream, start, stop string, count int64) *XMessageSliceCmd
+	XRevRange(stream, start, stop string) *XMessageSliceCmd
+	XRevRangeN(stream, start, stop string, count int64) *XMessageSliceCmd
+	XRead(a *XReadArgs) *XStreamSliceCmd
+	XReadStreams(streams ...string) *XStreamSliceCmd
+	XGroupCreate(stream, group, start string) *StatusCmd
+	XGroupCreateMkStream(stream, group, start string) *StatusCmd
+	XGroupSetID(stream, group, start string) *StatusCmd
+	XGroupDestroy(stream, group string) *IntCmd
+	XGroupDelConsumer(stream, group, consumer string) *IntCmd
+	XReadGroup(a *XReadGroupArgs) *XStreamSliceCmd
+	XAck(stream, group string, ids ...string) *IntCmd
+	XPending(stream, group string) *XPendingCmd
+	XPendingExt(a *XPendingExtArgs) *XPendingExtCmd
+	XClaim(a *XClaimArgs) *XMessageSliceCmd
+	XClaimJustID(a *XClaimArgs) *StringSliceCmd
+	XTrim(stream string, maxLen int64) *IntCmd
+	XTrimApprox(stream string, maxLen int64) *IntCmd
 	PFAdd(key string, els ...interface{}) *IntCmd
 	PFCount(keys ...string) *IntCmd
 	PFMerge(dest string, keys ...string) *StatusCmd
@@ -1564,6 +1586,298 @@ func (c *cmdable) ZUnionStore(dest string, store ZStore, keys ...string) *IntCmd
 
 //------------------------------------------------------------------------------
 
+// XAddArgs is used as an arg to XAdd.
+type XAddArgs struct {
+	Stream string
+	// Trims the stream to about MaxLenApprox entries, or exactly MaxLen.
+	MaxLen       int64
+	MaxLenApprox int64
+	// Defaults to "*", an ID generated by Redis.
+	ID     string
+	Values map[string]interface{}
+}
+
+// Redis `XADD stream [MAXLEN [~] count] id field value [field value ...]` command.
+func (c *cmdable) XAdd(a *XAddArgs) *StringCmd {
+	args := make([]interface{}, 0, 6+len(a.Values)*2)
+	args = append(args, "xadd", a.Stream)
+	if a.MaxLen > 0 {
+		args = append(args, "maxlen", a.MaxLen)
+	} else if a.MaxLenApprox > 0 {
+		args = append(args, "maxlen", "~", a.MaxLenApprox)
+	}
+	if a.ID != "" {
+		args = append(args, a.ID)
+	} else {
+		args = append(args, "*")
+	}
+	for k, v := range a.Values {
+		args = append(args, k, v)
+	}
+	cmd := NewStringCmd(args...)
+	c.process(cmd)
+	return cmd
+}
+
+func (c *cmdable) XDel(stream string, ids ...string) *IntCmd {
+	args := make([]interface{}, 2+len(ids))
+	args[0] = "xdel"
+	args[1] = stream
+	for i, id := range ids {
+		args[2+i] = id
+	}
+	cmd := NewIntCmd(args...)
+	c.process(cmd)
+	return cmd
+}
+
+func (c *cmdable) XLen(stream string) *IntCmd {
+	cmd := NewIntCmd("xlen", stream)
+	c.process(cmd)
+	return cmd
+}
+
+func (c *cmdable) XRange(stream, start, stop string) *XMessageSliceCmd {
+	cmd := NewXMessageSliceCmd("xrange", stream, start, stop)
+	c.process(cmd)
+	return cmd
+}
+
+func (c *cmdable) XRangeN(stream, start, stop string, count int64) *XMessageSliceCmd {
+	cmd := NewXMessageSliceCmd("xrange", stream, start, stop, "count", count)
+	c.process(cmd)
+	return cmd
+}
+
+func (c *cmdable) XRevRange(stream, start, stop string) *XMessageSliceCmd {
+	cmd := NewXMessageSliceCmd("xrevrange", stream, start, stop)
+	c.process(cmd)
+	return cmd
+}
+
+func (c *cmdable) XRevRangeN(stream, start, stop string, count int64) *XMessageSliceCmd {
+	cmd := NewXMessageSliceCmd("xrevrange", stream, start, stop, "count", count)
+	c.process(cmd)
+	return cmd
+}
+
+// XReadArgs is used as an arg to XRead.
+type XReadArgs struct {
+	// The stream names followed by the IDs to read after, e.g.
+	// []string{"s1", "s2", "0", "$"}.
+	Streams []string
+	Count   int64
+	// Blocks for at most Block when no message is available, or forever if
+	// Block is 0.  Doesn't block if Block is negative.
+	Block time.Duration
+}
+
+// Redis `XREAD [COUNT count] [BLOCK milliseconds] STREAMS key [key ...] id [id ...]` command.
+// It returns Nil if the timeout is reached.
+func (c *cmdable) XRead(a *XReadArgs) *XStreamSliceCmd {
+	args := make([]interface{}, 0, 6+len(a.Streams))
+	args = append(args, "xread")
+	if a.Count > 0 {
+		args = append(args, "count", a.Count)
+	}
+	if a.Block >= 0 {
+		args = append(args, "block", int64(a.Block/time.Millisecond))
+	}
+	args = append(args, "streams")
+	for _, s := range a.Streams {
+		args = append(args, s)
+	}
+
+	cmd := NewXStreamSliceCmd(args...)
+	if a.Block >= 0 {
+		cmd.setReadTimeout(readTimeout(a.Block))
+	}
+	c.process(cmd)
+	return cmd
+}
+
+// XReadStreams reads the given streams after the given IDs without blocking.
+func (c *cmdable) XReadStreams(streams ...string) *XStreamSliceCmd {
+	return c.XRead(&XReadArgs{
+		Streams: streams,
+		Block:   -1,
+	})
+}
+
+func (c *cmdable) XGroupCreate(stream, group, start string) *StatusCmd {
+	cmd := NewStatusCmd("xgroup", "create", stream, group, start)
+	c.process(cmd)
+	return cmd
+}
+
+// XGroupCreateMkStream creates the group, and the stream if it doesn't exist.
+func (c *cmdable) XGroupCreateMkStream(stream, group, start string) *StatusCmd {
+	cmd := NewStatusCmd("xgroup", "create", stream, group, start, "mkstream")
+	c.process(cmd)
+	return cmd
+}
+
+func (c *cmdable) XGroupSetID(stream, group, start string) *StatusCmd {
+	cmd := NewStatusCmd("xgroup", "setid", stream, group, start)
+	c.process(cmd)
+	return cmd
+}
+
+func (c *cmdable) XGroupDestroy(stream, group string) *IntCmd {
+	cmd := NewIntCmd("xgroup", "destroy", stream, group)
+	c.process(cmd)
+	return cmd
+}
+
+func (c *cmdable) XGroupDelConsumer(stream, group, consumer string) *IntCmd {
+	cmd := NewIntCmd("xgroup", "delconsumer", stream, group, consumer)
+	c.process(cmd)
+	return cmd
+}
+
+// XReadGroupArgs is used as an arg to XReadGroup.
+type XReadGroupArgs struct {
+	Group    string
+	Consumer string
+	// The stream names followed by the IDs to read after, ">" for messages
+	// never delivered to other consumers, e.g. []string{"s1", "s2", ">", ">"}.
+	Streams []string
+	Count   int64
+	// Blocks for at most Block when no message is available, or forever if
+	// Block is 0.  Doesn't block if Block is negative.
+	Block time.Duration
+	NoAck bool
+}
+
+// Redis `XREADGROUP GROUP group consumer [COUNT count] [BLOCK milliseconds] [NOACK] STREAMS key [key ...] id [id ...]` command.
+// It returns Nil if the timeout is reached.
+func (c *cmdable) XReadGroup(a *XReadGroupArgs) *XStreamSliceCmd {
+	args := make([]interface{}, 0, 9+len(a.Streams))
+	args = append(args, "xreadgroup", "group", a.Group, a.Consumer)
+	if a.Count > 0 {
+		args = append(args, "count", a.Count)
+	}
+	if a.Block >= 0 {
+		args = append(args, "block", int64(a.Block/time.Millisecond))
+	}
+	if a.NoAck {
+		args = append(args, "noack")
+	}
+	args = append(args, "streams")
+	for _, s := range a.Streams {
+		args = append(args, s)
+	}
+
+	cmd := NewXStreamSliceCmd(args...)
+	if a.Block >= 0 {
+		cmd.setReadTimeout(readTimeout(a.Block))
+	}
+	c.process(cmd)
+	return cmd
+}
+
+func (c *cmdable) XAck(stream, group string, ids ...string) *IntCmd {
+	args := make([]interface{}, 3+len(ids))
+	args[0] = "xack"
+	args[1] = stream
+	args[2] = group
+	for i, id := range ids {
+		args[3+i] = id
+	}
+	cmd := NewIntCmd(args...)
+	c.process(cmd)
+	return cmd
+}
+
+// XPending returns a summary of the messages delivered to the consumers of
+// the group and not acknowledged yet.
+func (c *cmdable) XPending(stream, group string) *XPendingCmd {
+	cmd := NewXPendingCmd("xpending", stream, group)
+	c.process(cmd)
+	return cmd
+}
+
+// XPendingExtArgs is used as an arg to XPendingExt.
+type XPendingExtArgs struct {
+	Stream string
+	Group  string
+	// Range of IDs, "-" and "+" for all the messages.
+	Start string
+	End   string
+	Count int64
+	// Only returns the messages of Consumer, if set.
+	Consumer string
+}
+
+// XPendingExt returns the messages delivered to the consumers of the group
+// and not acknowledged yet.
+func (c *cmdable) XPendingExt(a *XPendingExtArgs) *XPendingExtCmd {
+	args := make([]interface{}, 0, 7)
+	args = append(args, "xpending", a.Stream, a.Group, a.Start, a.End, a.Count)
+	if a.Consumer != "" {
+		args = append(args, a.Consumer)
+	}
+	cmd := NewXPendingExtCmd(args...)
+	c.process(cmd)
+	return cmd
+}
+
+// XClaimArgs is used as an arg to XClaim and XClaimJustID.
+type XClaimArgs struct {
+	Stream   string
+	Group    string
+	Consumer string
+	// Only claims the messages idle for at least MinIdle.
+	MinIdle  time.Duration
+	Messages []string
+}
+
+// XClaim transfers the given pending messages to the consumer and returns
+// them.
+func (c *cmdable) XClaim(a *XClaimArgs) *XMessageSliceCmd {
+	args := xClaimArgs(a)
+	cmd := NewXMessageSliceCmd(args...)
+	c.process(cmd)
+	return cmd
+}
+
+// XClaimJustID transfers the given pending messages to the consumer and
+// returns their IDs.
+func (c *cmdable) XClaimJustID(a *XClaimArgs) *StringSliceCmd {
+	args := xClaimArgs(a)
+	args = append(args, "justid")
+	cmd := NewStringSliceCmd(args...)
+	c.process(cmd)
+	return cmd
+}
+
+func xClaimArgs(a *XClaimArgs) []interface{} {
+	args := make([]interface{}, 0, 6+len(a.Messages))
+	args = append(args,
+		"xclaim",
+		a.Stream,
+		a.Group, a.Consumer,
+		int64(a.MinIdle/time.Millisecond))
+	for _, id := range a.Messages {
+		args = append(args, id)
+	}
+	return args
+}
+
+func (c *cmdable) XTrim(stream string, maxLen int64) *IntCmd {
+	cmd := NewIntCmd("xtrim", stream, "maxlen", maxLen)
+	c.process(cmd)
+	return cmd
+}
+
+func (c *cmdable) XTrimApprox(stream string, maxLen int64) *IntCmd {
+	cmd := NewIntCmd("xtrim", stream, "maxlen", "~", maxLen)
+	c.process(cmd)
+	return cmd
+}
+
+//------------------------------------------------------------------------------
+
 func (c *cmdable) PFAdd(key string, els ...interface{}) *IntCmd {
 	args := make([]interface{}, 2+len(els))
 	args[0] = "pfadd"
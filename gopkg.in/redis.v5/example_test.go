@@ -313,6 +313,33 @@ func ExamplePubSub_Receive() {
 	// received hello from mychannel2
 }
 
+func ExampleStreamConsumer() {
+	client.XAdd(&redis.XAddArgs{
+		Stream: "events",
+		Values: map[string]interface{}{"event": "signup"},
+	})
+
+	done := make(chan struct{})
+	consumer := redis.NewStreamConsumer(client, &redis.StreamConsumerOptions{
+		Streams:  []string{"events"},
+		Group:    "example",
+		Consumer: "consumer-1",
+	}, func(stream string, msg redis.XMessage) error {
+		fmt.Println(stream, msg.Values["event"])
+		close(done)
+		return nil
+	})
+
+	err := client.XGroupCreate("events", "example", "0").Err()
+	if err != nil {
+		panic(err)
+	}
+	go consumer.Run()
+	<-done
+	consumer.Close()
+	// Output: events signup
+}
+
 func ExampleScript() {
 	IncrByXX := redis.NewScript(`
 		if redis.call("GET", KEYS[1]) ~= false then
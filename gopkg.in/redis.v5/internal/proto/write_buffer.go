@@ -103,3 +103,38 @@ func (w *WriteBuffer) AppendBytes(p []byte) {
 	w.b = append(w.b, p...)
 	w.b = append(w.b, '\r', '\n')
 }
+
+func (w *WriteBuffer) AppendStatus(s string) {
+	w.b = append(w.b, StatusReply)
+	w.b = append(w.b, s...)
+	w.b = append(w.b, '\r', '\n')
+}
+
+func (w *WriteBuffer) AppendError(s string) {
+	w.b = append(w.b, ErrorReply)
+	w.b = append(w.b, s...)
+	w.b = append(w.b, '\r', '\n')
+}
+
+func (w *WriteBuffer) AppendInt(n int64) {
+	w.b = append(w.b, IntReply)
+	w.b = strconv.AppendInt(w.b, n, 10)
+	w.b = append(w.b, '\r', '\n')
+}
+
+// AppendNil appends a nil bulk string.
+func (w *WriteBuffer) AppendNil() {
+	w.b = append(w.b, StringReply, '-', '1', '\r', '\n')
+}
+
+// AppendArrayLen appends the header of an array of n elements, or of a nil
+// array if n is negative.
+func (w *WriteBuffer) AppendArrayLen(n int) {
+	w.b = append(w.b, ArrayReply)
+	if n < 0 {
+		w.b = append(w.b, '-', '1')
+	} else {
+		w.b = strconv.AppendUint(w.b, uint64(n), 10)
+	}
+	w.b = append(w.b, '\r', '\n')
+}
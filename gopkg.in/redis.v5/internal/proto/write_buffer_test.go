@@ -50,6 +50,21 @@ var _ = Describe("WriteBuffer", func() {
 		Expect(buf.Len()).To(Equal(26))
 	})
 
+	It("should append replies", func() {
+		buf.AppendArrayLen(5)
+		buf.AppendStatus("OK")
+		buf.AppendError("ERR failed")
+		buf.AppendInt(-42)
+		buf.AppendNil()
+		buf.AppendArrayLen(-1)
+		Expect(buf.Bytes()).To(Equal([]byte("*5\r\n" +
+			"+OK\r\n" +
+			"-ERR failed\r\n" +
+			":-42\r\n" +
+			"$-1\r\n" +
+			"*-1\r\n")))
+	})
+
 })
 
 func BenchmarkWriteBuffer_Append(b *testing.B) {
@@ -0,0 +1,136 @@
+package lock
+
+import (
+	"sync"
+	"time"
+
+	"gopkg.in/redis.v5/internal"
+)
+
+// Election elects a leader among the processes campaigning for the same key,
+// by having them try to obtain a lock.  The leader holds the lock until it
+// resigns or fails to renew it, after which another process is elected.
+type Election struct {
+	locker   *Locker
+	key      string
+	opt      Options
+	interval time.Duration
+
+	changes chan bool
+	closing chan struct{}
+	closed  chan struct{}
+
+	mu     sync.Mutex
+	leader bool
+}
+
+// NewElection starts campaigning for the key.  Processes which aren't the
+// leader try to obtain the lock every interval, or every TTL / 3 if it's 0.
+// Options may be nil; their retries are ignored.
+func NewElection(locker *Locker, key string, opt *Options, interval time.Duration) *Election {
+	var o Options
+	if opt != nil {
+		o = *opt
+	}
+	o.init()
+	o.RetryCount = 0
+	if o.RenewInterval < 0 {
+		o.RenewInterval = o.TTL / 3
+	}
+	if interval <= 0 {
+		interval = o.TTL / 3
+	}
+
+	e := &Election{
+		locker:   locker,
+		key:      key,
+		opt:      o,
+		interval: interval,
+		changes:  make(chan bool),
+		closing:  make(chan struct{}),
+		closed:   make(chan struct{}),
+	}
+	go e.campaign()
+	return e
+}
+
+// Leadership returns a channel which receives true when the process is
+// elected, and false when it loses the leadership.  It must be received from
+// for the election to proceed.  The channel is closed when the election is
+// closed.
+func (e *Election) Leadership() <-chan bool {
+	return e.changes
+}
+
+// IsLeader reports whether the process currently is the leader.
+func (e *Election) IsLeader() bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.leader
+}
+
+// Close stops campaigning, and resigns if the process is the leader.
+func (e *Election) Close() error {
+	select {
+	case <-e.closing:
+	default:
+		close(e.closing)
+	}
+	<-e.closed
+	return nil
+}
+
+func (e *Election) campaign() {
+	defer close(e.closed)
+	defer close(e.changes)
+
+	for {
+		lock, err := e.locker.Obtain(e.key, &e.opt)
+		if err == nil {
+			if !e.lead(lock) {
+				return
+			}
+		} else if err != ErrNotObtained {
+			internal.Logf("lock: campaigning for %s failed: %s", e.key, err)
+		}
+
+		select {
+		case <-e.closing:
+			return
+		case <-time.After(e.interval):
+		}
+	}
+}
+
+// lead holds the leadership until the lock is lost, or the election is
+// closed, in which case it returns false.
+func (e *Election) lead(lock *Lock) bool {
+	open := e.setLeader(true)
+	if open {
+		select {
+		case <-lock.Done():
+		case <-e.closing:
+			open = false
+		}
+	}
+	if !open {
+		lock.Release()
+	}
+	e.setLeader(false)
+	return open
+}
+
+// setLeader records and announces a change of leadership.  It returns false
+// if the election was closed meanwhile.
+func (e *Election) setLeader(leader bool) bool {
+	e.mu.Lock()
+	e.leader = leader
+	e.mu.Unlock()
+
+	select {
+	case e.changes <- leader:
+		return true
+	case <-e.closing:
+		return false
+	}
+}
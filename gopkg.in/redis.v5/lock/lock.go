@@ -0,0 +1,347 @@
+// Package lock implements distributed locks and leader election on Redis.
+//
+// Locks are keys set to a random token, which is checked when they are
+// extended or released, so that a process never releases a lock it no longer
+// holds.  A lock is renewed in the background while it's held.  With several
+// independent Redis servers, locks are obtained on a majority of them
+// following the Redlock algorithm.
+package lock
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"sync"
+	"time"
+
+	"gopkg.in/redis.v5"
+)
+
+var (
+	// ErrNotObtained is returned when a lock is held by someone else.
+	ErrNotObtained = errors.New("lock: not obtained")
+
+	// ErrNotHeld is returned when extending or releasing a lock which expired
+	// or was obtained by someone else.
+	ErrNotHeld = errors.New("lock: not held")
+)
+
+var (
+	extendScript = redis.NewScript(`
+if redis.call("get", KEYS[1]) == ARGV[1] then
+	return redis.call("pexpire", KEYS[1], ARGV[2])
+end
+return 0
+`)
+	releaseScript = redis.NewScript(`
+if redis.call("get", KEYS[1]) == ARGV[1] then
+	return redis.call("del", KEYS[1])
+end
+return 0
+`)
+)
+
+// Client is the subset of the redis.v5 clients used by locks, implemented
+// by *redis.Client, *redis.Ring and *redis.ClusterClient.
+type Client interface {
+	SetNX(key string, value interface{}, expiration time.Duration) *redis.BoolCmd
+	Eval(script string, keys []string, args ...interface{}) *redis.Cmd
+	EvalSha(sha1 string, keys []string, args ...interface{}) *redis.Cmd
+	ScriptExists(scripts ...string) *redis.BoolSliceCmd
+	ScriptLoad(script string) *redis.StringCmd
+}
+
+var _ Client = (*redis.Client)(nil)
+var _ Client = (*redis.Ring)(nil)
+var _ Client = (*redis.ClusterClient)(nil)
+
+// Options configures a lock.
+type Options struct {
+	// How long the lock is held without being renewed.
+	// Default is 10 seconds.
+	TTL time.Duration
+
+	// How many more times to try to obtain a lock held by someone else.
+	// Default is 0, which fails at once.
+	RetryCount int
+	// How long to wait between tries.
+	// Default is 100 milliseconds.
+	RetryBackoff time.Duration
+
+	// How often the lock is extended by TTL while held.
+	// Default is TTL / 3.  Set it to a negative value to disable renewal,
+	// in which case the lock is lost once its TTL elapses unless extended.
+	RenewInterval time.Duration
+}
+
+func (opt *Options) init() {
+	if opt.TTL == 0 {
+		opt.TTL = 10 * time.Second
+	}
+	if opt.RetryBackoff == 0 {
+		opt.RetryBackoff = 100 * time.Millisecond
+	}
+	if opt.RenewInterval == 0 {
+		opt.RenewInterval = opt.TTL / 3
+	}
+}
+
+// Locker obtains locks from one Redis server, or from a majority of several
+// independent ones.
+type Locker struct {
+	clients []Client
+	quorum  int
+}
+
+// New returns a Locker keeping locks on one Redis server or cluster.
+func New(client Client) *Locker {
+	return NewRedlock(client)
+}
+
+// NewRedlock returns a Locker keeping locks on a majority of the given
+// independent Redis servers, which tolerates the failure of a minority of
+// them.
+func NewRedlock(clients ...Client) *Locker {
+	return &Locker{
+		clients: clients,
+		quorum:  len(clients)/2 + 1,
+	}
+}
+
+// Obtain obtains the lock with the given key, retrying as configured if it's
+// held by someone else, in which case it returns ErrNotObtained.  Options
+// may be nil.
+func (l *Locker) Obtain(key string, opt *Options) (*Lock, error) {
+	var o Options
+	if opt != nil {
+		o = *opt
+	}
+	o.init()
+
+	token, err := newToken()
+	if err != nil {
+		return nil, err
+	}
+
+	for i := 0; ; i++ {
+		start := time.Now()
+		ok, err := l.obtain(key, token, o.TTL)
+		if ok {
+			lock := &Lock{
+				locker: l,
+				key:    key,
+				token:  token,
+				ttl:    o.TTL,
+				stop:   make(chan struct{}),
+				done:   make(chan struct{}),
+			}
+			lock.expiry = time.AfterFunc(validUntil(start, o.TTL).Sub(time.Now()), lock.lost)
+			if o.RenewInterval > 0 {
+				go lock.renew(o.RenewInterval)
+			}
+			return lock, nil
+		}
+		if i >= o.RetryCount {
+			if err != nil {
+				return nil, err
+			}
+			return nil, ErrNotObtained
+		}
+		time.Sleep(o.RetryBackoff)
+	}
+}
+
+// obtain sets the lock on a quorum of servers in less than its TTL, or
+// releases it.  It returns an error if no server could be reached.
+func (l *Locker) obtain(key, token string, ttl time.Duration) (bool, error) {
+	start := time.Now()
+	var n, failed int
+	var firstErr error
+	for _, client := range l.clients {
+		ok, err := client.SetNX(key, token, ttl).Result()
+		if err != nil {
+			failed++
+			if firstErr == nil {
+				firstErr = err
+			}
+		} else if ok {
+			n++
+		}
+	}
+
+	if n >= l.quorum && time.Now().Before(validUntil(start, ttl)) {
+		return true, nil
+	}
+	if n > 0 {
+		l.release(key, token)
+	}
+	if failed == len(l.clients) {
+		return false, firstErr
+	}
+	return false, nil
+}
+
+// extend sets the TTL of the lock on the servers where it's held, and
+// reports whether they are a quorum.
+func (l *Locker) extend(key, token string, ttl time.Duration) (bool, error) {
+	var n int
+	var firstErr error
+	for _, client := range l.clients {
+		v, err := extendScript.Run(client, []string{key}, token, int64(ttl/time.Millisecond)).Result()
+		if err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+		} else if v == int64(1) {
+			n++
+		}
+	}
+	if n >= l.quorum {
+		return true, nil
+	}
+	if n == 0 && firstErr != nil {
+		return false, firstErr
+	}
+	return false, nil
+}
+
+// release deletes the lock from the servers where it's held, and reports
+// whether they are a quorum.
+func (l *Locker) release(key, token string) (bool, error) {
+	var n int
+	var firstErr error
+	for _, client := range l.clients {
+		v, err := releaseScript.Run(client, []string{key}, token).Result()
+		if err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+		} else if v == int64(1) {
+			n++
+		}
+	}
+	if n >= l.quorum {
+		return true, nil
+	}
+	if n == 0 && firstErr != nil {
+		return false, firstErr
+	}
+	return false, nil
+}
+
+// validUntil returns when a lock set with the given TTL from start may have
+// expired.  The clock drift between servers counts against its validity.
+func validUntil(start time.Time, ttl time.Duration) time.Time {
+	drift := ttl/100 + 2*time.Millisecond
+	return start.Add(ttl - drift)
+}
+
+func newToken() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// Lock is an obtained lock.
+type Lock struct {
+	locker *Locker
+	key    string
+	token  string
+	ttl    time.Duration
+
+	mu       sync.Mutex
+	released bool
+	stop     chan struct{}
+	done     chan struct{}
+	lostOnce sync.Once
+	expiry   *time.Timer // calls lost when the lock may have expired
+}
+
+// Key returns the key of the lock.
+func (l *Lock) Key() string {
+	return l.key
+}
+
+// Token returns the random value identifying the holder of the lock.
+func (l *Lock) Token() string {
+	return l.token
+}
+
+// Done returns a channel which is closed when the lock is released, or lost
+// because it couldn't be renewed before it may have expired.
+func (l *Lock) Done() <-chan struct{} {
+	return l.done
+}
+
+// Extend sets the TTL of the lock, which must still be held.
+func (l *Lock) Extend(ttl time.Duration) error {
+	start := time.Now()
+	ok, err := l.locker.extend(l.key, l.token, ttl)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		l.lost()
+		return ErrNotHeld
+	}
+	l.mu.Lock()
+	if !l.released {
+		l.expiry.Reset(validUntil(start, ttl).Sub(time.Now()))
+	}
+	l.mu.Unlock()
+	return nil
+}
+
+// Release releases the lock and stops renewing it.  It returns ErrNotHeld if
+// the lock expired before.
+func (l *Lock) Release() error {
+	l.mu.Lock()
+	if l.released {
+		l.mu.Unlock()
+		return ErrNotHeld
+	}
+	l.released = true
+	l.expiry.Stop()
+	close(l.stop)
+	l.mu.Unlock()
+
+	defer l.lost()
+	ok, err := l.locker.release(l.key, l.token)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrNotHeld
+	}
+	return nil
+}
+
+func (l *Lock) lost() {
+	l.lostOnce.Do(func() {
+		close(l.done)
+	})
+}
+
+// renew extends the lock until it's released or lost.  The lock is lost if
+// it isn't held anymore, or by the expiry timer if it can't be extended in
+// time.
+func (l *Lock) renew(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-l.stop:
+			return
+		case <-l.done:
+			return
+		case <-ticker.C:
+		}
+
+		if err := l.Extend(l.ttl); err == ErrNotHeld {
+			return
+		}
+	}
+}
@@ -0,0 +1,166 @@
+package lock_test
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"gopkg.in/redis.v5/lock"
+)
+
+var _ = Describe("Lock", func() {
+	var locker *lock.Locker
+
+	BeforeEach(func() {
+		locker = lock.New(clients[0])
+	})
+
+	It("should obtain and release locks", func() {
+		l, err := locker.Obtain("job", &lock.Options{TTL: time.Minute})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(l.Key()).To(Equal("job"))
+		Expect(clients[0].Get("job").Val()).To(Equal(l.Token()))
+		Expect(clients[0].PTTL("job").Val()).To(BeNumerically("~", time.Minute, time.Second))
+
+		_, err = locker.Obtain("job", nil)
+		Expect(err).To(Equal(lock.ErrNotObtained))
+
+		Expect(l.Release()).NotTo(HaveOccurred())
+		Expect(l.Done()).To(BeClosed())
+		Expect(clients[0].Exists("job").Val()).To(BeFalse())
+		Expect(l.Release()).To(Equal(lock.ErrNotHeld))
+
+		l, err = locker.Obtain("job", nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(l.Release()).NotTo(HaveOccurred())
+	})
+
+	It("should retry to obtain locks", func() {
+		l, err := locker.Obtain("job", &lock.Options{TTL: 100 * time.Millisecond, RenewInterval: -1})
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = locker.Obtain("job", &lock.Options{RetryCount: 1, RetryBackoff: 10 * time.Millisecond})
+		Expect(err).To(Equal(lock.ErrNotObtained))
+
+		l2, err := locker.Obtain("job", &lock.Options{RetryCount: 20, RetryBackoff: 10 * time.Millisecond})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(l2.Token()).NotTo(Equal(l.Token()))
+		Expect(l2.Release()).NotTo(HaveOccurred())
+	})
+
+	It("should not release locks held by someone else", func() {
+		l, err := locker.Obtain("job", &lock.Options{TTL: 50 * time.Millisecond, RenewInterval: -1})
+		Expect(err).NotTo(HaveOccurred())
+		time.Sleep(100 * time.Millisecond)
+
+		l2, err := locker.Obtain("job", nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(l.Extend(time.Minute)).To(Equal(lock.ErrNotHeld))
+		Expect(l.Done()).To(BeClosed())
+		Expect(l.Release()).To(Equal(lock.ErrNotHeld))
+		Expect(clients[0].Get("job").Val()).To(Equal(l2.Token()))
+		Expect(l2.Release()).NotTo(HaveOccurred())
+	})
+
+	It("should extend locks", func() {
+		l, err := locker.Obtain("job", &lock.Options{TTL: time.Second, RenewInterval: -1})
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(l.Extend(time.Minute)).NotTo(HaveOccurred())
+		Expect(clients[0].PTTL("job").Val()).To(BeNumerically(">", time.Second))
+		Expect(l.Release()).NotTo(HaveOccurred())
+	})
+
+	It("should renew locks while held", func() {
+		l, err := locker.Obtain("job", &lock.Options{TTL: 100 * time.Millisecond})
+		Expect(err).NotTo(HaveOccurred())
+
+		time.Sleep(300 * time.Millisecond)
+		Expect(l.Done()).NotTo(BeClosed())
+		Expect(clients[0].Get("job").Val()).To(Equal(l.Token()))
+
+		// The lock is lost once it's taken away
+		Expect(clients[0].Del("job").Err()).NotTo(HaveOccurred())
+		Eventually(l.Done()).Should(BeClosed())
+		Expect(l.Release()).To(Equal(lock.ErrNotHeld))
+	})
+
+	It("should be lost once it may have expired", func() {
+		l, err := locker.Obtain("job", &lock.Options{TTL: 100 * time.Millisecond, RenewInterval: -1})
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(l.Extend(200 * time.Millisecond)).NotTo(HaveOccurred())
+		time.Sleep(150 * time.Millisecond)
+		Expect(l.Done()).NotTo(BeClosed())
+		Eventually(l.Done()).Should(BeClosed())
+	})
+
+	Describe("Redlock", func() {
+		BeforeEach(func() {
+			locker = lock.NewRedlock(clients[0], clients[1], clients[2])
+		})
+
+		It("should obtain locks on a majority of servers", func() {
+			Expect(clients[2].Set("job", "other", 0).Err()).NotTo(HaveOccurred())
+
+			l, err := locker.Obtain("job", nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(clients[0].Get("job").Val()).To(Equal(l.Token()))
+			Expect(clients[1].Get("job").Val()).To(Equal(l.Token()))
+
+			Expect(l.Extend(time.Minute)).NotTo(HaveOccurred())
+			Expect(l.Release()).NotTo(HaveOccurred())
+			Expect(clients[0].Exists("job").Val()).To(BeFalse())
+			Expect(clients[2].Get("job").Val()).To(Equal("other"))
+		})
+
+		It("should not obtain locks held on a majority of servers", func() {
+			Expect(clients[1].Set("job", "other", 0).Err()).NotTo(HaveOccurred())
+			Expect(clients[2].Set("job", "other", 0).Err()).NotTo(HaveOccurred())
+
+			_, err := locker.Obtain("job", nil)
+			Expect(err).To(Equal(lock.ErrNotObtained))
+			// and releases the minority it obtained
+			Expect(clients[0].Exists("job").Val()).To(BeFalse())
+		})
+
+		It("should lose locks taken away from a majority of servers", func() {
+			l, err := locker.Obtain("job", &lock.Options{RenewInterval: -1})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(clients[0].Del("job").Err()).NotTo(HaveOccurred())
+			Expect(l.Extend(time.Minute)).NotTo(HaveOccurred())
+
+			Expect(clients[1].Del("job").Err()).NotTo(HaveOccurred())
+			Expect(l.Extend(time.Minute)).To(Equal(lock.ErrNotHeld))
+		})
+	})
+})
+
+var _ = Describe("Election", func() {
+	It("should elect a leader", func() {
+		locker := lock.New(clients[0])
+		opt := &lock.Options{TTL: 200 * time.Millisecond}
+
+		e1 := lock.NewElection(locker, "leader", opt, 20*time.Millisecond)
+		Eventually(e1.Leadership()).Should(Receive(BeTrue()))
+		Expect(e1.IsLeader()).To(BeTrue())
+
+		e2 := lock.NewElection(locker, "leader", opt, 20*time.Millisecond)
+		defer e2.Close()
+		Consistently(e2.Leadership(), 300*time.Millisecond).ShouldNot(Receive())
+		Expect(e2.IsLeader()).To(BeFalse())
+
+		// The leader resigns
+		Expect(e1.Close()).NotTo(HaveOccurred())
+		Expect(e1.Leadership()).To(BeClosed())
+		Eventually(e2.Leadership()).Should(Receive(BeTrue()))
+
+		// and the new one loses the lock
+		Expect(clients[0].Del("leader").Err()).NotTo(HaveOccurred())
+		Eventually(e2.Leadership()).Should(Receive(BeFalse()))
+		Eventually(e2.Leadership()).Should(Receive(BeTrue()))
+	})
+})
@@ -0,0 +1,61 @@
+package lock_test
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"gopkg.in/redis.v5"
+)
+
+var redisPorts = []string{"6395", "6396", "6397"}
+
+var redisServerBin, _ = filepath.Abs(filepath.Join("..", "testdata", "redis", "src", "redis-server"))
+
+var (
+	processes []*os.Process
+	clients   []*redis.Client
+)
+
+var _ = BeforeSuite(func() {
+	for _, port := range redisPorts {
+		cmd := exec.Command(redisServerBin, "--port", port, "--save", "", "--appendonly", "no")
+		if testing.Verbose() {
+			cmd.Stdout = os.Stdout
+			cmd.Stderr = os.Stderr
+		}
+		Expect(cmd.Start()).NotTo(HaveOccurred())
+		processes = append(processes, cmd.Process)
+
+		client := redis.NewClient(&redis.Options{Addr: ":" + port})
+		Eventually(func() error {
+			return client.Ping().Err()
+		}, 30*time.Second).ShouldNot(HaveOccurred())
+		clients = append(clients, client)
+	}
+})
+
+var _ = AfterSuite(func() {
+	for _, client := range clients {
+		Expect(client.Close()).NotTo(HaveOccurred())
+	}
+	for _, process := range processes {
+		Expect(process.Kill()).NotTo(HaveOccurred())
+	}
+})
+
+var _ = BeforeEach(func() {
+	for _, client := range clients {
+		Expect(client.FlushDb().Err()).NotTo(HaveOccurred())
+	}
+})
+
+func TestGinkgoSuite(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "gopkg.in/redis.v5/lock")
+}
@@ -398,3 +398,204 @@ func timeParser(rd *proto.Reader, n int64) (interface{}, error) {
 
 	return time.Unix(sec, microsec*1000), nil
 }
+
+// Implements proto.MultiBulkParse
+func xMessageSliceParser(rd *proto.Reader, n int64) (interface{}, error) {
+	msgs := make([]XMessage, 0, n)
+	for i := int64(0); i < n; i++ {
+		v, err := rd.ReadArrayReply(xMessageParser)
+		if err == Nil {
+			// Claimed messages which were deleted.
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		msgs = append(msgs, *v.(*XMessage))
+	}
+	return msgs, nil
+}
+
+// Implements proto.MultiBulkParse
+func xMessageParser(rd *proto.Reader, n int64) (interface{}, error) {
+	if n != 2 {
+		return nil, fmt.Errorf("redis: got %d elements in stream message, expected 2", n)
+	}
+
+	id, err := rd.ReadStringReply()
+	if err != nil {
+		return nil, err
+	}
+
+	v, err := rd.ReadArrayReply(stringInterfaceMapParser)
+	if err == Nil {
+		return &XMessage{ID: id}, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	return &XMessage{
+		ID:     id,
+		Values: v.(map[string]interface{}),
+	}, nil
+}
+
+// Implements proto.MultiBulkParse
+func stringInterfaceMapParser(rd *proto.Reader, n int64) (interface{}, error) {
+	m := make(map[string]interface{}, n/2)
+	for i := int64(0); i < n; i += 2 {
+		key, err := rd.ReadStringReply()
+		if err != nil {
+			return nil, err
+		}
+
+		value, err := rd.ReadStringReply()
+		if err != nil {
+			return nil, err
+		}
+
+		m[key] = value
+	}
+	return m, nil
+}
+
+// Implements proto.MultiBulkParse
+func xStreamSliceParser(rd *proto.Reader, n int64) (interface{}, error) {
+	streams := make([]XStream, 0, n)
+	for i := int64(0); i < n; i++ {
+		v, err := rd.ReadArrayReply(xStreamParser)
+		if err != nil {
+			return nil, err
+		}
+		streams = append(streams, *v.(*XStream))
+	}
+	return streams, nil
+}
+
+// Implements proto.MultiBulkParse
+func xStreamParser(rd *proto.Reader, n int64) (interface{}, error) {
+	if n != 2 {
+		return nil, fmt.Errorf("redis: got %d elements in stream, expected 2", n)
+	}
+
+	stream, err := rd.ReadStringReply()
+	if err != nil {
+		return nil, err
+	}
+
+	v, err := rd.ReadArrayReply(xMessageSliceParser)
+	if err != nil {
+		return nil, err
+	}
+
+	return &XStream{
+		Stream:   stream,
+		Messages: v.([]XMessage),
+	}, nil
+}
+
+// Implements proto.MultiBulkParse
+func xPendingParser(rd *proto.Reader, n int64) (interface{}, error) {
+	if n != 4 {
+		return nil, fmt.Errorf("redis: got %d elements in XPENDING reply, expected 4", n)
+	}
+
+	count, err := rd.ReadIntReply()
+	if err != nil {
+		return nil, err
+	}
+
+	// The IDs and the consumers are nil when nothing is pending.
+	lower, err := rd.ReadStringReply()
+	if err != nil && err != Nil {
+		return nil, err
+	}
+
+	higher, err := rd.ReadStringReply()
+	if err != nil && err != Nil {
+		return nil, err
+	}
+
+	pending := &XPending{
+		Count:  count,
+		Lower:  lower,
+		Higher: higher,
+	}
+	v, err := rd.ReadArrayReply(xPendingConsumersParser)
+	if err == Nil {
+		return pending, nil
+	} else if err != nil {
+		return nil, err
+	}
+	pending.Consumers = v.(map[string]int64)
+	return pending, nil
+}
+
+// Implements proto.MultiBulkParse
+func xPendingConsumersParser(rd *proto.Reader, n int64) (interface{}, error) {
+	consumers := make(map[string]int64, n)
+	for i := int64(0); i < n; i++ {
+		m, err := rd.ReadArrayLen()
+		if err != nil {
+			return nil, err
+		}
+		if m != 2 {
+			return nil, fmt.Errorf("redis: got %d elements in XPENDING consumer, expected 2", m)
+		}
+
+		name, err := rd.ReadStringReply()
+		if err != nil {
+			return nil, err
+		}
+
+		// The count is a bulk string.
+		count, err := rd.ReadInt()
+		if err != nil {
+			return nil, err
+		}
+
+		consumers[name] = count
+	}
+	return consumers, nil
+}
+
+// Implements proto.MultiBulkParse
+func xPendingExtSliceParser(rd *proto.Reader, n int64) (interface{}, error) {
+	pending := make([]XPendingExt, 0, n)
+	for i := int64(0); i < n; i++ {
+		m, err := rd.ReadArrayLen()
+		if err != nil {
+			return nil, err
+		}
+		if m != 4 {
+			return nil, fmt.Errorf("redis: got %d elements in XPENDING message, expected 4", m)
+		}
+
+		id, err := rd.ReadStringReply()
+		if err != nil {
+			return nil, err
+		}
+
+		consumer, err := rd.ReadStringReply()
+		if err != nil {
+			return nil, err
+		}
+
+		idle, err := rd.ReadIntReply()
+		if err != nil {
+			return nil, err
+		}
+
+		retryCount, err := rd.ReadIntReply()
+		if err != nil {
+			return nil, err
+		}
+
+		pending = append(pending, XPendingExt{
+			ID:         id,
+			Consumer:   consumer,
+			Idle:       time.Duration(idle) * time.Millisecond,
+			RetryCount: retryCount,
+		})
+	}
+	return pending, nil
+}
@@ -0,0 +1,415 @@
+package redistest
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// The command is run at once in MULTI, rather than queued.
+	flagTx = 1 << iota
+	// The command is allowed in the subscribed state.
+	flagPubSub
+	// The command isn't allowed in scripts.
+	flagNoScript
+)
+
+type command struct {
+	fn func(c *conn, args []string) interface{}
+	// Number of arguments including the name of the command, or minimum
+	// number if negative.
+	arity int
+	flags int
+}
+
+var commands map[string]command
+
+func init() {
+	commands = map[string]command{
+		// Connection
+		"ping":   {ping, -1, flagPubSub},
+		"echo":   {echo, 2, 0},
+		"select": {selectDB, 2, 0},
+		"quit":   {quit, 1, flagTx | flagPubSub | flagNoScript},
+
+		// Server
+		"dbsize":   {dbSize, 1, 0},
+		"flushdb":  {flushDB, 1, 0},
+		"flushall": {flushAll, 1, 0},
+		"time":     {serverTime, 1, 0},
+
+		// Keys
+		"del":       {del, -2, 0},
+		"exists":    {exists, -2, 0},
+		"type":      {keyType, 2, 0},
+		"keys":      {keys, 2, 0},
+		"scan":      {scan, -2, 0},
+		"rename":    {rename, 3, 0},
+		"renamenx":  {renameNX, 3, 0},
+		"expire":    {expire(time.Second, false), 3, 0},
+		"pexpire":   {expire(time.Millisecond, false), 3, 0},
+		"expireat":  {expire(time.Second, true), 3, 0},
+		"pexpireat": {expire(time.Millisecond, true), 3, 0},
+		"ttl":       {ttl(time.Second), 2, 0},
+		"pttl":      {ttl(time.Millisecond), 2, 0},
+		"persist":   {persist, 2, 0},
+
+		// Strings
+		"get":         {get, 2, 0},
+		"set":         {setKey, -3, 0},
+		"setnx":       {setNX, 3, 0},
+		"setex":       {setEx(time.Second), 4, 0},
+		"psetex":      {setEx(time.Millisecond), 4, 0},
+		"getset":      {getSet, 3, 0},
+		"mget":        {mget, -2, 0},
+		"mset":        {mset, -3, 0},
+		"msetnx":      {msetNX, -3, 0},
+		"incr":        {incrBy(1, false), 2, 0},
+		"decr":        {incrBy(-1, false), 2, 0},
+		"incrby":      {incrBy(1, true), 3, 0},
+		"decrby":      {incrBy(-1, true), 3, 0},
+		"incrbyfloat": {incrByFloat, 3, 0},
+		"append":      {appendString, 3, 0},
+		"strlen":      {strlen, 2, 0},
+		"getrange":    {getRange, 4, 0},
+
+		// Hashes
+		"hset":         {hset, -4, 0},
+		"hsetnx":       {hsetNX, 4, 0},
+		"hmset":        {hmset, -4, 0},
+		"hget":         {hget, 3, 0},
+		"hmget":        {hmget, -3, 0},
+		"hdel":         {hdel, -3, 0},
+		"hexists":      {hexists, 3, 0},
+		"hlen":         {hlen, 2, 0},
+		"hgetall":      {hgetAll, 2, 0},
+		"hkeys":        {hkeys, 2, 0},
+		"hvals":        {hvals, 2, 0},
+		"hincrby":      {hincrBy, 4, 0},
+		"hincrbyfloat": {hincrByFloat, 4, 0},
+
+		// Lists
+		"lpush":     {push(true, false), -3, 0},
+		"rpush":     {push(false, false), -3, 0},
+		"lpushx":    {push(true, true), 3, 0},
+		"rpushx":    {push(false, true), 3, 0},
+		"lpop":      {pop(true), 2, 0},
+		"rpop":      {pop(false), 2, 0},
+		"llen":      {llen, 2, 0},
+		"lrange":    {lrange, 4, 0},
+		"lindex":    {lindex, 3, 0},
+		"lset":      {lset, 4, 0},
+		"lrem":      {lrem, 4, 0},
+		"ltrim":     {ltrim, 4, 0},
+		"linsert":   {linsert, 5, 0},
+		"rpoplpush": {rpoplpush, 3, 0},
+
+		// Sets
+		"sadd":        {sadd, -3, 0},
+		"srem":        {srem, -3, 0},
+		"smembers":    {smembers, 2, 0},
+		"sismember":   {sismember, 3, 0},
+		"scard":       {scard, 2, 0},
+		"spop":        {spop, -2, 0},
+		"srandmember": {srandMember, -2, 0},
+		"smove":       {smove, 4, 0},
+		"sinter":      {setOp(setInter, false), -2, 0},
+		"sunion":      {setOp(setUnion, false), -2, 0},
+		"sdiff":       {setOp(setDiff, false), -2, 0},
+		"sinterstore": {setOp(setInter, true), -3, 0},
+		"sunionstore": {setOp(setUnion, true), -3, 0},
+		"sdiffstore":  {setOp(setDiff, true), -3, 0},
+
+		// Sorted sets
+		"zadd":             {zadd, -4, 0},
+		"zincrby":          {zincrBy, 4, 0},
+		"zscore":           {zscore, 3, 0},
+		"zrem":             {zrem, -3, 0},
+		"zcard":            {zcard, 2, 0},
+		"zcount":           {zcount, 4, 0},
+		"zrange":           {zrange(false), -4, 0},
+		"zrevrange":        {zrange(true), -4, 0},
+		"zrangebyscore":    {zrangeByScore(false), -4, 0},
+		"zrevrangebyscore": {zrangeByScore(true), -4, 0},
+		"zrank":            {zrank(false), 3, 0},
+		"zrevrank":         {zrank(true), 3, 0},
+		"zremrangebyrank":  {zremRangeByRank, 4, 0},
+		"zremrangebyscore": {zremRangeByScore, 4, 0},
+
+		// Transactions
+		"multi":   {multi, 1, flagTx | flagNoScript},
+		"exec":    {exec, 1, flagTx | flagNoScript},
+		"discard": {discard, 1, flagTx | flagNoScript},
+		"watch":   {watch, -2, flagTx | flagNoScript},
+		"unwatch": {unwatch, 1, flagTx | flagNoScript},
+
+		// Pub/Sub
+		"subscribe":    {subscribe, -2, flagPubSub | flagNoScript},
+		"unsubscribe":  {unsubscribe, -1, flagPubSub | flagNoScript},
+		"psubscribe":   {psubscribe, -2, flagPubSub | flagNoScript},
+		"punsubscribe": {punsubscribe, -1, flagPubSub | flagNoScript},
+		"publish":      {publish, 3, 0},
+		"pubsub":       {pubsub, -2, 0},
+
+		// Scripting
+		"eval":    {eval, -3, flagNoScript},
+		"evalsha": {evalSHA, -3, flagNoScript},
+		"script":  {script, -2, flagNoScript},
+	}
+}
+
+// dispatch runs or queues a command and returns its reply.
+func (c *conn) dispatch(args []string) interface{} {
+	name := strings.ToLower(args[0])
+	cmd, err := lookupCommand(name, args)
+	if err != nil {
+		if c.multi {
+			c.dirty = true
+		}
+		return err
+	}
+	if c.subscribed() && cmd.flags&flagPubSub == 0 {
+		return errors.New("ERR only (P)SUBSCRIBE / (P)UNSUBSCRIBE / PING / QUIT allowed in this context")
+	}
+	if c.multi && cmd.flags&flagTx == 0 {
+		c.queued = append(c.queued, args)
+		return status("QUEUED")
+	}
+	return cmd.fn(c, args[1:])
+}
+
+func lookupCommand(name string, args []string) (command, error) {
+	cmd, ok := commands[name]
+	if !ok {
+		return cmd, fmt.Errorf("ERR unknown command '%s'", args[0])
+	}
+	if (cmd.arity > 0 && len(args) != cmd.arity) || len(args) < -cmd.arity {
+		return cmd, errArity(name)
+	}
+	return cmd, nil
+}
+
+//------------------------------------------------------------------------------
+
+func ping(c *conn, args []string) interface{} {
+	if len(args) > 1 {
+		return errArity("ping")
+	}
+	if c.subscribed() {
+		payload := ""
+		if len(args) == 1 {
+			payload = args[0]
+		}
+		return []interface{}{"pong", payload}
+	}
+	if len(args) == 1 {
+		return args[0]
+	}
+	return status("PONG")
+}
+
+func echo(c *conn, args []string) interface{} {
+	return args[0]
+}
+
+func selectDB(c *conn, args []string) interface{} {
+	n, err := strconv.Atoi(args[0])
+	if err != nil {
+		return errNotInt
+	}
+	if n < 0 || n >= 16 {
+		return errors.New("ERR DB index is out of range")
+	}
+	c.db = n
+	return statusOK
+}
+
+func quit(c *conn, args []string) interface{} {
+	c.quit = true
+	return statusOK
+}
+
+func dbSize(c *conn, args []string) interface{} {
+	return len(c.selected().sortedKeys(c.now()))
+}
+
+func flushDB(c *conn, args []string) interface{} {
+	c.selected().flush()
+	return statusOK
+}
+
+func flushAll(c *conn, args []string) interface{} {
+	c.srv.flushAll()
+	return statusOK
+}
+
+func serverTime(c *conn, args []string) interface{} {
+	now := c.now()
+	return []string{
+		strconv.FormatInt(now.Unix(), 10),
+		strconv.Itoa(now.Nanosecond() / 1000),
+	}
+}
+
+//------------------------------------------------------------------------------
+
+func del(c *conn, args []string) interface{} {
+	d := c.selected()
+	var n int
+	for _, key := range args {
+		if d.get(key, c.now()) != nil && d.del(key) {
+			n++
+		}
+	}
+	return n
+}
+
+func exists(c *conn, args []string) interface{} {
+	var n int
+	for _, key := range args {
+		if c.selected().get(key, c.now()) != nil {
+			n++
+		}
+	}
+	return n
+}
+
+func keyType(c *conn, args []string) interface{} {
+	e := c.selected().get(args[0], c.now())
+	if e == nil {
+		return status("none")
+	}
+	return status(typeName(e.value))
+}
+
+func keys(c *conn, args []string) interface{} {
+	keys := []string{}
+	for _, key := range c.selected().sortedKeys(c.now()) {
+		if match(args[0], key) {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+// scan iterates over the keys in order, using the index of the next key as
+// cursor.
+func scan(c *conn, args []string) interface{} {
+	cursor, err := strconv.Atoi(args[0])
+	if err != nil || cursor < 0 {
+		return errors.New("ERR invalid cursor")
+	}
+	pattern, count := "*", 10
+	for i := 1; i < len(args); i += 2 {
+		if i+1 == len(args) {
+			return errSyntax
+		}
+		switch strings.ToLower(args[i]) {
+		case "match":
+			pattern = args[i+1]
+		case "count":
+			count, err = strconv.Atoi(args[i+1])
+			if err != nil {
+				return errNotInt
+			}
+			if count < 1 {
+				return errSyntax
+			}
+		default:
+			return errSyntax
+		}
+	}
+
+	all := c.selected().sortedKeys(c.now())
+	keys := []string{}
+	for ; cursor < len(all) && count > 0; cursor++ {
+		if match(pattern, all[cursor]) {
+			keys = append(keys, all[cursor])
+		}
+		count--
+	}
+	if cursor >= len(all) {
+		cursor = 0
+	}
+	return []interface{}{strconv.Itoa(cursor), keys}
+}
+
+func rename(c *conn, args []string) interface{} {
+	d := c.selected()
+	e := d.get(args[0], c.now())
+	if e == nil {
+		return errNoSuchKey
+	}
+	d.del(args[0])
+	d.keys[args[1]] = e
+	d.touch(args[1])
+	return statusOK
+}
+
+func renameNX(c *conn, args []string) interface{} {
+	d := c.selected()
+	if d.get(args[0], c.now()) == nil {
+		return errNoSuchKey
+	}
+	if d.get(args[1], c.now()) != nil {
+		return 0
+	}
+	rename(c, args)
+	return 1
+}
+
+func expire(unit time.Duration, at bool) func(*conn, []string) interface{} {
+	return func(c *conn, args []string) interface{} {
+		n, err := parseInt(args[1])
+		if err != nil {
+			return err
+		}
+		d := c.selected()
+		e := d.get(args[0], c.now())
+		if e == nil {
+			return 0
+		}
+
+		var t time.Time
+		if at {
+			t = time.Unix(0, 0).Add(time.Duration(n) * unit)
+		} else {
+			t = c.now().Add(time.Duration(n) * unit)
+		}
+		if !c.now().Before(t) {
+			d.del(args[0])
+		} else {
+			e.expireAt = t
+			d.touch(args[0])
+		}
+		return 1
+	}
+}
+
+func ttl(unit time.Duration) func(*conn, []string) interface{} {
+	return func(c *conn, args []string) interface{} {
+		e := c.selected().get(args[0], c.now())
+		if e == nil {
+			return -2
+		}
+		if e.expireAt.IsZero() {
+			return -1
+		}
+		left := e.expireAt.Sub(c.now())
+		return int64((left + unit/2) / unit)
+	}
+}
+
+func persist(c *conn, args []string) interface{} {
+	d := c.selected()
+	e := d.get(args[0], c.now())
+	if e == nil || e.expireAt.IsZero() {
+		return 0
+	}
+	e.expireAt = time.Time{}
+	d.touch(args[0])
+	return 1
+}
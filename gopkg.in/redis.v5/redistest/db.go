@@ -0,0 +1,300 @@
+package redistest
+
+import (
+	"sort"
+	"time"
+)
+
+// The values of keys.
+type (
+	hash map[string]string
+	list struct{ items []string }
+	set  map[string]struct{}
+	zset map[string]float64
+)
+
+type entry struct {
+	value    interface{} // string, hash, *list, set or zset
+	expireAt time.Time   // zero if the key doesn't expire
+}
+
+func typeName(value interface{}) string {
+	switch value.(type) {
+	case string:
+		return "string"
+	case hash:
+		return "hash"
+	case *list:
+		return "list"
+	case set:
+		return "set"
+	case zset:
+		return "zset"
+	}
+	return "none"
+}
+
+// db is a numbered database.
+type db struct {
+	keys map[string]*entry
+
+	// Versions of the keys, bumped when they are modified, for WATCH.
+	versions map[string]uint64
+}
+
+func newDB() *db {
+	return &db{
+		keys:     make(map[string]*entry),
+		versions: make(map[string]uint64),
+	}
+}
+
+// get returns the entry of a key, or nil if it doesn't exist or expired.
+func (d *db) get(key string, now time.Time) *entry {
+	e, ok := d.keys[key]
+	if !ok {
+		return nil
+	}
+	if !e.expireAt.IsZero() && !now.Before(e.expireAt) {
+		d.del(key)
+		return nil
+	}
+	return e
+}
+
+// set sets the value of a key, removing its TTL.
+func (d *db) set(key string, value interface{}) *entry {
+	e := &entry{value: value}
+	d.keys[key] = e
+	d.touch(key)
+	return e
+}
+
+func (d *db) del(key string) bool {
+	if _, ok := d.keys[key]; !ok {
+		return false
+	}
+	delete(d.keys, key)
+	d.touch(key)
+	return true
+}
+
+func (d *db) touch(key string) {
+	d.versions[key]++
+}
+
+func (d *db) flush() {
+	for key := range d.keys {
+		d.del(key)
+	}
+}
+
+// sortedKeys returns the keys which didn't expire in order.
+func (d *db) sortedKeys(now time.Time) []string {
+	keys := make([]string, 0, len(d.keys))
+	for key := range d.keys {
+		if d.get(key, now) != nil {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// The lookups below return errWrongType if the key holds another type of
+// value, and a nil value if it doesn't exist, unless create is true.
+
+func (c *conn) getString(key string) (string, bool, error) {
+	e := c.selected().get(key, c.now())
+	if e == nil {
+		return "", false, nil
+	}
+	s, ok := e.value.(string)
+	if !ok {
+		return "", false, errWrongType
+	}
+	return s, true, nil
+}
+
+func (c *conn) lookup(key string, create func() interface{}) (interface{}, error) {
+	d := c.selected()
+	e := d.get(key, c.now())
+	if e == nil {
+		if create == nil {
+			return nil, nil
+		}
+		e = d.set(key, create())
+	}
+	return e.value, nil
+}
+
+func (c *conn) getHash(key string, create bool) (hash, error) {
+	var fn func() interface{}
+	if create {
+		fn = func() interface{} { return make(hash) }
+	}
+	v, err := c.lookup(key, fn)
+	if v == nil || err != nil {
+		return nil, err
+	}
+	h, ok := v.(hash)
+	if !ok {
+		return nil, errWrongType
+	}
+	return h, nil
+}
+
+func (c *conn) getList(key string, create bool) (*list, error) {
+	var fn func() interface{}
+	if create {
+		fn = func() interface{} { return new(list) }
+	}
+	v, err := c.lookup(key, fn)
+	if v == nil || err != nil {
+		return nil, err
+	}
+	l, ok := v.(*list)
+	if !ok {
+		return nil, errWrongType
+	}
+	return l, nil
+}
+
+func (c *conn) getSet(key string, create bool) (set, error) {
+	var fn func() interface{}
+	if create {
+		fn = func() interface{} { return make(set) }
+	}
+	v, err := c.lookup(key, fn)
+	if v == nil || err != nil {
+		return nil, err
+	}
+	s, ok := v.(set)
+	if !ok {
+		return nil, errWrongType
+	}
+	return s, nil
+}
+
+func (c *conn) getZSet(key string, create bool) (zset, error) {
+	var fn func() interface{}
+	if create {
+		fn = func() interface{} { return make(zset) }
+	}
+	v, err := c.lookup(key, fn)
+	if v == nil || err != nil {
+		return nil, err
+	}
+	z, ok := v.(zset)
+	if !ok {
+		return nil, errWrongType
+	}
+	return z, nil
+}
+
+// modified records the modification of a key, deleting it if it holds an
+// empty collection.
+func (c *conn) modified(key string) {
+	d := c.selected()
+	e, ok := d.keys[key]
+	if !ok {
+		return
+	}
+	var n int
+	switch v := e.value.(type) {
+	case string:
+		n = 1
+	case hash:
+		n = len(v)
+	case *list:
+		n = len(v.items)
+	case set:
+		n = len(v)
+	case zset:
+		n = len(v)
+	}
+	if n == 0 {
+		d.del(key)
+	} else {
+		d.touch(key)
+	}
+}
+
+// match reports whether s matches the glob-style pattern, as used by KEYS
+// and PSUBSCRIBE.
+func match(pattern, s string) bool {
+	for len(pattern) > 0 {
+		switch pattern[0] {
+		case '*':
+			for len(pattern) > 1 && pattern[1] == '*' {
+				pattern = pattern[1:]
+			}
+			if len(pattern) == 1 {
+				return true
+			}
+			for i := 0; i <= len(s); i++ {
+				if match(pattern[1:], s[i:]) {
+					return true
+				}
+			}
+			return false
+		case '?':
+			if len(s) == 0 {
+				return false
+			}
+		case '[':
+			if len(s) == 0 {
+				return false
+			}
+			end, ok := matchClass(pattern[1:], s[0])
+			if !ok {
+				return false
+			}
+			if end >= len(pattern) {
+				// An unterminated class ends the pattern.
+				return len(s) == 1
+			}
+			pattern = pattern[end:]
+		case '\\':
+			if len(pattern) > 1 {
+				pattern = pattern[1:]
+			}
+			fallthrough
+		default:
+			if len(s) == 0 || pattern[0] != s[0] {
+				return false
+			}
+		}
+		pattern = pattern[1:]
+		s = s[1:]
+	}
+	return len(s) == 0
+}
+
+// matchClass matches b against the class at the start of pattern, which
+// follows '['.  It returns the index of the closing ']' in pattern plus one.
+func matchClass(pattern string, b byte) (int, bool) {
+	not := len(pattern) > 0 && pattern[0] == '^'
+	i := 0
+	if not {
+		i++
+	}
+	var matched bool
+	for ; i < len(pattern) && pattern[i] != ']'; i++ {
+		switch {
+		case pattern[i] == '\\' && i+1 < len(pattern):
+			i++
+			matched = matched || pattern[i] == b
+		case i+2 < len(pattern) && pattern[i+1] == '-' && pattern[i+2] != ']':
+			lo, hi := pattern[i], pattern[i+2]
+			if lo > hi {
+				lo, hi = hi, lo
+			}
+			matched = matched || (b >= lo && b <= hi)
+			i += 2
+		default:
+			matched = matched || pattern[i] == b
+		}
+	}
+	return i + 1, matched != not
+}
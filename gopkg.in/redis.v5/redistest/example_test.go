@@ -0,0 +1,52 @@
+package redistest_test
+
+import (
+	"fmt"
+	"time"
+
+	"gopkg.in/redis.v5"
+	"gopkg.in/redis.v5/redistest"
+)
+
+func ExampleServer() {
+	srv, err := redistest.NewServer()
+	if err != nil {
+		panic(err)
+	}
+	defer srv.Close()
+
+	client := redis.NewClient(&redis.Options{Addr: srv.Addr()})
+	defer client.Close()
+
+	client.Set("session", "token", time.Hour)
+	fmt.Println(client.Get("session").Val())
+
+	srv.FastForward(time.Hour)
+	fmt.Println(client.Get("session").Err())
+	// Output: token
+	// redis: nil
+}
+
+func ExampleServer_HandleScript() {
+	srv, err := redistest.NewServer()
+	if err != nil {
+		panic(err)
+	}
+	defer srv.Close()
+
+	script := redis.NewScript(`return redis.call("incrby", KEYS[1], ARGV[1])`)
+	srv.HandleScript(`return redis.call("incrby", KEYS[1], ARGV[1])`,
+		func(call redistest.CallFunc, keys, args []string) interface{} {
+			n, err := call("incrby", keys[0], args[0])
+			if err != nil {
+				return err
+			}
+			return n
+		})
+
+	client := redis.NewClient(&redis.Options{Addr: srv.Addr()})
+	defer client.Close()
+
+	fmt.Println(script.Run(client, []string{"counter"}, 5).Val())
+	// Output: 5
+}
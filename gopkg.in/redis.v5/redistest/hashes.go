@@ -0,0 +1,200 @@
+package redistest
+
+import (
+	"sort"
+	"strconv"
+)
+
+func hset(c *conn, args []string) interface{} {
+	if len(args)%2 != 1 {
+		return errArity("hset")
+	}
+	h, err := c.getHash(args[0], true)
+	if err != nil {
+		return err
+	}
+	var n int
+	for i := 1; i < len(args); i += 2 {
+		if _, ok := h[args[i]]; !ok {
+			n++
+		}
+		h[args[i]] = args[i+1]
+	}
+	c.modified(args[0])
+	return n
+}
+
+func hsetNX(c *conn, args []string) interface{} {
+	h, err := c.getHash(args[0], true)
+	if err != nil {
+		return err
+	}
+	if _, ok := h[args[1]]; ok {
+		return 0
+	}
+	h[args[1]] = args[2]
+	c.modified(args[0])
+	return 1
+}
+
+func hmset(c *conn, args []string) interface{} {
+	if len(args)%2 != 1 {
+		return errArity("hmset")
+	}
+	if err, ok := hset(c, args).(error); ok {
+		return err
+	}
+	return statusOK
+}
+
+func hget(c *conn, args []string) interface{} {
+	h, err := c.getHash(args[0], false)
+	if err != nil {
+		return err
+	}
+	v, ok := h[args[1]]
+	if !ok {
+		return nil
+	}
+	return v
+}
+
+func hmget(c *conn, args []string) interface{} {
+	h, err := c.getHash(args[0], false)
+	if err != nil {
+		return err
+	}
+	vals := make([]interface{}, len(args)-1)
+	for i, field := range args[1:] {
+		if v, ok := h[field]; ok {
+			vals[i] = v
+		}
+	}
+	return vals
+}
+
+func hdel(c *conn, args []string) interface{} {
+	h, err := c.getHash(args[0], false)
+	if err != nil {
+		return err
+	}
+	var n int
+	for _, field := range args[1:] {
+		if _, ok := h[field]; ok {
+			delete(h, field)
+			n++
+		}
+	}
+	if n > 0 {
+		c.modified(args[0])
+	}
+	return n
+}
+
+func hexists(c *conn, args []string) interface{} {
+	h, err := c.getHash(args[0], false)
+	if err != nil {
+		return err
+	}
+	_, ok := h[args[1]]
+	return ok
+}
+
+func hlen(c *conn, args []string) interface{} {
+	h, err := c.getHash(args[0], false)
+	if err != nil {
+		return err
+	}
+	return len(h)
+}
+
+func (h hash) fields() []string {
+	fields := make([]string, 0, len(h))
+	for field := range h {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+	return fields
+}
+
+func hgetAll(c *conn, args []string) interface{} {
+	h, err := c.getHash(args[0], false)
+	if err != nil {
+		return err
+	}
+	vals := make([]string, 0, 2*len(h))
+	for _, field := range h.fields() {
+		vals = append(vals, field, h[field])
+	}
+	return vals
+}
+
+func hkeys(c *conn, args []string) interface{} {
+	h, err := c.getHash(args[0], false)
+	if err != nil {
+		return err
+	}
+	return h.fields()
+}
+
+func hvals(c *conn, args []string) interface{} {
+	h, err := c.getHash(args[0], false)
+	if err != nil {
+		return err
+	}
+	vals := make([]string, 0, len(h))
+	for _, field := range h.fields() {
+		vals = append(vals, h[field])
+	}
+	return vals
+}
+
+func hincrBy(c *conn, args []string) interface{} {
+	delta, err := parseInt(args[2])
+	if err != nil {
+		return err
+	}
+	h, err := c.getHash(args[0], false)
+	if err != nil {
+		return err
+	}
+	var n int64
+	if v, ok := h[args[1]]; ok {
+		n, err = strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return errHashNotInt
+		}
+	}
+	if h == nil {
+		h, _ = c.getHash(args[0], true)
+	}
+	n += delta
+	h[args[1]] = strconv.FormatInt(n, 10)
+	c.modified(args[0])
+	return n
+}
+
+func hincrByFloat(c *conn, args []string) interface{} {
+	delta, err := parseFloat(args[2])
+	if err != nil {
+		return err
+	}
+	h, err := c.getHash(args[0], false)
+	if err != nil {
+		return err
+	}
+	var f float64
+	if v, ok := h[args[1]]; ok {
+		f, err = parseFloat(v)
+		if err != nil {
+			return errHashNotFloat
+		}
+	}
+	if h == nil {
+		h, _ = c.getHash(args[0], true)
+	}
+	f += delta
+	h[args[1]] = formatFloat(f)
+	c.modified(args[0])
+	return f
+}
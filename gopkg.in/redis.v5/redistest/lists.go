@@ -0,0 +1,249 @@
+package redistest
+
+import "strings"
+
+func push(left, exists bool) func(*conn, []string) interface{} {
+	return func(c *conn, args []string) interface{} {
+		l, err := c.getList(args[0], !exists)
+		if err != nil {
+			return err
+		}
+		if l == nil {
+			return 0
+		}
+		for _, v := range args[1:] {
+			if left {
+				l.items = append([]string{v}, l.items...)
+			} else {
+				l.items = append(l.items, v)
+			}
+		}
+		c.modified(args[0])
+		return len(l.items)
+	}
+}
+
+func pop(left bool) func(*conn, []string) interface{} {
+	return func(c *conn, args []string) interface{} {
+		l, err := c.getList(args[0], false)
+		if err != nil {
+			return err
+		}
+		if l == nil {
+			return nil
+		}
+		var v string
+		if left {
+			v, l.items = l.items[0], l.items[1:]
+		} else {
+			v, l.items = l.items[len(l.items)-1], l.items[:len(l.items)-1]
+		}
+		c.modified(args[0])
+		return v
+	}
+}
+
+func llen(c *conn, args []string) interface{} {
+	l, err := c.getList(args[0], false)
+	if err != nil || l == nil {
+		return errOrZero(err)
+	}
+	return len(l.items)
+}
+
+func errOrZero(err error) interface{} {
+	if err != nil {
+		return err
+	}
+	return 0
+}
+
+func lrange(c *conn, args []string) interface{} {
+	start, err := parseInt(args[1])
+	if err != nil {
+		return err
+	}
+	stop, err := parseInt(args[2])
+	if err != nil {
+		return err
+	}
+	l, err := c.getList(args[0], false)
+	if err != nil {
+		return err
+	}
+	if l == nil {
+		return []string{}
+	}
+	i, j, ok := rangeIndexes(start, stop, len(l.items))
+	if !ok {
+		return []string{}
+	}
+	return append([]string(nil), l.items[i:j+1]...)
+}
+
+// index converts an index where negative values count from the end, and
+// reports whether it's in range.
+func (l *list) index(s string) (int, bool, error) {
+	i, err := parseInt(s)
+	if err != nil {
+		return 0, false, err
+	}
+	if i < 0 {
+		i += int64(len(l.items))
+	}
+	return int(i), i >= 0 && i < int64(len(l.items)), nil
+}
+
+func lindex(c *conn, args []string) interface{} {
+	l, err := c.getList(args[0], false)
+	if err != nil {
+		return err
+	}
+	if l == nil {
+		if _, err := parseInt(args[1]); err != nil {
+			return err
+		}
+		return nil
+	}
+	i, ok, err := l.index(args[1])
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return nil
+	}
+	return l.items[i]
+}
+
+func lset(c *conn, args []string) interface{} {
+	l, err := c.getList(args[0], false)
+	if err != nil {
+		return err
+	}
+	if l == nil {
+		return errNoSuchKey
+	}
+	i, ok, err := l.index(args[1])
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return errOutOfRange
+	}
+	l.items[i] = args[2]
+	c.modified(args[0])
+	return statusOK
+}
+
+// lrem removes count occurrences of a value from the head, from the tail if
+// count is negative, or all of them if it's 0.
+func lrem(c *conn, args []string) interface{} {
+	count, err := parseInt(args[1])
+	if err != nil {
+		return err
+	}
+	l, err := c.getList(args[0], false)
+	if err != nil || l == nil {
+		return errOrZero(err)
+	}
+
+	fromTail := count < 0
+	if fromTail {
+		count = -count
+	}
+	remove := make(map[int]bool)
+	for k := 0; k < len(l.items); k++ {
+		i := k
+		if fromTail {
+			i = len(l.items) - 1 - k
+		}
+		if l.items[i] == args[2] {
+			remove[i] = true
+			if int64(len(remove)) == count {
+				break
+			}
+		}
+	}
+	if len(remove) == 0 {
+		return 0
+	}
+
+	items := make([]string, 0, len(l.items)-len(remove))
+	for i, v := range l.items {
+		if !remove[i] {
+			items = append(items, v)
+		}
+	}
+	l.items = items
+	c.modified(args[0])
+	return len(remove)
+}
+
+func ltrim(c *conn, args []string) interface{} {
+	start, err := parseInt(args[1])
+	if err != nil {
+		return err
+	}
+	stop, err := parseInt(args[2])
+	if err != nil {
+		return err
+	}
+	l, err := c.getList(args[0], false)
+	if err != nil {
+		return err
+	}
+	if l == nil {
+		return statusOK
+	}
+	i, j, ok := rangeIndexes(start, stop, len(l.items))
+	if ok {
+		l.items = l.items[i : j+1]
+	} else {
+		l.items = nil
+	}
+	c.modified(args[0])
+	return statusOK
+}
+
+func linsert(c *conn, args []string) interface{} {
+	var after bool
+	switch strings.ToLower(args[1]) {
+	case "before":
+	case "after":
+		after = true
+	default:
+		return errSyntax
+	}
+	l, err := c.getList(args[0], false)
+	if err != nil || l == nil {
+		return errOrZero(err)
+	}
+	for i, v := range l.items {
+		if v != args[2] {
+			continue
+		}
+		if after {
+			i++
+		}
+		l.items = append(l.items[:i], append([]string{args[3]}, l.items[i:]...)...)
+		c.modified(args[0])
+		return len(l.items)
+	}
+	return -1
+}
+
+func rpoplpush(c *conn, args []string) interface{} {
+	src, err := c.getList(args[0], false)
+	if err != nil {
+		return err
+	}
+	if src == nil {
+		return nil
+	}
+	if _, err := c.getList(args[1], false); err != nil {
+		return err
+	}
+	v := pop(false)(c, args[:1])
+	push(true, false)(c, []string{args[1], v.(string)})
+	return v
+}
@@ -0,0 +1,134 @@
+package redistest
+
+import (
+	"errors"
+	"sort"
+	"strings"
+)
+
+func (c *conn) subscribed() bool {
+	return len(c.channels) > 0 || len(c.patterns) > 0
+}
+
+func (c *conn) subscriptions() int {
+	return len(c.channels) + len(c.patterns)
+}
+
+func subscribe(c *conn, args []string) interface{} {
+	if c.channels == nil {
+		c.channels = make(map[string]bool)
+	}
+	return c.subscribe("subscribe", c.channels, args)
+}
+
+func psubscribe(c *conn, args []string) interface{} {
+	if c.patterns == nil {
+		c.patterns = make(map[string]bool)
+	}
+	return c.subscribe("psubscribe", c.patterns, args)
+}
+
+func (c *conn) subscribe(kind string, subs map[string]bool, names []string) interface{} {
+	var replies multiReply
+	for _, name := range names {
+		subs[name] = true
+		replies = append(replies, []interface{}{kind, name, c.subscriptions()})
+	}
+	return replies
+}
+
+func unsubscribe(c *conn, args []string) interface{} {
+	return c.unsubscribe("unsubscribe", c.channels, args)
+}
+
+func punsubscribe(c *conn, args []string) interface{} {
+	return c.unsubscribe("punsubscribe", c.patterns, args)
+}
+
+// unsubscribe unsubscribes from the given channels or patterns, or from all
+// of them if there are none.
+func (c *conn) unsubscribe(kind string, subs map[string]bool, names []string) interface{} {
+	if len(names) == 0 {
+		names = sortedNames(subs)
+		if len(names) == 0 {
+			return []interface{}{kind, nil, c.subscriptions()}
+		}
+	}
+	var replies multiReply
+	for _, name := range names {
+		delete(subs, name)
+		replies = append(replies, []interface{}{kind, name, c.subscriptions()})
+	}
+	return replies
+}
+
+func sortedNames(subs map[string]bool) []string {
+	names := make([]string, 0, len(subs))
+	for name := range subs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// publish sends the message to the subscribers of the channel, and returns
+// their number.
+func publish(c *conn, args []string) interface{} {
+	channel, msg := args[0], args[1]
+	var n int
+	for sub := range c.srv.conns {
+		if sub.channels[channel] {
+			sub.send([]interface{}{"message", channel, msg})
+			n++
+		}
+		for pattern := range sub.patterns {
+			if match(pattern, channel) {
+				sub.send([]interface{}{"pmessage", pattern, channel, msg})
+				n++
+			}
+		}
+	}
+	return n
+}
+
+// pubsub implements PUBSUB CHANNELS [pattern], NUMSUB [channel ...] and
+// NUMPAT.
+func pubsub(c *conn, args []string) interface{} {
+	switch strings.ToLower(args[0]) {
+	case "channels":
+		if len(args) > 2 {
+			return errArity("pubsub")
+		}
+		all := make(map[string]bool)
+		for sub := range c.srv.conns {
+			for channel := range sub.channels {
+				if len(args) == 1 || match(args[1], channel) {
+					all[channel] = true
+				}
+			}
+		}
+		return sortedNames(all)
+	case "numsub":
+		vals := make([]interface{}, 0, 2*len(args[1:]))
+		for _, channel := range args[1:] {
+			var n int
+			for sub := range c.srv.conns {
+				if sub.channels[channel] {
+					n++
+				}
+			}
+			vals = append(vals, channel, n)
+		}
+		return vals
+	case "numpat":
+		if len(args) > 1 {
+			return errArity("pubsub")
+		}
+		var n int
+		for sub := range c.srv.conns {
+			n += len(sub.patterns)
+		}
+		return n
+	}
+	return errors.New("ERR Unknown PUBSUB subcommand or wrong number of arguments for '" + args[0] + "'")
+}
@@ -0,0 +1,145 @@
+package redistest
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+
+	"gopkg.in/redis.v5/internal/proto"
+)
+
+var (
+	errWrongType  = errors.New("WRONGTYPE Operation against a key holding the wrong kind of value")
+	errNotInt     = errors.New("ERR value is not an integer or out of range")
+	errNotFloat   = errors.New("ERR value is not a valid float")
+	errSyntax     = errors.New("ERR syntax error")
+	errNoSuchKey  = errors.New("ERR no such key")
+	errOutOfRange = errors.New("ERR index out of range")
+
+	errHashNotInt   = errors.New("ERR hash value is not an integer")
+	errHashNotFloat = errors.New("ERR hash value is not a float")
+)
+
+func errArity(name string) error {
+	return fmt.Errorf("ERR wrong number of arguments for '%s' command", name)
+}
+
+// Replies are nil, string, int64, float64, []string, []interface{},
+// error or one of the types below.
+type (
+	// status is a simple string reply.
+	status string
+
+	// nilArray is the reply of EXEC when a watched key was modified.
+	nilArray struct{}
+
+	// multiReply is several replies sent in a row, by (P)SUBSCRIBE and
+	// (P)UNSUBSCRIBE.
+	multiReply []interface{}
+)
+
+const statusOK = status("OK")
+
+func appendReply(w *proto.WriteBuffer, reply interface{}) {
+	switch v := reply.(type) {
+	case nil:
+		w.AppendNil()
+	case status:
+		w.AppendStatus(string(v))
+	case error:
+		w.AppendError(v.Error())
+	case string:
+		w.AppendString(v)
+	case int64:
+		w.AppendInt(v)
+	case int:
+		w.AppendInt(int64(v))
+	case bool:
+		if v {
+			w.AppendInt(1)
+		} else {
+			w.AppendInt(0)
+		}
+	case float64:
+		w.AppendString(formatFloat(v))
+	case []string:
+		w.AppendArrayLen(len(v))
+		for _, s := range v {
+			w.AppendString(s)
+		}
+	case []interface{}:
+		w.AppendArrayLen(len(v))
+		for _, r := range v {
+			appendReply(w, r)
+		}
+	case nilArray:
+		w.AppendArrayLen(-1)
+	case multiReply:
+		for _, r := range v {
+			appendReply(w, r)
+		}
+	default:
+		w.AppendError(fmt.Sprintf("ERR redistest: unsupported reply %T", reply))
+	}
+}
+
+// callReply converts a reply to the value returned by CallFunc.
+func callReply(reply interface{}) (interface{}, error) {
+	switch v := reply.(type) {
+	case error:
+		return nil, v
+	case status:
+		return string(v), nil
+	case int:
+		return int64(v), nil
+	case bool:
+		if v {
+			return int64(1), nil
+		}
+		return int64(0), nil
+	case float64:
+		return formatFloat(v), nil
+	case []string:
+		vals := make([]interface{}, len(v))
+		for i, s := range v {
+			vals[i] = s
+		}
+		return vals, nil
+	case []interface{}:
+		vals := make([]interface{}, len(v))
+		for i, r := range v {
+			val, err := callReply(r)
+			if err != nil {
+				vals[i] = err
+			} else {
+				vals[i] = val
+			}
+		}
+		return vals, nil
+	case nilArray:
+		return nil, nil
+	default:
+		return v, nil
+	}
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
+
+func parseInt(s string) (int64, error) {
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return 0, errNotInt
+	}
+	return n, nil
+}
+
+func parseFloat(s string) (float64, error) {
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil || math.IsNaN(f) {
+		return 0, errNotFloat
+	}
+	return f, nil
+}
@@ -0,0 +1,85 @@
+package redistest
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+)
+
+var errNoScript = errors.New("NOSCRIPT No matching script. Please use EVAL.")
+
+func eval(c *conn, args []string) interface{} {
+	sha := scriptSHA(args[0])
+	c.srv.loaded[sha] = true
+	return c.runScript(sha, args[1:])
+}
+
+func evalSHA(c *conn, args []string) interface{} {
+	sha := strings.ToLower(args[0])
+	if !c.srv.loaded[sha] {
+		return errNoScript
+	}
+	return c.runScript(sha, args[1:])
+}
+
+// runScript runs the ScriptFunc registered for the script, given the number
+// of keys followed by the keys and arguments.
+func (c *conn) runScript(sha string, args []string) interface{} {
+	numKeys, err := strconv.Atoi(args[0])
+	if err != nil {
+		return errNotInt
+	}
+	if numKeys < 0 {
+		return errors.New("ERR Number of keys can't be negative")
+	}
+	if numKeys > len(args)-1 {
+		return errors.New("ERR Number of keys can't be greater than number of args")
+	}
+	fn, ok := c.srv.scripts[sha]
+	if !ok {
+		return errors.New("ERR redistest: no ScriptFunc registered for script " + sha)
+	}
+	keys, argv := args[1:1+numKeys], args[1+numKeys:]
+	return fn(c.call, keys, argv)
+}
+
+// call runs a command from a script.
+func (c *conn) call(args ...string) (interface{}, error) {
+	if len(args) == 0 {
+		return nil, errors.New("ERR Please specify at least one argument for redis.call()")
+	}
+	name := strings.ToLower(args[0])
+	cmd, err := lookupCommand(name, args)
+	if err != nil {
+		return nil, err
+	}
+	if cmd.flags&flagNoScript != 0 {
+		return nil, errors.New("ERR This Redis command is not allowed from scripts")
+	}
+	return callReply(cmd.fn(c, args[1:]))
+}
+
+// script implements SCRIPT LOAD, EXISTS, FLUSH and KILL.
+func script(c *conn, args []string) interface{} {
+	switch strings.ToLower(args[0]) {
+	case "load":
+		if len(args) != 2 {
+			return errArity("script")
+		}
+		sha := scriptSHA(args[1])
+		c.srv.loaded[sha] = true
+		return sha
+	case "exists":
+		vals := make([]interface{}, len(args)-1)
+		for i, sha := range args[1:] {
+			vals[i] = c.srv.loaded[strings.ToLower(sha)]
+		}
+		return vals
+	case "flush":
+		c.srv.loaded = make(map[string]bool)
+		return statusOK
+	case "kill":
+		return errors.New("NOTBUSY No scripts in execution right now.")
+	}
+	return errors.New("ERR Unknown SCRIPT subcommand or wrong number of arguments for '" + args[0] + "'")
+}
@@ -0,0 +1,283 @@
+// Package redistest implements an in-memory stand-in for a Redis server,
+// which speaks RESP over a loopback listener so that code using redis.v5 can
+// be tested without running redis-server.
+//
+// The server supports the commands on strings, hashes, lists, sets and sorted
+// sets, key expiry, MULTI/EXEC with WATCH, and pub/sub.  Lua isn't
+// available: scripts are stood in for by Go functions registered with
+// HandleScript.  The clock of the server can be moved forward with
+// FastForward to test TTLs.
+package redistest
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"io"
+	"net"
+	"sync"
+	"time"
+
+	"gopkg.in/redis.v5/internal/proto"
+)
+
+// ScriptFunc stands in for a Lua script run with EVAL or EVALSHA.  It's run
+// atomically, and runs commands with call like redis.call.  It returns the
+// reply of the script: nil, a string, an int64, a []interface{} of those, or
+// an error.
+type ScriptFunc func(call CallFunc, keys, args []string) interface{}
+
+// CallFunc runs a command from a ScriptFunc and returns its reply, which is
+// nil, a string, an int64 or a []interface{}.
+type CallFunc func(args ...string) (interface{}, error)
+
+// Server is an in-memory Redis server.
+type Server struct {
+	ln net.Listener
+	wg sync.WaitGroup
+
+	mu      sync.Mutex
+	offset  time.Duration
+	dbs     map[int]*db
+	conns   map[*conn]struct{}
+	scripts map[string]ScriptFunc
+	loaded  map[string]bool
+}
+
+// NewServer starts a server listening on a random loopback port.
+func NewServer() (*Server, error) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+	s := &Server{
+		ln:      ln,
+		dbs:     make(map[int]*db),
+		conns:   make(map[*conn]struct{}),
+		scripts: make(map[string]ScriptFunc),
+		loaded:  make(map[string]bool),
+	}
+	s.wg.Add(1)
+	go s.serve()
+	return s, nil
+}
+
+// Addr returns the address the server listens on.
+func (s *Server) Addr() string {
+	return s.ln.Addr().String()
+}
+
+// Close stops the server and closes its connections.
+func (s *Server) Close() error {
+	err := s.ln.Close()
+	s.mu.Lock()
+	for c := range s.conns {
+		c.nc.Close()
+	}
+	s.mu.Unlock()
+	s.wg.Wait()
+	return err
+}
+
+// Now returns the time of the server clock.
+func (s *Server) Now() time.Time {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.now()
+}
+
+func (s *Server) now() time.Time {
+	return time.Now().Add(s.offset)
+}
+
+// FastForward moves the server clock forward, expiring the keys whose TTL
+// elapsed.
+func (s *Server) FastForward(d time.Duration) {
+	s.mu.Lock()
+	s.offset += d
+	s.mu.Unlock()
+}
+
+// FlushAll deletes the keys of all the databases.
+func (s *Server) FlushAll() {
+	s.mu.Lock()
+	s.flushAll()
+	s.mu.Unlock()
+}
+
+func (s *Server) flushAll() {
+	for _, d := range s.dbs {
+		d.flush()
+	}
+}
+
+// HandleScript registers fn to be run in place of the Lua script src.
+func (s *Server) HandleScript(src string, fn ScriptFunc) {
+	s.mu.Lock()
+	s.scripts[scriptSHA(src)] = fn
+	s.mu.Unlock()
+}
+
+func scriptSHA(src string) string {
+	h := sha1.New()
+	io.WriteString(h, src)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func (s *Server) db(i int) *db {
+	d, ok := s.dbs[i]
+	if !ok {
+		d = newDB()
+		s.dbs[i] = d
+	}
+	return d
+}
+
+func (s *Server) serve() {
+	defer s.wg.Done()
+	for {
+		nc, err := s.ln.Accept()
+		if err != nil {
+			return
+		}
+		c := &conn{
+			srv: s,
+			nc:  nc,
+			rd:  proto.NewReader(nc),
+			wb:  proto.NewWriteBuffer(),
+		}
+		c.cond = sync.NewCond(&c.qmu)
+		s.mu.Lock()
+		s.conns[c] = struct{}{}
+		s.mu.Unlock()
+
+		s.wg.Add(2)
+		go c.serve()
+		go c.write()
+	}
+}
+
+type watchKey struct {
+	db  int
+	key string
+}
+
+// conn is a client connection and its state.  Commands are run with the
+// server mutex held, which also guards the state.  Replies are queued and
+// written by another goroutine, so that a subscriber which doesn't read its
+// messages doesn't block the server.
+type conn struct {
+	srv *Server
+	nc  net.Conn
+	rd  *proto.Reader
+	wb  *proto.WriteBuffer
+
+	qmu    sync.Mutex
+	cond   *sync.Cond
+	queue  [][]byte
+	closed bool
+
+	db   int
+	quit bool
+
+	multi   bool
+	dirty   bool // a command failed to be queued
+	queued  [][]string
+	watched map[watchKey]uint64
+
+	channels map[string]bool
+	patterns map[string]bool
+}
+
+func (c *conn) serve() {
+	defer c.srv.wg.Done()
+	defer c.close()
+
+	for {
+		args, err := c.readCommand()
+		if err != nil {
+			return
+		}
+		if len(args) == 0 {
+			continue
+		}
+
+		c.srv.mu.Lock()
+		c.send(c.dispatch(args))
+		c.srv.mu.Unlock()
+		if c.quit {
+			return
+		}
+	}
+}
+
+func (c *conn) readCommand() ([]string, error) {
+	n, err := c.rd.ReadArrayLen()
+	if err != nil {
+		return nil, err
+	}
+	args := make([]string, n)
+	for i := range args {
+		args[i], err = c.rd.ReadStringReply()
+		if err != nil {
+			return nil, err
+		}
+	}
+	return args, nil
+}
+
+// send queues a reply to the connection.
+func (c *conn) send(reply interface{}) {
+	c.wb.Reset()
+	appendReply(c.wb, reply)
+	b := append([]byte(nil), c.wb.Bytes()...)
+
+	c.qmu.Lock()
+	c.queue = append(c.queue, b)
+	c.qmu.Unlock()
+	c.cond.Signal()
+}
+
+// write writes the queued replies until the connection is closed, and
+// closes it once they are all written.
+func (c *conn) write() {
+	defer c.srv.wg.Done()
+	defer c.nc.Close()
+
+	for {
+		c.qmu.Lock()
+		for len(c.queue) == 0 && !c.closed {
+			c.cond.Wait()
+		}
+		queue := c.queue
+		c.queue = nil
+		c.qmu.Unlock()
+
+		if len(queue) == 0 {
+			return
+		}
+		for _, b := range queue {
+			if _, err := c.nc.Write(b); err != nil {
+				return
+			}
+		}
+	}
+}
+
+func (c *conn) close() {
+	c.srv.mu.Lock()
+	delete(c.srv.conns, c)
+	c.srv.mu.Unlock()
+
+	c.qmu.Lock()
+	c.closed = true
+	c.qmu.Unlock()
+	c.cond.Signal()
+}
+
+func (c *conn) selected() *db {
+	return c.srv.db(c.db)
+}
+
+func (c *conn) now() time.Time {
+	return c.srv.now()
+}
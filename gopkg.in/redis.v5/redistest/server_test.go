@@ -0,0 +1,442 @@
+package redistest_test
+
+import (
+	"crypto/sha1"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"gopkg.in/redis.v5"
+	"gopkg.in/redis.v5/redistest"
+)
+
+func TestGinkgoSuite(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "gopkg.in/redis.v5/redistest")
+}
+
+var _ = Describe("Server", func() {
+	var srv *redistest.Server
+	var client *redis.Client
+
+	BeforeEach(func() {
+		var err error
+		srv, err = redistest.NewServer()
+		Expect(err).NotTo(HaveOccurred())
+		client = redis.NewClient(&redis.Options{Addr: srv.Addr()})
+	})
+
+	AfterEach(func() {
+		Expect(client.Close()).NotTo(HaveOccurred())
+		Expect(srv.Close()).NotTo(HaveOccurred())
+	})
+
+	It("should ping", func() {
+		Expect(client.Ping().Val()).To(Equal("PONG"))
+		Expect(client.Echo("hello").Val()).To(Equal("hello"))
+	})
+
+	It("should reply errors", func() {
+		err := client.Process(redis.NewCmd("unknown"))
+		Expect(err).To(MatchError("ERR unknown command 'unknown'"))
+
+		err = client.Process(redis.NewCmd("get", "a", "b"))
+		Expect(err).To(MatchError("ERR wrong number of arguments for 'get' command"))
+
+		Expect(client.HSet("hash", "field", "value").Err()).NotTo(HaveOccurred())
+		err = client.Get("hash").Err()
+		Expect(err).To(MatchError("WRONGTYPE Operation against a key holding the wrong kind of value"))
+	})
+
+	It("should select databases", func() {
+		other := redis.NewClient(&redis.Options{Addr: srv.Addr(), DB: 1})
+		defer other.Close()
+
+		Expect(other.Set("key", "1", 0).Err()).NotTo(HaveOccurred())
+		Expect(client.Exists("key").Val()).To(BeFalse())
+		Expect(other.Exists("key").Val()).To(BeTrue())
+		Expect(other.DbSize().Val()).To(Equal(int64(1)))
+
+		srv.FlushAll()
+		Expect(other.Exists("key").Val()).To(BeFalse())
+	})
+
+	Describe("keys", func() {
+		It("should delete, list and rename keys", func() {
+			Expect(client.MSet("a1", "1", "a2", "2", "b", "3").Err()).NotTo(HaveOccurred())
+			Expect(client.Keys("a*").Val()).To(Equal([]string{"a1", "a2"}))
+			Expect(client.Keys("[ab]?").Val()).To(Equal([]string{"a1", "a2"}))
+			Expect(client.Type("b").Val()).To(Equal("string"))
+
+			Expect(client.Del("a1", "a2", "c").Val()).To(Equal(int64(2)))
+			Expect(client.Rename("b", "c").Err()).NotTo(HaveOccurred())
+			Expect(client.Get("c").Val()).To(Equal("3"))
+			Expect(client.Rename("b", "c").Err()).To(MatchError("ERR no such key"))
+			Expect(client.Type("b").Val()).To(Equal("none"))
+		})
+
+		It("should scan keys", func() {
+			for _, key := range []string{"a", "b", "c", "d", "e"} {
+				Expect(client.Set(key, key, 0).Err()).NotTo(HaveOccurred())
+			}
+
+			var all []string
+			var cursor uint64
+			for {
+				keys, next, err := client.Scan(cursor, "", 2).Result()
+				Expect(err).NotTo(HaveOccurred())
+				all = append(all, keys...)
+				if next == 0 {
+					break
+				}
+				cursor = next
+			}
+			Expect(all).To(Equal([]string{"a", "b", "c", "d", "e"}))
+		})
+
+		It("should expire keys", func() {
+			Expect(client.Set("key", "value", 10*time.Second).Err()).NotTo(HaveOccurred())
+			Expect(client.TTL("key").Val()).To(Equal(10 * time.Second))
+			Expect(client.PTTL("key").Val()).To(BeNumerically("~", 10*time.Second, 100*time.Millisecond))
+
+			srv.FastForward(5 * time.Second)
+			Expect(client.TTL("key").Val()).To(Equal(5 * time.Second))
+
+			srv.FastForward(5 * time.Second)
+			Expect(client.Get("key").Err()).To(Equal(redis.Nil))
+			Expect(client.TTL("key").Val()).To(Equal(-2 * time.Second))
+		})
+
+		It("should set and remove TTLs", func() {
+			Expect(client.Set("key", "value", 0).Err()).NotTo(HaveOccurred())
+			Expect(client.TTL("key").Val()).To(Equal(-1 * time.Second))
+
+			Expect(client.Expire("key", time.Minute).Val()).To(BeTrue())
+			Expect(client.Persist("key").Val()).To(BeTrue())
+			srv.FastForward(time.Hour)
+			Expect(client.Exists("key").Val()).To(BeTrue())
+
+			Expect(client.ExpireAt("key", srv.Now().Add(time.Minute)).Val()).To(BeTrue())
+			srv.FastForward(time.Minute)
+			Expect(client.Exists("key").Val()).To(BeFalse())
+			Expect(client.Expire("key", time.Minute).Val()).To(BeFalse())
+		})
+
+		It("should move the server time", func() {
+			before := client.Time().Val()
+			srv.FastForward(time.Hour)
+			Expect(client.Time().Val()).To(BeTemporally("~", before.Add(time.Hour), time.Second))
+		})
+	})
+
+	Describe("strings", func() {
+		It("should set and get", func() {
+			Expect(client.Get("key").Err()).To(Equal(redis.Nil))
+			Expect(client.Set("key", "hello", 0).Val()).To(Equal("OK"))
+			Expect(client.Get("key").Val()).To(Equal("hello"))
+			Expect(client.GetSet("key", "world").Val()).To(Equal("hello"))
+			Expect(client.Append("key", "!").Val()).To(Equal(int64(6)))
+			Expect(client.StrLen("key").Val()).To(Equal(int64(6)))
+			Expect(client.GetRange("key", 0, -2).Val()).To(Equal("world"))
+			Expect(client.MGet("key", "missing").Val()).To(Equal([]interface{}{"world!", nil}))
+		})
+
+		It("should set if not exists or exists", func() {
+			Expect(client.SetNX("key", "1", 0).Val()).To(BeTrue())
+			Expect(client.SetNX("key", "2", time.Minute).Val()).To(BeFalse())
+			Expect(client.SetXX("key", "3", time.Minute).Val()).To(BeTrue())
+			Expect(client.SetXX("missing", "3", 0).Val()).To(BeFalse())
+			Expect(client.Get("key").Val()).To(Equal("3"))
+			Expect(client.TTL("key").Val()).To(Equal(time.Minute))
+
+			Expect(client.MSetNX("key", "4", "other", "5").Val()).To(BeFalse())
+			Expect(client.Exists("other").Val()).To(BeFalse())
+		})
+
+		It("should increment", func() {
+			Expect(client.Incr("n").Val()).To(Equal(int64(1)))
+			Expect(client.IncrBy("n", 10).Val()).To(Equal(int64(11)))
+			Expect(client.DecrBy("n", 5).Val()).To(Equal(int64(6)))
+			Expect(client.Decr("n").Val()).To(Equal(int64(5)))
+			Expect(client.IncrByFloat("n", 0.5).Val()).To(Equal(5.5))
+			Expect(client.Incr("n").Err()).To(MatchError("ERR value is not an integer or out of range"))
+		})
+
+		It("should keep the TTL when incrementing", func() {
+			Expect(client.Set("n", "1", time.Minute).Err()).NotTo(HaveOccurred())
+			Expect(client.Incr("n").Val()).To(Equal(int64(2)))
+			Expect(client.TTL("n").Val()).To(Equal(time.Minute))
+		})
+	})
+
+	Describe("hashes", func() {
+		It("should set and get fields", func() {
+			Expect(client.HSet("hash", "a", "1").Val()).To(BeTrue())
+			Expect(client.HSet("hash", "a", "2").Val()).To(BeFalse())
+			Expect(client.HSetNX("hash", "a", "3").Val()).To(BeFalse())
+			Expect(client.HMSet("hash", map[string]string{"b": "2", "c": "3"}).Err()).NotTo(HaveOccurred())
+
+			Expect(client.HGet("hash", "a").Val()).To(Equal("2"))
+			Expect(client.HGet("hash", "d").Err()).To(Equal(redis.Nil))
+			Expect(client.HMGet("hash", "a", "d").Val()).To(Equal([]interface{}{"2", nil}))
+			Expect(client.HGetAll("hash").Val()).To(Equal(map[string]string{"a": "2", "b": "2", "c": "3"}))
+			Expect(client.HKeys("hash").Val()).To(Equal([]string{"a", "b", "c"}))
+			Expect(client.HVals("hash").Val()).To(Equal([]string{"2", "2", "3"}))
+			Expect(client.HLen("hash").Val()).To(Equal(int64(3)))
+			Expect(client.HExists("hash", "b").Val()).To(BeTrue())
+		})
+
+		It("should increment fields", func() {
+			Expect(client.HIncrBy("hash", "n", 2).Val()).To(Equal(int64(2)))
+			Expect(client.HIncrByFloat("hash", "n", 0.5).Val()).To(Equal(2.5))
+			Expect(client.HIncrBy("hash", "n", 1).Err()).To(MatchError("ERR hash value is not an integer"))
+		})
+
+		It("should delete empty hashes", func() {
+			Expect(client.HSet("hash", "a", "1").Err()).NotTo(HaveOccurred())
+			Expect(client.HDel("hash", "a", "b").Val()).To(Equal(int64(1)))
+			Expect(client.Exists("hash").Val()).To(BeFalse())
+		})
+	})
+
+	Describe("lists", func() {
+		It("should push and pop", func() {
+			Expect(client.RPush("list", "b", "c").Val()).To(Equal(int64(2)))
+			Expect(client.LPush("list", "a").Val()).To(Equal(int64(3)))
+			Expect(client.LPushX("missing", "a").Val()).To(Equal(int64(0)))
+			Expect(client.LRange("list", 0, -1).Val()).To(Equal([]string{"a", "b", "c"}))
+			Expect(client.LLen("list").Val()).To(Equal(int64(3)))
+
+			Expect(client.LPop("list").Val()).To(Equal("a"))
+			Expect(client.RPop("list").Val()).To(Equal("c"))
+			Expect(client.RPopLPush("list", "other").Val()).To(Equal("b"))
+			Expect(client.Exists("list").Val()).To(BeFalse())
+			Expect(client.LPop("list").Err()).To(Equal(redis.Nil))
+			Expect(client.LRange("other", 0, -1).Val()).To(Equal([]string{"b"}))
+		})
+
+		It("should index and modify", func() {
+			Expect(client.RPush("list", "a", "b", "a", "c", "a").Err()).NotTo(HaveOccurred())
+			Expect(client.LIndex("list", -1).Val()).To(Equal("a"))
+			Expect(client.LIndex("list", 10).Err()).To(Equal(redis.Nil))
+			Expect(client.LSet("list", 1, "B").Err()).NotTo(HaveOccurred())
+			Expect(client.LSet("list", 10, "B").Err()).To(MatchError("ERR index out of range"))
+
+			Expect(client.LRem("list", -2, "a").Val()).To(Equal(int64(2)))
+			Expect(client.LRange("list", 0, -1).Val()).To(Equal([]string{"a", "B", "c"}))
+			Expect(client.LInsert("list", "after", "B", "b").Val()).To(Equal(int64(4)))
+			Expect(client.LInsert("list", "before", "x", "b").Val()).To(Equal(int64(-1)))
+			Expect(client.LTrim("list", 1, 2).Err()).NotTo(HaveOccurred())
+			Expect(client.LRange("list", 0, -1).Val()).To(Equal([]string{"B", "b"}))
+		})
+	})
+
+	Describe("sets", func() {
+		It("should add and remove members", func() {
+			Expect(client.SAdd("set", "a", "b", "a").Val()).To(Equal(int64(2)))
+			Expect(client.SIsMember("set", "a").Val()).To(BeTrue())
+			Expect(client.SCard("set").Val()).To(Equal(int64(2)))
+			Expect(client.SMembers("set").Val()).To(ConsistOf("a", "b"))
+			Expect(client.SRem("set", "a", "c").Val()).To(Equal(int64(1)))
+			Expect(client.SMove("set", "other", "b").Val()).To(BeTrue())
+			Expect(client.Exists("set").Val()).To(BeFalse())
+			Expect(client.SPop("other").Val()).To(Equal("b"))
+			Expect(client.SPop("other").Err()).To(Equal(redis.Nil))
+		})
+
+		It("should combine sets", func() {
+			Expect(client.SAdd("s1", "a", "b", "c").Err()).NotTo(HaveOccurred())
+			Expect(client.SAdd("s2", "b", "c", "d").Err()).NotTo(HaveOccurred())
+			Expect(client.SInter("s1", "s2").Val()).To(ConsistOf("b", "c"))
+			Expect(client.SUnion("s1", "s2").Val()).To(ConsistOf("a", "b", "c", "d"))
+			Expect(client.SDiff("s1", "s2").Val()).To(ConsistOf("a"))
+			Expect(client.SInterStore("s3", "s1", "s2").Val()).To(Equal(int64(2)))
+			Expect(client.SMembers("s3").Val()).To(ConsistOf("b", "c"))
+			Expect(client.SRandMemberN("s3", -5).Val()).To(HaveLen(5))
+		})
+	})
+
+	Describe("sorted sets", func() {
+		BeforeEach(func() {
+			n, err := client.ZAdd("zset",
+				redis.Z{Score: 1, Member: "one"},
+				redis.Z{Score: 2, Member: "two"},
+				redis.Z{Score: 3, Member: "three"},
+			).Result()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(n).To(Equal(int64(3)))
+		})
+
+		It("should add members", func() {
+			Expect(client.ZAddNX("zset", redis.Z{Score: 5, Member: "one"}).Val()).To(Equal(int64(0)))
+			Expect(client.ZAddXXCh("zset", redis.Z{Score: 5, Member: "one"}).Val()).To(Equal(int64(1)))
+			Expect(client.ZIncr("zset", redis.Z{Score: 1, Member: "one"}).Val()).To(Equal(float64(6)))
+			Expect(client.ZIncrBy("zset", 0.5, "two").Val()).To(Equal(2.5))
+			Expect(client.ZScore("zset", "one").Val()).To(Equal(float64(6)))
+			Expect(client.ZCard("zset").Val()).To(Equal(int64(3)))
+		})
+
+		It("should range by rank and score", func() {
+			Expect(client.ZRange("zset", 0, -1).Val()).To(Equal([]string{"one", "two", "three"}))
+			Expect(client.ZRevRange("zset", 0, 0).Val()).To(Equal([]string{"three"}))
+			Expect(client.ZRangeWithScores("zset", 0, 0).Val()).To(Equal([]redis.Z{{Score: 1, Member: "one"}}))
+
+			Expect(client.ZRangeByScore("zset", redis.ZRangeBy{Min: "(1", Max: "+inf"}).Val()).To(Equal([]string{"two", "three"}))
+			Expect(client.ZRevRangeByScore("zset", redis.ZRangeBy{Min: "-inf", Max: "3", Offset: 1, Count: 1}).Val()).To(Equal([]string{"two"}))
+			Expect(client.ZCount("zset", "2", "3").Val()).To(Equal(int64(2)))
+			Expect(client.ZRank("zset", "two").Val()).To(Equal(int64(1)))
+			Expect(client.ZRevRank("zset", "two").Val()).To(Equal(int64(1)))
+			Expect(client.ZRank("zset", "four").Err()).To(Equal(redis.Nil))
+		})
+
+		It("should remove members", func() {
+			Expect(client.ZRem("zset", "one", "four").Val()).To(Equal(int64(1)))
+			Expect(client.ZRemRangeByScore("zset", "3", "3").Val()).To(Equal(int64(1)))
+			Expect(client.ZRemRangeByRank("zset", 0, -1).Val()).To(Equal(int64(1)))
+			Expect(client.Exists("zset").Val()).To(BeFalse())
+		})
+	})
+
+	Describe("transactions", func() {
+		It("should run transactions", func() {
+			pipe := client.TxPipeline()
+			incr := pipe.Incr("n")
+			get := pipe.Get("n")
+			_, err := pipe.Exec()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(incr.Val()).To(Equal(int64(1)))
+			Expect(get.Val()).To(Equal("1"))
+		})
+
+		It("should fail when a watched key is modified", func() {
+			err := client.Watch(func(tx *redis.Tx) error {
+				Expect(client.Set("key", "other", 0).Err()).NotTo(HaveOccurred())
+				_, err := tx.Pipelined(func(pipe *redis.Pipeline) error {
+					pipe.Set("key", "value", 0)
+					return nil
+				})
+				return err
+			}, "key")
+			Expect(err).To(Equal(redis.TxFailedErr))
+			Expect(client.Get("key").Val()).To(Equal("other"))
+
+			err = client.Watch(func(tx *redis.Tx) error {
+				_, err := tx.Pipelined(func(pipe *redis.Pipeline) error {
+					pipe.Set("key", "value", 0)
+					return nil
+				})
+				return err
+			}, "key")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(client.Get("key").Val()).To(Equal("value"))
+		})
+
+		It("should abort transactions with invalid commands", func() {
+			pipe := client.TxPipeline()
+			pipe.Set("key", "value", 0)
+			pipe.Process(redis.NewCmd("unknown"))
+			_, err := pipe.Exec()
+			Expect(err).To(MatchError("EXECABORT Transaction discarded because of previous errors."))
+			Expect(client.Exists("key").Val()).To(BeFalse())
+		})
+	})
+
+	Describe("pub/sub", func() {
+		It("should publish messages", func() {
+			pubsub, err := client.Subscribe("news")
+			Expect(err).NotTo(HaveOccurred())
+			defer pubsub.Close()
+			Expect(pubsub.PSubscribe("sport.*")).NotTo(HaveOccurred())
+
+			msgi, err := pubsub.ReceiveTimeout(time.Second)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(msgi).To(Equal(&redis.Subscription{Kind: "subscribe", Channel: "news", Count: 1}))
+			msgi, err = pubsub.ReceiveTimeout(time.Second)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(msgi).To(Equal(&redis.Subscription{Kind: "psubscribe", Channel: "sport.*", Count: 2}))
+
+			Expect(client.PubSubChannels("*").Val()).To(Equal([]string{"news"}))
+			Expect(client.PubSubNumSub("news").Val()).To(Equal(map[string]int64{"news": 1}))
+			Expect(client.PubSubNumPat().Val()).To(Equal(int64(1)))
+
+			Expect(client.Publish("news", "hello").Val()).To(Equal(int64(1)))
+			Expect(client.Publish("sport.tennis", "ace").Val()).To(Equal(int64(1)))
+			Expect(client.Publish("weather", "rain").Val()).To(Equal(int64(0)))
+
+			msg, err := pubsub.ReceiveMessage()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(msg).To(Equal(&redis.Message{Channel: "news", Payload: "hello"}))
+			msg, err = pubsub.ReceiveMessage()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(msg).To(Equal(&redis.Message{Pattern: "sport.*", Channel: "sport.tennis", Payload: "ace"}))
+
+			Expect(pubsub.Ping("hi")).NotTo(HaveOccurred())
+			msgi, err = pubsub.ReceiveTimeout(time.Second)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(msgi).To(Equal(&redis.Pong{Payload: "hi"}))
+
+			Expect(pubsub.Unsubscribe()).NotTo(HaveOccurred())
+			msgi, err = pubsub.ReceiveTimeout(time.Second)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(msgi).To(Equal(&redis.Subscription{Kind: "unsubscribe", Channel: "news", Count: 1}))
+		})
+
+		It("should not block on subscribers which don't read", func() {
+			pubsub, err := client.Subscribe("news")
+			Expect(err).NotTo(HaveOccurred())
+			defer pubsub.Close()
+
+			payload := strings.Repeat("x", 1<<20)
+			done := make(chan struct{})
+			go func() {
+				defer GinkgoRecover()
+				for i := 0; i < 16; i++ {
+					Expect(client.Publish("news", payload).Val()).To(Equal(int64(1)))
+				}
+				close(done)
+			}()
+			Eventually(done, 5*time.Second).Should(BeClosed())
+			Expect(client.Ping().Val()).To(Equal("PONG"))
+		})
+	})
+
+	Describe("scripting", func() {
+		const src = `return redis.call("incrby", KEYS[1], ARGV[1])`
+
+		BeforeEach(func() {
+			srv.HandleScript(src, func(call redistest.CallFunc, keys, args []string) interface{} {
+				v, err := call("incrby", keys[0], args[0])
+				if err != nil {
+					return err
+				}
+				return v
+			})
+		})
+
+		It("should run registered scripts", func() {
+			script := redis.NewScript(src)
+			hash := fmt.Sprintf("%x", sha1.Sum([]byte(src)))
+			Expect(client.ScriptExists(hash).Val()).To(Equal([]bool{false}))
+			Expect(script.Run(client, []string{"n"}, 2).Val()).To(Equal(int64(2)))
+			Expect(script.EvalSha(client, []string{"n"}, 3).Val()).To(Equal(int64(5)))
+			Expect(client.ScriptExists(hash).Val()).To(Equal([]bool{true}))
+
+			Expect(client.ScriptFlush().Err()).NotTo(HaveOccurred())
+			err := script.EvalSha(client, []string{"n"}, 3).Err()
+			Expect(err).To(MatchError("NOSCRIPT No matching script. Please use EVAL."))
+			Expect(script.Load(client).Val()).To(Equal(hash))
+
+			err = script.Run(client, []string{"n"}, "x").Err()
+			Expect(err).To(MatchError("ERR value is not an integer or out of range"))
+		})
+
+		It("should not run unregistered scripts", func() {
+			err := client.Eval("return 1", nil).Err()
+			Expect(err).To(HaveOccurred())
+		})
+	})
+})
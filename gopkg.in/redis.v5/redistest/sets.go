@@ -0,0 +1,259 @@
+package redistest
+
+import (
+	"math/rand"
+	"sort"
+)
+
+func (s set) members() []string {
+	members := make([]string, 0, len(s))
+	for m := range s {
+		members = append(members, m)
+	}
+	sort.Strings(members)
+	return members
+}
+
+func sadd(c *conn, args []string) interface{} {
+	s, err := c.getSet(args[0], true)
+	if err != nil {
+		return err
+	}
+	var n int
+	for _, m := range args[1:] {
+		if _, ok := s[m]; !ok {
+			s[m] = struct{}{}
+			n++
+		}
+	}
+	c.modified(args[0])
+	return n
+}
+
+func srem(c *conn, args []string) interface{} {
+	s, err := c.getSet(args[0], false)
+	if err != nil {
+		return err
+	}
+	var n int
+	for _, m := range args[1:] {
+		if _, ok := s[m]; ok {
+			delete(s, m)
+			n++
+		}
+	}
+	if n > 0 {
+		c.modified(args[0])
+	}
+	return n
+}
+
+func smembers(c *conn, args []string) interface{} {
+	s, err := c.getSet(args[0], false)
+	if err != nil {
+		return err
+	}
+	return s.members()
+}
+
+func sismember(c *conn, args []string) interface{} {
+	s, err := c.getSet(args[0], false)
+	if err != nil {
+		return err
+	}
+	_, ok := s[args[1]]
+	return ok
+}
+
+func scard(c *conn, args []string) interface{} {
+	s, err := c.getSet(args[0], false)
+	if err != nil {
+		return err
+	}
+	return len(s)
+}
+
+// spop implements SPOP key [count].
+func spop(c *conn, args []string) interface{} {
+	if len(args) > 2 {
+		return errSyntax
+	}
+	count := int64(1)
+	if len(args) == 2 {
+		n, err := parseInt(args[1])
+		if err != nil {
+			return err
+		}
+		if n < 0 {
+			return errOutOfRange
+		}
+		count = n
+	}
+	s, err := c.getSet(args[0], false)
+	if err != nil {
+		return err
+	}
+
+	members := s.members()
+	rand.Shuffle(len(members), func(i, j int) {
+		members[i], members[j] = members[j], members[i]
+	})
+	if int64(len(members)) > count {
+		members = members[:count]
+	}
+	for _, m := range members {
+		delete(s, m)
+	}
+	if len(members) > 0 {
+		c.modified(args[0])
+	}
+
+	if len(args) == 2 {
+		return members
+	}
+	if len(members) == 0 {
+		return nil
+	}
+	return members[0]
+}
+
+// srandMember implements SRANDMEMBER key [count], where a negative count
+// allows the same member to be returned several times.
+func srandMember(c *conn, args []string) interface{} {
+	if len(args) > 2 {
+		return errSyntax
+	}
+	count := int64(1)
+	if len(args) == 2 {
+		n, err := parseInt(args[1])
+		if err != nil {
+			return err
+		}
+		count = n
+	}
+	s, err := c.getSet(args[0], false)
+	if err != nil {
+		return err
+	}
+
+	members := s.members()
+	var picked []string
+	if count < 0 {
+		picked = make([]string, 0, -count)
+		for i := int64(0); i < -count && len(members) > 0; i++ {
+			picked = append(picked, members[rand.Intn(len(members))])
+		}
+	} else {
+		rand.Shuffle(len(members), func(i, j int) {
+			members[i], members[j] = members[j], members[i]
+		})
+		if int64(len(members)) > count {
+			members = members[:count]
+		}
+		picked = members
+	}
+
+	if len(args) == 2 {
+		return picked
+	}
+	if len(picked) == 0 {
+		return nil
+	}
+	return picked[0]
+}
+
+func smove(c *conn, args []string) interface{} {
+	src, err := c.getSet(args[0], false)
+	if err != nil {
+		return err
+	}
+	if _, err := c.getSet(args[1], false); err != nil {
+		return err
+	}
+	if _, ok := src[args[2]]; !ok {
+		return 0
+	}
+	delete(src, args[2])
+	c.modified(args[0])
+	dst, _ := c.getSet(args[1], true)
+	dst[args[2]] = struct{}{}
+	c.modified(args[1])
+	return 1
+}
+
+func setInter(sets []set) set {
+	res := make(set)
+	if len(sets) == 0 {
+		return res
+	}
+	for m := range sets[0] {
+		in := true
+		for _, s := range sets[1:] {
+			if _, ok := s[m]; !ok {
+				in = false
+				break
+			}
+		}
+		if in {
+			res[m] = struct{}{}
+		}
+	}
+	return res
+}
+
+func setUnion(sets []set) set {
+	res := make(set)
+	for _, s := range sets {
+		for m := range s {
+			res[m] = struct{}{}
+		}
+	}
+	return res
+}
+
+func setDiff(sets []set) set {
+	res := make(set)
+	if len(sets) == 0 {
+		return res
+	}
+	for m := range sets[0] {
+		res[m] = struct{}{}
+	}
+	for _, s := range sets[1:] {
+		for m := range s {
+			delete(res, m)
+		}
+	}
+	return res
+}
+
+// setOp implements SINTER, SUNION, SDIFF and their STORE variants, which
+// take the destination key first.
+func setOp(op func([]set) set, store bool) func(*conn, []string) interface{} {
+	return func(c *conn, args []string) interface{} {
+		keys := args
+		if store {
+			keys = args[1:]
+		}
+		sets := make([]set, len(keys))
+		for i, key := range keys {
+			s, err := c.getSet(key, false)
+			if err != nil {
+				return err
+			}
+			sets[i] = s
+		}
+
+		res := op(sets)
+		if !store {
+			return res.members()
+		}
+		d := c.selected()
+		if len(res) == 0 {
+			d.del(args[0])
+		} else {
+			d.set(args[0], res)
+		}
+		return len(res)
+	}
+}
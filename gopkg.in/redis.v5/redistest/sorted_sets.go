@@ -0,0 +1,391 @@
+package redistest
+
+import (
+	"errors"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+type zmember struct {
+	member string
+	score  float64
+}
+
+type zmembers []zmember
+
+func (z zmembers) Len() int      { return len(z) }
+func (z zmembers) Swap(i, j int) { z[i], z[j] = z[j], z[i] }
+func (z zmembers) Less(i, j int) bool {
+	if z[i].score != z[j].score {
+		return z[i].score < z[j].score
+	}
+	return z[i].member < z[j].member
+}
+
+// sorted returns the members ordered by score, then lexicographically.
+func (z zset) sorted(rev bool) []zmember {
+	members := make([]zmember, 0, len(z))
+	for m, score := range z {
+		members = append(members, zmember{m, score})
+	}
+	if rev {
+		sort.Sort(sort.Reverse(zmembers(members)))
+	} else {
+		sort.Sort(zmembers(members))
+	}
+	return members
+}
+
+func zreply(members []zmember, withScores bool) []string {
+	vals := make([]string, 0, len(members))
+	for _, m := range members {
+		vals = append(vals, m.member)
+		if withScores {
+			vals = append(vals, formatFloat(m.score))
+		}
+	}
+	return vals
+}
+
+// zadd implements ZADD key [NX|XX] [CH] [INCR] score member [score member ...].
+func zadd(c *conn, args []string) interface{} {
+	var nx, xx, ch, incr bool
+	i := 1
+flags:
+	for ; i < len(args); i++ {
+		switch strings.ToLower(args[i]) {
+		case "nx":
+			nx = true
+		case "xx":
+			xx = true
+		case "ch":
+			ch = true
+		case "incr":
+			incr = true
+		default:
+			break flags
+		}
+	}
+	pairs := args[i:]
+	if len(pairs) == 0 || len(pairs)%2 != 0 {
+		return errSyntax
+	}
+	if nx && xx {
+		return errors.New("ERR XX and NX options at the same time are not compatible")
+	}
+	if incr && len(pairs) != 2 {
+		return errors.New("ERR INCR option supports a single increment-element pair")
+	}
+	scores := make([]float64, len(pairs)/2)
+	for j := range scores {
+		score, err := parseFloat(pairs[2*j])
+		if err != nil {
+			return err
+		}
+		scores[j] = score
+	}
+
+	z, err := c.getZSet(args[0], false)
+	if err != nil {
+		return err
+	}
+	if z == nil {
+		if xx {
+			if incr {
+				return nil
+			}
+			return 0
+		}
+		z, _ = c.getZSet(args[0], true)
+	}
+	defer c.modified(args[0])
+
+	if incr {
+		member := pairs[1]
+		old, ok := z[member]
+		if (nx && ok) || (xx && !ok) {
+			return nil
+		}
+		score := old + scores[0]
+		if math.IsNaN(score) {
+			return errors.New("ERR resulting score is not a number (NaN)")
+		}
+		z[member] = score
+		return score
+	}
+
+	var added, changed int
+	for j, score := range scores {
+		member := pairs[2*j+1]
+		old, ok := z[member]
+		if (nx && ok) || (xx && !ok) {
+			continue
+		}
+		if !ok {
+			added++
+		} else if old != score {
+			changed++
+		}
+		z[member] = score
+	}
+	if ch {
+		return added + changed
+	}
+	return added
+}
+
+func zincrBy(c *conn, args []string) interface{} {
+	return zadd(c, []string{args[0], "incr", args[1], args[2]})
+}
+
+func zscore(c *conn, args []string) interface{} {
+	z, err := c.getZSet(args[0], false)
+	if err != nil {
+		return err
+	}
+	score, ok := z[args[1]]
+	if !ok {
+		return nil
+	}
+	return score
+}
+
+func zrem(c *conn, args []string) interface{} {
+	z, err := c.getZSet(args[0], false)
+	if err != nil {
+		return err
+	}
+	var n int
+	for _, m := range args[1:] {
+		if _, ok := z[m]; ok {
+			delete(z, m)
+			n++
+		}
+	}
+	if n > 0 {
+		c.modified(args[0])
+	}
+	return n
+}
+
+func zcard(c *conn, args []string) interface{} {
+	z, err := c.getZSet(args[0], false)
+	if err != nil {
+		return err
+	}
+	return len(z)
+}
+
+// scoreBound is a bound of a score range, such as "1.5", "(1.5" or "-inf".
+type scoreBound struct {
+	score     float64
+	exclusive bool
+}
+
+var errNotFloatBound = errors.New("ERR min or max is not a float")
+
+func parseScoreBound(s string) (scoreBound, error) {
+	var b scoreBound
+	if strings.HasPrefix(s, "(") {
+		b.exclusive = true
+		s = s[1:]
+	}
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil || math.IsNaN(f) {
+		return b, errNotFloatBound
+	}
+	b.score = f
+	return b, nil
+}
+
+func (b scoreBound) above(score float64) bool {
+	if b.exclusive {
+		return score > b.score
+	}
+	return score >= b.score
+}
+
+func (b scoreBound) below(score float64) bool {
+	if b.exclusive {
+		return score < b.score
+	}
+	return score <= b.score
+}
+
+func (z zset) byScore(min, max scoreBound, rev bool) []zmember {
+	var members []zmember
+	for _, m := range z.sorted(rev) {
+		if min.above(m.score) && max.below(m.score) {
+			members = append(members, m)
+		}
+	}
+	return members
+}
+
+func zcount(c *conn, args []string) interface{} {
+	min, err := parseScoreBound(args[1])
+	if err != nil {
+		return err
+	}
+	max, err := parseScoreBound(args[2])
+	if err != nil {
+		return err
+	}
+	z, err := c.getZSet(args[0], false)
+	if err != nil {
+		return err
+	}
+	return len(z.byScore(min, max, false))
+}
+
+// zrange implements Z(REV)RANGE key start stop [WITHSCORES].
+func zrange(rev bool) func(*conn, []string) interface{} {
+	return func(c *conn, args []string) interface{} {
+		start, err := parseInt(args[1])
+		if err != nil {
+			return err
+		}
+		stop, err := parseInt(args[2])
+		if err != nil {
+			return err
+		}
+		var withScores bool
+		switch {
+		case len(args) == 4 && strings.ToLower(args[3]) == "withscores":
+			withScores = true
+		case len(args) > 3:
+			return errSyntax
+		}
+
+		z, err := c.getZSet(args[0], false)
+		if err != nil {
+			return err
+		}
+		members := z.sorted(rev)
+		i, j, ok := rangeIndexes(start, stop, len(members))
+		if !ok {
+			return []string{}
+		}
+		return zreply(members[i:j+1], withScores)
+	}
+}
+
+// zrangeByScore implements ZRANGEBYSCORE key min max and ZREVRANGEBYSCORE
+// key max min, with the options [WITHSCORES] [LIMIT offset count].
+func zrangeByScore(rev bool) func(*conn, []string) interface{} {
+	return func(c *conn, args []string) interface{} {
+		minArg, maxArg := args[1], args[2]
+		if rev {
+			minArg, maxArg = maxArg, minArg
+		}
+		min, err := parseScoreBound(minArg)
+		if err != nil {
+			return err
+		}
+		max, err := parseScoreBound(maxArg)
+		if err != nil {
+			return err
+		}
+
+		var withScores bool
+		offset, count := int64(0), int64(-1)
+		for i := 3; i < len(args); i++ {
+			switch strings.ToLower(args[i]) {
+			case "withscores":
+				withScores = true
+			case "limit":
+				if i+2 >= len(args) {
+					return errSyntax
+				}
+				if offset, err = parseInt(args[i+1]); err != nil {
+					return err
+				}
+				if count, err = parseInt(args[i+2]); err != nil {
+					return err
+				}
+				i += 2
+			default:
+				return errSyntax
+			}
+		}
+
+		z, err := c.getZSet(args[0], false)
+		if err != nil {
+			return err
+		}
+		members := z.byScore(min, max, rev)
+		if offset < 0 || offset >= int64(len(members)) {
+			return []string{}
+		}
+		members = members[offset:]
+		if count >= 0 && count < int64(len(members)) {
+			members = members[:count]
+		}
+		return zreply(members, withScores)
+	}
+}
+
+func zrank(rev bool) func(*conn, []string) interface{} {
+	return func(c *conn, args []string) interface{} {
+		z, err := c.getZSet(args[0], false)
+		if err != nil {
+			return err
+		}
+		for i, m := range z.sorted(rev) {
+			if m.member == args[1] {
+				return i
+			}
+		}
+		return nil
+	}
+}
+
+func zremRangeByRank(c *conn, args []string) interface{} {
+	start, err := parseInt(args[1])
+	if err != nil {
+		return err
+	}
+	stop, err := parseInt(args[2])
+	if err != nil {
+		return err
+	}
+	z, err := c.getZSet(args[0], false)
+	if err != nil {
+		return err
+	}
+	members := z.sorted(false)
+	i, j, ok := rangeIndexes(start, stop, len(members))
+	if !ok {
+		return 0
+	}
+	for _, m := range members[i : j+1] {
+		delete(z, m.member)
+	}
+	c.modified(args[0])
+	return j - i + 1
+}
+
+func zremRangeByScore(c *conn, args []string) interface{} {
+	min, err := parseScoreBound(args[1])
+	if err != nil {
+		return err
+	}
+	max, err := parseScoreBound(args[2])
+	if err != nil {
+		return err
+	}
+	z, err := c.getZSet(args[0], false)
+	if err != nil {
+		return err
+	}
+	members := z.byScore(min, max, false)
+	for _, m := range members {
+		delete(z, m.member)
+	}
+	if len(members) > 0 {
+		c.modified(args[0])
+	}
+	return len(members)
+}
@@ -0,0 +1,263 @@
+package redistest
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+func get(c *conn, args []string) interface{} {
+	s, ok, err := c.getString(args[0])
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return nil
+	}
+	return s
+}
+
+// setKey implements SET key value [EX seconds] [PX milliseconds] [NX|XX].
+func setKey(c *conn, args []string) interface{} {
+	var ttl time.Duration
+	var nx, xx bool
+	for i := 2; i < len(args); i++ {
+		switch opt := strings.ToLower(args[i]); opt {
+		case "nx":
+			nx = true
+		case "xx":
+			xx = true
+		case "ex", "px":
+			if i+1 == len(args) {
+				return errSyntax
+			}
+			i++
+			n, err := parseInt(args[i])
+			if err != nil {
+				return err
+			}
+			if n <= 0 {
+				return fmt.Errorf("ERR invalid expire time in set")
+			}
+			if opt == "ex" {
+				ttl = time.Duration(n) * time.Second
+			} else {
+				ttl = time.Duration(n) * time.Millisecond
+			}
+		default:
+			return errSyntax
+		}
+	}
+	if nx && xx {
+		return errSyntax
+	}
+
+	d := c.selected()
+	exists := d.get(args[0], c.now()) != nil
+	if (nx && exists) || (xx && !exists) {
+		return nil
+	}
+	e := d.set(args[0], args[1])
+	if ttl > 0 {
+		e.expireAt = c.now().Add(ttl)
+	}
+	return statusOK
+}
+
+func setNX(c *conn, args []string) interface{} {
+	if setKey(c, []string{args[0], args[1], "nx"}) == nil {
+		return 0
+	}
+	return 1
+}
+
+func setEx(unit time.Duration) func(*conn, []string) interface{} {
+	return func(c *conn, args []string) interface{} {
+		n, err := parseInt(args[1])
+		if err != nil {
+			return err
+		}
+		if n <= 0 {
+			return fmt.Errorf("ERR invalid expire time in %s", unitCommand(unit, "setex", "psetex"))
+		}
+		e := c.selected().set(args[0], args[2])
+		e.expireAt = c.now().Add(time.Duration(n) * unit)
+		return statusOK
+	}
+}
+
+func unitCommand(unit time.Duration, sec, ms string) string {
+	if unit == time.Second {
+		return sec
+	}
+	return ms
+}
+
+func getSet(c *conn, args []string) interface{} {
+	s, ok, err := c.getString(args[0])
+	if err != nil {
+		return err
+	}
+	c.selected().set(args[0], args[1])
+	if !ok {
+		return nil
+	}
+	return s
+}
+
+func mget(c *conn, args []string) interface{} {
+	vals := make([]interface{}, len(args))
+	for i, key := range args {
+		if s, ok, err := c.getString(key); ok && err == nil {
+			vals[i] = s
+		}
+	}
+	return vals
+}
+
+func mset(c *conn, args []string) interface{} {
+	if len(args)%2 != 0 {
+		return errArity("mset")
+	}
+	for i := 0; i < len(args); i += 2 {
+		c.selected().set(args[i], args[i+1])
+	}
+	return statusOK
+}
+
+func msetNX(c *conn, args []string) interface{} {
+	if len(args)%2 != 0 {
+		return errArity("msetnx")
+	}
+	for i := 0; i < len(args); i += 2 {
+		if c.selected().get(args[i], c.now()) != nil {
+			return 0
+		}
+	}
+	mset(c, args)
+	return 1
+}
+
+// setString sets a string value, keeping the TTL of the key.
+func (c *conn) setString(key, value string) {
+	d := c.selected()
+	if e := d.get(key, c.now()); e != nil {
+		e.value = value
+		d.touch(key)
+		return
+	}
+	d.set(key, value)
+}
+
+func incrBy(sign int64, withArg bool) func(*conn, []string) interface{} {
+	return func(c *conn, args []string) interface{} {
+		delta := sign
+		if withArg {
+			n, err := parseInt(args[1])
+			if err != nil {
+				return err
+			}
+			delta *= n
+		}
+
+		s, ok, err := c.getString(args[0])
+		if err != nil {
+			return err
+		}
+		var n int64
+		if ok {
+			n, err = parseInt(s)
+			if err != nil {
+				return err
+			}
+		}
+		if (delta > 0 && n+delta < n) || (delta < 0 && n+delta > n) {
+			return fmt.Errorf("ERR increment or decrement would overflow")
+		}
+		n += delta
+		c.setString(args[0], strconv.FormatInt(n, 10))
+		return n
+	}
+}
+
+func incrByFloat(c *conn, args []string) interface{} {
+	delta, err := parseFloat(args[1])
+	if err != nil {
+		return err
+	}
+	s, ok, err := c.getString(args[0])
+	if err != nil {
+		return err
+	}
+	var f float64
+	if ok {
+		f, err = parseFloat(s)
+		if err != nil {
+			return err
+		}
+	}
+	f += delta
+	c.setString(args[0], formatFloat(f))
+	return f
+}
+
+func appendString(c *conn, args []string) interface{} {
+	s, _, err := c.getString(args[0])
+	if err != nil {
+		return err
+	}
+	s += args[1]
+	c.setString(args[0], s)
+	return len(s)
+}
+
+func strlen(c *conn, args []string) interface{} {
+	s, _, err := c.getString(args[0])
+	if err != nil {
+		return err
+	}
+	return len(s)
+}
+
+func getRange(c *conn, args []string) interface{} {
+	start, err := parseInt(args[1])
+	if err != nil {
+		return err
+	}
+	end, err := parseInt(args[2])
+	if err != nil {
+		return err
+	}
+	s, _, err := c.getString(args[0])
+	if err != nil {
+		return err
+	}
+	i, j, ok := rangeIndexes(start, end, len(s))
+	if !ok {
+		return ""
+	}
+	return s[i : j+1]
+}
+
+// rangeIndexes converts the inclusive range start, end of a sequence of n
+// elements, where negative indexes count from the end, to valid indexes.  It
+// returns false if the range is empty.
+func rangeIndexes(start, end int64, n int) (int, int, bool) {
+	if start < 0 {
+		start += int64(n)
+	}
+	if end < 0 {
+		end += int64(n)
+	}
+	if start < 0 {
+		start = 0
+	}
+	if end >= int64(n) {
+		end = int64(n) - 1
+	}
+	if start > end || start >= int64(n) {
+		return 0, 0, false
+	}
+	return int(start), int(end), true
+}
@@ -0,0 +1,85 @@
+package redistest
+
+import "errors"
+
+func multi(c *conn, args []string) interface{} {
+	if c.multi {
+		return errors.New("ERR MULTI calls can not be nested")
+	}
+	c.multi = true
+	return statusOK
+}
+
+// exec runs the queued commands, unless one failed to be queued or a watched
+// key was modified.
+func exec(c *conn, args []string) interface{} {
+	if !c.multi {
+		return errors.New("ERR EXEC without MULTI")
+	}
+	queued, dirty, modified := c.queued, c.dirty, c.watchedModified()
+	c.discard()
+	if dirty {
+		return errors.New("EXECABORT Transaction discarded because of previous errors.")
+	}
+	if modified {
+		return nilArray{}
+	}
+
+	replies := make([]interface{}, len(queued))
+	for i, args := range queued {
+		replies[i] = c.dispatch(args)
+	}
+	return replies
+}
+
+func discard(c *conn, args []string) interface{} {
+	if !c.multi {
+		return errors.New("ERR DISCARD without MULTI")
+	}
+	c.discard()
+	return statusOK
+}
+
+// discard ends the transaction and unwatches all keys.
+func (c *conn) discard() {
+	c.multi = false
+	c.dirty = false
+	c.queued = nil
+	c.watched = nil
+}
+
+func watch(c *conn, args []string) interface{} {
+	if c.multi {
+		return errors.New("ERR WATCH inside MULTI is not allowed")
+	}
+	if c.watched == nil {
+		c.watched = make(map[watchKey]uint64)
+	}
+	d := c.selected()
+	for _, key := range args {
+		wk := watchKey{c.db, key}
+		if _, ok := c.watched[wk]; ok {
+			continue
+		}
+		// Expire the key first, so that it isn't seen as modified later.
+		d.get(key, c.now())
+		c.watched[wk] = d.versions[key]
+	}
+	return statusOK
+}
+
+func unwatch(c *conn, args []string) interface{} {
+	c.watched = nil
+	return statusOK
+}
+
+func (c *conn) watchedModified() bool {
+	for wk, version := range c.watched {
+		d := c.srv.db(wk.db)
+		d.get(wk.key, c.now())
+		if d.versions[wk.key] != version {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,297 @@
+package redis
+
+import (
+	"math"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"gopkg.in/redis.v5/internal"
+)
+
+type streamer interface {
+	XGroupCreateMkStream(stream, group, start string) *StatusCmd
+	XReadGroup(a *XReadGroupArgs) *XStreamSliceCmd
+	XAck(stream, group string, ids ...string) *IntCmd
+	XRange(stream, start, stop string) *XMessageSliceCmd
+	XPendingExt(a *XPendingExtArgs) *XPendingExtCmd
+	XClaim(a *XClaimArgs) *XMessageSliceCmd
+}
+
+var _ streamer = (*Client)(nil)
+var _ streamer = (*Ring)(nil)
+var _ streamer = (*ClusterClient)(nil)
+
+// StreamHandler processes a message read from a stream.  The message is
+// acknowledged if it returns nil, and delivered again once it's reclaimed
+// otherwise.
+type StreamHandler func(stream string, msg XMessage) error
+
+// StreamConsumerOptions configures a StreamConsumer.
+type StreamConsumerOptions struct {
+	// Streams read by the consumer.  With a cluster they must hash to the
+	// same slot.
+	Streams  []string
+	Group    string
+	Consumer string
+
+	// Creates the group, and the streams, if they don't exist, reading the
+	// messages added from then on.
+	CreateGroup bool
+
+	// Maximum number of messages read at once.
+	// Default is 10.
+	Count int64
+	// How long XREADGROUP blocks waiting for messages, which bounds the time
+	// Run takes to return after Close.
+	// Default is 5 seconds.
+	Block time.Duration
+
+	// Messages pending for longer than ClaimIdle, whether their consumer
+	// failed to process them or died, are claimed and processed again.
+	// Default is 1 minute.  Set it to a negative value to disable claiming.
+	ClaimIdle time.Duration
+	// How often pending messages are checked.
+	// Default is ClaimIdle.
+	ClaimInterval time.Duration
+	// Messages which failed to be processed after MaxDeliveries deliveries
+	// are acknowledged and dropped.
+	// Default is 0, which delivers messages until they are processed.
+	MaxDeliveries int64
+
+	// How long to wait before reading again after an error.
+	// Default is 1 second.
+	RetryBackoff time.Duration
+}
+
+func (opt *StreamConsumerOptions) init() {
+	if opt.Count == 0 {
+		opt.Count = 10
+	}
+	if opt.Block == 0 {
+		opt.Block = 5 * time.Second
+	}
+	if opt.ClaimIdle == 0 {
+		opt.ClaimIdle = time.Minute
+	}
+	if opt.ClaimInterval == 0 {
+		opt.ClaimInterval = opt.ClaimIdle
+	}
+	if opt.RetryBackoff == 0 {
+		opt.RetryBackoff = time.Second
+	}
+}
+
+// StreamConsumer is a member of a consumer group which reads messages from
+// streams, passes them to a handler and acknowledges them once processed.
+// It also claims the messages left pending by failed or dead consumers.
+type StreamConsumer struct {
+	client  streamer
+	opt     *StreamConsumerOptions
+	handler StreamHandler
+
+	closed    int32 // atomic
+	lastClaim time.Time
+}
+
+// NewStreamConsumer returns a consumer reading the streams with the given
+// client, which is one of *Client, *Ring and *ClusterClient.
+func NewStreamConsumer(client streamer, opt *StreamConsumerOptions, handler StreamHandler) *StreamConsumer {
+	opt.init()
+	return &StreamConsumer{
+		client:  client,
+		opt:     opt,
+		handler: handler,
+	}
+}
+
+// Run reads and processes messages until the consumer is closed.  It
+// returns an error if the group can't be created.
+func (c *StreamConsumer) Run() error {
+	if c.opt.CreateGroup {
+		for _, stream := range c.opt.Streams {
+			err := c.client.XGroupCreateMkStream(stream, c.opt.Group, "$").Err()
+			if err != nil && !strings.HasPrefix(err.Error(), "BUSYGROUP") {
+				return err
+			}
+		}
+	}
+
+	streams := make([]string, 2*len(c.opt.Streams))
+	copy(streams, c.opt.Streams)
+	for i := range c.opt.Streams {
+		streams[len(c.opt.Streams)+i] = ">"
+	}
+
+	for !c.isClosed() {
+		if c.opt.ClaimIdle > 0 && time.Since(c.lastClaim) >= c.opt.ClaimInterval {
+			c.lastClaim = time.Now()
+			for _, stream := range c.opt.Streams {
+				if err := c.claim(stream); err != nil {
+					internal.Logf("redis: claiming messages of %s failed: %s", stream, err)
+				}
+			}
+		}
+
+		res, err := c.client.XReadGroup(&XReadGroupArgs{
+			Group:    c.opt.Group,
+			Consumer: c.opt.Consumer,
+			Streams:  streams,
+			Count:    c.opt.Count,
+			Block:    c.opt.Block,
+		}).Result()
+		if err == Nil {
+			continue
+		} else if err != nil {
+			if c.isClosed() {
+				break
+			}
+			internal.Logf("redis: XREADGROUP failed: %s", err)
+			time.Sleep(c.opt.RetryBackoff)
+			continue
+		}
+
+		for _, s := range res {
+			for _, msg := range s.Messages {
+				c.process(s.Stream, msg)
+			}
+		}
+	}
+	return nil
+}
+
+// claim processes the messages of the stream pending for longer than
+// ClaimIdle.  The pending entries are paged through, so that messages still
+// being processed by other consumers don't hide the idle ones behind them.
+func (c *StreamConsumer) claim(stream string) error {
+	start := "-"
+	for !c.isClosed() {
+		pending, err := c.client.XPendingExt(&XPendingExtArgs{
+			Stream: stream,
+			Group:  c.opt.Group,
+			Start:  start,
+			End:    "+",
+			Count:  c.opt.Count,
+		}).Result()
+		if err != nil {
+			return err
+		}
+		if len(pending) == 0 {
+			break
+		}
+		if err := c.claimPending(stream, pending); err != nil {
+			return err
+		}
+		if int64(len(pending)) < c.opt.Count {
+			break
+		}
+		start = nextStreamID(pending[len(pending)-1].ID)
+	}
+	return nil
+}
+
+// claimPending processes the pending messages idle for longer than
+// ClaimIdle.
+func (c *StreamConsumer) claimPending(stream string, pending []XPendingExt) error {
+	var ids, drop []string
+	for _, p := range pending {
+		if p.Idle < c.opt.ClaimIdle {
+			continue
+		}
+		if c.opt.MaxDeliveries > 0 && p.RetryCount >= c.opt.MaxDeliveries {
+			internal.Logf("redis: dropping message %s of %s delivered %d times", p.ID, stream, p.RetryCount)
+			drop = append(drop, p.ID)
+		} else {
+			ids = append(ids, p.ID)
+		}
+	}
+	if len(drop) > 0 {
+		if err := c.client.XAck(stream, c.opt.Group, drop...).Err(); err != nil {
+			return err
+		}
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+
+	msgs, err := c.client.XClaim(&XClaimArgs{
+		Stream:   stream,
+		Group:    c.opt.Group,
+		Consumer: c.opt.Consumer,
+		MinIdle:  c.opt.ClaimIdle,
+		Messages: ids,
+	}).Result()
+	if err != nil {
+		return err
+	}
+	claimed := make(map[string]bool, len(msgs))
+	for _, msg := range msgs {
+		claimed[msg.ID] = true
+		c.process(stream, msg)
+	}
+
+	// XCLAIM returns nil for messages deleted from the stream, which would
+	// stay pending forever, so they are acknowledged.  The other missing
+	// messages were claimed by another consumer in the meantime.
+	for _, id := range ids {
+		if claimed[id] {
+			continue
+		}
+		msgs, err := c.client.XRange(stream, id, id).Result()
+		if err != nil {
+			return err
+		}
+		if len(msgs) == 0 {
+			c.process(stream, XMessage{ID: id})
+		}
+	}
+	return nil
+}
+
+// nextStreamID returns the smallest stream ID greater than id, since
+// XPENDING ranges are inclusive.
+func nextStreamID(id string) string {
+	i := strings.IndexByte(id, '-')
+	if i < 0 {
+		return id
+	}
+	ms, err := strconv.ParseUint(id[:i], 10, 64)
+	if err != nil {
+		return id
+	}
+	seq, err := strconv.ParseUint(id[i+1:], 10, 64)
+	if err != nil {
+		return id
+	}
+	if seq == math.MaxUint64 {
+		ms, seq = ms+1, 0
+	} else {
+		seq++
+	}
+	return strconv.FormatUint(ms, 10) + "-" + strconv.FormatUint(seq, 10)
+}
+
+func (c *StreamConsumer) process(stream string, msg XMessage) {
+	// Messages deleted from the stream are only acknowledged.
+	if msg.Values != nil {
+		if err := c.handler(stream, msg); err != nil {
+			internal.Logf("redis: processing message %s of %s failed: %s", msg.ID, stream, err)
+			return
+		}
+	}
+	if err := c.client.XAck(stream, c.opt.Group, msg.ID).Err(); err != nil {
+		internal.Logf("redis: XACK %s of %s failed: %s", msg.ID, stream, err)
+	}
+}
+
+func (c *StreamConsumer) isClosed() bool {
+	return atomic.LoadInt32(&c.closed) == 1
+}
+
+// Close stops the consumer.  Run returns once it finished processing the
+// messages it read, within Block.
+func (c *StreamConsumer) Close() error {
+	atomic.StoreInt32(&c.closed, 1)
+	return nil
+}
@@ -0,0 +1,211 @@
+package redis_test
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"gopkg.in/redis.v5"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("StreamConsumer", func() {
+	var client *redis.Client
+
+	BeforeEach(func() {
+		client = redis.NewClient(redisOptions())
+		Expect(client.FlushDb().Err()).NotTo(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		Expect(client.Close()).NotTo(HaveOccurred())
+	})
+
+	// Runs a consumer until it received n messages and returns their IDs
+	consume := func(opt *redis.StreamConsumerOptions, n int, handler redis.StreamHandler) []string {
+		var mu sync.Mutex
+		var ids []string
+		done := make(chan struct{})
+		consumer := redis.NewStreamConsumer(client, opt, func(stream string, msg redis.XMessage) error {
+			mu.Lock()
+			defer mu.Unlock()
+			Expect(stream).To(Equal("events"))
+			ids = append(ids, msg.ID)
+			if len(ids) == n {
+				close(done)
+			}
+			return handler(stream, msg)
+		})
+
+		stopped := make(chan error)
+		go func() {
+			stopped <- consumer.Run()
+		}()
+		Eventually(done, 5*time.Second).Should(BeClosed())
+		Expect(consumer.Close()).NotTo(HaveOccurred())
+		Eventually(stopped, 5*time.Second).Should(Receive(BeNil()))
+		return ids
+	}
+
+	add := func(values ...string) []string {
+		var ids []string
+		for _, v := range values {
+			id, err := client.XAdd(&redis.XAddArgs{
+				Stream: "events",
+				Values: map[string]interface{}{"v": v},
+			}).Result()
+			Expect(err).NotTo(HaveOccurred())
+			ids = append(ids, id)
+		}
+		return ids
+	}
+
+	ok := func(string, redis.XMessage) error { return nil }
+
+	It("should create the group and acknowledge processed messages", func() {
+		old := add("old")
+
+		opt := &redis.StreamConsumerOptions{
+			Streams:     []string{"events"},
+			Group:       "group",
+			Consumer:    "consumer",
+			CreateGroup: true,
+			Block:       100 * time.Millisecond,
+		}
+		added := make(chan []string, 1)
+		go func() {
+			defer GinkgoRecover()
+			time.Sleep(200 * time.Millisecond)
+			added <- add("a", "b", "c")
+		}()
+		processed := consume(opt, 3, ok)
+		// Messages added before the group was created are not read
+		Expect(processed).To(Equal(<-added))
+		Expect(processed).NotTo(ContainElement(old[0]))
+
+		info, err := client.XPending("events", "group").Result()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(info.Count).To(Equal(int64(0)))
+	})
+
+	It("should reclaim failed messages", func() {
+		Expect(client.XGroupCreateMkStream("events", "group", "0").Err()).NotTo(HaveOccurred())
+		ids := add("a", "b")
+
+		opt := &redis.StreamConsumerOptions{
+			Streams:       []string{"events"},
+			Group:         "group",
+			Consumer:      "consumer",
+			Block:         100 * time.Millisecond,
+			ClaimIdle:     100 * time.Millisecond,
+			ClaimInterval: 50 * time.Millisecond,
+		}
+		var failed bool
+		processed := consume(opt, 3, func(_ string, msg redis.XMessage) error {
+			if msg.Values["v"] == "a" && !failed {
+				failed = true
+				return errors.New("failed")
+			}
+			return nil
+		})
+		Expect(processed).To(Equal([]string{ids[0], ids[1], ids[0]}))
+
+		info, err := client.XPending("events", "group").Result()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(info.Count).To(Equal(int64(0)))
+	})
+
+	It("should take over the messages of dead consumers", func() {
+		Expect(client.XGroupCreateMkStream("events", "group", "0").Err()).NotTo(HaveOccurred())
+		ids := add("a", "b", "c")
+
+		// Another consumer read the messages and died
+		err := client.XReadGroup(&redis.XReadGroupArgs{
+			Group:    "group",
+			Consumer: "dead",
+			Streams:  []string{"events", ">"},
+			Count:    2,
+		}).Err()
+		Expect(err).NotTo(HaveOccurred())
+		// and one was deleted meanwhile
+		Expect(client.XDel("events", ids[1]).Err()).NotTo(HaveOccurred())
+		time.Sleep(100 * time.Millisecond)
+
+		opt := &redis.StreamConsumerOptions{
+			Streams:   []string{"events"},
+			Group:     "group",
+			Consumer:  "consumer",
+			Block:     100 * time.Millisecond,
+			ClaimIdle: 50 * time.Millisecond,
+		}
+		Expect(consume(opt, 2, ok)).To(ConsistOf(ids[0], ids[2]))
+
+		info, err := client.XPending("events", "group").Result()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(info.Count).To(Equal(int64(0)))
+	})
+
+	It("should claim idle messages past a page of busy ones", func() {
+		Expect(client.XGroupCreateMkStream("events", "group", "0").Err()).NotTo(HaveOccurred())
+		ids := add("a", "b", "c")
+
+		err := client.XReadGroup(&redis.XReadGroupArgs{
+			Group:    "group",
+			Consumer: "dead",
+			Streams:  []string{"events", ">"},
+		}).Err()
+		Expect(err).NotTo(HaveOccurred())
+		time.Sleep(200 * time.Millisecond)
+		// The first messages are still being processed by another consumer
+		err = client.XClaim(&redis.XClaimArgs{
+			Stream:   "events",
+			Group:    "group",
+			Consumer: "busy",
+			Messages: ids[:2],
+		}).Err()
+		Expect(err).NotTo(HaveOccurred())
+
+		opt := &redis.StreamConsumerOptions{
+			Streams:   []string{"events"},
+			Group:     "group",
+			Consumer:  "consumer",
+			Count:     2,
+			Block:     100 * time.Millisecond,
+			ClaimIdle: 150 * time.Millisecond,
+		}
+		Expect(consume(opt, 1, ok)).To(Equal([]string{ids[2]}))
+	})
+
+	It("should drop messages delivered too many times", func() {
+		Expect(client.XGroupCreateMkStream("events", "group", "0").Err()).NotTo(HaveOccurred())
+		add("poison")
+
+		var mu sync.Mutex
+		var deliveries int
+		consumer := redis.NewStreamConsumer(client, &redis.StreamConsumerOptions{
+			Streams:       []string{"events"},
+			Group:         "group",
+			Consumer:      "consumer",
+			Block:         50 * time.Millisecond,
+			ClaimIdle:     10 * time.Millisecond,
+			ClaimInterval: 10 * time.Millisecond,
+			MaxDeliveries: 2,
+		}, func(string, redis.XMessage) error {
+			mu.Lock()
+			deliveries++
+			mu.Unlock()
+			return errors.New("poison")
+		})
+		go consumer.Run()
+		defer consumer.Close()
+
+		Eventually(func() int64 {
+			return client.XPending("events", "group").Val().Count
+		}, 5*time.Second).Should(Equal(int64(0)))
+		mu.Lock()
+		Expect(deliveries).To(Equal(2))
+		mu.Unlock()
+	})
+})